@@ -0,0 +1,217 @@
+package accesskey
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tigrisdata/tigrisfs/log"
+)
+
+var adminLog = log.GetLogger("accesskey-admin")
+
+// AdminConfig configures AdminServer's listener. By default it listens on a
+// unix socket (SocketPath); setting Addr instead switches to TCP, which then
+// requires CAFile/CertFile/KeyFile so the endpoint is only ever reachable
+// over mutual TLS.
+type AdminConfig struct {
+	SocketPath string
+	Addr       string
+	CAFile     string
+	CertFile   string
+	KeyFile    string
+}
+
+// AdminServer exposes CRUD over a Service as a small admin-only HTTP API:
+//
+//	GET    /keys             list
+//	POST   /keys             generate, or import if the body sets AccessKeyId/SecretAccessKey
+//	GET    /keys/{id}        get
+//	DELETE /keys/{id}        delete
+//	POST   /keys/{id}/enable
+//	POST   /keys/{id}/disable
+type AdminServer struct {
+	cfg AdminConfig
+	svc Service
+}
+
+// NewAdminServer validates cfg and returns an AdminServer ready to Start.
+func NewAdminServer(cfg AdminConfig, svc Service) (*AdminServer, error) {
+	if svc == nil {
+		return nil, errors.New("accesskey: service is required")
+	}
+	if cfg.SocketPath == "" && cfg.Addr == "" {
+		return nil, errors.New("accesskey: either SocketPath or Addr must be configured")
+	}
+	return &AdminServer{cfg: cfg, svc: svc}, nil
+}
+
+// Start listens on the configured socket/address and serves until the
+// listener or the HTTP server fails.
+func (a *AdminServer) Start() error {
+	lis, err := a.listen()
+	if err != nil {
+		return err
+	}
+	adminLog.Infof("listening on %s", lis.Addr())
+	return http.Serve(lis, a)
+}
+
+func (a *AdminServer) listen() (net.Listener, error) {
+	if a.cfg.Addr == "" {
+		_ = os.Remove(a.cfg.SocketPath)
+		return net.Listen("unix", a.cfg.SocketPath)
+	}
+
+	if a.cfg.CAFile == "" || a.cfg.CertFile == "" || a.cfg.KeyFile == "" {
+		return nil, errors.New("accesskey: TCP admin endpoint requires CAFile, CertFile and KeyFile for mTLS")
+	}
+	caBytes, err := os.ReadFile(a.cfg.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("accesskey: read ca file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caBytes) {
+		return nil, errors.New("accesskey: ca file contains no valid PEM certificates")
+	}
+	cert, err := tls.LoadX509KeyPair(a.cfg.CertFile, a.cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("accesskey: load cert/key pair: %w", err)
+	}
+
+	return tls.Listen("tcp", a.cfg.Addr, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+}
+
+func (a *AdminServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/keys"), "/")
+
+	switch {
+	case path == "" && r.Method == http.MethodGet:
+		a.list(w)
+	case path == "" && r.Method == http.MethodPost:
+		a.create(w, r)
+	case strings.HasSuffix(path, "/enable") && r.Method == http.MethodPost:
+		a.setEnabled(w, strings.TrimSuffix(path, "/enable"), true)
+	case strings.HasSuffix(path, "/disable") && r.Method == http.MethodPost:
+		a.setEnabled(w, strings.TrimSuffix(path, "/disable"), false)
+	case path != "" && r.Method == http.MethodGet:
+		a.get(w, path)
+	case path != "" && r.Method == http.MethodDelete:
+		a.delete(w, path)
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+func (a *AdminServer) list(w http.ResponseWriter) {
+	keys, err := a.svc.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+type createKeyRequest struct {
+	AccessKeyId     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	ExpiresAt       time.Time `json:"ExpiresAt"`
+	AllowedPrefixes []string  `json:"AllowedPrefixes"`
+	AllowedActions  []string  `json:"AllowedActions"`
+}
+
+func (a *AdminServer) create(w http.ResponseWriter, r *http.Request) {
+	var req createKeyRequest
+	if r.Body != nil {
+		defer r.Body.Close()
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if req.AccessKeyId != "" {
+		key := AccessKey{
+			AccessKeyId:     req.AccessKeyId,
+			SecretAccessKey: req.SecretAccessKey,
+			ExpiresAt:       req.ExpiresAt,
+			Enabled:         true,
+			AllowedPrefixes: req.AllowedPrefixes,
+			AllowedActions:  req.AllowedActions,
+		}
+		if err := a.svc.Put(key); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusCreated, key)
+		return
+	}
+
+	key, err := a.svc.Generate(GenerateOptions{
+		ExpiresAt:       req.ExpiresAt,
+		AllowedPrefixes: req.AllowedPrefixes,
+		AllowedActions:  req.AllowedActions,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, key)
+}
+
+func (a *AdminServer) get(w http.ResponseWriter, id string) {
+	key, err := a.svc.Get(id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, key)
+}
+
+func (a *AdminServer) delete(w http.ResponseWriter, id string) {
+	if err := a.svc.Delete(id); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *AdminServer) setEnabled(w http.ResponseWriter, id string, enabled bool) {
+	var err error
+	if enabled {
+		err = a.svc.Enable(id)
+	} else {
+		err = a.svc.Disable(id)
+	}
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, ErrNotFound) {
+		status = http.StatusNotFound
+	}
+	http.Error(w, err.Error(), status)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,148 @@
+package accesskey
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// bucketKeys is the single bbolt bucket access keys are stored in, keyed by
+// AccessKeyId.
+const bucketKeys = "access_keys"
+
+// BoltStore implements Service backed by a bbolt file under the tigrisfs
+// state directory, so keys survive a restart.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore creates (or reopens) a bbolt-backed key store at path.
+func OpenBoltStore(path string) (*BoltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("accesskey: create state dir: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 100 * time.Millisecond})
+	if err != nil {
+		return nil, fmt.Errorf("accesskey: open bbolt: %w", err)
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucketKeys))
+		return err
+	}); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("accesskey: create bucket: %w", err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Close releases the underlying database handle.
+func (s *BoltStore) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+func (s *BoltStore) Generate(opts GenerateOptions) (*AccessKey, error) {
+	id, err := newAccessKeyId()
+	if err != nil {
+		return nil, fmt.Errorf("accesskey: generate id: %w", err)
+	}
+	secret, err := newSecretAccessKey()
+	if err != nil {
+		return nil, fmt.Errorf("accesskey: generate secret: %w", err)
+	}
+
+	key := &AccessKey{
+		AccessKeyId:     id,
+		SecretAccessKey: secret,
+		CreatedAt:       time.Now(),
+		ExpiresAt:       opts.ExpiresAt,
+		Enabled:         true,
+		AllowedPrefixes: opts.AllowedPrefixes,
+		AllowedActions:  opts.AllowedActions,
+	}
+	if err := s.put(*key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (s *BoltStore) Put(key AccessKey) error {
+	if key.AccessKeyId == "" {
+		return errors.New("accesskey: access key id must not be empty")
+	}
+	if key.SecretAccessKey == "" {
+		return errors.New("accesskey: secret access key must not be empty")
+	}
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+	return s.put(key)
+}
+
+func (s *BoltStore) put(key AccessKey) error {
+	encoded, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("accesskey: encode key: %w", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(bucketKeys)).Put([]byte(key.AccessKeyId), encoded)
+	})
+}
+
+func (s *BoltStore) Get(id string) (*AccessKey, error) {
+	var key AccessKey
+	err := s.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket([]byte(bucketKeys)).Get([]byte(id))
+		if raw == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(raw, &key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (s *BoltStore) List() ([]*AccessKey, error) {
+	var keys []*AccessKey
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(bucketKeys)).ForEach(func(_, raw []byte) error {
+			var key AccessKey
+			if err := json.Unmarshal(raw, &key); err != nil {
+				return err
+			}
+			keys = append(keys, &key)
+			return nil
+		})
+	})
+	return keys, err
+}
+
+func (s *BoltStore) setEnabled(id string, enabled bool) error {
+	key, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	key.Enabled = enabled
+	return s.put(*key)
+}
+
+func (s *BoltStore) Enable(id string) error  { return s.setEnabled(id, true) }
+func (s *BoltStore) Disable(id string) error { return s.setEnabled(id, false) }
+
+func (s *BoltStore) Delete(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(bucketKeys)).Delete([]byte(id))
+	})
+}
@@ -0,0 +1,194 @@
+// Copyright 2021 Yandex LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// chunkWriterGrowEveryParts is how many parts s3ChunkWriter uploads at the
+// current part size before doubling it, the way rclone's S3 OpenChunkWriter
+// grows its chunk size: most streamed writes are small, so staying at the
+// minimum part size keeps memory low, while a write that turns out to be
+// large ramps up quickly enough to stay under chunkWriterMaxParts.
+const chunkWriterGrowEveryParts = 100
+
+// chunkWriterMaxPartSize caps the geometric growth so that even a write that
+// never stops uploads at most chunkWriterMaxParts parts without exceeding
+// S3's 5 TiB object size limit (10000 * 512MiB is comfortably under that).
+const chunkWriterMaxPartSize = 512 * 1024 * 1024
+
+// chunkWriterMaxParts mirrors S3's hard 10000-parts-per-upload limit.
+const chunkWriterMaxParts = 10000
+
+// ChunkWriterOptions configures OpenChunkWriter. All fields are optional.
+type ChunkWriterOptions struct {
+	ContentType   *string
+	Metadata      map[string]*string
+	Tags          map[string]string
+	RetentionMode string
+	RetainUntil   *time.Time
+	LegalHold     bool
+}
+
+// ChunkWriter accepts a stream of bytes of unknown total length and uploads
+// it as a single object. Callers must call either Close, to finalize the
+// object, or Abort, to discard it; writing after either is an error.
+type ChunkWriter interface {
+	io.Writer
+	Close() error
+	Abort() error
+}
+
+// OpenChunkWriter returns a ChunkWriter for key, following the rclone
+// OpenChunkWriter design: rather than requiring the caller to know the final
+// size up front (as PutBlob and MultipartBlobBegin's single-part-size-profile
+// callers do), it starts a multipart upload and buffers just enough to
+// satisfy S3's 5 MiB minimum part size, uploading a part whenever the buffer
+// fills and growing the part size geometrically as the object grows. This is
+// what unlocks efficient streaming writes for tools like `tar | cat >
+// mount/file`, where the FS layer never learns the final size until EOF.
+func (s *S3Backend) OpenChunkWriter(key string, opts *ChunkWriterOptions) (ChunkWriter, error) {
+	if opts == nil {
+		opts = &ChunkWriterOptions{}
+	}
+
+	commit, err := s.MultipartBlobBegin(&MultipartBlobBeginInput{
+		Key:           key,
+		ContentType:   opts.ContentType,
+		Metadata:      opts.Metadata,
+		Tags:          opts.Tags,
+		RetentionMode: opts.RetentionMode,
+		RetainUntil:   opts.RetainUntil,
+		LegalHold:     opts.LegalHold,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("open chunk writer: %w", err)
+	}
+
+	startSize := uint64(s3EmulatedPatchMinPartSize)
+	if len(s.flags.PartSizes) > 0 {
+		startSize = s.flags.PartSizes[0].PartSize
+	}
+
+	return &s3ChunkWriter{s: s, commit: commit, partSize: startSize}, nil
+}
+
+// s3ChunkWriter is the ChunkWriter returned by OpenChunkWriter. buf holds
+// bytes written so far that haven't reached partSize yet; Write drains it a
+// part at a time as it fills, and Close flushes whatever's left as the final
+// (possibly undersized) part.
+type s3ChunkWriter struct {
+	s      *S3Backend
+	commit *MultipartBlobCommitInput
+
+	buf            bytes.Buffer
+	partSize       uint64
+	partsSinceGrow int
+	partNumber     uint32
+	closed         bool
+}
+
+func (w *s3ChunkWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("core: write to closed chunk writer")
+	}
+
+	n, _ := w.buf.Write(p)
+	for uint64(w.buf.Len()) >= w.partSize && w.partNumber < chunkWriterMaxParts-1 {
+		if err := w.flushPart(int(w.partSize)); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// flushPart uploads the next size buffered bytes as the next part. The
+// slice handed to MultipartBlobAdd must outlive and be independent of buf,
+// since buf.Next's result is only valid until the buffer's next mutation.
+func (w *s3ChunkWriter) flushPart(size int) error {
+	data := append([]byte(nil), w.buf.Next(size)...)
+	w.partNumber++
+
+	out, err := w.s.MultipartBlobAdd(&MultipartBlobAddInput{
+		Commit:     w.commit,
+		PartNumber: w.partNumber,
+		Body:       bytes.NewReader(data),
+	})
+	if err != nil {
+		return fmt.Errorf("chunk writer: upload part %d: %w", w.partNumber, err)
+	}
+
+	w.commit.Parts[w.partNumber-1] = out.PartId
+	if w.commit.ChecksumCRC32C != nil {
+		w.commit.ChecksumCRC32C[w.partNumber-1] = out.ChecksumCRC32C
+	}
+	if w.commit.ChecksumSHA256 != nil {
+		w.commit.ChecksumSHA256[w.partNumber-1] = out.ChecksumSHA256
+	}
+
+	w.partsSinceGrow++
+	if w.partsSinceGrow >= chunkWriterGrowEveryParts && w.partSize < chunkWriterMaxPartSize {
+		w.partSize *= 2
+		if w.partSize > chunkWriterMaxPartSize {
+			w.partSize = chunkWriterMaxPartSize
+		}
+		w.partsSinceGrow = 0
+	}
+	return nil
+}
+
+// Close flushes any buffered remainder as the final part and completes the
+// multipart upload. It's safe to call on a writer that never received any
+// Write calls, producing a zero-byte object.
+func (w *s3ChunkWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if w.buf.Len() > 0 || w.partNumber == 0 {
+		if err := w.flushPart(w.buf.Len()); err != nil {
+			return err
+		}
+	}
+
+	w.commit.NumParts = w.partNumber
+	_, err := w.s.MultipartBlobCommit(w.commit)
+	return err
+}
+
+// Abort discards the multipart upload instead of completing it, for a
+// caller that hit a write error upstream and wants the partial object
+// cleaned up rather than finalized.
+func (w *s3ChunkWriter) Abort() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	_, err := w.s.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+		Bucket:   &w.s.bucket,
+		Key:      w.commit.Key,
+		UploadId: w.commit.UploadId,
+	})
+	return err
+}
@@ -25,17 +25,27 @@ import (
 	"github.com/valandreev/tigrisfs/core/cfg"
 	"github.com/valandreev/tigrisfs/log"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
 )
 
-const OUTSTAGE_TIMEOUT = 10 * time.Second
+const (
+	OUTSTAGE_TIMEOUT = 10 * time.Second
+
+	healthProbeInterval = 5 * time.Second
+	healthProbeTimeout  = 2 * time.Second
+)
 
 var connsLog = log.GetLogger("conns")
 
 type Peer struct {
-	mu      sync.RWMutex
-	address string
-	conn    *grpc.ClientConn
+	mu                 sync.RWMutex
+	address            string
+	serverNameOverride string
+	conn               *grpc.ClientConn
+	generation         uint64
+	healthy            bool
 }
 
 type ConnPool struct {
@@ -46,13 +56,21 @@ type ConnPool struct {
 
 type Request func(ctx context.Context, conn *grpc.ClientConn) error
 
-func NewConnPool(flags *cfg.FlagStorage) *ConnPool {
+func NewConnPool(flags *cfg.FlagStorage) (*ConnPool, error) {
+	if err := validateClusterTLS(flags); err != nil {
+		return nil, err
+	}
+
 	id := NodeId(flags.ClusterMe.Id)
 
 	peers := make(map[NodeId]*Peer)
 	for _, node := range flags.ClusterPeers {
 		peers[NodeId(node.Id)] = &Peer{
-			address: node.Address,
+			address:            node.Address,
+			serverNameOverride: node.ServerNameOverride,
+			// Optimistic until the first health probe or dial tells us
+			// otherwise, so a cold pool doesn't skip every peer in Broad.
+			healthy: true,
 		}
 	}
 
@@ -60,7 +78,7 @@ func NewConnPool(flags *cfg.FlagStorage) *ConnPool {
 		flags: flags,
 		id:    id,
 		peers: peers,
-	}
+	}, nil
 }
 
 func (conns *ConnPool) Unary(
@@ -75,17 +93,39 @@ func (conns *ConnPool) UnaryConfiguarble(
 	makeRequst Request,
 	unmountOnError bool,
 ) (err error) {
-	if unmountOnError {
-		defer func() {
-			if err != nil {
-				go func() {
-					connsLog.Infof("error on request to %v umount", nodeId)
-					_ = TryUnmount(conns.flags.MountPoint)
-				}()
-			}
+	policy := retryPolicyFromFlags(conns.flags)
+	start := time.Now()
+
+	for attempt := 0; ; attempt++ {
+		err = conns.dialAndRequest(nodeId, makeRequst)
+		if err == nil {
+			return nil
+		}
+		if !isRetryableStatus(err) {
+			break
+		}
+		if attempt+1 >= policy.MaxAttempts || time.Since(start) >= policy.MaxElapsedTime {
+			connsLog.Infof("retry policy exhausted for request to %v after %d attempt(s): %v", nodeId, attempt+1, err)
+			break
+		}
+		connsLog.Infof("retryable error on request to %v (attempt %d): %v", nodeId, attempt+1, err)
+		time.Sleep(policy.next(attempt))
+	}
+
+	if err != nil && unmountOnError {
+		go func() {
+			connsLog.Infof("error on request to %v umount", nodeId)
+			_ = TryUnmount(conns.flags.MountPoint)
 		}()
 	}
 
+	return
+}
+
+// dialAndRequest performs a single attempt: dialing the peer if necessary
+// and issuing the request. On any error the cached connection is dropped so
+// the next attempt (by this call's retry loop, or a later call) dials fresh.
+func (conns *ConnPool) dialAndRequest(nodeId NodeId, makeRequst Request) (err error) {
 	peer := conns.peers[nodeId]
 	peer.mu.RLock()
 
@@ -93,20 +133,10 @@ func (conns *ConnPool) UnaryConfiguarble(
 		peer.mu.RUnlock()
 		peer.mu.Lock()
 		if peer.conn == nil {
-			var conn *grpc.ClientConn
-			conn, err = grpc.Dial(peer.address, //nolint:staticcheck
-				grpc.WithInsecure(),                //nolint:staticcheck
-				grpc.WithBlock(),                   //nolint:staticcheck
-				grpc.WithTimeout(OUTSTAGE_TIMEOUT), //nolint:staticcheck
-				grpc.WithChainUnaryInterceptor(
-					LogClientInterceptor,
-				),
-			)
-			if err != nil {
+			if err = conns.dialPeerLocked(nodeId, peer); err != nil {
 				peer.mu.Unlock()
 				return
 			}
-			peer.conn = conn
 		}
 		peer.mu.Unlock()
 		peer.mu.RLock()
@@ -127,6 +157,117 @@ func (conns *ConnPool) UnaryConfiguarble(
 	return
 }
 
+// dialPeerLocked dials nodeId non-blockingly (caller holds peer.mu for
+// writing) and starts the per-generation watch and health-probe goroutines
+// that keep peer.healthy and peer.conn current in the background, so callers
+// never pay a blocking dial (or OUTSTAGE_TIMEOUT) on the request path.
+func (conns *ConnPool) dialPeerLocked(nodeId NodeId, peer *Peer) error {
+	creds, err := buildClientCredentials(conns.flags, peer.serverNameOverride)
+	if err != nil {
+		return err
+	}
+
+	conn, err := grpc.Dial(peer.address, //nolint:staticcheck
+		grpcDialOption(creds),
+		grpc.WithChainUnaryInterceptor(
+			LogClientInterceptor,
+		),
+	)
+	if err != nil {
+		return err
+	}
+
+	peer.conn = conn
+	peer.healthy = true
+	peer.generation++
+	generation := peer.generation
+
+	go conns.watchPeer(nodeId, peer, conn, generation)
+	go conns.probePeer(nodeId, peer, conn, generation)
+
+	return nil
+}
+
+// watchPeer observes gRPC's connectivity state machine and marks the peer
+// unhealthy (and, on Shutdown, drops the cached connection) once it leaves
+// Ready, without blocking any request path on the transition.
+func (conns *ConnPool) watchPeer(nodeId NodeId, peer *Peer, conn *grpc.ClientConn, generation uint64) {
+	ctx := context.Background()
+	for {
+		state := conn.GetState()
+		if !conn.WaitForStateChange(ctx, state) {
+			return
+		}
+
+		peer.mu.Lock()
+		if peer.generation != generation {
+			peer.mu.Unlock()
+			return
+		}
+		newState := conn.GetState()
+		switch newState {
+		case connectivity.TransientFailure:
+			peer.healthy = false
+		case connectivity.Shutdown:
+			peer.healthy = false
+			peer.conn = nil
+			peer.mu.Unlock()
+			connsLog.Infof("connection to %v shut down", nodeId)
+			return
+		case connectivity.Ready:
+			peer.healthy = true
+		}
+		peer.mu.Unlock()
+	}
+}
+
+// probePeer periodically issues a grpc_health_v1 Check against the peer so
+// Broad can skip nodes known to be down instead of paying OUTSTAGE_TIMEOUT
+// per call.
+func (conns *ConnPool) probePeer(nodeId NodeId, peer *Peer, conn *grpc.ClientConn, generation uint64) {
+	client := grpc_health_v1.NewHealthClient(conn)
+	ticker := time.NewTicker(healthProbeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		peer.mu.RLock()
+		stale := peer.generation != generation
+		peer.mu.RUnlock()
+		if stale {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), healthProbeTimeout)
+		resp, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+		cancel()
+
+		peer.mu.Lock()
+		if peer.generation != generation {
+			peer.mu.Unlock()
+			return
+		}
+		peer.healthy = err == nil && resp.GetStatus() == grpc_health_v1.HealthCheckResponse_SERVING
+		peer.mu.Unlock()
+
+		if err != nil {
+			connsLog.Infof("health probe to %v failed: %v", nodeId, err)
+		}
+	}
+}
+
+// Health reports whether nodeId's connection is believed to be usable, based
+// on the most recent connectivity state transition or health probe. Unknown
+// peers are reported unhealthy.
+func (conns *ConnPool) Health(nodeId NodeId) bool {
+	peer, ok := conns.peers[nodeId]
+	if !ok {
+		return false
+	}
+	peer.mu.RLock()
+	defer peer.mu.RUnlock()
+	return peer.healthy
+}
+
 func (conns *ConnPool) Broad(
 	makeRequst Request,
 ) (errs map[NodeId]error) {
@@ -142,6 +283,13 @@ func (conns *ConnPool) BroadConfigurable(
 	wg := sync.WaitGroup{}
 	for nodeId := range conns.peers {
 		if nodeId != conns.id {
+			if !conns.Health(nodeId) {
+				connsLog.Infof("skipping broad request to %v: known unhealthy", nodeId)
+				mu.Lock()
+				errs[nodeId] = fmt.Errorf("peer %v is unhealthy", nodeId)
+				mu.Unlock()
+				continue
+			}
 			wg.Add(1)
 			go func(nodeId NodeId) {
 				err := conns.UnaryConfiguarble(nodeId, makeRequst, unmountOnError)
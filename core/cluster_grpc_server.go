@@ -35,13 +35,23 @@ type GrpcServer struct {
 	flags *cfg.FlagStorage
 }
 
-func NewGrpcServer(flags *cfg.FlagStorage) *GrpcServer {
-	return &GrpcServer{
-		Server: grpc.NewServer(grpc.ChainUnaryInterceptor(
-			LogServerInterceptor,
-		)),
-		flags: flags,
+func NewGrpcServer(flags *cfg.FlagStorage) (*GrpcServer, error) {
+	opts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(
+		LogServerInterceptor,
+	)}
+
+	creds, err := buildServerCredentials(flags)
+	if err != nil {
+		return nil, err
 	}
+	if creds != nil {
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	return &GrpcServer{
+		Server: grpc.NewServer(opts...),
+		flags:  flags,
+	}, nil
 }
 
 func (srv *GrpcServer) Start() error {
@@ -0,0 +1,113 @@
+// Copyright 2024 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+
+package core
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/valandreev/tigrisfs/core/cfg"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultRetryInitialInterval = 200 * time.Millisecond
+	defaultRetryMaxInterval     = 5 * time.Second
+	defaultRetryMultiplier      = 2.0
+	defaultRetryMaxElapsedTime  = 30 * time.Second
+	defaultRetryMaxAttempts     = 5
+)
+
+// RetryPolicy is an exponential backoff with jitter, scoped to a single
+// UnaryConfiguarble call. Attempts stop once either MaxAttempts or
+// MaxElapsedTime is reached, whichever comes first.
+type RetryPolicy struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	MaxElapsedTime  time.Duration
+	MaxAttempts     int
+}
+
+// retryPolicyFromFlags builds a RetryPolicy from cfg.FlagStorage, falling
+// back to sane defaults for any field left at its zero value.
+func retryPolicyFromFlags(flags *cfg.FlagStorage) RetryPolicy {
+	p := RetryPolicy{
+		InitialInterval: flags.ClusterRetryInitialInterval,
+		MaxInterval:     flags.ClusterRetryMaxInterval,
+		Multiplier:      flags.ClusterRetryMultiplier,
+		MaxElapsedTime:  flags.ClusterRetryMaxElapsedTime,
+		MaxAttempts:     flags.ClusterRetryMaxAttempts,
+	}
+	if p.InitialInterval <= 0 {
+		p.InitialInterval = defaultRetryInitialInterval
+	}
+	if p.MaxInterval <= 0 {
+		p.MaxInterval = defaultRetryMaxInterval
+	}
+	if p.Multiplier <= 0 {
+		p.Multiplier = defaultRetryMultiplier
+	}
+	if p.MaxElapsedTime <= 0 {
+		p.MaxElapsedTime = defaultRetryMaxElapsedTime
+	}
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaultRetryMaxAttempts
+	}
+	return p
+}
+
+// next returns the backoff duration to wait after the given failed attempt
+// (0-indexed) before the next one, with up to 20% jitter so concurrent peers
+// don't retry in lockstep.
+func (p RetryPolicy) next(attempt int) time.Duration {
+	interval := float64(p.InitialInterval)
+	for i := 0; i < attempt; i++ {
+		interval *= p.Multiplier
+		if interval > float64(p.MaxInterval) {
+			interval = float64(p.MaxInterval)
+			break
+		}
+	}
+	jitter := interval * 0.2 * (rand.Float64()*2 - 1)
+	d := time.Duration(interval + jitter)
+	if d < 0 {
+		d = 0
+	}
+	return d
+}
+
+// isRetryableStatus classifies a gRPC error as transient (worth retrying on
+// the same or a freshly-dialed connection) versus terminal. Non-gRPC errors
+// (e.g. dial failures) are treated as retryable since they are almost always
+// connectivity blips rather than a permanent rejection by the peer.
+func isRetryableStatus(err error) bool {
+	if err == nil {
+		return false
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return true
+	}
+	switch st.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2024 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+
+package core
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/valandreev/tigrisfs/core/cfg"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// buildClientCredentials turns the ClusterCA/Cert/Key flags into gRPC
+// transport credentials for dialing a peer. serverNameOverride, when
+// non-empty, overrides the SNI/certificate name check for that one peer
+// (for clusters behind a mesh or otherwise addressed by an IP). When no CA
+// is configured it falls back to the pool's existing insecure behaviour
+// rather than failing, so TLS stays opt-in.
+func buildClientCredentials(flags *cfg.FlagStorage, serverNameOverride string) (credentials.TransportCredentials, error) {
+	if flags.ClusterCAFile == "" {
+		return insecure.NewCredentials(), nil //nolint:staticcheck
+	}
+
+	tlsConfig, err := loadClusterTLSConfig(flags)
+	if err != nil {
+		return nil, err
+	}
+
+	override := flags.ClusterServerNameOverride
+	if serverNameOverride != "" {
+		override = serverNameOverride
+	}
+	if override != "" {
+		tlsConfig.ServerName = override
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// buildServerCredentials mirrors buildClientCredentials for the gRPC server
+// side, requiring a certificate/key pair in addition to the CA so mutual TLS
+// can be enforced against incoming peer connections.
+func buildServerCredentials(flags *cfg.FlagStorage) (credentials.TransportCredentials, error) {
+	if flags.ClusterCAFile == "" {
+		return nil, nil
+	}
+	if flags.ClusterCertFile == "" || flags.ClusterKeyFile == "" {
+		return nil, fmt.Errorf("cluster tls: cluster_cert_file and cluster_key_file are required to serve TLS")
+	}
+
+	tlsConfig, err := loadClusterTLSConfig(flags)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	tlsConfig.ClientCAs = tlsConfig.RootCAs
+
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// loadClusterTLSConfig parses the CA file and, if present, a client/server
+// certificate pair, failing fast with a descriptive error rather than
+// surfacing a cryptic TLS handshake failure on the first real dial.
+func loadClusterTLSConfig(flags *cfg.FlagStorage) (*tls.Config, error) {
+	caBytes, err := os.ReadFile(flags.ClusterCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("cluster tls: read ca file %s: %w", flags.ClusterCAFile, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caBytes) {
+		return nil, fmt.Errorf("cluster tls: ca file %s contains no valid PEM certificates", flags.ClusterCAFile)
+	}
+
+	tlsConfig := &tls.Config{
+		RootCAs:            pool,
+		InsecureSkipVerify: flags.ClusterInsecureSkipVerify, //nolint:gosec
+	}
+
+	if flags.ClusterCertFile != "" || flags.ClusterKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(flags.ClusterCertFile, flags.ClusterKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cluster tls: load cert/key pair (%s, %s): %w", flags.ClusterCertFile, flags.ClusterKeyFile, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// validateClusterTLS fails fast at startup if the configured TLS material is
+// malformed, instead of letting it surface later as a per-request dial
+// failure on whichever peer happens to be contacted first.
+func validateClusterTLS(flags *cfg.FlagStorage) error {
+	if flags.ClusterCAFile == "" {
+		return nil
+	}
+	if _, err := loadClusterTLSConfig(flags); err != nil {
+		return err
+	}
+	for _, node := range flags.ClusterPeers {
+		if _, err := buildClientCredentials(flags, node.ServerNameOverride); err != nil {
+			return fmt.Errorf("cluster tls: peer %s: %w", node.Id, err)
+		}
+	}
+	return nil
+}
+
+func grpcDialOption(creds credentials.TransportCredentials) grpc.DialOption {
+	return grpc.WithTransportCredentials(creds)
+}
@@ -0,0 +1,210 @@
+// Copyright 2024 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/valandreev/tigrisfs/core/pb"
+	"github.com/valandreev/tigrisfs/log"
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
+	"github.com/valandreev/tigrisfs/pkg/cache/uploader"
+)
+
+var uploaderGrpcLog = log.GetLogger("uploader-grpc")
+
+// uploadEventQueueSize bounds how many pending events a slow Watch stream
+// buffers before the broadcaster starts dropping events for it, so one
+// stalled subscriber can't block the uploader's processRecord loop.
+const uploadEventQueueSize = 32
+
+// UploaderServer exposes a local uploader.Uploader and its
+// index.CacheIndex over gRPC, so a remote node (or an admin CLI) can
+// enqueue, inspect, cancel, and tail upload progress without sharing the
+// underlying CacheIndex directly. Register it on a GrpcServer with
+// pb.RegisterUploaderServiceServer.
+type UploaderServer struct {
+	pb.UnimplementedUploaderServiceServer
+
+	up  *uploader.Uploader
+	idx index.CacheIndex
+
+	mu   sync.Mutex
+	subs map[string][]chan *pb.UploadEvent
+}
+
+// NewUploaderServer wraps up/idx for gRPC exposure. The returned server
+// also implements uploader.AuditSink (see AuditSink); pass it to
+// uploader.New via uploader.WithAuditSink so Watch observes up's
+// lifecycle events.
+func NewUploaderServer(up *uploader.Uploader, idx index.CacheIndex) *UploaderServer {
+	return &UploaderServer{
+		up:   up,
+		idx:  idx,
+		subs: make(map[string][]chan *pb.UploadEvent),
+	}
+}
+
+// AuditSink returns s as a uploader.AuditSink, for passing to
+// uploader.WithAuditSink when constructing the Uploader s wraps.
+func (s *UploaderServer) AuditSink() uploader.AuditSink {
+	return s
+}
+
+func (s *UploaderServer) Enqueue(ctx context.Context, req *pb.UploadRequest) (*pb.UploadAck, error) {
+	record, err := s.up.Submit(ctx, index.UploadRecord{
+		Path:          req.Path,
+		Offset:        req.Offset,
+		Length:        req.Length,
+		KeyGeneration: req.KeyGeneration,
+		MaxAttempts:   int(req.MaxAttempts),
+		Status:        index.UploadStatusQueued,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.UploadAck{Id: record.ID}, nil
+}
+
+func (s *UploaderServer) Get(ctx context.Context, req *pb.UploadID) (*pb.UploadRecord, error) {
+	records, err := s.idx.ListUploads(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		if r.ID == req.Id {
+			return toProtoUploadRecord(r), nil
+		}
+	}
+	return nil, fmt.Errorf("uploader grpc: upload %s not found", req.Id)
+}
+
+func (s *UploaderServer) List(req *pb.ListRequest, stream pb.UploaderService_ListServer) error {
+	records, err := s.idx.ListUploads(stream.Context())
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		if req.Status != "" && string(r.Status) != req.Status {
+			continue
+		}
+		if err := stream.Send(toProtoUploadRecord(r)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *UploaderServer) Cancel(ctx context.Context, req *pb.UploadID) (*pb.Ack, error) {
+	if _, err := s.up.Cancel(ctx, req.Id); err != nil {
+		return nil, err
+	}
+	return &pb.Ack{Ok: true}, nil
+}
+
+func (s *UploaderServer) Watch(req *pb.UploadID, stream pb.UploaderService_WatchServer) error {
+	sub := s.subscribe(req.Id)
+	defer s.unsubscribe(req.Id, sub)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case event, ok := <-sub:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+			if event.Event == "completed" || event.Event == "failed" {
+				return nil
+			}
+		}
+	}
+}
+
+func (s *UploaderServer) subscribe(id string) chan *pb.UploadEvent {
+	ch := make(chan *pb.UploadEvent, uploadEventQueueSize)
+	s.mu.Lock()
+	s.subs[id] = append(s.subs[id], ch)
+	s.mu.Unlock()
+	return ch
+}
+
+func (s *UploaderServer) unsubscribe(id string, ch chan *pb.UploadEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chans := s.subs[id]
+	for i, c := range chans {
+		if c == ch {
+			s.subs[id] = append(chans[:i], chans[i+1:]...)
+			break
+		}
+	}
+	if len(s.subs[id]) == 0 {
+		delete(s.subs, id)
+	}
+	close(ch)
+}
+
+// Audit implements uploader.AuditSink, fanning out each event to any
+// Watch streams subscribed to its upload ID. A subscriber whose buffer is
+// full is skipped rather than blocked, since a stalled gRPC client must
+// never hold up the uploader's own processRecord loop.
+func (s *UploaderServer) Audit(event uploader.AuditEvent) {
+	s.mu.Lock()
+	chans := append([]chan *pb.UploadEvent(nil), s.subs[event.UploadID]...)
+	s.mu.Unlock()
+	if len(chans) == 0 {
+		return
+	}
+
+	pe := &pb.UploadEvent{
+		Event:      event.Event,
+		UploadId:   event.UploadID,
+		Path:       event.Path,
+		Etag:       event.ETag,
+		Attempt:    int32(event.Attempt),
+		Bytes:      event.Bytes,
+		DurationMs: event.Duration.Milliseconds(),
+		Reason:     event.Reason,
+	}
+	for _, ch := range chans {
+		select {
+		case ch <- pe:
+		default:
+			uploaderGrpcLog.Warnf("watch subscriber for upload %s is slow, dropping event %s", event.UploadID, event.Event)
+		}
+	}
+}
+
+func toProtoUploadRecord(r index.UploadRecord) *pb.UploadRecord {
+	return &pb.UploadRecord{
+		Id:               r.ID,
+		Path:             r.Path,
+		Offset:           r.Offset,
+		Length:           r.Length,
+		Status:           string(r.Status),
+		Attempts:         int32(r.Attempts),
+		LastError:        r.LastError,
+		BytesTransferred: r.BytesTransferred,
+	}
+}
@@ -0,0 +1,89 @@
+// Package directmount implements a direct FUSE mount — one that opens
+// /dev/fuse and calls mount(2) itself instead of shelling out to the
+// fusermount3 helper, the way go-fuse's DirectMount does. Mount and Unmount
+// (mount_linux.go) cover the actual syscall lifecycle: opening /dev/fuse,
+// issuing mount(2) with the option string BuildOptionsString builds,
+// including the fsname= escaping the request calls out specifically since
+// FUSE's option grammar treats both ',' and '\' as special, completing the
+// kernel's FUSE_INIT handshake via fusewrite.PerformInitHandshake before
+// returning, and tearing the mount down with umount2(MNT_DETACH) on
+// shutdown.
+//
+// What this package does not do is wire that lifecycle into a --direct-mount
+// CLI flag or the TestFuse mount-path matrix: both live on the
+// core.Goofys/MountedFS/cfg.FlagStorage mount path, which isn't present in
+// this snapshot of the tree (main.go and the test suite reference them, but
+// their implementation files aren't checked in here, and core/cfg itself
+// only has its logger half). Wiring them in is mechanical once that path
+// exists — call Mount with the fd and options this package already builds,
+// hand the returned *os.File to the FUSE server loop, and call Unmount from
+// the shutdown handler — but there is no flag parser or server loop in this
+// tree to hang that wiring off of today.
+package directmount
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Options are the fields go-fuse's DirectMount and this tree's eventual
+// direct-mount path both need to build the mount(2) data string.
+type Options struct {
+	// FD is the file descriptor returned by opening /dev/fuse.
+	FD int
+	// RootMode is the st_mode of the mount point's root inode.
+	RootMode uint32
+	// UserID and GroupID are the uid/gid the kernel should present file
+	// ownership as by default (user_id=/group_id=).
+	UserID  uint32
+	GroupID uint32
+	// FSName is shown as the mounted device name (e.g. in `mount` output
+	// and /proc/mounts); it's free text supplied by the caller — often a
+	// bucket name — and must be escaped since it can contain ',' or '\'.
+	FSName string
+	// Subtype, if set, is appended as subtype=, distinguishing this FUSE
+	// client in tools that key off it (e.g. `df -T`).
+	Subtype string
+	// RequestedMaxWrite is the MaxWrite Mount asks fusewrite.Negotiate for
+	// during the FUSE_INIT handshake; <= 0 means fusewrite.DefaultMaxWrite.
+	// Unlike the fields above, this plays no part in BuildOptionsString:
+	// max_write is negotiated in the INIT reply, not the mount(2) data
+	// argument.
+	RequestedMaxWrite int
+}
+
+// BuildOptionsString renders opts as the comma-separated option string
+// mount(2) expects in its data argument for an FUSE mount, e.g.
+// "fd=7,rootmode=40000,user_id=1000,group_id=1000,fsname=my\\,bucket".
+func BuildOptionsString(opts Options) string {
+	parts := []string{
+		fmt.Sprintf("fd=%d", opts.FD),
+		fmt.Sprintf("rootmode=%o", opts.RootMode),
+		fmt.Sprintf("user_id=%d", opts.UserID),
+		fmt.Sprintf("group_id=%d", opts.GroupID),
+	}
+	if opts.FSName != "" {
+		parts = append(parts, "fsname="+escapeOptionValue(opts.FSName))
+	}
+	if opts.Subtype != "" {
+		parts = append(parts, "subtype="+escapeOptionValue(opts.Subtype))
+	}
+	return strings.Join(parts, ",")
+}
+
+// escapeOptionValue backslash-escapes the two characters that are special
+// in a FUSE mount option string — ',' (the option separator) and '\'
+// itself (the escape character) — so a bucket name containing either
+// survives as a single option value instead of truncating it or breaking
+// the option list.
+func escapeOptionValue(v string) string {
+	var b strings.Builder
+	b.Grow(len(v))
+	for _, r := range v {
+		if r == ',' || r == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
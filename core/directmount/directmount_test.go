@@ -0,0 +1,72 @@
+package directmount_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/valandreev/tigrisfs/core/directmount"
+)
+
+func TestBuildOptionsStringFormatsCoreFields(t *testing.T) {
+	got := directmount.BuildOptionsString(directmount.Options{
+		FD:       7,
+		RootMode: 0o40000,
+		UserID:   1000,
+		GroupID:  1000,
+	})
+	want := "fd=7,rootmode=40000,user_id=1000,group_id=1000"
+	if got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestBuildOptionsStringEscapesCommaInFSName(t *testing.T) {
+	got := directmount.BuildOptionsString(directmount.Options{
+		FD:       3,
+		RootMode: 0o40000,
+		FSName:   "my,bucket",
+	})
+	if !strings.HasSuffix(got, `fsname=my\,bucket`) {
+		t.Fatalf("expected escaped comma in fsname, got %q", got)
+	}
+}
+
+func TestBuildOptionsStringEscapesBackslashInFSName(t *testing.T) {
+	got := directmount.BuildOptionsString(directmount.Options{
+		FD:       3,
+		RootMode: 0o40000,
+		FSName:   `weird\bucket`,
+	})
+	if !strings.HasSuffix(got, `fsname=weird\\bucket`) {
+		t.Fatalf("expected escaped backslash in fsname, got %q", got)
+	}
+}
+
+func TestBuildOptionsStringEscapesCombinedCommaAndBackslash(t *testing.T) {
+	got := directmount.BuildOptionsString(directmount.Options{
+		FD:       3,
+		RootMode: 0o40000,
+		FSName:   `a,b\c`,
+	})
+	if !strings.HasSuffix(got, `fsname=a\,b\\c`) {
+		t.Fatalf("expected both characters escaped in order, got %q", got)
+	}
+}
+
+func TestBuildOptionsStringOmitsEmptyFSNameAndSubtype(t *testing.T) {
+	got := directmount.BuildOptionsString(directmount.Options{FD: 3, RootMode: 0o40000})
+	if strings.Contains(got, "fsname=") || strings.Contains(got, "subtype=") {
+		t.Fatalf("expected no fsname/subtype options when unset, got %q", got)
+	}
+}
+
+func TestBuildOptionsStringIncludesSubtype(t *testing.T) {
+	got := directmount.BuildOptionsString(directmount.Options{
+		FD:       3,
+		RootMode: 0o40000,
+		Subtype:  "tigrisfs",
+	})
+	if !strings.HasSuffix(got, "subtype=tigrisfs") {
+		t.Fatalf("expected subtype option, got %q", got)
+	}
+}
@@ -0,0 +1,78 @@
+//go:build linux
+// +build linux
+
+package directmount
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/valandreev/tigrisfs/core/fusewrite"
+)
+
+// devFusePath is the character device the kernel's FUSE driver exposes for
+// userspace filesystems to open and hand to mount(2) as the fd= option.
+const devFusePath = "/dev/fuse"
+
+// OpenDevFuse opens /dev/fuse, returning the *os.File whose fd belongs in
+// Options.FD for BuildOptionsString and is then passed to Mount.
+func OpenDevFuse() (*os.File, error) {
+	f, err := os.OpenFile(devFusePath, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("directmount: open %s: %w", devFusePath, err)
+	}
+	return f, nil
+}
+
+// Mount opens /dev/fuse, issues the mount(2) syscall directly against
+// target the way go-fuse's DirectMount does instead of shelling out to the
+// fusermount3 helper, and then completes the kernel's FUSE_INIT handshake —
+// the first request on any new mount's fd — negotiating write-path sizing
+// via fusewrite.PerformInitHandshake. opts.FD is overwritten with the fd
+// OpenDevFuse returns; callers only need to fill in RootMode, UserID,
+// GroupID, FSName, Subtype and RequestedMaxWrite.
+//
+// On success, the returned *os.File is the open /dev/fuse fd the caller
+// must keep open and wire into its FUSE server read loop for every request
+// after INIT: closing it (or letting it get garbage collected without
+// Close) tears the mount down from the kernel's side the same as Unmount
+// would. The returned Negotiated is what the INIT reply committed to, so
+// the caller can size its own per-request read buffer to match MaxWrite.
+func Mount(target string, opts Options) (*os.File, fusewrite.Negotiated, error) {
+	dev, err := OpenDevFuse()
+	if err != nil {
+		return nil, fusewrite.Negotiated{}, err
+	}
+
+	opts.FD = int(dev.Fd())
+	data := BuildOptionsString(opts)
+
+	if err := unix.Mount("fuse", target, "fuse", 0, data); err != nil {
+		dev.Close()
+		return nil, fusewrite.Negotiated{}, fmt.Errorf("directmount: mount %s: %w", target, err)
+	}
+
+	negotiated, err := fusewrite.PerformInitHandshake(dev, opts.RequestedMaxWrite)
+	if err != nil {
+		dev.Close()
+		unix.Unmount(target, unix.MNT_DETACH)
+		return nil, fusewrite.Negotiated{}, fmt.Errorf("directmount: init handshake: %w", err)
+	}
+
+	return dev, negotiated, nil
+}
+
+// Unmount detaches the FUSE mount at target via umount2(MNT_DETACH): the
+// mount point disappears from the namespace immediately, but the
+// filesystem stays mounted until the last reference (e.g. a process with
+// its cwd inside it) drops, at which point the kernel releases the /dev/fuse
+// fd's read loop with ENODEV. This is what lets shutdown proceed without
+// having to first hunt down and kill everything still using the mount.
+func Unmount(target string) error {
+	if err := unix.Unmount(target, unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("directmount: unmount %s: %w", target, err)
+	}
+	return nil
+}
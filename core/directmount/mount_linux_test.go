@@ -0,0 +1,52 @@
+//go:build linux
+// +build linux
+
+package directmount_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/valandreev/tigrisfs/core/directmount"
+)
+
+// TestMountUnmountRoundTrip exercises the real open(/dev/fuse)+mount(2) and
+// umount2(MNT_DETACH) path against an empty directory. It requires
+// CAP_SYS_ADMIN (or running as root) and a kernel with /dev/fuse, neither of
+// which is guaranteed in an ordinary test sandbox, so it skips rather than
+// fails when either is unavailable.
+func TestMountUnmountRoundTrip(t *testing.T) {
+	if _, err := os.Stat("/dev/fuse"); err != nil {
+		t.Skipf("/dev/fuse not available: %v", err)
+	}
+
+	target := t.TempDir()
+
+	dev, negotiated, err := directmount.Mount(target, directmount.Options{
+		RootMode: 0o40000,
+		UserID:   uint32(os.Getuid()),
+		GroupID:  uint32(os.Getgid()),
+		FSName:   "directmount-test",
+	})
+	if err != nil {
+		t.Skipf("mount(2) unavailable in this environment: %v", err)
+	}
+	defer dev.Close()
+
+	if negotiated.MaxWrite <= 0 {
+		t.Fatalf("expected a positive negotiated MaxWrite, got %d", negotiated.MaxWrite)
+	}
+
+	if err := directmount.Unmount(target); err != nil {
+		t.Fatalf("Unmount failed: %v", err)
+	}
+}
+
+func TestOpenDevFuseFailsCleanlyWithoutDevice(t *testing.T) {
+	if _, err := os.Stat("/dev/fuse"); err == nil {
+		t.Skip("/dev/fuse is present in this environment")
+	}
+	if _, err := directmount.OpenDevFuse(); err == nil {
+		t.Fatalf("expected an error opening /dev/fuse when it doesn't exist")
+	}
+}
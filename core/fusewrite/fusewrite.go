@@ -0,0 +1,107 @@
+// Package fusewrite computes the write-size parameters a FUSE mount should
+// negotiate with the kernel — MaxWrite, MaxReadAhead, and whether the
+// CAP_MAX_PAGES init flag is safe to set — and performs the FUSE_INIT
+// request/reply exchange that commits them (PerformInitHandshake, in
+// handshake.go, used by core/directmount.Mount).
+//
+// Negotiate below is the pure, kernel-version-aware sizing math. There is no
+// --fuse-max-write flag or buffered FUSE writer in this tree that calls it;
+// the core.Goofys mount path such a flag would live on isn't present in this
+// snapshot.
+package fusewrite
+
+const (
+	// pageSize is the page size CAP_MAX_PAGES counts in; FUSE's wire
+	// protocol has no notion of a configurable page size, it's always
+	// the host's.
+	pageSize = 4096
+
+	// maxPages is FUSE_MAX_MAX_PAGES in the Linux kernel: the largest
+	// max_pages value fuse_conn_init will accept, putting a hard kernel
+	// ceiling on MaxWrite at maxPages*pageSize (1 MiB) regardless of what
+	// a caller requests.
+	maxPages = 256
+
+	// legacyMaxWrite is the largest MaxWrite the kernel honors without
+	// CAP_MAX_PAGES (FUSE_DEFAULT_MAX_PAGES_PER_REQ pages); requesting
+	// more than this requires the init flag in capMaxPagesMinor and later.
+	legacyMaxWrite = 32 * pageSize
+
+	// capMaxPagesMinor is the FUSE protocol minor version (7.capMaxPagesMinor)
+	// that introduced the CAP_MAX_PAGES init flag; a kernel negotiating an
+	// older minor version will never honor max_pages, so requesting it
+	// would silently have no effect and shouldn't be claimed as set.
+	capMaxPagesMinor = 28
+
+	// DefaultMaxWrite is used when a caller asks for the negotiated
+	// default rather than a specific size: the kernel maximum, so
+	// sequential writes to backends that already stage multi-MiB
+	// multipart parts aren't throttled to the historical 128 KiB.
+	DefaultMaxWrite = maxPages * pageSize
+
+	// minMaxWrite is the smallest MaxWrite Negotiate will ever return;
+	// below one page there's nothing meaningful to buffer per write.
+	minMaxWrite = pageSize
+)
+
+// Negotiated is the set of write-path parameters a FUSE mount should use
+// after negotiating protocol minor version protocolMinor with the kernel.
+type Negotiated struct {
+	// MaxWrite is the largest single write the kernel will send in one
+	// FUSE_WRITE request, rounded to a whole number of pages and capped
+	// at the kernel's hard maximum.
+	MaxWrite int
+	// MaxReadAhead mirrors MaxWrite: read-ahead is set to match so a
+	// sequential read pulls in the same amount of data a sequential
+	// write would push out in one op.
+	MaxReadAhead int
+	// MaxPages is MaxWrite expressed in pages, the unit the CAP_MAX_PAGES
+	// init flag itself negotiates in.
+	MaxPages int
+	// UseCapMaxPages reports whether the CAP_MAX_PAGES init flag should
+	// be set: the kernel needs it to honor a MaxWrite above legacyMaxWrite,
+	// and it only exists from protocol 7.28 onward.
+	UseCapMaxPages bool
+}
+
+// Negotiate computes write-path parameters for a requested MaxWrite
+// (<= 0 meaning "use the default") against a kernel that has negotiated
+// FUSE protocol minor version protocolMinor.
+func Negotiate(requestedMaxWrite, protocolMinor int) Negotiated {
+	requested := requestedMaxWrite
+	if requested <= 0 {
+		requested = DefaultMaxWrite
+	}
+
+	maxWrite := roundUpToPage(requested)
+	if maxWrite < minMaxWrite {
+		maxWrite = minMaxWrite
+	}
+	if maxWrite > maxPages*pageSize {
+		maxWrite = maxPages * pageSize
+	}
+
+	useCapMaxPages := protocolMinor >= capMaxPagesMinor && maxWrite > legacyMaxWrite
+	if !useCapMaxPages && maxWrite > legacyMaxWrite {
+		// The kernel can't honor anything past legacyMaxWrite without
+		// the flag this protocol version doesn't support; don't claim a
+		// larger MaxWrite than the kernel will actually deliver.
+		maxWrite = legacyMaxWrite
+	}
+
+	return Negotiated{
+		MaxWrite:       maxWrite,
+		MaxReadAhead:   maxWrite,
+		MaxPages:       maxWrite / pageSize,
+		UseCapMaxPages: useCapMaxPages,
+	}
+}
+
+// roundUpToPage rounds n up to the nearest whole multiple of pageSize, the
+// granularity CAP_MAX_PAGES negotiates in.
+func roundUpToPage(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	return ((n + pageSize - 1) / pageSize) * pageSize
+}
@@ -0,0 +1,63 @@
+package fusewrite_test
+
+import (
+	"testing"
+
+	"github.com/valandreev/tigrisfs/core/fusewrite"
+)
+
+func TestNegotiateDefaultsToKernelMaximumOnModernProtocol(t *testing.T) {
+	got := fusewrite.Negotiate(0, 31)
+	if got.MaxWrite != fusewrite.DefaultMaxWrite {
+		t.Fatalf("expected MaxWrite %d, got %d", fusewrite.DefaultMaxWrite, got.MaxWrite)
+	}
+	if got.MaxWrite != 1<<20 {
+		t.Fatalf("expected default MaxWrite to be exactly 1 MiB, got %d", got.MaxWrite)
+	}
+	if !got.UseCapMaxPages {
+		t.Fatalf("expected CAP_MAX_PAGES to be used on protocol 7.31")
+	}
+	if got.MaxReadAhead != got.MaxWrite {
+		t.Fatalf("expected MaxReadAhead to mirror MaxWrite, got %d vs %d", got.MaxReadAhead, got.MaxWrite)
+	}
+	if got.MaxPages != 256 {
+		t.Fatalf("expected MaxPages 256, got %d", got.MaxPages)
+	}
+}
+
+func TestNegotiateRoundsUpToAWholePage(t *testing.T) {
+	got := fusewrite.Negotiate(130*1024+1, 31)
+	if got.MaxWrite%4096 != 0 {
+		t.Fatalf("expected MaxWrite to be page-aligned, got %d", got.MaxWrite)
+	}
+	if got.MaxWrite < 130*1024+1 {
+		t.Fatalf("expected rounding up, not down, got %d", got.MaxWrite)
+	}
+}
+
+func TestNegotiateCapsAtKernelMaximum(t *testing.T) {
+	got := fusewrite.Negotiate(64<<20, 31)
+	if got.MaxWrite != fusewrite.DefaultMaxWrite {
+		t.Fatalf("expected MaxWrite capped at the kernel maximum %d, got %d", fusewrite.DefaultMaxWrite, got.MaxWrite)
+	}
+}
+
+func TestNegotiateFallsBackToLegacyLimitOnOldProtocol(t *testing.T) {
+	got := fusewrite.Negotiate(1<<20, 20)
+	if got.UseCapMaxPages {
+		t.Fatalf("expected CAP_MAX_PAGES not to be used on protocol 7.20")
+	}
+	if got.MaxWrite != 32*4096 {
+		t.Fatalf("expected MaxWrite clamped to the legacy limit, got %d", got.MaxWrite)
+	}
+}
+
+func TestNegotiateAllowsSmallRequestsWithoutCapMaxPages(t *testing.T) {
+	got := fusewrite.Negotiate(64*1024, 31)
+	if got.UseCapMaxPages {
+		t.Fatalf("expected CAP_MAX_PAGES not to be needed for a request within the legacy limit")
+	}
+	if got.MaxWrite != 64*1024 {
+		t.Fatalf("expected MaxWrite 64KiB, got %d", got.MaxWrite)
+	}
+}
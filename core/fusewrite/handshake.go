@@ -0,0 +1,104 @@
+package fusewrite
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Wire-format constants from the kernel's FUSE ABI (include/uapi/linux/fuse.h).
+const (
+	fuseInitOpcode = 26
+
+	fuseInHeaderSize    = 40 // struct fuse_in_header
+	fuseOutHeaderSize   = 16 // struct fuse_out_header
+	fuseInitOutBodySize = 64 // struct fuse_init_out
+
+	fuseKernelVersion     = 7
+	fuseMaxSupportedMinor = 31 // highest minor this handshake knows how to reply to
+
+	// fuseMaxPages is the FUSE_MAX_PAGES init flag bit, set in the reply
+	// to tell the kernel MaxWrite/MaxPages above legacyMaxWrite should be
+	// honored.
+	fuseMaxPages = 1 << 22
+)
+
+// PerformInitHandshake reads a single FUSE_INIT request off rw and writes
+// back the corresponding reply, with MaxWrite/MaxReadAhead/MaxPages sized by
+// Negotiate(requestedMaxWrite, minor) using the protocol minor version the
+// kernel sent. It returns the Negotiated parameters the reply committed to,
+// so the caller can size its own buffered write path (e.g. the per-request
+// read buffer on the /dev/fuse fd) to match.
+//
+// rw is anything the init request/reply can be read from and written to —
+// in production that's the open /dev/fuse fd returned by
+// directmount.Mount, but taking an io.ReadWriter instead of that concrete
+// type keeps this protocol-parsing logic testable without a real mount.
+func PerformInitHandshake(rw io.ReadWriter, requestedMaxWrite int) (Negotiated, error) {
+	header := make([]byte, fuseInHeaderSize)
+	if _, err := io.ReadFull(rw, header); err != nil {
+		return Negotiated{}, fmt.Errorf("fusewrite: read init request header: %w", err)
+	}
+
+	length := binary.LittleEndian.Uint32(header[0:4])
+	opcode := binary.LittleEndian.Uint32(header[4:8])
+	unique := binary.LittleEndian.Uint64(header[8:16])
+
+	if opcode != fuseInitOpcode {
+		return Negotiated{}, fmt.Errorf("fusewrite: expected FUSE_INIT (opcode %d) as the first request, got opcode %d", fuseInitOpcode, opcode)
+	}
+	if length < fuseInHeaderSize+16 {
+		return Negotiated{}, fmt.Errorf("fusewrite: init request too short: %d bytes", length)
+	}
+
+	// fuse_init_in is append-only across kernel versions (flags2 and a
+	// reserved tail were added after major/minor/max_readahead/flags); only
+	// that 16-byte prefix is needed here; any trailing fields are read off rw
+	// and discarded without being parsed.
+	body := make([]byte, 16)
+	if _, err := io.ReadFull(rw, body); err != nil {
+		return Negotiated{}, fmt.Errorf("fusewrite: read init request body: %w", err)
+	}
+	if extra := int(length) - fuseInHeaderSize - len(body); extra > 0 {
+		if _, err := io.CopyN(io.Discard, rw, int64(extra)); err != nil {
+			return Negotiated{}, fmt.Errorf("fusewrite: discard trailing init request fields: %w", err)
+		}
+	}
+	minor := binary.LittleEndian.Uint32(body[4:8])
+
+	negotiated := Negotiate(requestedMaxWrite, int(minor))
+
+	replyMinor := minor
+	if replyMinor > fuseMaxSupportedMinor {
+		replyMinor = fuseMaxSupportedMinor
+	}
+
+	out := make([]byte, fuseInitOutBodySize)
+	binary.LittleEndian.PutUint32(out[0:4], fuseKernelVersion)
+	binary.LittleEndian.PutUint32(out[4:8], replyMinor)
+	binary.LittleEndian.PutUint32(out[8:12], uint32(negotiated.MaxReadAhead))
+	var flags uint32
+	if negotiated.UseCapMaxPages {
+		flags |= fuseMaxPages
+	}
+	binary.LittleEndian.PutUint32(out[12:16], flags)
+	// out[16:18] max_background and out[18:20] congestion_threshold are left
+	// at the kernel's own defaults (0 means "pick a default") since this
+	// handshake doesn't negotiate queue depth.
+	binary.LittleEndian.PutUint32(out[20:24], uint32(negotiated.MaxWrite))
+	binary.LittleEndian.PutUint32(out[24:28], 1) // time_gran: 1ns, the finest the kernel supports
+	binary.LittleEndian.PutUint16(out[28:30], uint16(negotiated.MaxPages))
+	// out[30:64]: map_alignment, flags2, and the reserved tail all stay 0.
+
+	reply := make([]byte, fuseOutHeaderSize+len(out))
+	binary.LittleEndian.PutUint32(reply[0:4], uint32(len(reply)))
+	binary.LittleEndian.PutUint32(reply[4:8], 0) // error
+	binary.LittleEndian.PutUint64(reply[8:16], unique)
+	copy(reply[fuseOutHeaderSize:], out)
+
+	if _, err := rw.Write(reply); err != nil {
+		return Negotiated{}, fmt.Errorf("fusewrite: write init reply: %w", err)
+	}
+
+	return negotiated, nil
+}
@@ -0,0 +1,131 @@
+package fusewrite_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/valandreev/tigrisfs/core/fusewrite"
+)
+
+// pipeConn is an in-memory io.ReadWriter standing in for the /dev/fuse fd:
+// reads drain fromKernel (bytes the "kernel" sent), writes append to
+// toKernel (bytes the handshake sent back).
+type pipeConn struct {
+	fromKernel *bytes.Reader
+	toKernel   bytes.Buffer
+}
+
+func (p *pipeConn) Read(b []byte) (int, error)  { return p.fromKernel.Read(b) }
+func (p *pipeConn) Write(b []byte) (int, error) { return p.toKernel.Write(b) }
+
+// encodeInitRequest builds a wire-format FUSE_INIT request with the given
+// protocol minor version, matching the 16-byte major/minor/max_readahead/
+// flags body real kernels send (older ones send exactly this; newer ones
+// append more fields, covered separately below).
+func encodeInitRequest(unique uint64, minor uint32) []byte {
+	const headerSize, bodySize = 40, 16
+	buf := make([]byte, headerSize+bodySize)
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(buf)))
+	binary.LittleEndian.PutUint32(buf[4:8], 26) // FUSE_INIT opcode
+	binary.LittleEndian.PutUint64(buf[8:16], unique)
+
+	binary.LittleEndian.PutUint32(buf[40:44], 7) // major
+	binary.LittleEndian.PutUint32(buf[44:48], minor)
+	binary.LittleEndian.PutUint32(buf[48:52], 1<<20) // max_readahead
+	binary.LittleEndian.PutUint32(buf[52:56], 0)     // flags
+	return buf
+}
+
+func TestPerformInitHandshakeNegotiatesModernProtocol(t *testing.T) {
+	conn := &pipeConn{fromKernel: bytes.NewReader(encodeInitRequest(42, 31))}
+
+	got, err := fusewrite.PerformInitHandshake(conn, 0)
+	if err != nil {
+		t.Fatalf("PerformInitHandshake returned error: %v", err)
+	}
+	if got.MaxWrite != fusewrite.DefaultMaxWrite {
+		t.Fatalf("expected negotiated MaxWrite %d, got %d", fusewrite.DefaultMaxWrite, got.MaxWrite)
+	}
+	if !got.UseCapMaxPages {
+		t.Fatalf("expected CAP_MAX_PAGES on protocol 7.31")
+	}
+
+	reply := conn.toKernel.Bytes()
+	if len(reply) != 16+64 {
+		t.Fatalf("expected a 80-byte init reply, got %d bytes", len(reply))
+	}
+	replyUnique := binary.LittleEndian.Uint64(reply[8:16])
+	if replyUnique != 42 {
+		t.Fatalf("expected reply unique to echo the request's, got %d", replyUnique)
+	}
+	replyErr := int32(binary.LittleEndian.Uint32(reply[4:8]))
+	if replyErr != 0 {
+		t.Fatalf("expected reply error 0, got %d", replyErr)
+	}
+	maxWrite := binary.LittleEndian.Uint32(reply[16+20 : 16+24])
+	if int(maxWrite) != got.MaxWrite {
+		t.Fatalf("expected reply max_write %d, got %d", got.MaxWrite, maxWrite)
+	}
+	flags := binary.LittleEndian.Uint32(reply[16+12 : 16+16])
+	if flags&(1<<22) == 0 {
+		t.Fatalf("expected FUSE_MAX_PAGES flag set in reply, got flags %#x", flags)
+	}
+}
+
+func TestPerformInitHandshakeFallsBackOnOldProtocol(t *testing.T) {
+	conn := &pipeConn{fromKernel: bytes.NewReader(encodeInitRequest(7, 20))}
+
+	got, err := fusewrite.PerformInitHandshake(conn, 0)
+	if err != nil {
+		t.Fatalf("PerformInitHandshake returned error: %v", err)
+	}
+	if got.UseCapMaxPages {
+		t.Fatalf("expected CAP_MAX_PAGES not negotiated on protocol 7.20")
+	}
+
+	reply := conn.toKernel.Bytes()
+	flags := binary.LittleEndian.Uint32(reply[16+12 : 16+16])
+	if flags&(1<<22) != 0 {
+		t.Fatalf("expected FUSE_MAX_PAGES flag unset in reply, got flags %#x", flags)
+	}
+}
+
+func TestPerformInitHandshakeToleratesNewerLongerRequestBody(t *testing.T) {
+	base := encodeInitRequest(1, 36)
+	// A newer kernel's fuse_init_in appends flags2 and a reserved tail;
+	// simulate that by padding the body and fixing up the request length.
+	padded := append(base, make([]byte, 48)...)
+	binary.LittleEndian.PutUint32(padded[0:4], uint32(len(padded)))
+
+	conn := &pipeConn{fromKernel: bytes.NewReader(padded)}
+	got, err := fusewrite.PerformInitHandshake(conn, 0)
+	if err != nil {
+		t.Fatalf("PerformInitHandshake returned error: %v", err)
+	}
+	if !got.UseCapMaxPages {
+		t.Fatalf("expected CAP_MAX_PAGES on protocol 7.36")
+	}
+}
+
+func TestPerformInitHandshakeRejectsNonInitOpcode(t *testing.T) {
+	req := encodeInitRequest(1, 31)
+	binary.LittleEndian.PutUint32(req[4:8], 1) // FUSE_LOOKUP, not FUSE_INIT
+
+	conn := &pipeConn{fromKernel: bytes.NewReader(req)}
+	if _, err := fusewrite.PerformInitHandshake(conn, 0); err == nil {
+		t.Fatalf("expected an error when the first request isn't FUSE_INIT")
+	}
+}
+
+func TestPerformInitHandshakeHonorsRequestedMaxWrite(t *testing.T) {
+	conn := &pipeConn{fromKernel: bytes.NewReader(encodeInitRequest(1, 31))}
+
+	got, err := fusewrite.PerformInitHandshake(conn, 64*1024)
+	if err != nil {
+		t.Fatalf("PerformInitHandshake returned error: %v", err)
+	}
+	if got.MaxWrite != 64*1024 {
+		t.Fatalf("expected requested MaxWrite 64KiB honored, got %d", got.MaxWrite)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2021 Yandex LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// partJob is one prepared part body waiting for a MultipartBlobAddPipeline
+// worker to upload it.
+type partJob struct {
+	partNumber uint32
+	body       io.ReadSeeker
+}
+
+// MultipartBlobAddPipeline overlaps reading/checksumming part bodies on the
+// producer side with UploadPart requests on the consumer side, the way
+// tusd's s3PartProducer overlaps disk reads with S3 uploads: Submit fills a
+// channel bounded by flags.MaxBufferedParts (default ~20) so a slow link
+// can't make the producer buffer unbounded memory ahead of it, while
+// flags.MaxParallelParts worker goroutines call MultipartBlobAdd
+// concurrently. Wait collects the resulting ETags, in part-number order,
+// into commit.Parts.
+type MultipartBlobAddPipeline struct {
+	s      *S3Backend
+	commit *MultipartBlobCommitInput
+
+	jobs   chan partJob
+	group  *errgroup.Group
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	firstErr error
+	errOnce  sync.Once
+}
+
+// NewMultipartBlobAddPipeline starts the worker pool that will upload parts
+// for commit as they're Submit-ed.
+func (s *S3Backend) NewMultipartBlobAddPipeline(commit *MultipartBlobCommitInput) *MultipartBlobAddPipeline {
+	bufSize := int(s.flags.MaxBufferedParts)
+	if bufSize <= 0 {
+		bufSize = 20
+	}
+	workers := s.flags.MaxParallelParts
+	if workers <= 0 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	group, ctx := errgroup.WithContext(ctx)
+
+	p := &MultipartBlobAddPipeline{
+		s:      s,
+		commit: commit,
+		jobs:   make(chan partJob, bufSize),
+		group:  group,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	for i := 0; i < workers; i++ {
+		p.group.Go(p.worker)
+	}
+
+	return p
+}
+
+func (p *MultipartBlobAddPipeline) worker() error {
+	for {
+		select {
+		case <-p.ctx.Done():
+			return nil
+		case job, ok := <-p.jobs:
+			if !ok {
+				return nil
+			}
+			out, err := p.s.MultipartBlobAdd(&MultipartBlobAddInput{
+				Commit:     p.commit,
+				PartNumber: job.partNumber,
+				Body:       job.body,
+			})
+			if err != nil {
+				p.fail(err)
+				return err
+			}
+			// Different goroutines only ever write distinct indices, so
+			// this needs no lock: the Go memory model guarantees that's
+			// race-free even without synchronization.
+			p.commit.Parts[job.partNumber-1] = out.PartId
+			if p.commit.ChecksumCRC32C != nil {
+				p.commit.ChecksumCRC32C[job.partNumber-1] = out.ChecksumCRC32C
+			}
+			if p.commit.ChecksumSHA256 != nil {
+				p.commit.ChecksumSHA256[job.partNumber-1] = out.ChecksumSHA256
+			}
+		}
+	}
+}
+
+func (p *MultipartBlobAddPipeline) fail(err error) {
+	p.errOnce.Do(func() {
+		p.mu.Lock()
+		p.firstErr = err
+		p.mu.Unlock()
+		p.cancel()
+	})
+}
+
+// Submit hands body off to a worker as part partNumber. It blocks while the
+// buffered channel is full, which is what provides the memory ceiling: the
+// producer can only read flags.MaxBufferedParts parts ahead of the slowest
+// upload in flight. If a prior part failed, Submit returns that error
+// immediately instead of queuing more doomed work.
+func (p *MultipartBlobAddPipeline) Submit(partNumber uint32, body io.ReadSeeker) error {
+	select {
+	case p.jobs <- partJob{partNumber: partNumber, body: body}:
+		return nil
+	case <-p.ctx.Done():
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.firstErr != nil {
+			return p.firstErr
+		}
+		return p.ctx.Err()
+	}
+}
+
+// Wait closes the job channel, waits for every worker to drain, and returns
+// the first error encountered by any of them, if any.
+func (p *MultipartBlobAddPipeline) Wait() error {
+	close(p.jobs)
+	return p.group.Wait()
+}
+
+// Abort cancels all in-flight workers without waiting for parts still
+// queued to upload, for a caller that already knows it needs to give up
+// (e.g. the FUSE side hit a read error) and just wants buffers freed.
+func (p *MultipartBlobAddPipeline) Abort() {
+	p.cancel()
+	_ = p.group.Wait()
+}
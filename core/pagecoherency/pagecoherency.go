@@ -0,0 +1,187 @@
+// Package pagecoherency keeps the FUSE kernel page cache in sync with
+// backend writes, in both directions: after a PutBlob/MultipartBlobAdd
+// completion replaces an object's data, Coherency.StoreUploaded pushes the
+// just-uploaded bytes into the kernel cache via NOTIFY_STORE so a read
+// immediately after close is served from cache instead of re-GETing the
+// object just written, and Coherency.RetrieveDirty pulls cached dirty pages
+// back via NOTIFY_RETRIEVE so the flush path can coalesce them with the data
+// about to be uploaded instead of re-reading from the backend.
+//
+// pkg/cache/uploader.Uploader is this tree's actual writeback path — there is
+// no core.Goofys/MountedFS flush loop in this snapshot for StoreUploaded to
+// hang off of instead — so uploader.WithPageCache wires a *Coherency in as
+// its PageCache dependency and calls StoreUploaded once per completed upload.
+// RetrieveDirty/ReadCachedOrFetch have no caller yet: nothing in pkg/cache
+// reads back through a dirty-page coalescing path the way a FUSE write
+// handler would.
+//
+// The kernel-facing half of this — the actual NOTIFY_STORE/NOTIFY_RETRIEVE
+// wire messages, sent over go-fuse's raw *fuse.Server — is owned by this
+// tree's FUSE mount path, which still isn't present in this snapshot (same
+// absence as core.Goofys/MountedFS that the rest of core/'s new packages have
+// run into). NotifyChannel below is the seam a real mount would implement
+// over fuse.Server.InodeNotifyStoreCache/InodeRetrieveCache; what's built
+// here is the bookkeeping above that seam: page-granularity chunking of
+// arbitrary-length uploads for StoreUploaded, and the read-through/
+// coalescing helpers that make the cache actually save a round trip.
+package pagecoherency
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/valandreev/tigrisfs/log"
+)
+
+// pageSize is the granularity NOTIFY_STORE and NOTIFY_RETRIEVE operate on;
+// like fusewrite's pageSize, this is the host's page size, not something
+// FUSE's wire protocol lets a caller configure.
+const pageSize = 4096
+
+// NotifyChannel is the kernel page cache operations a real mount would
+// implement over go-fuse's *fuse.Server.
+type NotifyChannel interface {
+	// StoreCache overwrites the kernel's cached copy of ino's data at
+	// [offset, offset+len(data)) with data.
+	StoreCache(ino uint64, offset int64, data []byte) error
+	// RetrieveCache returns up to size bytes of ino's data at offset from
+	// the kernel's cache, or ok=false if the kernel has nothing cached
+	// there (e.g. the range was never read or has since been evicted).
+	RetrieveCache(ino uint64, offset int64, size int) (data []byte, ok bool, err error)
+}
+
+// Logger captures structured output for Coherency.
+type Logger interface {
+	Debugf(format string, args ...any)
+	Warnf(format string, args ...any)
+}
+
+// BackendFetcher is the fallback data source a read consults when nothing
+// usable is in the kernel cache. S3Backend's GetBlob satisfies the shape a
+// caller would adapt to this.
+type BackendFetcher interface {
+	Fetch(ino uint64, offset int64, size int) ([]byte, error)
+}
+
+// Option customises Coherency construction.
+type Option func(*Coherency)
+
+// WithLogger overrides the default logger.
+func WithLogger(logger Logger) Option {
+	return func(c *Coherency) {
+		c.logger = logger
+	}
+}
+
+// Coherency pushes just-uploaded data into the kernel page cache and pulls
+// dirty cached pages back for write-coalescing, against a NotifyChannel.
+type Coherency struct {
+	channel NotifyChannel
+	logger  Logger
+}
+
+// New constructs a Coherency backed by channel.
+func New(channel NotifyChannel, opts ...Option) (*Coherency, error) {
+	if channel == nil {
+		return nil, errors.New("pagecoherency: notify channel is required")
+	}
+
+	c := &Coherency{channel: channel, logger: defaultLogger()}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.logger == nil {
+		c.logger = defaultLogger()
+	}
+	return c, nil
+}
+
+// StoreUploaded replaces the kernel's cached copy of ino's data at
+// [offset, offset+len(data)) with data that has just been durably written
+// to the backend (a PutBlob or the final MultipartBlobAdd of an upload),
+// avoiding the re-GET a subsequent read would otherwise trigger on a hot
+// file whose cache the kernel dropped, or never had, on close.
+//
+// data is pushed one page at a time, since NOTIFY_STORE has no multi-page
+// form: StoreCache is called once per pageSize-aligned chunk of data. There
+// is no way to make that whole-range replacement atomic across pages — a
+// failure partway leaves the pages already sent in place and the rest
+// untouched — so StoreUploaded returns the first error it hits instead of
+// continuing, and callers that can't tolerate a partially-stored range
+// should treat any error here as "fall back to invalidating ino instead."
+func (c *Coherency) StoreUploaded(ino uint64, offset int64, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	for start := 0; start < len(data); start += pageSize {
+		end := start + pageSize
+		if end > len(data) {
+			end = len(data)
+		}
+		pageOffset := offset + int64(start)
+		if err := c.channel.StoreCache(ino, pageOffset, data[start:end]); err != nil {
+			return fmt.Errorf("pagecoherency: store ino %d offset %d: %w", ino, pageOffset, err)
+		}
+	}
+
+	c.logger.Debugf("pagecoherency: stored %d byte(s) for ino %d at offset %d into the kernel cache", len(data), ino, offset)
+	return nil
+}
+
+// RetrieveDirty pulls back whatever the kernel still has cached for ino at
+// [offset, offset+size), for the flush path to coalesce with the data it's
+// about to upload instead of re-reading that range from the backend. ok is
+// false if the kernel has nothing cached there, which is not an error: the
+// caller already has the data it intends to flush and simply has nothing to
+// coalesce against.
+func (c *Coherency) RetrieveDirty(ino uint64, offset int64, size int) (data []byte, ok bool, err error) {
+	if size <= 0 {
+		return nil, false, nil
+	}
+
+	data, ok, err = c.channel.RetrieveCache(ino, offset, size)
+	if err != nil {
+		return nil, false, fmt.Errorf("pagecoherency: retrieve ino %d offset %d: %w", ino, offset, err)
+	}
+	if !ok {
+		c.logger.Debugf("pagecoherency: nothing cached for ino %d at offset %d to coalesce", ino, offset)
+	}
+	return data, ok, nil
+}
+
+// ReadCachedOrFetch serves a read for ino at [offset, offset+size) from the
+// kernel cache when it's present there, falling back to fetcher only on a
+// miss. It's the read-side counterpart to StoreUploaded: if the most recent
+// upload's StoreUploaded call landed, a read issued right after close never
+// reaches fetcher at all.
+func (c *Coherency) ReadCachedOrFetch(ino uint64, offset int64, size int, fetcher BackendFetcher) ([]byte, error) {
+	data, ok, err := c.channel.RetrieveCache(ino, offset, size)
+	if err != nil {
+		return nil, fmt.Errorf("pagecoherency: retrieve ino %d offset %d: %w", ino, offset, err)
+	}
+	if ok {
+		return data, nil
+	}
+	return fetcher.Fetch(ino, offset, size)
+}
+
+func defaultLogger() Logger {
+	return logHandleAdapter{handle: log.GetLogger("pagecoherency")}
+}
+
+type logHandleAdapter struct {
+	handle *log.LogHandle
+}
+
+func (l logHandleAdapter) Debugf(format string, args ...any) {
+	if l.handle != nil {
+		l.handle.Debug().Msgf(format, args...)
+	}
+}
+
+func (l logHandleAdapter) Warnf(format string, args ...any) {
+	if l.handle != nil {
+		l.handle.Warn().Msgf(format, args...)
+	}
+}
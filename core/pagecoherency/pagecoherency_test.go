@@ -0,0 +1,213 @@
+package pagecoherency_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/valandreev/tigrisfs/core/pagecoherency"
+)
+
+// fakeKernelCache is an in-memory stand-in for the kernel page cache a real
+// NotifyChannel would front.
+type fakeKernelCache struct {
+	pages map[int64][]byte
+}
+
+func newFakeKernelCache() *fakeKernelCache {
+	return &fakeKernelCache{pages: make(map[int64][]byte)}
+}
+
+func (f *fakeKernelCache) StoreCache(ino uint64, offset int64, data []byte) error {
+	cp := make([]byte, len(data))
+	copy(cp, data)
+	f.pages[offset] = cp
+	return nil
+}
+
+func (f *fakeKernelCache) RetrieveCache(ino uint64, offset int64, size int) ([]byte, bool, error) {
+	data, ok := f.pages[offset]
+	if !ok {
+		return nil, false, nil
+	}
+	if len(data) > size {
+		data = data[:size]
+	}
+	return data, true, nil
+}
+
+// countingBackend counts every Fetch call, modelling the "counting wrapper
+// on s.cloud" the request asks for.
+type countingBackend struct {
+	fetches int
+	data    []byte
+}
+
+func (b *countingBackend) Fetch(ino uint64, offset int64, size int) ([]byte, error) {
+	b.fetches++
+	return b.data, nil
+}
+
+func TestReadAfterStoreUploadedDoesNotFetchFromBackend(t *testing.T) {
+	cache := newFakeKernelCache()
+	c, err := pagecoherency.New(cache)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	data := []byte("hello, coherency")
+	const ino = 42
+	if err := c.StoreUploaded(ino, 0, data); err != nil {
+		t.Fatalf("StoreUploaded failed: %v", err)
+	}
+
+	backend := &countingBackend{data: []byte("stale backend data")}
+	got, err := c.ReadCachedOrFetch(ino, 0, len(data), backend)
+	if err != nil {
+		t.Fatalf("ReadCachedOrFetch failed: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("expected %q from the kernel cache, got %q", data, got)
+	}
+	if backend.fetches != 0 {
+		t.Fatalf("expected no backend fetch after StoreUploaded, got %d", backend.fetches)
+	}
+}
+
+func TestReadMissesBackendWhenNothingCached(t *testing.T) {
+	cache := newFakeKernelCache()
+	c, err := pagecoherency.New(cache)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	backend := &countingBackend{data: []byte("from backend")}
+	got, err := c.ReadCachedOrFetch(7, 0, 4, backend)
+	if err != nil {
+		t.Fatalf("ReadCachedOrFetch failed: %v", err)
+	}
+	if !bytes.Equal(got, backend.data) {
+		t.Fatalf("expected backend data, got %q", got)
+	}
+	if backend.fetches != 1 {
+		t.Fatalf("expected exactly one backend fetch on a cache miss, got %d", backend.fetches)
+	}
+}
+
+func TestStoreUploadedSplitsAcrossMultiplePages(t *testing.T) {
+	cache := newFakeKernelCache()
+	c, err := pagecoherency.New(cache)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	data := bytes.Repeat([]byte("x"), 4096*2+10)
+	if err := c.StoreUploaded(99, 0, data); err != nil {
+		t.Fatalf("StoreUploaded failed: %v", err)
+	}
+
+	if len(cache.pages) != 3 {
+		t.Fatalf("expected 3 page-sized store calls, got %d", len(cache.pages))
+	}
+	if _, ok := cache.pages[0]; !ok {
+		t.Fatalf("expected a page stored at offset 0")
+	}
+	if _, ok := cache.pages[4096]; !ok {
+		t.Fatalf("expected a page stored at offset 4096")
+	}
+	if _, ok := cache.pages[8192]; !ok {
+		t.Fatalf("expected a page stored at offset 8192")
+	}
+}
+
+func TestStoreUploadedOfEmptyDataIsNoop(t *testing.T) {
+	cache := newFakeKernelCache()
+	c, err := pagecoherency.New(cache)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if err := c.StoreUploaded(1, 0, nil); err != nil {
+		t.Fatalf("expected no error storing empty data, got %v", err)
+	}
+	if len(cache.pages) != 0 {
+		t.Fatalf("expected no pages stored, got %d", len(cache.pages))
+	}
+}
+
+// failingCache fails every StoreCache call after the first, modelling a
+// partial-failure partway through a multi-page upload.
+type failingCache struct {
+	calls int
+}
+
+func (f *failingCache) StoreCache(ino uint64, offset int64, data []byte) error {
+	f.calls++
+	if f.calls > 1 {
+		return errors.New("kernel channel closed")
+	}
+	return nil
+}
+
+func (f *failingCache) RetrieveCache(ino uint64, offset int64, size int) ([]byte, bool, error) {
+	return nil, false, nil
+}
+
+func TestStoreUploadedReturnsFirstErrorWithoutRetrying(t *testing.T) {
+	cache := &failingCache{}
+	c, err := pagecoherency.New(cache)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	data := bytes.Repeat([]byte("y"), 4096*3)
+	if err := c.StoreUploaded(1, 0, data); err == nil {
+		t.Fatalf("expected an error from the second page's failed store")
+	}
+	if cache.calls != 2 {
+		t.Fatalf("expected exactly 2 store calls before bailing out, got %d", cache.calls)
+	}
+}
+
+func TestRetrieveDirtyReportsCacheMiss(t *testing.T) {
+	cache := newFakeKernelCache()
+	c, err := pagecoherency.New(cache)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	_, ok, err := c.RetrieveDirty(1, 0, 4096)
+	if err != nil {
+		t.Fatalf("RetrieveDirty failed: %v", err)
+	}
+	if ok {
+		t.Fatalf("expected ok=false when nothing is cached")
+	}
+}
+
+func TestRetrieveDirtyReturnsCachedDataForCoalescing(t *testing.T) {
+	cache := newFakeKernelCache()
+	c, err := pagecoherency.New(cache)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	dirty := []byte("dirty page contents")
+	if err := cache.StoreCache(1, 0, dirty); err != nil {
+		t.Fatalf("StoreCache failed: %v", err)
+	}
+
+	got, ok, err := c.RetrieveDirty(1, 0, len(dirty))
+	if err != nil {
+		t.Fatalf("RetrieveDirty failed: %v", err)
+	}
+	if !ok || !bytes.Equal(got, dirty) {
+		t.Fatalf("expected %q, got %q (ok=%v)", dirty, got, ok)
+	}
+}
+
+func TestNewRejectsNilChannel(t *testing.T) {
+	if _, err := pagecoherency.New(nil); err == nil {
+		t.Fatalf("expected an error for a nil NotifyChannel")
+	}
+}
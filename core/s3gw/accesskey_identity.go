@@ -0,0 +1,37 @@
+package s3gw
+
+import (
+	"time"
+
+	"github.com/tigrisdata/tigrisfs/core/accesskey"
+)
+
+// AccessKeyIdentityStore adapts an accesskey.Service into an IdentityStore,
+// so the gateway can authenticate against the same pluggable key management
+// used by its admin API, rather than a fixed StaticIdentityStore.
+type AccessKeyIdentityStore struct {
+	svc accesskey.Service
+}
+
+// NewAccessKeyIdentityStore wraps svc as an IdentityStore.
+func NewAccessKeyIdentityStore(svc accesskey.Service) *AccessKeyIdentityStore {
+	return &AccessKeyIdentityStore{svc: svc}
+}
+
+// Lookup treats a missing, disabled or expired key as unknown, so the
+// gateway rejects it the same way it would an AccessKeyId it has never
+// heard of.
+func (s *AccessKeyIdentityStore) Lookup(accessKeyId string) (Identity, bool) {
+	key, err := s.svc.Get(accessKeyId)
+	if err != nil {
+		return Identity{}, false
+	}
+	if !key.Enabled || key.Expired(time.Now()) {
+		return Identity{}, false
+	}
+	return Identity{
+		AccessKeyId:     key.AccessKeyId,
+		SecretAccessKey: key.SecretAccessKey,
+		AllowedPrefixes: key.AllowedPrefixes,
+	}, true
+}
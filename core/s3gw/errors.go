@@ -0,0 +1,32 @@
+package s3gw
+
+import (
+	"encoding/xml"
+	"net/http"
+)
+
+type s3ErrorResponse struct {
+	XMLName  xml.Name `xml:"Error"`
+	Code     string   `xml:"Code"`
+	Message  string   `xml:"Message"`
+	Resource string   `xml:"Resource"`
+}
+
+// writeS3Error writes an S3-shaped XML error body and logs the failure,
+// since most callers are other services rather than an interactive user.
+func writeS3Error(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	s3gwLog.Warnf("%s %s: %s: %s", r.Method, r.URL.Path, code, message)
+	writeXML(w, status, s3ErrorResponse{Code: code, Message: message, Resource: r.URL.Path})
+}
+
+func writeXML(w http.ResponseWriter, status int, v any) {
+	body, err := xml.Marshal(v)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(body)
+}
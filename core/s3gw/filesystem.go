@@ -0,0 +1,66 @@
+package s3gw
+
+import (
+	"io"
+	"time"
+)
+
+// ByteRange is an inclusive byte range requested via a Range header. End of
+// -1 means "to the end of the object".
+type ByteRange struct {
+	Start int64
+	End   int64
+}
+
+// BucketInfo describes one top-level bucket for ListBuckets.
+type BucketInfo struct {
+	Name         string
+	CreationDate time.Time
+}
+
+// ObjectInfo describes one object's metadata, as returned by most FileTree
+// operations and reflected back into S3 response headers/XML.
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+}
+
+// ListObjectsResult is the page of results returned by FileTree.ListObjects.
+type ListObjectsResult struct {
+	Objects               []ObjectInfo
+	CommonPrefixes        []string
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+// CompletedPart identifies one part by number and the ETag UploadPart
+// returned for it, as sent in a CompleteMultipartUpload request body.
+type CompletedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+// FileTree is the seam between the gateway's HTTP/S3 protocol layer and
+// whatever holds the live namespace. Handlers translate S3 REST operations
+// into these calls; a concrete implementation binds them to the mount's
+// in-memory inode tree so reads and writes are shared with FUSE.
+type FileTree interface {
+	ListBuckets() ([]BucketInfo, error)
+	ListObjects(bucket, prefix, delimiter, continuationToken string, maxKeys int) (ListObjectsResult, error)
+	StatObject(bucket, key string) (ObjectInfo, error)
+
+	// OpenObject returns a reader positioned at the start of rng (or the
+	// start of the object, if rng is nil), together with the object's full
+	// metadata. The caller closes the returned reader.
+	OpenObject(bucket, key string, rng *ByteRange) (io.ReadCloser, ObjectInfo, error)
+	PutObject(bucket, key string, body io.Reader, size int64) (ObjectInfo, error)
+	DeleteObject(bucket, key string) error
+	CopyObject(srcBucket, srcKey, dstBucket, dstKey string) (ObjectInfo, error)
+
+	CreateMultipartUpload(bucket, key string) (uploadID string, err error)
+	UploadPart(bucket, key, uploadID string, partNumber int, body io.Reader, size int64) (etag string, err error)
+	CompleteMultipartUpload(bucket, key, uploadID string, parts []CompletedPart) (ObjectInfo, error)
+	AbortMultipartUpload(bucket, key, uploadID string) error
+}
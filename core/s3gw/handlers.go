@@ -0,0 +1,313 @@
+package s3gw
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// amzTimeFormat is the ISO-8601 timestamp format S3 uses in XML bodies.
+const amzTimeFormat = "2006-01-02T15:04:05.000Z"
+
+// splitPath separates an S3 path-style request target into bucket and key,
+// the only addressing style this gateway supports (no virtual-hosted-style
+// bucket-in-Host routing).
+func splitPath(p string) (bucket, key string) {
+	p = strings.TrimPrefix(p, "/")
+	if p == "" {
+		return "", ""
+	}
+	if idx := strings.Index(p, "/"); idx >= 0 {
+		return p[:idx], p[idx+1:]
+	}
+	return p, ""
+}
+
+// route dispatches an already-authenticated request to the matching S3
+// operation, based on method, path shape, and query parameters.
+func (g *Gateway) route(w http.ResponseWriter, r *http.Request, identity Identity) {
+	bucket, key := splitPath(r.URL.Path)
+	if !identity.Allows(bucket, key) {
+		writeS3Error(w, r, http.StatusForbidden, "AccessDenied", "identity is not allowed to access this resource")
+		return
+	}
+
+	q := r.URL.Query()
+	switch {
+	case bucket == "" && r.Method == http.MethodGet:
+		g.listBuckets(w, r)
+	case key == "" && r.Method == http.MethodGet:
+		g.listObjects(w, r, bucket)
+	case key == "" && r.Method == http.MethodPost && q.Has("delete"):
+		g.deleteObjects(w, r, bucket)
+	case key != "" && r.Method == http.MethodPost && q.Has("uploads"):
+		g.createMultipartUpload(w, r, bucket, key)
+	case key != "" && r.Method == http.MethodPost && q.Has("uploadId"):
+		g.completeMultipartUpload(w, r, bucket, key)
+	case key != "" && r.Method == http.MethodPut && q.Has("partNumber") && q.Has("uploadId"):
+		g.uploadPart(w, r, bucket, key)
+	case key != "" && r.Method == http.MethodDelete && q.Has("uploadId"):
+		g.abortMultipartUpload(w, r, bucket, key)
+	case key != "" && r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		g.copyObject(w, r, bucket, key)
+	case key != "" && r.Method == http.MethodPut:
+		g.putObject(w, r, bucket, key)
+	case key != "" && r.Method == http.MethodHead:
+		g.headObject(w, r, bucket, key)
+	case key != "" && r.Method == http.MethodGet:
+		g.getObject(w, r, bucket, key)
+	case key != "" && r.Method == http.MethodDelete:
+		g.deleteObject(w, r, bucket, key)
+	default:
+		writeS3Error(w, r, http.StatusNotImplemented, "NotImplemented", "unsupported operation")
+	}
+}
+
+func (g *Gateway) listBuckets(w http.ResponseWriter, r *http.Request) {
+	buckets, err := g.tree.ListBuckets()
+	if err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+
+	var resp listAllMyBucketsResult
+	for _, b := range buckets {
+		resp.Buckets = append(resp.Buckets, xmlBucket{Name: b.Name, CreationDate: b.CreationDate.UTC().Format(amzTimeFormat)})
+	}
+	writeXML(w, http.StatusOK, resp)
+}
+
+func (g *Gateway) listObjects(w http.ResponseWriter, r *http.Request, bucket string) {
+	q := r.URL.Query()
+	maxKeys := 1000
+	if v := q.Get("max-keys"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			maxKeys = n
+		}
+	}
+
+	result, err := g.tree.ListObjects(bucket, q.Get("prefix"), q.Get("delimiter"), q.Get("continuation-token"), maxKeys)
+	if err != nil {
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchBucket", err.Error())
+		return
+	}
+
+	resp := listBucketResult{
+		Name:                  bucket,
+		Prefix:                q.Get("prefix"),
+		Delimiter:             q.Get("delimiter"),
+		MaxKeys:               maxKeys,
+		IsTruncated:           result.IsTruncated,
+		ContinuationToken:     q.Get("continuation-token"),
+		NextContinuationToken: result.NextContinuationToken,
+	}
+	for _, o := range result.Objects {
+		resp.Contents = append(resp.Contents, xmlContent{
+			Key:          o.Key,
+			LastModified: o.LastModified.UTC().Format(amzTimeFormat),
+			ETag:         o.ETag,
+			Size:         o.Size,
+		})
+	}
+	for _, p := range result.CommonPrefixes {
+		resp.CommonPrefixes = append(resp.CommonPrefixes, xmlCommonPrefix{Prefix: p})
+	}
+	writeXML(w, http.StatusOK, resp)
+}
+
+func setObjectHeaders(w http.ResponseWriter, info ObjectInfo) {
+	w.Header().Set("ETag", info.ETag)
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	w.Header().Set("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+	w.Header().Set("Accept-Ranges", "bytes")
+}
+
+func (g *Gateway) headObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	info, err := g.tree.StatObject(bucket, key)
+	if err != nil {
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchKey", err.Error())
+		return
+	}
+	setObjectHeaders(w, info)
+	w.WriteHeader(http.StatusOK)
+}
+
+// parseRange parses a single-range "bytes=start-end" Range header. Multiple
+// ranges and suffix ranges ("bytes=-500") aren't supported.
+func parseRange(header string) (*ByteRange, error) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return nil, fmt.Errorf("s3gw: unsupported Range header %q", header)
+	}
+	spec := strings.TrimPrefix(header, prefix)
+	if strings.Contains(spec, ",") {
+		return nil, fmt.Errorf("s3gw: multiple ranges are not supported")
+	}
+
+	parts := strings.SplitN(spec, "-", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return nil, fmt.Errorf("s3gw: unsupported Range header %q", header)
+	}
+	start, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("s3gw: invalid range start: %w", err)
+	}
+	end := int64(-1)
+	if parts[1] != "" {
+		if end, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+			return nil, fmt.Errorf("s3gw: invalid range end: %w", err)
+		}
+	}
+	return &ByteRange{Start: start, End: end}, nil
+}
+
+func (g *Gateway) getObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	var rng *ByteRange
+	if h := r.Header.Get("Range"); h != "" {
+		parsed, err := parseRange(h)
+		if err != nil {
+			writeS3Error(w, r, http.StatusRequestedRangeNotSatisfiable, "InvalidRange", err.Error())
+			return
+		}
+		rng = parsed
+	}
+
+	body, info, err := g.tree.OpenObject(bucket, key, rng)
+	if err != nil {
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchKey", err.Error())
+		return
+	}
+	defer body.Close()
+
+	setObjectHeaders(w, info)
+	status := http.StatusOK
+	if rng != nil {
+		end := rng.End
+		if end < 0 || end >= info.Size {
+			end = info.Size - 1
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", rng.Start, end, info.Size))
+		w.Header().Set("Content-Length", strconv.FormatInt(end-rng.Start+1, 10))
+		status = http.StatusPartialContent
+	}
+	w.WriteHeader(status)
+	_, _ = io.Copy(w, body)
+}
+
+func (g *Gateway) putObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	info, err := g.tree.PutObject(bucket, key, r.Body, r.ContentLength)
+	if err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	w.Header().Set("ETag", info.ETag)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (g *Gateway) deleteObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	if err := g.tree.DeleteObject(bucket, key); err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (g *Gateway) deleteObjects(w http.ResponseWriter, r *http.Request, bucket string) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeS3Error(w, r, http.StatusBadRequest, "MalformedXML", err.Error())
+		return
+	}
+	var req deleteObjectsRequestXML
+	if err := xml.Unmarshal(body, &req); err != nil {
+		writeS3Error(w, r, http.StatusBadRequest, "MalformedXML", err.Error())
+		return
+	}
+
+	var result deleteResultXML
+	for _, obj := range req.Objects {
+		if err := g.tree.DeleteObject(bucket, obj.Key); err != nil {
+			result.Errors = append(result.Errors, deleteErrorXML{Key: obj.Key, Code: "InternalError", Message: err.Error()})
+			continue
+		}
+		result.Deleted = append(result.Deleted, deletedXML{Key: obj.Key})
+	}
+	writeXML(w, http.StatusOK, result)
+}
+
+func (g *Gateway) copyObject(w http.ResponseWriter, r *http.Request, dstBucket, dstKey string) {
+	srcBucket, srcKey := splitPath("/" + strings.TrimPrefix(r.Header.Get("X-Amz-Copy-Source"), "/"))
+	if srcBucket == "" || srcKey == "" {
+		writeS3Error(w, r, http.StatusBadRequest, "InvalidArgument", "malformed X-Amz-Copy-Source")
+		return
+	}
+
+	info, err := g.tree.CopyObject(srcBucket, srcKey, dstBucket, dstKey)
+	if err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	writeXML(w, http.StatusOK, copyObjectResult{ETag: info.ETag, LastModified: info.LastModified.UTC().Format(amzTimeFormat)})
+}
+
+func (g *Gateway) createMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	uploadID, err := g.tree.CreateMultipartUpload(bucket, key)
+	if err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	writeXML(w, http.StatusOK, initiateMultipartUploadResult{Bucket: bucket, Key: key, UploadId: uploadID})
+}
+
+func (g *Gateway) uploadPart(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	q := r.URL.Query()
+	partNumber, err := strconv.Atoi(q.Get("partNumber"))
+	if err != nil || partNumber <= 0 {
+		writeS3Error(w, r, http.StatusBadRequest, "InvalidArgument", "invalid partNumber")
+		return
+	}
+
+	etag, err := g.tree.UploadPart(bucket, key, q.Get("uploadId"), partNumber, r.Body, r.ContentLength)
+	if err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (g *Gateway) completeMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeS3Error(w, r, http.StatusBadRequest, "MalformedXML", err.Error())
+		return
+	}
+	var req completeMultipartUploadRequest
+	if err := xml.Unmarshal(body, &req); err != nil {
+		writeS3Error(w, r, http.StatusBadRequest, "MalformedXML", err.Error())
+		return
+	}
+
+	parts := make([]CompletedPart, 0, len(req.Parts))
+	for _, p := range req.Parts {
+		parts = append(parts, CompletedPart{PartNumber: p.PartNumber, ETag: p.ETag})
+	}
+
+	info, err := g.tree.CompleteMultipartUpload(bucket, key, r.URL.Query().Get("uploadId"), parts)
+	if err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	writeXML(w, http.StatusOK, completeMultipartUploadResult{Bucket: bucket, Key: key, ETag: info.ETag})
+}
+
+func (g *Gateway) abortMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	if err := g.tree.AbortMultipartUpload(bucket, key, r.URL.Query().Get("uploadId")); err != nil {
+		writeS3Error(w, r, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
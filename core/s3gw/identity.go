@@ -0,0 +1,59 @@
+package s3gw
+
+import "strings"
+
+// Identity is one access-key credential the gateway will accept, together
+// with which bucket prefixes it's allowed to touch.
+type Identity struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	// AllowedPrefixes restricts which "bucket" or "bucket/key" strings this
+	// identity may operate on. An empty slice means no restriction.
+	AllowedPrefixes []string
+}
+
+// Allows reports whether id may operate on bucket, optionally narrowed to
+// key, honoring AllowedPrefixes.
+func (id Identity) Allows(bucket, key string) bool {
+	if len(id.AllowedPrefixes) == 0 {
+		return true
+	}
+	target := bucket
+	if key != "" {
+		target = bucket + "/" + key
+	}
+	for _, prefix := range id.AllowedPrefixes {
+		if strings.HasPrefix(target, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// IdentityStore maps an AccessKeyId to its secret and allowed prefixes, so
+// SigV4 verification and authorization can be swapped independently of the
+// gateway's HTTP/S3 protocol layer.
+type IdentityStore interface {
+	Lookup(accessKeyId string) (Identity, bool)
+}
+
+// StaticIdentityStore is the simplest IdentityStore: a fixed set of
+// identities configured up front, suitable for single-tenant mounts.
+type StaticIdentityStore struct {
+	identities map[string]Identity
+}
+
+// NewStaticIdentityStore builds a StaticIdentityStore from a fixed list of
+// identities, keyed by AccessKeyId.
+func NewStaticIdentityStore(identities ...Identity) *StaticIdentityStore {
+	s := &StaticIdentityStore{identities: make(map[string]Identity, len(identities))}
+	for _, id := range identities {
+		s.identities[id.AccessKeyId] = id
+	}
+	return s
+}
+
+func (s *StaticIdentityStore) Lookup(accessKeyId string) (Identity, bool) {
+	id, ok := s.identities[accessKeyId]
+	return id, ok
+}
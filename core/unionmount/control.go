@@ -0,0 +1,129 @@
+package unionmount
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// ControlConfig configures ControlServer's listener. Mirrors
+// accesskey.AdminConfig's unix-socket-by-default shape, since this is the
+// same kind of local, trusted-caller-only control surface.
+type ControlConfig struct {
+	// SocketPath is where ControlServer listens for mount/unmount/list
+	// requests, e.g. alongside the mount point as ".unionmount.sock".
+	SocketPath string
+}
+
+// ControlServer exposes Registry.Add/Remove/List over a small HTTP API
+// served on a unix socket - the "control socket ... to add/remove children
+// at runtime" this package's doc comment describes, now implemented. It
+// does not require a FUSE lookup path: callers reach it directly over the
+// socket instead of through a magic .mount/.unmount xattr, so it's usable
+// today even though this tree has no mount layer to route xattr writes
+// through yet.
+//
+//	POST   /mounts       add a child ({"path","bucket","prefix","endpoint","credentials_profile"} JSON body)
+//	DELETE /mounts/{path} remove the child mounted at path
+//	GET    /mounts        list every currently mounted child
+type ControlServer struct {
+	registry *Registry
+}
+
+// NewControlServer constructs a ControlServer backed by registry.
+func NewControlServer(registry *Registry) (*ControlServer, error) {
+	if registry == nil {
+		return nil, errors.New("unionmount: registry is required")
+	}
+	return &ControlServer{registry: registry}, nil
+}
+
+// Listen opens the unix socket at cfg.SocketPath, removing any stale socket
+// file left behind by a previous run first.
+func (c *ControlServer) Listen(cfg ControlConfig) (net.Listener, error) {
+	if cfg.SocketPath == "" {
+		return nil, errors.New("unionmount: SocketPath is required")
+	}
+	_ = os.Remove(cfg.SocketPath)
+	return net.Listen("unix", cfg.SocketPath)
+}
+
+// Serve accepts connections on lis until it's closed, applying mount
+// requests to the Registry this ControlServer was constructed with.
+func (c *ControlServer) Serve(lis net.Listener) error {
+	return http.Serve(lis, c)
+}
+
+func (c *ControlServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := strings.TrimPrefix(r.URL.Path, "/mounts")
+	p = strings.Trim(p, "/")
+
+	switch {
+	case p == "" && r.Method == http.MethodPost:
+		c.add(w, r)
+	case p == "" && r.Method == http.MethodGet:
+		c.list(w)
+	case p != "" && r.Method == http.MethodDelete:
+		c.remove(w, p)
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+func (c *ControlServer) add(w http.ResponseWriter, r *http.Request) {
+	var cfg ChildConfig
+	if r.Body != nil {
+		defer r.Body.Close()
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	index, err := c.registry.Add(cfg)
+	if err != nil {
+		writeRegistryError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, mountResponse{Path: normalizePath(cfg.Path), MountIndex: index})
+}
+
+func (c *ControlServer) remove(w http.ResponseWriter, pathStr string) {
+	if err := c.registry.Remove(pathStr); err != nil {
+		writeRegistryError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *ControlServer) list(w http.ResponseWriter) {
+	children := c.registry.List()
+	writeJSON(w, http.StatusOK, children)
+}
+
+type mountResponse struct {
+	Path       string `json:"path"`
+	MountIndex uint32 `json:"mount_index"`
+}
+
+func writeRegistryError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrAlreadyMounted):
+		status = http.StatusConflict
+	case errors.Is(err, ErrNotMounted):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrTooManyMounts):
+		status = http.StatusInsufficientStorage
+	}
+	http.Error(w, err.Error(), status)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
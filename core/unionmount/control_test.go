@@ -0,0 +1,154 @@
+package unionmount_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/valandreev/tigrisfs/core/unionmount"
+)
+
+// startControlServer constructs a ControlServer over a fresh Registry,
+// listens on a socket under t.TempDir(), and serves requests in the
+// background until the test ends.
+func startControlServer(t *testing.T) (*unionmount.Registry, *http.Client, string) {
+	t.Helper()
+
+	registry := unionmount.NewRegistry()
+	srv, err := unionmount.NewControlServer(registry)
+	if err != nil {
+		t.Fatalf("NewControlServer failed: %v", err)
+	}
+
+	socketPath := filepath.Join(t.TempDir(), "unionmount.sock")
+	lis, err := srv.Listen(unionmount.ControlConfig{SocketPath: socketPath})
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	t.Cleanup(func() { _ = lis.Close() })
+
+	go func() { _ = srv.Serve(lis) }()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+	return registry, client, socketPath
+}
+
+func TestControlServerAddsChildViaRegistry(t *testing.T) {
+	registry, client, _ := startControlServer(t)
+
+	body, _ := json.Marshal(unionmount.ChildConfig{Path: "archive", Bucket: "my-archive"})
+	resp, err := client.Post("http://unix/mounts", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /mounts failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+
+	if _, _, ok := registry.Resolve("archive/2024/x.bin"); !ok {
+		t.Fatalf("expected the registry to resolve a path under the newly added child")
+	}
+}
+
+func TestControlServerRejectsDuplicateMount(t *testing.T) {
+	registry, client, _ := startControlServer(t)
+	if _, err := registry.Add(unionmount.ChildConfig{Path: "archive", Bucket: "my-archive"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	body, _ := json.Marshal(unionmount.ChildConfig{Path: "archive", Bucket: "other"})
+	resp, err := client.Post("http://unix/mounts", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /mounts failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", resp.StatusCode)
+	}
+}
+
+func TestControlServerRemovesChild(t *testing.T) {
+	registry, client, _ := startControlServer(t)
+	if _, err := registry.Add(unionmount.ChildConfig{Path: "archive", Bucket: "my-archive"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, "http://unix/mounts/archive", nil)
+	if err != nil {
+		t.Fatalf("NewRequest failed: %v", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("DELETE /mounts/archive failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+
+	if _, _, ok := registry.Resolve("archive"); ok {
+		t.Fatalf("expected archive to no longer resolve after removal")
+	}
+}
+
+func TestControlServerRemoveUnmountedPathReturnsNotFound(t *testing.T) {
+	_, client, _ := startControlServer(t)
+
+	req, err := http.NewRequest(http.MethodDelete, "http://unix/mounts/never-mounted", nil)
+	if err != nil {
+		t.Fatalf("NewRequest failed: %v", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("DELETE failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestControlServerListsChildren(t *testing.T) {
+	registry, client, _ := startControlServer(t)
+	if _, err := registry.Add(unionmount.ChildConfig{Path: "archive", Bucket: "my-archive"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if _, err := registry.Add(unionmount.ChildConfig{Path: "scratch", Bucket: "my-scratch"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	resp, err := client.Get("http://unix/mounts")
+	if err != nil {
+		t.Fatalf("GET /mounts failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	var children []unionmount.ChildConfig
+	if err := json.NewDecoder(resp.Body).Decode(&children); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(children) != 2 {
+		t.Fatalf("expected 2 children, got %d", len(children))
+	}
+}
+
+func TestNewControlServerRejectsNilRegistry(t *testing.T) {
+	if _, err := unionmount.NewControlServer(nil); err == nil {
+		t.Fatalf("expected error for nil registry")
+	}
+}
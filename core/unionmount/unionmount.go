@@ -0,0 +1,289 @@
+// Package unionmount manages the child mounts behind a multi-bucket union
+// namespace: a YAML config listing {path, bucket, prefix, endpoint,
+// credentials_profile} entries, a Registry supporting runtime add/remove of
+// those entries, the inode-ID namespace segmentation each child needs so two
+// mounts never hand out the same inode number, and statfs usage
+// aggregation across them.
+//
+// ControlServer (control.go) is the runtime add/remove control surface:
+// a small HTTP API served over a unix socket that calls Registry.Add/Remove/
+// List directly, independent of any FUSE lookup path. A "child mount"
+// feature test exercised against core.Goofys's Mount/Unmount (as
+// TestNestedMountUnmountSimple/TestUnmountBucketWithChild - named in the
+// request that asked for this package - would be) can't exist in this
+// snapshot: that mount implementation isn't present here, so there's no
+// FUSE lookup path to route a .mount/.unmount xattr write through, or for
+// Registry.Resolve to be called from. What's built here is everything that
+// doesn't depend on that path existing: the config format, the registry,
+// its control socket, the inode segmentation, and the usage aggregation.
+package unionmount
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrAlreadyMounted is returned by Registry.Add for a path that already has
+// a child mounted on it.
+var ErrAlreadyMounted = errors.New("unionmount: path already mounted")
+
+// ErrNotMounted is returned by Registry.Remove for a path with no child
+// mounted on it.
+var ErrNotMounted = errors.New("unionmount: path not mounted")
+
+// ErrTooManyMounts is returned by Registry.Add once MaxMounts children are
+// already registered.
+var ErrTooManyMounts = errors.New("unionmount: too many concurrent child mounts")
+
+// ChildConfig describes one bucket mounted into the union namespace.
+type ChildConfig struct {
+	// Path is where this child is mounted, relative to the union root
+	// (e.g. "archive/2024"); it shadows the parent for everything under it.
+	Path string `yaml:"path" json:"path"`
+	// Bucket is the backing bucket name.
+	Bucket string `yaml:"bucket" json:"bucket"`
+	// Prefix restricts the child to objects under this key prefix; empty
+	// means the whole bucket.
+	Prefix string `yaml:"prefix" json:"prefix"`
+	// Endpoint overrides the default backend endpoint for this child,
+	// for buckets hosted on a different region or provider.
+	Endpoint string `yaml:"endpoint" json:"endpoint"`
+	// CredentialsProfile names the credentials profile to authenticate
+	// this child's backend requests with.
+	CredentialsProfile string `yaml:"credentials_profile" json:"credentials_profile"`
+}
+
+// Config is the top-level YAML document listing every child mount to
+// attach at startup.
+type Config struct {
+	Children []ChildConfig `yaml:"children"`
+}
+
+// LoadConfig reads and validates a Config from path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse unionmount config: %w", err)
+	}
+
+	seen := make(map[string]struct{}, len(cfg.Children))
+	for i := range cfg.Children {
+		cfg.Children[i].Path = normalizePath(cfg.Children[i].Path)
+		c := cfg.Children[i]
+		if c.Path == "" {
+			return nil, fmt.Errorf("unionmount config: child %d: path is required", i)
+		}
+		if c.Bucket == "" {
+			return nil, fmt.Errorf("unionmount config: child %q: bucket is required", c.Path)
+		}
+		if _, dup := seen[c.Path]; dup {
+			return nil, fmt.Errorf("unionmount config: duplicate path %q", c.Path)
+		}
+		seen[c.Path] = struct{}{}
+	}
+
+	return &cfg, nil
+}
+
+// Inode-ID namespace segmentation: the top mountIndexBits bits of a global
+// inode number identify which mount it belongs to, and the remaining bits
+// are that mount's own local inode number. Index 0 is reserved for the
+// union root itself, so a freshly mounted child's local inode numbering
+// never collides with the root filesystem's.
+const (
+	mountIndexBits  = 12
+	mountIndexShift = 64 - mountIndexBits
+	// MaxMounts is the largest number of concurrent child mounts the
+	// inode namespace has room for (the root takes index 0).
+	MaxMounts      = 1<<mountIndexBits - 1
+	localInodeMask = 1<<mountIndexShift - 1
+)
+
+// SegmentInode combines mountIndex and localInode into a single global
+// inode number, or returns an error if localInode doesn't fit in the bits
+// left over after reserving mountIndexBits for the mount index.
+func SegmentInode(mountIndex uint32, localInode uint64) (uint64, error) {
+	if mountIndex > MaxMounts {
+		return 0, fmt.Errorf("unionmount: mount index %d exceeds MaxMounts %d", mountIndex, MaxMounts)
+	}
+	if localInode > localInodeMask {
+		return 0, fmt.Errorf("unionmount: local inode %d does not fit in %d bits", localInode, mountIndexShift)
+	}
+	return uint64(mountIndex)<<mountIndexShift | localInode, nil
+}
+
+// SplitInode recovers the mount index and local inode number a global
+// inode was built from via SegmentInode.
+func SplitInode(global uint64) (mountIndex uint32, localInode uint64) {
+	return uint32(global >> mountIndexShift), global & localInodeMask
+}
+
+// Usage is a filesystem's statfs-level capacity and inode counts.
+type Usage struct {
+	TotalBytes  uint64
+	FreeBytes   uint64
+	TotalInodes uint64
+	FreeInodes  uint64
+}
+
+// AggregateUsage sums per-mount Usage into the single statfs result the
+// union root reports, since each child mount has its own backend quota.
+func AggregateUsage(usages []Usage) Usage {
+	var total Usage
+	for _, u := range usages {
+		total.TotalBytes += u.TotalBytes
+		total.FreeBytes += u.FreeBytes
+		total.TotalInodes += u.TotalInodes
+		total.FreeInodes += u.FreeInodes
+	}
+	return total
+}
+
+// mountEntry is what the Registry tracks per mounted child.
+type mountEntry struct {
+	cfg   ChildConfig
+	index uint32
+}
+
+// Registry is the runtime, goroutine-safe set of mounted children. It
+// supports adding and removing children at runtime (the "runtime add/remove
+// ... through a control socket or a magic xattr" the request asks for would
+// call Add/Remove directly) and resolving which child, if any, shadows a
+// given lookup path.
+type Registry struct {
+	mu      sync.RWMutex
+	byPath  map[string]*mountEntry
+	freeIdx []uint32
+	nextIdx uint32
+}
+
+// NewRegistry constructs an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{byPath: make(map[string]*mountEntry)}
+}
+
+// Add mounts cfg, allocating it a fresh (or recycled) mount index. It
+// returns ErrAlreadyMounted if cfg.Path already has a child mounted on it,
+// and ErrTooManyMounts once MaxMounts children are registered at once.
+func (r *Registry) Add(cfg ChildConfig) (uint32, error) {
+	p := normalizePath(cfg.Path)
+	if p == "" {
+		return 0, errors.New("unionmount: path is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.byPath[p]; ok {
+		return 0, ErrAlreadyMounted
+	}
+
+	index, err := r.allocIndex()
+	if err != nil {
+		return 0, err
+	}
+
+	cfg.Path = p
+	r.byPath[p] = &mountEntry{cfg: cfg, index: index}
+	return index, nil
+}
+
+// Remove unmounts the child at path. It returns ErrNotMounted if nothing is
+// mounted there.
+func (r *Registry) Remove(pathStr string) error {
+	p := normalizePath(pathStr)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.byPath[p]
+	if !ok {
+		return ErrNotMounted
+	}
+	delete(r.byPath, p)
+	r.freeIdx = append(r.freeIdx, entry.index)
+	return nil
+}
+
+// Resolve returns the child mounted at the longest registered path that is
+// an ancestor of (or equal to) pathStr, since a child shadows its parent
+// for everything under it. It reports ok=false if no child covers pathStr.
+func (r *Registry) Resolve(pathStr string) (cfg ChildConfig, mountIndex uint32, ok bool) {
+	p := normalizePath(pathStr)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for {
+		if entry, found := r.byPath[p]; found {
+			return entry.cfg, entry.index, true
+		}
+		if p == "" {
+			return ChildConfig{}, 0, false
+		}
+		p = parentPath(p)
+	}
+}
+
+// List returns every currently mounted child, in no particular order.
+func (r *Registry) List() []ChildConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]ChildConfig, 0, len(r.byPath))
+	for _, entry := range r.byPath {
+		out = append(out, entry.cfg)
+	}
+	return out
+}
+
+// allocIndex hands out a free mount index, reusing the one most recently
+// freed by Remove before minting a new one, and starting from 1 since index
+// 0 is reserved for the union root's own inode namespace. Callers must
+// already hold r.mu.
+func (r *Registry) allocIndex() (uint32, error) {
+	if n := len(r.freeIdx); n > 0 {
+		idx := r.freeIdx[n-1]
+		r.freeIdx = r.freeIdx[:n-1]
+		return idx, nil
+	}
+	if r.nextIdx == 0 {
+		r.nextIdx = 1
+	}
+	if r.nextIdx > MaxMounts {
+		return 0, ErrTooManyMounts
+	}
+	idx := r.nextIdx
+	r.nextIdx++
+	return idx, nil
+}
+
+// normalizePath cleans pathStr into the form Registry and Resolve key
+// lookups compare against: slash-separated, no leading/trailing slash.
+func normalizePath(p string) string {
+	p = strings.Trim(path.Clean("/"+p), "/")
+	if p == "." {
+		return ""
+	}
+	return p
+}
+
+// parentPath returns p's parent in the same normalized form, or "" if p is
+// already the root.
+func parentPath(p string) string {
+	if i := strings.LastIndexByte(p, '/'); i >= 0 {
+		return p[:i]
+	}
+	return ""
+}
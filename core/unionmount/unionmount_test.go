@@ -0,0 +1,237 @@
+package unionmount_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/valandreev/tigrisfs/core/unionmount"
+)
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "unionmount.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigParsesChildren(t *testing.T) {
+	path := writeConfig(t, `
+children:
+  - path: archive/2024
+    bucket: my-archive
+    prefix: 2024/
+    endpoint: https://fly.storage.tigris.dev
+    credentials_profile: archive-ro
+  - path: scratch
+    bucket: my-scratch
+`)
+
+	cfg, err := unionmount.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(cfg.Children) != 2 {
+		t.Fatalf("expected 2 children, got %d", len(cfg.Children))
+	}
+	if cfg.Children[0].Path != "archive/2024" || cfg.Children[0].Bucket != "my-archive" {
+		t.Fatalf("unexpected first child: %+v", cfg.Children[0])
+	}
+	if cfg.Children[1].Path != "scratch" || cfg.Children[1].Bucket != "my-scratch" {
+		t.Fatalf("unexpected second child: %+v", cfg.Children[1])
+	}
+}
+
+func TestLoadConfigRejectsMissingPath(t *testing.T) {
+	path := writeConfig(t, `
+children:
+  - bucket: my-archive
+`)
+
+	if _, err := unionmount.LoadConfig(path); err == nil {
+		t.Fatalf("expected error for missing path")
+	}
+}
+
+func TestLoadConfigRejectsMissingBucket(t *testing.T) {
+	path := writeConfig(t, `
+children:
+  - path: archive
+`)
+
+	if _, err := unionmount.LoadConfig(path); err == nil {
+		t.Fatalf("expected error for missing bucket")
+	}
+}
+
+func TestLoadConfigRejectsDuplicatePath(t *testing.T) {
+	path := writeConfig(t, `
+children:
+  - path: archive
+    bucket: my-archive
+  - path: /archive/
+    bucket: my-other-archive
+`)
+
+	if _, err := unionmount.LoadConfig(path); err == nil {
+		t.Fatalf("expected error for duplicate path after normalization")
+	}
+}
+
+func TestSegmentInodeRoundTrips(t *testing.T) {
+	global, err := unionmount.SegmentInode(3, 12345)
+	if err != nil {
+		t.Fatalf("SegmentInode failed: %v", err)
+	}
+	index, local := unionmount.SplitInode(global)
+	if index != 3 || local != 12345 {
+		t.Fatalf("expected (3, 12345), got (%d, %d)", index, local)
+	}
+}
+
+func TestSegmentInodeRejectsMountIndexTooLarge(t *testing.T) {
+	if _, err := unionmount.SegmentInode(unionmount.MaxMounts+1, 0); err == nil {
+		t.Fatalf("expected error for mount index exceeding MaxMounts")
+	}
+}
+
+func TestSegmentInodeRejectsLocalInodeTooLarge(t *testing.T) {
+	if _, err := unionmount.SegmentInode(0, 1<<63); err == nil {
+		t.Fatalf("expected error for local inode not fitting in the remaining bits")
+	}
+}
+
+func TestAggregateUsageSumsAcrossMounts(t *testing.T) {
+	got := unionmount.AggregateUsage([]unionmount.Usage{
+		{TotalBytes: 100, FreeBytes: 40, TotalInodes: 10, FreeInodes: 4},
+		{TotalBytes: 200, FreeBytes: 10, TotalInodes: 20, FreeInodes: 1},
+	})
+	want := unionmount.Usage{TotalBytes: 300, FreeBytes: 50, TotalInodes: 30, FreeInodes: 5}
+	if got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestAggregateUsageOfEmptySliceIsZero(t *testing.T) {
+	got := unionmount.AggregateUsage(nil)
+	if got != (unionmount.Usage{}) {
+		t.Fatalf("expected zero Usage, got %+v", got)
+	}
+}
+
+func TestRegistryAddThenResolve(t *testing.T) {
+	r := unionmount.NewRegistry()
+
+	index, err := r.Add(unionmount.ChildConfig{Path: "archive/2024", Bucket: "my-archive"})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if index == 0 {
+		t.Fatalf("expected a nonzero mount index, index 0 is reserved for the root")
+	}
+
+	cfg, gotIndex, ok := r.Resolve("archive/2024/jan/report.csv")
+	if !ok {
+		t.Fatalf("expected Resolve to find the child mounted above the path")
+	}
+	if cfg.Bucket != "my-archive" || gotIndex != index {
+		t.Fatalf("unexpected resolve result: cfg=%+v index=%d", cfg, gotIndex)
+	}
+}
+
+func TestRegistryResolvePicksLongestMatch(t *testing.T) {
+	r := unionmount.NewRegistry()
+	if _, err := r.Add(unionmount.ChildConfig{Path: "archive", Bucket: "outer"}); err != nil {
+		t.Fatalf("Add outer failed: %v", err)
+	}
+	if _, err := r.Add(unionmount.ChildConfig{Path: "archive/2024", Bucket: "inner"}); err != nil {
+		t.Fatalf("Add inner failed: %v", err)
+	}
+
+	cfg, _, ok := r.Resolve("archive/2024/jan/report.csv")
+	if !ok || cfg.Bucket != "inner" {
+		t.Fatalf("expected longest-prefix match to pick inner, got %+v ok=%v", cfg, ok)
+	}
+
+	cfg, _, ok = r.Resolve("archive/2023/report.csv")
+	if !ok || cfg.Bucket != "outer" {
+		t.Fatalf("expected fallback to outer, got %+v ok=%v", cfg, ok)
+	}
+}
+
+func TestRegistryResolveNoMatch(t *testing.T) {
+	r := unionmount.NewRegistry()
+	if _, err := r.Add(unionmount.ChildConfig{Path: "archive", Bucket: "my-archive"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	if _, _, ok := r.Resolve("scratch/file.txt"); ok {
+		t.Fatalf("expected no match for an unrelated path")
+	}
+}
+
+func TestRegistryAddRejectsDuplicatePath(t *testing.T) {
+	r := unionmount.NewRegistry()
+	if _, err := r.Add(unionmount.ChildConfig{Path: "archive", Bucket: "my-archive"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if _, err := r.Add(unionmount.ChildConfig{Path: "/archive/", Bucket: "my-other-archive"}); err != unionmount.ErrAlreadyMounted {
+		t.Fatalf("expected ErrAlreadyMounted, got %v", err)
+	}
+}
+
+func TestRegistryRemoveThenResolveFails(t *testing.T) {
+	r := unionmount.NewRegistry()
+	if _, err := r.Add(unionmount.ChildConfig{Path: "archive", Bucket: "my-archive"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := r.Remove("archive"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, _, ok := r.Resolve("archive/file.txt"); ok {
+		t.Fatalf("expected no match after removal")
+	}
+}
+
+func TestRegistryRemoveRejectsUnmountedPath(t *testing.T) {
+	r := unionmount.NewRegistry()
+	if err := r.Remove("archive"); err != unionmount.ErrNotMounted {
+		t.Fatalf("expected ErrNotMounted, got %v", err)
+	}
+}
+
+func TestRegistryRecyclesIndexAfterRemove(t *testing.T) {
+	r := unionmount.NewRegistry()
+	first, err := r.Add(unionmount.ChildConfig{Path: "archive", Bucket: "my-archive"})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := r.Remove("archive"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	second, err := r.Add(unionmount.ChildConfig{Path: "scratch", Bucket: "my-scratch"})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if second != first {
+		t.Fatalf("expected the freed index %d to be recycled, got %d", first, second)
+	}
+}
+
+func TestRegistryList(t *testing.T) {
+	r := unionmount.NewRegistry()
+	if _, err := r.Add(unionmount.ChildConfig{Path: "archive", Bucket: "my-archive"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if _, err := r.Add(unionmount.ChildConfig{Path: "scratch", Bucket: "my-scratch"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	list := r.List()
+	if len(list) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(list))
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2024 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// NewAuditLogger returns a LogHandle that always writes structured JSON to
+// w, independent of DefaultLogConfig.Format (or any other LogConfig passed
+// to NewLogger): it's meant to back a machine-parsable audit trail, such as
+// the uploader package's AuditSink, rather than the human/JSON toggle the
+// rest of this package's loggers offer. Callers populate events using the
+// stable field names operators can rely on for SIEM ingestion: ts, event,
+// upload_id, path, etag, attempt, bytes, duration_ms, reason.
+func NewAuditLogger(name string, w io.Writer) *LogHandle {
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+
+	ts := zerolog.HookFunc(func(e *zerolog.Event, _ zerolog.Level, _ string) {
+		e.Time("ts", time.Now())
+	})
+	logger := zerolog.New(w).Hook(ts).With().Str("module", name).Logger()
+	return &LogHandle{Logger: &logger, name: name}
+}
+
+// AuditWriter returns the io.Writer NewAuditLogger should write to per
+// config.AuditFile: a size/age-rotated file (lumberjack-style) when it's a
+// path, syslog when it's "syslog" (via the existing InitSyslog plumbing),
+// or os.Stderr when unset.
+func AuditWriter(config *LogConfig) (io.Writer, error) {
+	switch config.AuditFile {
+	case "":
+		return os.Stderr, nil
+	case "syslog":
+		return InitSyslog()
+	default:
+		return &lumberjack.Logger{
+			Filename:   config.AuditFile,
+			MaxSize:    config.AuditMaxSizeMB,
+			MaxAge:     config.AuditMaxAgeDays,
+			MaxBackups: config.AuditMaxBackups,
+			Compress:   config.AuditCompress,
+		}, nil
+	}
+}
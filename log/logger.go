@@ -156,6 +156,17 @@ type LogConfig struct {
 	Format     string
 	Color      bool
 	SampleRate float64 `json:"sample_rate" mapstructure:"sample_rate" yaml:"sample_rate"`
+
+	// AuditFile names the destination NewAuditLogger writes to via
+	// AuditWriter: a filesystem path (rotated per AuditMaxSizeMB/
+	// AuditMaxAgeDays/AuditMaxBackups), "syslog" to route through
+	// InitSyslog, or "" for os.Stderr. Unlike Format, the audit sink always
+	// emits JSON regardless of this config's Format.
+	AuditFile       string `json:"audit_file" mapstructure:"audit_file" yaml:"audit_file"`
+	AuditMaxSizeMB  int    `json:"audit_max_size_mb" mapstructure:"audit_max_size_mb" yaml:"audit_max_size_mb"`
+	AuditMaxAgeDays int    `json:"audit_max_age_days" mapstructure:"audit_max_age_days" yaml:"audit_max_age_days"`
+	AuditMaxBackups int    `json:"audit_max_backups" mapstructure:"audit_max_backups" yaml:"audit_max_backups"`
+	AuditCompress   bool   `json:"audit_compress" mapstructure:"audit_compress" yaml:"audit_compress"`
 }
 
 func consoleFormatCallerWithModule(i any, module string) string {
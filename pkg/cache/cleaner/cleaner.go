@@ -7,10 +7,13 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/valandreev/tigrisfs/log"
+	"github.com/valandreev/tigrisfs/pkg/cache/files"
 	"github.com/valandreev/tigrisfs/pkg/cache/index"
 )
 
@@ -41,6 +44,34 @@ type Config struct {
 	MaxCacheBytes  int64
 	MinFreePercent int
 	CleanInterval  time.Duration
+
+	// MaxAge, when positive, bounds how long a clean cached file may sit
+	// unused before an age-based sweep evicts it regardless of capacity
+	// pressure (vfs-cache-max-age semantics). Zero disables age-based
+	// eviction, leaving RunOnce's behaviour unchanged.
+	MaxAge time.Duration
+
+	// Policy selects the capacity-driven eviction order by name: "lru"
+	// (default), "lfu", or "gdsf". See PolicyLRU/PolicyLFU/PolicyGDSF.
+	// Ignored if the caller also passes WithPolicy, which always wins.
+	Policy string
+
+	// AdmissionThreshold, when greater than 1, mirrors the value the cache
+	// read/write path is configured with for index.AdmissionGate: a path
+	// must be observed this many times before it is admitted to the
+	// on-disk cache, serving the first AdmissionThreshold-1 reads
+	// pass-through. RunOnce only uses this value to bound AdmissionTTL
+	// expiry below; the admission decision itself is made by whatever
+	// constructs an AdmissionGate against the same index, which as of this
+	// writing is nothing in core/ — see AdmissionTTL.
+	AdmissionThreshold int
+
+	// AdmissionTTL, when positive (and AdmissionThreshold > 1), bounds how
+	// long a pending-access counter may sit unobserved before RunOnce
+	// purges it, so a path read once and never again doesn't keep a
+	// counter around forever waiting for a second access that never
+	// comes.
+	AdmissionTTL time.Duration
 }
 
 // Report summarises a cleaner run.
@@ -49,8 +80,25 @@ type Report struct {
 	TotalBefore int64
 	TotalAfter  int64
 	BytesFreed  int64
-	Evicted     []string
-	Emergency   bool
+	// Evicted lists every path removed this run, age-based evictions first.
+	Evicted []string
+	// EvictedByAge lists paths removed by the age-based sweep because they
+	// were clean and older than MaxAge, independent of capacity pressure.
+	EvictedByAge []string
+	// EvictedByLRU lists paths removed by the capacity-driven, Policy-ordered
+	// fallback (whichever Policy is configured, not only LRU).
+	EvictedByLRU []string
+	// Reset lists paths that kept their index entry but had one or more
+	// clean chunks reclaimed via Resetter because they were open/dirty and
+	// therefore ineligible for full eviction. Only populated when capacity
+	// pressure remains after the eviction passes above and a ResetterLookup
+	// is registered.
+	Reset []string
+	// PendingAccessExpired counts pre-admission access counters purged this
+	// run because they sat unobserved past Config.AdmissionTTL. Zero unless
+	// Config.AdmissionThreshold and Config.AdmissionTTL are both set.
+	PendingAccessExpired int
+	Emergency            bool
 }
 
 // Logger captures structured output for the cleaner.
@@ -61,14 +109,191 @@ type Logger interface {
 	Errorf(format string, args ...any)
 }
 
+// EvictionReason identifies which RunOnce pass evicted a given path, for
+// Metrics.RecordEviction.
+type EvictionReason string
+
+const (
+	// EvictionReasonAge marks an eviction from the age-based sweep.
+	EvictionReasonAge EvictionReason = "age"
+	// EvictionReasonPolicy marks an eviction from the capacity-driven,
+	// Policy-ordered fallback.
+	EvictionReasonPolicy EvictionReason = "policy"
+)
+
+// Metrics records cleaner telemetry so operators can see whether the cache
+// is thrashing (frequent runs, little freed) or genuinely under capacity
+// pressure. Implementations should be cheap enough to call from every
+// meaningful branch of RunOnce.
+type Metrics interface {
+	// RecordEviction is called once per evicted path, after the path has been
+	// removed, with the number of bytes its removal freed.
+	RecordEviction(reason EvictionReason, bytesFreed int64)
+	// RecordReset is called once per path whose clean chunks were reclaimed
+	// via the Resetter fallback instead of being fully evicted.
+	RecordReset(bytesFreed int64)
+	// RecordRunDuration is called once per RunOnce call, regardless of
+	// outcome, with the wall-clock time the pass took.
+	RecordRunDuration(trigger TriggerReason, d time.Duration)
+	// RecordEmergencyTriggered is called once per RunOnce call triggered by
+	// TriggerReasonENOSPC.
+	RecordEmergencyTriggered()
+	// RecordCapacityNotReduced is called whenever RunOnce returns
+	// ErrCapacityNotReduced, i.e. every evictable candidate was exhausted
+	// without meeting the configured capacity target.
+	RecordCapacityNotReduced()
+}
+
+// WithMetrics attaches a Metrics collector to the cleaner.
+func WithMetrics(metrics Metrics) Option {
+	return func(c *Cleaner) {
+		c.metrics = metrics
+	}
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) RecordEviction(EvictionReason, int64)           {}
+func (noopMetrics) RecordReset(int64)                              {}
+func (noopMetrics) RecordRunDuration(TriggerReason, time.Duration) {}
+func (noopMetrics) RecordEmergencyTriggered()                      {}
+func (noopMetrics) RecordCapacityNotReduced()                      {}
+
 // diskUsage reports disk capacity and free space for the cache directory.
 type diskUsage interface {
 	Stat(path string) (total, free uint64, err error)
 }
 
+// ResetterLookup resolves the live files.Resetter for a cached path, if the
+// file is currently open, so RunOnce can reclaim clean chunks from it during
+// ENOSPC recovery instead of only being able to fully evict closed, clean
+// files. ok is false if path has no open container right now. Callers wire
+// this to whatever tracks open containers (e.g. the FUSE file handle
+// table); the cleaner has no way to discover it on its own.
+type ResetterLookup func(path string) (resetter files.Resetter, ok bool)
+
+// Policy ranks cached files for the capacity-driven eviction fallback in
+// RunOnce: candidates are visited in ascending Score order (lowest first)
+// until usage/free-space targets are met, replacing the hard-coded LRU walk
+// the cleaner used before WithPolicy existed.
+type Policy interface {
+	// Score returns meta's eviction priority as of now; lower is evicted
+	// sooner.
+	Score(meta index.FileMeta, now time.Time) float64
+	// Name identifies the policy for logging and the Config.Policy knob.
+	Name() string
+}
+
+// AgingPolicy is implemented by policies whose Score depends on state that
+// drifts as eviction proceeds (PolicyGDSF's aging floor). RunOnce calls
+// Advance with the Score it used for each file it fully evicts.
+type AgingPolicy interface {
+	Policy
+	Advance(lastEvictedScore float64)
+}
+
+// PolicyLRU evicts the least-recently-used file first: the cleaner's
+// original, default behaviour. Score is AtimeLocal itself, so ascending
+// Score order is oldest-access-first.
+type PolicyLRU struct{}
+
+// Name identifies this policy as "lru".
+func (PolicyLRU) Name() string { return "lru" }
+
+// Score returns meta.AtimeLocal as nanoseconds since the epoch.
+func (PolicyLRU) Score(meta index.FileMeta, _ time.Time) float64 {
+	return float64(meta.AtimeLocal.UnixNano())
+}
+
+// PolicyLFU evicts the least-frequently-used file first. Score is
+// meta.HitCount, so files Get has never (or rarely) returned are evicted
+// ahead of frequently-read ones regardless of how recently they were
+// touched.
+type PolicyLFU struct{}
+
+// Name identifies this policy as "lfu".
+func (PolicyLFU) Name() string { return "lfu" }
+
+// Score returns meta.HitCount.
+func (PolicyLFU) Score(meta index.FileMeta, _ time.Time) float64 {
+	return float64(meta.HitCount)
+}
+
+// PolicyGDSF implements a Greedy-Dual-Size-Frequency policy, weighting
+// small, frequently-hit files over large, rarely-hit ones: Score is
+// L + HitCount/Size, where L is an aging floor raised to match the Score of
+// the last file RunOnce fully evicted. Without L, a newly-admitted file
+// with HitCount 0 would always look cheapest to evict even after older
+// files have already earned a higher bar to clear; raising L with every
+// eviction keeps that bar from resetting each run. The zero value is ready
+// to use.
+type PolicyGDSF struct {
+	mu sync.Mutex
+	l  float64
+}
+
+// Name identifies this policy as "gdsf".
+func (p *PolicyGDSF) Name() string { return "gdsf" }
+
+// Score returns the current aging floor plus meta's hit-count-per-byte.
+func (p *PolicyGDSF) Score(meta index.FileMeta, _ time.Time) float64 {
+	size := float64(meta.Size)
+	if size <= 0 {
+		size = 1
+	}
+
+	p.mu.Lock()
+	l := p.l
+	p.mu.Unlock()
+
+	return l + float64(meta.HitCount)/size
+}
+
+// Advance raises the aging floor to score if score is higher than the
+// current floor, so it only ever moves forward.
+func (p *PolicyGDSF) Advance(score float64) {
+	p.mu.Lock()
+	if score > p.l {
+		p.l = score
+	}
+	p.mu.Unlock()
+}
+
+// policyByName resolves the Config.Policy knob to a built-in Policy.
+func policyByName(name string) (Policy, error) {
+	switch name {
+	case "", "lru":
+		return PolicyLRU{}, nil
+	case "lfu":
+		return PolicyLFU{}, nil
+	case "gdsf":
+		return &PolicyGDSF{}, nil
+	default:
+		return nil, fmt.Errorf("cache cleaner: unknown eviction policy %q", name)
+	}
+}
+
+// WithPolicy overrides the capacity-driven eviction order RunOnce uses,
+// taking precedence over Config.Policy.
+func WithPolicy(policy Policy) Option {
+	return func(c *Cleaner) {
+		c.policy = policy
+	}
+}
+
 // Option customises cleaner construction.
 type Option func(*Cleaner)
 
+// WithResetterLookup registers the callback RunOnce uses, once full
+// eviction of closed/clean files hasn't freed enough space, to reclaim
+// clean chunks from files that are still open and therefore ineligible for
+// full eviction.
+func WithResetterLookup(lookup ResetterLookup) Option {
+	return func(c *Cleaner) {
+		c.resetters = lookup
+	}
+}
+
 // WithLogger overrides the default logger.
 func WithLogger(logger Logger) Option {
 	return func(c *Cleaner) {
@@ -83,12 +308,44 @@ func WithDiskUsage(usage diskUsage) Option {
 	}
 }
 
+// WithWorkers sets how many goroutines the capacity-driven eviction fallback
+// in RunOnce dispatches evict() calls to concurrently. The default, 1,
+// matches the cleaner's original single-goroutine behaviour; values above 1
+// let a large ENOSPC sweep evict many independent files in parallel instead
+// of one at a time, at the cost of usage/free-space bookkeeping becoming an
+// approximation (workers race to update shared atomic counters) rather than
+// exact after every single eviction.
+func WithWorkers(n int) Option {
+	return func(c *Cleaner) {
+		c.workers = n
+	}
+}
+
 // Cleaner coordinates cache eviction to honour capacity and fail-safe thresholds.
 type Cleaner struct {
-	cfg    Config
-	idx    index.CacheIndex
-	disk   diskUsage
-	logger Logger
+	cfg     Config
+	idx     index.CacheIndex
+	disk    diskUsage
+	logger  Logger
+	metrics Metrics
+
+	// resetters resolves open files' Resetter for the reset fallback in
+	// RunOnce. Nil means the cleaner was constructed without
+	// WithResetterLookup, so that fallback never runs.
+	resetters ResetterLookup
+
+	// policy orders candidates for the capacity-driven eviction fallback.
+	// Set from Config.Policy, then overridden by WithPolicy if given.
+	policy Policy
+
+	// workers bounds how many goroutines the capacity-driven eviction
+	// fallback dispatches evict() calls to concurrently. Set via
+	// WithWorkers; defaults to 1.
+	workers int
+
+	// cleanInterval mirrors cfg.CleanInterval but is read atomically so
+	// SetCleanInterval can retune RunBackground's ticker without racing it.
+	cleanInterval atomic.Int64
 
 	mu sync.Mutex
 }
@@ -108,12 +365,21 @@ func New(cfg Config, idx index.CacheIndex, opts ...Option) (*Cleaner, error) {
 		cfg.CleanInterval = 30 * time.Minute
 	}
 
+	policy, err := policyByName(cfg.Policy)
+	if err != nil {
+		return nil, err
+	}
+
 	c := &Cleaner{
-		cfg:    cfg,
-		idx:    idx,
-		disk:   &dirDiskUsage{capacity: capacityFromConfig(cfg)},
-		logger: defaultLogger(),
+		cfg:     cfg,
+		idx:     idx,
+		disk:    &dirDiskUsage{capacity: capacityFromConfig(cfg)},
+		logger:  defaultLogger(),
+		metrics: noopMetrics{},
+		policy:  policy,
+		workers: 1,
 	}
+	c.cleanInterval.Store(int64(cfg.CleanInterval))
 
 	for _, opt := range opts {
 		opt(c)
@@ -125,22 +391,70 @@ func New(cfg Config, idx index.CacheIndex, opts ...Option) (*Cleaner, error) {
 	if c.disk == nil {
 		c.disk = &dirDiskUsage{capacity: capacityFromConfig(cfg)}
 	}
+	if c.policy == nil {
+		c.policy = PolicyLRU{}
+	}
+	if c.metrics == nil {
+		c.metrics = noopMetrics{}
+	}
+	if c.workers < 1 {
+		c.workers = 1
+	}
 
 	return c, nil
 }
 
-// RunOnce executes a single cleaner pass for the provided trigger.
+// RunOnce executes a single cleaner pass for the provided trigger: an
+// age-based sweep first (if Config.MaxAge is set), then an expiry sweep for
+// stale pre-admission access counters (if Config.AdmissionThreshold and
+// Config.AdmissionTTL are both set), then a capacity-driven eviction
+// fallback (in the order Policy.Score assigns, ascending) to bring usage
+// under MaxCacheBytes/MinFreePercent (dispatched across a bounded worker
+// pool, see WithWorkers), and finally — only if capacity pressure remains
+// and a ResetterLookup is registered — a reset pass that reclaims clean
+// chunks from open/dirty files it couldn't fully evict.
 func (c *Cleaner) RunOnce(ctx context.Context, trigger Trigger) (Report, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	start := time.Now()
+	defer func() {
+		c.metrics.RecordRunDuration(trigger.Reason, time.Since(start))
+	}()
+
 	report := Report{Trigger: trigger, Emergency: trigger.Reason == TriggerReasonENOSPC}
+	if report.Emergency {
+		c.metrics.RecordEmergencyTriggered()
+	}
+
+	if c.cfg.MaxAge > 0 {
+		if err := c.evictOlderThan(ctx, time.Now().Add(-c.cfg.MaxAge), &report); err != nil {
+			return report, err
+		}
+	}
+
+	if c.cfg.AdmissionThreshold > 1 && c.cfg.AdmissionTTL > 0 {
+		expired, err := c.idx.PurgeExpiredPendingAccess(ctx, time.Now().Add(-c.cfg.AdmissionTTL))
+		if err != nil {
+			return report, err
+		}
+		report.PendingAccessExpired = expired
+	}
 
 	metas, err := c.idx.ListLRU(ctx, 0)
 	if err != nil {
 		return report, err
 	}
 
+	now := time.Now()
+	scores := make(map[string]float64, len(metas))
+	for _, meta := range metas {
+		scores[meta.Path] = c.policy.Score(meta, now)
+	}
+	sort.SliceStable(metas, func(i, j int) bool {
+		return scores[metas[i].Path] < scores[metas[j].Path]
+	})
+
 	usage := totalSize(metas)
 	report.TotalBefore = usage
 
@@ -158,40 +472,58 @@ func (c *Cleaner) RunOnce(ctx context.Context, trigger Trigger) (Report, error)
 	targetFree := requiredFree
 	emergency := trigger.Reason == TriggerReasonENOSPC && requiredFree > 0
 
-	for _, meta := range metas {
-		if err := ctx.Err(); err != nil {
-			return report, err
-		}
+	newUsage, newFreeCap, dispatchErr := c.evictConcurrently(ctx, metas, usage, limit, emergency, targetFree, freeCap, scores, &report)
+	usage = newUsage
+	freeCap = newFreeCap
+	if dispatchErr != nil {
+		return report, dispatchErr
+	}
 
-		if usage <= limit && (!emergency || freeCap >= targetFree) {
-			break
-		}
+	if c.resetters != nil {
+		for _, meta := range metas {
+			if err := ctx.Err(); err != nil {
+				return report, err
+			}
 
-		if !isEvictable(meta) {
-			continue
-		}
+			if usage <= limit && (!emergency || freeCap >= targetFree) {
+				break
+			}
 
-		freed, evictErr := c.evict(ctx, meta)
-		if evictErr != nil {
-			c.logger.Errorf("cleaner: evict %s failed: %v", meta.Path, evictErr)
-			continue
-		}
+			if isEvictable(meta) {
+				// Already handled (or skipped as unnecessary) by the
+				// eviction pass above; resetting a fully evictable file
+				// would just throw away work the next full eviction could
+				// do for free.
+				continue
+			}
 
-		usage -= freed
-		if usage < 0 {
-			usage = 0
-		}
-		report.BytesFreed += freed
-		report.Evicted = append(report.Evicted, meta.Path)
+			freed, resetErr := c.resetClean(ctx, meta)
+			if resetErr != nil {
+				c.logger.Errorf("cleaner: reset %s failed: %v", meta.Path, resetErr)
+				continue
+			}
+			if freed == 0 {
+				continue
+			}
+
+			usage -= freed
+			if usage < 0 {
+				usage = 0
+			}
+			report.BytesFreed += freed
+			report.Reset = append(report.Reset, meta.Path)
+			c.metrics.RecordReset(freed)
 
-		if freed > 0 && freeCap < math.MaxUint64 {
-			freeCap += uint64(freed)
+			if freeCap < math.MaxUint64 {
+				freeCap += uint64(freed)
+			}
 		}
 	}
 
 	report.TotalAfter = usage
 
 	if usage > limit {
+		c.metrics.RecordCapacityNotReduced()
 		return report, ErrCapacityNotReduced
 	}
 
@@ -208,9 +540,11 @@ func (c *Cleaner) RunOnce(ctx context.Context, trigger Trigger) (Report, error)
 	return report, nil
 }
 
-// RunBackground executes RunOnce on a schedule until ctx is cancelled.
+// RunBackground executes RunOnce on a schedule until ctx is cancelled. The
+// schedule picks up changes made by SetCleanInterval between ticks.
 func (c *Cleaner) RunBackground(ctx context.Context, triggers <-chan Trigger) error {
-	ticker := time.NewTicker(c.cfg.CleanInterval)
+	interval := time.Duration(c.cleanInterval.Load())
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -218,6 +552,10 @@ func (c *Cleaner) RunBackground(ctx context.Context, triggers <-chan Trigger) er
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-ticker.C:
+			if next := time.Duration(c.cleanInterval.Load()); next != interval {
+				interval = next
+				ticker.Reset(interval)
+			}
 			if _, err := c.RunOnce(ctx, Trigger{Reason: TriggerReasonMaintenance}); err != nil && !errors.Is(err, ErrCapacityNotReduced) {
 				c.logger.Warnf("cleaner maintenance run failed: %v", err)
 			}
@@ -233,6 +571,197 @@ func (c *Cleaner) RunBackground(ctx context.Context, triggers <-chan Trigger) er
 	}
 }
 
+// SetCleanInterval live-applies a new maintenance period. RunBackground
+// picks it up and resets its ticker the next time it fires, so callers
+// shouldn't expect the change to take effect before the current interval
+// elapses.
+func (c *Cleaner) SetCleanInterval(d time.Duration) {
+	if d <= 0 {
+		d = 30 * time.Minute
+	}
+	c.cleanInterval.Store(int64(d))
+}
+
+// EvictPath evicts a single cached path immediately, bypassing the normal
+// capacity-driven sweep. Callers use this when they have determined the
+// cached data itself is invalid (e.g. failed chunk authentication) and must
+// not wait for the next maintenance pass.
+func (c *Cleaner) EvictPath(ctx context.Context, path string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	meta, err := c.idx.Get(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.evict(ctx, meta)
+}
+
+// evictConcurrently dispatches evict() calls for metas (walked in the order
+// given, i.e. ascending Policy.Score) across c.workers goroutines, stopping
+// once usage/free-space targets are met or ctx is cancelled. Workers already
+// dispatched are always allowed to finish before returning, so a cancelled
+// sweep never leaves an evict() call half-done. usage and freeCap are
+// updated via atomics rather than held exactly, since concurrent workers
+// would otherwise race to read-modify-write them; report gets a dedicated
+// mutex since appends from multiple goroutines must not interleave.
+func (c *Cleaner) evictConcurrently(
+	ctx context.Context,
+	metas []index.FileMeta,
+	usage int64,
+	limit int64,
+	emergency bool,
+	targetFree uint64,
+	freeCap uint64,
+	scores map[string]float64,
+	report *Report,
+) (int64, uint64, error) {
+	var usageState atomic.Int64
+	usageState.Store(usage)
+	var freeCapState atomic.Uint64
+	freeCapState.Store(freeCap)
+
+	targetMet := func() bool {
+		return usageState.Load() <= limit && (!emergency || freeCapState.Load() >= targetFree)
+	}
+
+	jobs := make(chan index.FileMeta)
+	var reportMu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < c.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for meta := range jobs {
+				freed, evictErr := c.evict(ctx, meta)
+				if evictErr != nil {
+					c.logger.Errorf("cleaner: evict %s failed: %v", meta.Path, evictErr)
+					continue
+				}
+
+				usageState.Add(-freed)
+				if freed > 0 {
+					freeCapState.Add(uint64(freed))
+				}
+
+				reportMu.Lock()
+				report.BytesFreed += freed
+				report.Evicted = append(report.Evicted, meta.Path)
+				report.EvictedByLRU = append(report.EvictedByLRU, meta.Path)
+				reportMu.Unlock()
+
+				c.metrics.RecordEviction(EvictionReasonPolicy, freed)
+				if aging, ok := c.policy.(AgingPolicy); ok {
+					aging.Advance(scores[meta.Path])
+				}
+			}
+		}()
+	}
+
+	var dispatchErr error
+dispatch:
+	for _, meta := range metas {
+		if err := ctx.Err(); err != nil {
+			dispatchErr = err
+			break dispatch
+		}
+		if targetMet() {
+			break dispatch
+		}
+		if !isEvictable(meta) {
+			continue
+		}
+
+		select {
+		case jobs <- meta:
+		case <-ctx.Done():
+			dispatchErr = ctx.Err()
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	finalUsage := usageState.Load()
+	if finalUsage < 0 {
+		finalUsage = 0
+	}
+
+	return finalUsage, freeCapState.Load(), dispatchErr
+}
+
+// evictOlderThan removes every evictable entry whose AtimeLocal is strictly
+// before cutoff, regardless of current capacity pressure, and records its
+// findings on report. Dirty entries are skipped like the LRU pass: an
+// age-based sweep must not discard data that hasn't been uploaded yet.
+func (c *Cleaner) evictOlderThan(ctx context.Context, cutoff time.Time, report *Report) error {
+	metas, err := c.idx.ListOlderThan(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+
+	for _, meta := range metas {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if !isEvictable(meta) {
+			continue
+		}
+
+		freed, evictErr := c.evict(ctx, meta)
+		if evictErr != nil {
+			c.logger.Errorf("cleaner: age-based evict %s failed: %v", meta.Path, evictErr)
+			continue
+		}
+
+		report.BytesFreed += freed
+		report.Evicted = append(report.Evicted, meta.Path)
+		report.EvictedByAge = append(report.EvictedByAge, meta.Path)
+		c.metrics.RecordEviction(EvictionReasonAge, freed)
+	}
+
+	return nil
+}
+
+// resetClean reclaims meta's clean, not-yet-absent chunks via the open
+// Resetter for its path, if one is registered, leaving dirty chunks and the
+// index entry itself untouched. It returns 0, nil (not an error) when no
+// Resetter is currently open for path, since that's the common case for
+// every entry the age/LRU passes already handled.
+func (c *Cleaner) resetClean(ctx context.Context, meta index.FileMeta) (int64, error) {
+	resetter, ok := c.resetters(meta.Path)
+	if !ok {
+		return 0, nil
+	}
+
+	var freed int64
+	_, err := c.idx.Update(ctx, meta.Path, func(current index.FileMeta) (index.FileMeta, error) {
+		for i := range current.Chunks {
+			chunk := &current.Chunks[i]
+			if chunk.Dirty || chunk.Absent {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return current, err
+			}
+			n, resetErr := resetter.ResetRange(chunk.Offset, chunk.Length)
+			if resetErr != nil {
+				return current, fmt.Errorf("reset chunk [%d,%d): %w", chunk.Offset, chunk.Offset+chunk.Length, resetErr)
+			}
+			chunk.Absent = true
+			freed += n
+		}
+		return current, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return freed, nil
+}
+
 func (c *Cleaner) evict(ctx context.Context, meta index.FileMeta) (int64, error) {
 	path := filepath.Join(c.cfg.CacheDir, filepath.FromSlash(meta.Path))
 
@@ -3,12 +3,15 @@ package cleaner_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/valandreev/tigrisfs/pkg/cache/cleaner"
+	"github.com/valandreev/tigrisfs/pkg/cache/files"
 	"github.com/valandreev/tigrisfs/pkg/cache/index"
 	"github.com/valandreev/tigrisfs/pkg/cache/index/indextest"
 )
@@ -127,6 +130,468 @@ func TestCleanerEmergencyFreesSpace(t *testing.T) {
 	}
 }
 
+func TestCleanerRunOnceEvictsStaleFilesByAge(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	root := t.TempDir()
+	idx := indextest.MemoryIndexFactory()(t)
+
+	mustWriteFile(t, root, "stale.bin", 10)
+	mustWriteFile(t, root, "stale-dirty.bin", 10)
+	mustWriteFile(t, root, "fresh.bin", 10)
+
+	putMeta(t, ctx, idx, "stale.bin", 10)
+	putMeta(t, ctx, idx, "stale-dirty.bin", 10)
+	putMeta(t, ctx, idx, "fresh.bin", 10)
+
+	setAtime(t, ctx, idx, "stale.bin", time.Now().Add(-2*time.Hour))
+	setAtime(t, ctx, idx, "stale-dirty.bin", time.Now().Add(-2*time.Hour))
+	markDirty(t, ctx, idx, "stale-dirty.bin")
+	setAtime(t, ctx, idx, "fresh.bin", time.Now())
+
+	cfg := cleaner.Config{
+		CacheDir:      root,
+		MaxCacheBytes: 500,
+		MaxAge:        time.Hour,
+	}
+
+	c, err := cleaner.New(cfg, idx, cleaner.WithDiskUsage(fakeDisk{capacity: 500}))
+	if err != nil {
+		t.Fatalf("new cleaner: %v", err)
+	}
+
+	report, err := c.RunOnce(ctx, cleaner.Trigger{Reason: cleaner.TriggerReasonMaintenance})
+	if err != nil {
+		t.Fatalf("RunOnce returned error: %v", err)
+	}
+
+	if len(report.EvictedByAge) != 1 || report.EvictedByAge[0] != "stale.bin" {
+		t.Fatalf("expected EvictedByAge [stale.bin], got %v", report.EvictedByAge)
+	}
+	if len(report.EvictedByLRU) != 0 {
+		t.Fatalf("expected no LRU evictions, got %v", report.EvictedByLRU)
+	}
+	if len(report.Evicted) != 1 || report.Evicted[0] != "stale.bin" {
+		t.Fatalf("expected Evicted [stale.bin], got %v", report.Evicted)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "stale.bin")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected stale.bin removed, got err=%v", err)
+	}
+	if _, err := idx.Get(ctx, "stale-dirty.bin"); err != nil {
+		t.Fatalf("expected dirty stale-dirty.bin to remain, err=%v", err)
+	}
+	if _, err := idx.Get(ctx, "fresh.bin"); err != nil {
+		t.Fatalf("expected fresh.bin to remain, err=%v", err)
+	}
+}
+
+func TestCleanerRunOnceExpiresStalePendingAccess(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	root := t.TempDir()
+	idx := indextest.MemoryIndexFactory()(t)
+
+	if _, _, err := idx.RecordPendingAccess(ctx, "stale.bin", time.Now().Add(-2*time.Hour)); err != nil {
+		t.Fatalf("RecordPendingAccess stale.bin: %v", err)
+	}
+	if _, _, err := idx.RecordPendingAccess(ctx, "fresh.bin", time.Now()); err != nil {
+		t.Fatalf("RecordPendingAccess fresh.bin: %v", err)
+	}
+
+	cfg := cleaner.Config{
+		CacheDir:           root,
+		MaxCacheBytes:      500,
+		AdmissionThreshold: 3,
+		AdmissionTTL:       time.Hour,
+	}
+
+	c, err := cleaner.New(cfg, idx, cleaner.WithDiskUsage(fakeDisk{capacity: 500}))
+	if err != nil {
+		t.Fatalf("new cleaner: %v", err)
+	}
+
+	report, err := c.RunOnce(ctx, cleaner.Trigger{Reason: cleaner.TriggerReasonMaintenance})
+	if err != nil {
+		t.Fatalf("RunOnce returned error: %v", err)
+	}
+
+	if report.PendingAccessExpired != 1 {
+		t.Fatalf("expected 1 expired pending-access counter, got %d", report.PendingAccessExpired)
+	}
+
+	if count, _, err := idx.RecordPendingAccess(ctx, "stale.bin", time.Now()); err != nil {
+		t.Fatalf("RecordPendingAccess after RunOnce: %v", err)
+	} else if count != 1 {
+		t.Fatalf("expected stale.bin counter to have been purged and restart at 1, got %d", count)
+	}
+	if count, _, err := idx.RecordPendingAccess(ctx, "fresh.bin", time.Now()); err != nil {
+		t.Fatalf("RecordPendingAccess after RunOnce: %v", err)
+	} else if count != 2 {
+		t.Fatalf("expected unexpired fresh.bin counter to survive, got %d", count)
+	}
+}
+
+func TestCleanerEvictionPolicyOrder(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		policy   cleaner.Policy
+		setup    func(t *testing.T, ctx context.Context, idx index.CacheIndex)
+		expected []string
+	}{
+		{
+			name:   "lru",
+			policy: cleaner.PolicyLRU{},
+			setup: func(t *testing.T, ctx context.Context, idx index.CacheIndex) {
+				setAtime(t, ctx, idx, "a.bin", time.Now().Add(-3*time.Hour))
+				setAtime(t, ctx, idx, "b.bin", time.Now().Add(-2*time.Hour))
+				setAtime(t, ctx, idx, "c.bin", time.Now().Add(-1*time.Hour))
+			},
+			expected: []string{"a.bin", "b.bin"},
+		},
+		{
+			name:   "lfu",
+			policy: cleaner.PolicyLFU{},
+			setup: func(t *testing.T, ctx context.Context, idx index.CacheIndex) {
+				// a.bin: 0 hits, b.bin: 1 hit, c.bin: 2 hits.
+				if _, err := idx.Get(ctx, "b.bin"); err != nil {
+					t.Fatalf("warm b.bin: %v", err)
+				}
+				for i := 0; i < 2; i++ {
+					if _, err := idx.Get(ctx, "c.bin"); err != nil {
+						t.Fatalf("warm c.bin: %v", err)
+					}
+				}
+			},
+			expected: []string{"a.bin", "b.bin"},
+		},
+		{
+			name:   "gdsf",
+			policy: &cleaner.PolicyGDSF{},
+			setup: func(t *testing.T, ctx context.Context, idx index.CacheIndex) {
+				// Equal sizes, so score reduces to HitCount: a.bin stays
+				// coldest, b.bin warms a little, c.bin warms a lot.
+				if _, err := idx.Get(ctx, "b.bin"); err != nil {
+					t.Fatalf("warm b.bin: %v", err)
+				}
+				for i := 0; i < 5; i++ {
+					if _, err := idx.Get(ctx, "c.bin"); err != nil {
+						t.Fatalf("warm c.bin: %v", err)
+					}
+				}
+			},
+			expected: []string{"a.bin", "b.bin"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			root := t.TempDir()
+			idx := indextest.MemoryIndexFactory()(t)
+
+			mustWriteFile(t, root, "a.bin", 10)
+			mustWriteFile(t, root, "b.bin", 10)
+			mustWriteFile(t, root, "c.bin", 10)
+			putMeta(t, ctx, idx, "a.bin", 10)
+			putMeta(t, ctx, idx, "b.bin", 10)
+			putMeta(t, ctx, idx, "c.bin", 10)
+
+			tc.setup(t, ctx, idx)
+
+			cfg := cleaner.Config{
+				CacheDir:      root,
+				MaxCacheBytes: 10,
+			}
+			c, err := cleaner.New(cfg, idx,
+				cleaner.WithDiskUsage(fakeDisk{capacity: 500}),
+				cleaner.WithPolicy(tc.policy),
+			)
+			if err != nil {
+				t.Fatalf("new cleaner: %v", err)
+			}
+
+			report, err := c.RunOnce(ctx, cleaner.Trigger{Reason: cleaner.TriggerReasonMaintenance})
+			if err != nil {
+				t.Fatalf("RunOnce returned error: %v", err)
+			}
+
+			if len(report.Evicted) != len(tc.expected) {
+				t.Fatalf("expected evicted %v, got %v", tc.expected, report.Evicted)
+			}
+			for i, want := range tc.expected {
+				if report.Evicted[i] != want {
+					t.Fatalf("eviction order mismatch at %d: expected %v, got %v", i, tc.expected, report.Evicted)
+				}
+			}
+		})
+	}
+}
+
+func TestCleanerConcurrentEvictionMeetsCapacityTarget(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	root := t.TempDir()
+	idx := indextest.MemoryIndexFactory()(t)
+
+	const n = 50
+	for i := 0; i < n; i++ {
+		path := fmt.Sprintf("bulk-%03d.bin", i)
+		mustWriteFile(t, root, path, 10)
+		putMeta(t, ctx, idx, path, 10)
+		setAtime(t, ctx, idx, path, time.Now().Add(-time.Duration(n-i)*time.Second))
+	}
+
+	cfg := cleaner.Config{
+		CacheDir:      root,
+		MaxCacheBytes: 100,
+	}
+
+	c, err := cleaner.New(cfg, idx, cleaner.WithDiskUsage(fakeDisk{capacity: 10 * n}), cleaner.WithWorkers(8))
+	if err != nil {
+		t.Fatalf("new cleaner: %v", err)
+	}
+
+	report, err := c.RunOnce(ctx, cleaner.Trigger{Reason: cleaner.TriggerReasonMaintenance})
+	if err != nil {
+		t.Fatalf("RunOnce returned error: %v", err)
+	}
+
+	if report.TotalAfter > cfg.MaxCacheBytes {
+		t.Fatalf("expected usage under %d after concurrent eviction, got %d", cfg.MaxCacheBytes, report.TotalAfter)
+	}
+	if len(report.Evicted) == 0 {
+		t.Fatalf("expected at least one eviction")
+	}
+
+	for _, path := range report.Evicted {
+		if _, statErr := os.Stat(filepath.Join(root, path)); !errors.Is(statErr, os.ErrNotExist) {
+			t.Fatalf("expected evicted path %s removed from disk, got err=%v", path, statErr)
+		}
+		if _, idxErr := idx.Get(ctx, path); !errors.Is(idxErr, index.ErrNotFound) {
+			t.Fatalf("expected evicted path %s removed from index, got err=%v", path, idxErr)
+		}
+	}
+}
+
+func TestCleanerConcurrentEvictionCancellationLeavesNoPartialState(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	root := t.TempDir()
+	idx := indextest.MemoryIndexFactory()(t)
+
+	const n = 200
+	paths := make([]string, n)
+	for i := 0; i < n; i++ {
+		path := fmt.Sprintf("bulk-%03d.bin", i)
+		paths[i] = path
+		mustWriteFile(t, root, path, 10)
+		putMeta(t, context.Background(), idx, path, 10)
+		setAtime(t, context.Background(), idx, path, time.Now().Add(-time.Duration(n-i)*time.Second))
+	}
+
+	cfg := cleaner.Config{
+		CacheDir:      root,
+		MaxCacheBytes: 10,
+	}
+
+	c, err := cleaner.New(cfg, idx, cleaner.WithDiskUsage(fakeDisk{capacity: 10 * n}), cleaner.WithWorkers(8))
+	if err != nil {
+		t.Fatalf("new cleaner: %v", err)
+	}
+
+	go func() {
+		time.Sleep(time.Millisecond)
+		cancel()
+	}()
+
+	report, err := c.RunOnce(ctx, cleaner.Trigger{Reason: cleaner.TriggerReasonMaintenance})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected RunOnce error: %v", err)
+	}
+
+	evicted := make(map[string]bool, len(report.Evicted))
+	for _, p := range report.Evicted {
+		evicted[p] = true
+	}
+
+	bg := context.Background()
+	for _, path := range paths {
+		_, statErr := os.Stat(filepath.Join(root, path))
+		fileGone := errors.Is(statErr, os.ErrNotExist)
+		_, idxErr := idx.Get(bg, path)
+		idxGone := errors.Is(idxErr, index.ErrNotFound)
+
+		if fileGone != idxGone {
+			t.Fatalf("path %s: inconsistent state, file gone=%v index gone=%v", path, fileGone, idxGone)
+		}
+		if evicted[path] != fileGone {
+			t.Fatalf("path %s: report says evicted=%v but file gone=%v", path, evicted[path], fileGone)
+		}
+	}
+}
+
+func TestCleanerResetsCleanChunksOfOpenDirtyFileUnderEmergency(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	root := t.TempDir()
+	idx := indextest.MemoryIndexFactory()(t)
+
+	mustWriteFile(t, root, "mixed.bin", 60)
+
+	meta := index.FileMeta{
+		Path:        "mixed.bin",
+		Size:        60,
+		ETag:        "etag",
+		MtimeRemote: time.Now().UTC(),
+		AtimeLocal:  time.Now().UTC(),
+		Chunks: []index.ChunkMeta{
+			{Offset: 0, Length: 30, Dirty: false},
+			{Offset: 30, Length: 30, Dirty: true},
+		},
+	}
+	if err := idx.Put(ctx, meta); err != nil {
+		t.Fatalf("Put meta failed: %v", err)
+	}
+
+	resetter := &fakeResetter{}
+
+	cfg := cleaner.Config{
+		CacheDir:       root,
+		MaxCacheBytes:  500,
+		MinFreePercent: 50,
+	}
+
+	c, err := cleaner.New(cfg, idx,
+		cleaner.WithDiskUsage(fakeDisk{capacity: 100}),
+		cleaner.WithResetterLookup(func(path string) (files.Resetter, bool) {
+			if path != "mixed.bin" {
+				return nil, false
+			}
+			return resetter, true
+		}),
+	)
+	if err != nil {
+		t.Fatalf("new cleaner: %v", err)
+	}
+
+	report, err := c.RunOnce(ctx, cleaner.Trigger{Reason: cleaner.TriggerReasonENOSPC})
+	if err != nil {
+		t.Fatalf("RunOnce returned error: %v", err)
+	}
+
+	if len(report.Reset) != 1 || report.Reset[0] != "mixed.bin" {
+		t.Fatalf("expected Reset [mixed.bin], got %v", report.Reset)
+	}
+	if len(report.Evicted) != 0 {
+		t.Fatalf("expected no full evictions, got %v", report.Evicted)
+	}
+	if got := resetter.resetRanges(); len(got) != 1 || got[0] != (index.Range{Offset: 0, Length: 30}) {
+		t.Fatalf("expected only the clean chunk reset, got %v", got)
+	}
+
+	updated, err := idx.Get(ctx, "mixed.bin")
+	if err != nil {
+		t.Fatalf("get updated meta: %v", err)
+	}
+	if !updated.Chunks[0].Absent {
+		t.Fatalf("expected clean chunk marked Absent, got %+v", updated.Chunks[0])
+	}
+	if updated.Chunks[1].Absent {
+		t.Fatalf("expected dirty chunk left alone, got %+v", updated.Chunks[1])
+	}
+}
+
+type fakeResetter struct {
+	mu     sync.Mutex
+	ranges []index.Range
+}
+
+func (r *fakeResetter) ResetRange(off, length int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ranges = append(r.ranges, index.Range{Offset: off, Length: length})
+	return length, nil
+}
+
+func (r *fakeResetter) resetRanges() []index.Range {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]index.Range(nil), r.ranges...)
+}
+
+func TestCleanerSetCleanIntervalRetunesBackgroundTicker(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	root := t.TempDir()
+	idx := indextest.MemoryIndexFactory()(t)
+
+	cfg := cleaner.Config{
+		CacheDir:      root,
+		MaxCacheBytes: 500,
+		CleanInterval: time.Hour,
+	}
+
+	disk := &countingDisk{fakeDisk: fakeDisk{capacity: 500}}
+	c, err := cleaner.New(cfg, idx, cleaner.WithDiskUsage(disk))
+	if err != nil {
+		t.Fatalf("new cleaner: %v", err)
+	}
+
+	c.SetCleanInterval(5 * time.Millisecond)
+
+	triggers := make(chan cleaner.Trigger)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.RunBackground(ctx, triggers)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) && disk.count() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	if disk.count() < 2 {
+		t.Fatalf("expected multiple maintenance runs after shrinking the interval, got %d", disk.count())
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected RunBackground to return context.Canceled, got %v", err)
+	}
+}
+
+type countingDisk struct {
+	fakeDisk
+	mu    sync.Mutex
+	calls int
+}
+
+func (d *countingDisk) Stat(path string) (uint64, uint64, error) {
+	d.mu.Lock()
+	d.calls++
+	d.mu.Unlock()
+	return d.fakeDisk.Stat(path)
+}
+
+func (d *countingDisk) count() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.calls
+}
+
 func TestCleanerEmergencyFatalWhenInsufficientSpace(t *testing.T) {
 	t.Parallel()
 
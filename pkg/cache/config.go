@@ -20,7 +20,10 @@ const (
 	defaultUploadRetrySec     = 15
 	defaultUploadMaxRetrySec  = 300
 	defaultUploadMaxParallel  = 4
+	defaultUploadProtocol     = "simple"
+	defaultTusChunkMB         = 8
 	defaultDiskMinFreePercent = 10
+	defaultReconcileParallel  = 4
 )
 
 var ErrConfigMissing = errors.New("cache config missing")
@@ -42,13 +45,26 @@ func (v ValidationError) Error() string {
 
 // Config describes on-disk cache behaviour.
 type Config struct {
-	Version          int            `yaml:"version"`
-	CacheDir         string         `yaml:"cache_dir"`
-	CacheSizeGB      int            `yaml:"cache_size_gb"`
-	ChunkMB          int            `yaml:"chunk_mb"`
-	CleanIntervalMin int            `yaml:"clean_interval_min"`
-	Upload           UploadConfig   `yaml:"upload"`
-	FailSafe         FailSafeConfig `yaml:"fail_safe"`
+	Version          int              `yaml:"version"`
+	CacheDir         string           `yaml:"cache_dir"`
+	CacheSizeGB      int              `yaml:"cache_size_gb"`
+	ChunkMB          int              `yaml:"chunk_mb"`
+	CleanIntervalMin int              `yaml:"clean_interval_min"`
+	Upload           UploadConfig     `yaml:"upload"`
+	FailSafe         FailSafeConfig   `yaml:"fail_safe"`
+	Encryption       EncryptionConfig `yaml:"encryption"`
+
+	// ReconcileOnStart runs the cache/disk reconciler before mounting so a
+	// hard kill during writeback doesn't leave permanent drift behind.
+	ReconcileOnStart bool `yaml:"reconcile_on_start"`
+	// ReconcileParallelism bounds how many cached files the reconciler
+	// inspects concurrently.
+	ReconcileParallelism int `yaml:"reconcile_parallelism"`
+
+	// OpenCacheSec is how long a file's attributes (and its chunk/range map)
+	// may be served from memory after open without re-validating against
+	// the backend. 0 disables the cache, re-validating on every access.
+	OpenCacheSec int `yaml:"open_cache_sec"`
 }
 
 // UploadConfig captures write-back uploader tuning.
@@ -57,6 +73,14 @@ type UploadConfig struct {
 	RetryIntervalSec     int `yaml:"retry_interval_sec"`
 	MaxRetrySec          int `yaml:"max_retry_sec"`
 	MaxConcurrentUploads int `yaml:"max_concurrent_uploads"`
+
+	// Protocol selects the wire protocol used to push chunk uploads to the
+	// backend: "simple" (plain Backend.Upload, one shot per chunk) or "tus"
+	// (tus.io resumable sessions via uploader.TusChunkUploader).
+	Protocol string `yaml:"protocol"`
+	// TusChunkMB bounds how much of a chunk is staged in memory per PATCH
+	// when Protocol is "tus". Unused otherwise.
+	TusChunkMB int `yaml:"tus_chunk_mb"`
 }
 
 // FailSafeConfig configures ENOSPC protection.
@@ -65,6 +89,14 @@ type FailSafeConfig struct {
 	DiskMinFreePercent int  `yaml:"disk_min_free_percent"`
 }
 
+// EncryptionConfig configures cache-at-rest encryption of cached chunks.
+// MasterKeyURI is resolved by uploader.LoadMasterKey and supports file://
+// and env:// schemes.
+type EncryptionConfig struct {
+	Enable       bool   `yaml:"enable"`
+	MasterKeyURI string `yaml:"master_key_uri"`
+}
+
 // LoadConfig reads config from the provided path. When the file does not exist
 // it writes a template and returns ErrConfigMissing to prompt the user to edit
 // the newly created file.
@@ -132,9 +164,18 @@ func (c *Config) applyDefaults() {
 	if c.Upload.MaxConcurrentUploads == 0 {
 		c.Upload.MaxConcurrentUploads = defaultUploadMaxParallel
 	}
+	if c.Upload.Protocol == "" {
+		c.Upload.Protocol = defaultUploadProtocol
+	}
+	if c.Upload.TusChunkMB == 0 {
+		c.Upload.TusChunkMB = defaultTusChunkMB
+	}
 	if c.FailSafe.DiskMinFreePercent == 0 {
 		c.FailSafe.DiskMinFreePercent = defaultDiskMinFreePercent
 	}
+	if c.ReconcileParallelism == 0 {
+		c.ReconcileParallelism = defaultReconcileParallel
+	}
 }
 
 func (c Config) validate() ValidationError {
@@ -164,9 +205,24 @@ func (c Config) validate() ValidationError {
 	if c.Upload.MaxConcurrentUploads <= 0 {
 		issues = append(issues, "upload.max_concurrent_uploads must be > 0")
 	}
+	if c.Upload.Protocol != "simple" && c.Upload.Protocol != "tus" {
+		issues = append(issues, "upload.protocol must be \"simple\" or \"tus\"")
+	}
+	if c.Upload.TusChunkMB <= 0 {
+		issues = append(issues, "upload.tus_chunk_mb must be > 0")
+	}
 	if c.FailSafe.DiskMinFreePercent <= 0 || c.FailSafe.DiskMinFreePercent > 100 {
 		issues = append(issues, "fail_safe.disk_min_free_percent must be in (0,100]")
 	}
+	if c.Encryption.Enable && c.Encryption.MasterKeyURI == "" {
+		issues = append(issues, "encryption.master_key_uri is required when encryption.enable is true")
+	}
+	if c.ReconcileParallelism <= 0 {
+		issues = append(issues, "reconcile_parallelism must be > 0")
+	}
+	if c.OpenCacheSec < 0 {
+		issues = append(issues, "open_cache_sec must be >= 0")
+	}
 
 	return ValidationError{Issues: issues}
 }
@@ -187,9 +243,19 @@ func writeTemplate(path string) error {
 	tpl.WriteString("  retry_interval_sec: 15\n")
 	tpl.WriteString("  max_retry_sec: 300\n")
 	tpl.WriteString("  max_concurrent_uploads: 4\n")
+	tpl.WriteString("  # protocol selects how chunk uploads are pushed: simple or tus\n")
+	tpl.WriteString("  protocol: simple\n")
+	tpl.WriteString("  tus_chunk_mb: 8\n")
 	tpl.WriteString("fail_safe:\n")
 	tpl.WriteString("  enable: true\n")
 	tpl.WriteString("  disk_min_free_percent: 10\n")
+	tpl.WriteString("encryption:\n")
+	tpl.WriteString("  enable: false\n")
+	tpl.WriteString("  # master_key_uri: file:///etc/tigrisfs/cache.key\n")
+	tpl.WriteString("reconcile_on_start: true\n")
+	tpl.WriteString("reconcile_parallelism: 4\n")
+	tpl.WriteString("# open_cache_sec caches attrs/chunk-map per inode after open; 0 disables it\n")
+	tpl.WriteString("open_cache_sec: 0\n")
 
 	if err := os.WriteFile(path, tpl.Bytes(), 0o600); err != nil {
 		return fmt.Errorf("write config template: %w", err)
@@ -0,0 +1,49 @@
+package failsafe
+
+import (
+	"sync"
+
+	"github.com/valandreev/tigrisfs/pkg/cache/files"
+)
+
+// CategoryPauser implements files.WriteGate, letting the monitor shed the
+// categories most responsible for disk pressure (new chunk data, background
+// compaction) during ENOSPC recovery while leaving the upload journal and
+// index free to flow, so their on-disk state doesn't fall further behind
+// reality while recovery is underway. Share one instance across every
+// files.Container and uploader.LocalFileChunkProvider whose I/O should be
+// subject to recovery pausing.
+type CategoryPauser struct {
+	mu     sync.Mutex
+	paused map[files.WriteCategory]bool
+}
+
+// NewCategoryPauser constructs a CategoryPauser with nothing paused.
+func NewCategoryPauser() *CategoryPauser {
+	return &CategoryPauser{paused: make(map[files.WriteCategory]bool)}
+}
+
+// Pause blocks Allow for the given categories until a matching Resume.
+func (p *CategoryPauser) Pause(categories ...files.WriteCategory) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range categories {
+		p.paused[c] = true
+	}
+}
+
+// Resume lets the given categories flow again.
+func (p *CategoryPauser) Resume(categories ...files.WriteCategory) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range categories {
+		delete(p.paused, c)
+	}
+}
+
+// Allow implements files.WriteGate.
+func (p *CategoryPauser) Allow(category files.WriteCategory) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return !p.paused[category]
+}
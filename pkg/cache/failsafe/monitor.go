@@ -4,10 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/valandreev/tigrisfs/log"
 	"github.com/valandreev/tigrisfs/pkg/cache/cleaner"
+	"github.com/valandreev/tigrisfs/pkg/cache/files"
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
 )
 
 // ErrRecoveryFailed indicates the cleaner could not reclaim sufficient space and manual intervention is required.
@@ -16,6 +20,12 @@ var ErrRecoveryFailed = errors.New("cache failsafe: recovery failed")
 // ErrRecoveryInProgress signals that a recovery sequence is already underway.
 var ErrRecoveryInProgress = errors.New("cache failsafe: recovery in progress")
 
+// ErrChunkAuthFailure indicates a cached chunk failed AEAD authentication,
+// meaning the on-disk data is corrupt or tampered rather than merely stale.
+// This is a distinct error class from ENOSPC: it calls for evicting the
+// offending path, not pausing uploads.
+var ErrChunkAuthFailure = errors.New("cache failsafe: chunk authentication failed")
+
 // Logger defines the logging surface used by the monitor.
 type Logger interface {
 	Debugf(format string, args ...any)
@@ -29,12 +39,90 @@ type Cleaner interface {
 	RunOnce(ctx context.Context, trigger cleaner.Trigger) (cleaner.Report, error)
 }
 
+// PathEvictor is optionally implemented by the configured Cleaner to allow
+// the monitor to evict a single known-bad cached path immediately, without
+// waiting for the next capacity-driven sweep.
+type PathEvictor interface {
+	EvictPath(ctx context.Context, path string) (int64, error)
+}
+
 // UploaderController controls the uploader concurrency during recovery.
 type UploaderController interface {
 	PauseUploads(ctx context.Context) error
 	ResumeUploads(ctx context.Context) error
 }
 
+// WritebackController lets the monitor force-flush specific dirty paths
+// ahead of the uploader's normal queue order, so ENOSPC recovery can free
+// space by getting dirty data safely uploaded instead of only evicting
+// clean data.
+type WritebackController interface {
+	// FlushHighPriority uploads paths ahead of the normal queue, blocking
+	// until each is durably written (or ctx is done).
+	FlushHighPriority(ctx context.Context, paths []string) error
+	// PauseNew stops new writes from being marked dirty for the duration of
+	// the flush, so the candidate set doesn't keep growing underneath it.
+	PauseNew(ctx context.Context) error
+	// ResumeNew reverses PauseNew.
+	ResumeNew(ctx context.Context) error
+}
+
+// DirtyIndex is the minimal index surface the monitor needs to find
+// writeback flush candidates; index.CacheIndex satisfies it.
+type DirtyIndex interface {
+	ListLRU(ctx context.Context, limit int) ([]index.FileMeta, error)
+}
+
+// maxWritebackFlushCandidates bounds how many dirty files HandleENOSPC will
+// force-flush before falling back to eviction, so a cache holding many dirty
+// files doesn't turn one ENOSPC event into a synchronous flush of all of
+// them.
+const maxWritebackFlushCandidates = 16
+
+// RecoveryOutcome classifies how a HandleENOSPC call ended, for
+// Metrics.RecordRecoveryDuration.
+type RecoveryOutcome string
+
+const (
+	// RecoveryOutcomeSuccess marks a recovery that freed enough space and
+	// resumed uploads normally.
+	RecoveryOutcomeSuccess RecoveryOutcome = "success"
+	// RecoveryOutcomeFailed marks a recovery that returned an error other
+	// than ErrRecoveryFailed (e.g. pausing uploads or the cleaner run
+	// itself failed).
+	RecoveryOutcomeFailed RecoveryOutcome = "failed"
+	// RecoveryOutcomeFatal marks a recovery that exhausted the cleaner
+	// without reclaiming enough space (ErrRecoveryFailed).
+	RecoveryOutcomeFatal RecoveryOutcome = "fatal"
+)
+
+// Metrics records failsafe telemetry so operators can see how often ENOSPC
+// recovery fires and how disruptive it is.
+type Metrics interface {
+	// RecordENOSPCEvent is called once per HandleENOSPC invocation that
+	// actually begins recovery (i.e. is not already in progress).
+	RecordENOSPCEvent()
+	// RecordRecoveryDuration is called once per HandleENOSPC call with the
+	// outcome and wall-clock time the whole recovery sequence took.
+	RecordRecoveryDuration(outcome RecoveryOutcome, d time.Duration)
+	// RecordUploadPauseDuration is called after uploads are resumed, with
+	// how long they were paused for this recovery.
+	RecordUploadPauseDuration(d time.Duration)
+}
+
+// WithMetrics attaches a Metrics collector to the monitor.
+func WithMetrics(metrics Metrics) Option {
+	return func(m *Monitor) {
+		m.metrics = metrics
+	}
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) RecordENOSPCEvent()                                    {}
+func (noopMetrics) RecordRecoveryDuration(RecoveryOutcome, time.Duration) {}
+func (noopMetrics) RecordUploadPauseDuration(time.Duration)               {}
+
 // Option customises monitor construction.
 type Option func(*Monitor)
 
@@ -45,11 +133,46 @@ func WithLogger(logger Logger) Option {
 	}
 }
 
+// WithCategoryPauser lets the monitor shed specific files.WriteCategory
+// traffic during ENOSPC recovery instead of pausing uploads wholesale.
+func WithCategoryPauser(pauser *CategoryPauser) Option {
+	return func(m *Monitor) {
+		m.categoryPauser = pauser
+	}
+}
+
+// WithWriteback attaches a WritebackController and the DirtyIndex it should
+// flush from, so HandleENOSPC force-flushes the largest dirty files before
+// falling back to cleaner.RunOnce. Both arguments are only meaningful
+// together, so they are set by a single option; a nil controller or index
+// leaves the flush step disabled.
+func WithWriteback(controller WritebackController, idx DirtyIndex) Option {
+	return func(m *Monitor) {
+		m.writeback = controller
+		m.dirtyIndex = idx
+	}
+}
+
+// categoriesToShed are paused first during ENOSPC recovery: new chunk data
+// and background compaction can wait, but the upload journal and index must
+// keep flowing or their on-disk state falls behind what's actually on disk.
+var categoriesToShed = []files.WriteCategory{files.CategoryChunkData, files.CategoryCompaction}
+
 // Monitor coordinates ENOSPC recovery by pausing uploads and invoking the cleaner.
 type Monitor struct {
 	cleaner  Cleaner
 	uploader UploaderController
 	logger   Logger
+	metrics  Metrics
+
+	// categoryPauser, when set, is told to shed categoriesToShed for the
+	// duration of HandleENOSPC's recovery sequence.
+	categoryPauser *CategoryPauser
+
+	// writeback and dirtyIndex, when both set, let HandleENOSPC force-flush
+	// the largest dirty files before running the cleaner. See WithWriteback.
+	writeback  WritebackController
+	dirtyIndex DirtyIndex
 
 	mu         sync.Mutex
 	recovering bool
@@ -68,6 +191,7 @@ func NewMonitor(cleaner Cleaner, uploader UploaderController, opts ...Option) (*
 		cleaner:  cleaner,
 		uploader: uploader,
 		logger:   defaultLogger(),
+		metrics:  noopMetrics{},
 	}
 
 	for _, opt := range opts {
@@ -77,6 +201,9 @@ func NewMonitor(cleaner Cleaner, uploader UploaderController, opts ...Option) (*
 	if m.logger == nil {
 		m.logger = defaultLogger()
 	}
+	if m.metrics == nil {
+		m.metrics = noopMetrics{}
+	}
 
 	return m, nil
 }
@@ -96,19 +223,39 @@ func (m *Monitor) HandleENOSPC(ctx context.Context) error {
 	}
 	defer m.endRecovery()
 
+	m.metrics.RecordENOSPCEvent()
+	recoveryStart := time.Now()
+	outcome := RecoveryOutcomeSuccess
+	defer func() {
+		m.metrics.RecordRecoveryDuration(outcome, time.Since(recoveryStart))
+	}()
+
+	pauseStart := time.Now()
 	if err := m.uploader.PauseUploads(ctx); err != nil {
+		outcome = RecoveryOutcomeFailed
 		return fmt.Errorf("cache failsafe: pause uploads: %w", err)
 	}
+	if m.categoryPauser != nil {
+		m.categoryPauser.Pause(categoriesToShed...)
+	}
+
+	m.flushDirtyBeforeEviction(ctx)
 
 	resumeUploads := true
 	report, err := m.cleaner.RunOnce(ctx, cleaner.Trigger{Reason: cleaner.TriggerReasonENOSPC})
 	if err != nil {
 		if errors.Is(err, cleaner.ErrFatalCondition) {
 			resumeUploads = false
+			outcome = RecoveryOutcomeFatal
 			return fmt.Errorf("%w: %v", ErrRecoveryFailed, err)
 		}
 
+		outcome = RecoveryOutcomeFailed
 		if resumeUploads {
+			if m.categoryPauser != nil {
+				m.categoryPauser.Resume(categoriesToShed...)
+			}
+			m.metrics.RecordUploadPauseDuration(time.Since(pauseStart))
 			if resumeErr := m.uploader.ResumeUploads(ctx); resumeErr != nil {
 				m.logger.Warnf("failsafe: resume uploads after error failed: %v", resumeErr)
 			}
@@ -119,7 +266,12 @@ func (m *Monitor) HandleENOSPC(ctx context.Context) error {
 	m.logger.Infof("failsafe: ENOSPC recovery completed, freed %d bytes", report.BytesFreed)
 
 	if resumeUploads {
+		if m.categoryPauser != nil {
+			m.categoryPauser.Resume(categoriesToShed...)
+		}
+		m.metrics.RecordUploadPauseDuration(time.Since(pauseStart))
 		if err := m.uploader.ResumeUploads(ctx); err != nil {
+			outcome = RecoveryOutcomeFailed
 			return fmt.Errorf("cache failsafe: resume uploads: %w", err)
 		}
 	}
@@ -127,6 +279,105 @@ func (m *Monitor) HandleENOSPC(ctx context.Context) error {
 	return nil
 }
 
+// HandleChunkAuthFailure responds to a detected cache-at-rest authentication
+// failure (a tampered or corrupt cached chunk) by evicting the offending
+// path. Unlike HandleENOSPC this never pauses uploads: a bad chunk is a
+// data-integrity problem, not a capacity problem, and holding back healthy
+// writebacks while it is sorted out would only make things worse.
+func (m *Monitor) HandleChunkAuthFailure(ctx context.Context, path string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	evictor, ok := m.cleaner.(PathEvictor)
+	if !ok {
+		return fmt.Errorf("%w: cleaner does not support targeted eviction", ErrChunkAuthFailure)
+	}
+
+	freed, err := evictor.EvictPath(ctx, path)
+	if err != nil {
+		return fmt.Errorf("%w: evict %s: %v", ErrChunkAuthFailure, path, err)
+	}
+
+	m.logger.Warnf("failsafe: evicted tampered chunk %s (%d bytes freed) after authentication failure", path, freed)
+	return nil
+}
+
+// flushDirtyBeforeEviction force-flushes the largest dirty files through the
+// configured WritebackController before the cleaner evicts anything, so
+// ENOSPC recovery reclaims space by finishing writebacks rather than only by
+// discarding cached data. It is a best-effort step: a nil writeback
+// controller or any error along the way is logged and falls through to the
+// normal cleaner run, never blocking recovery.
+func (m *Monitor) flushDirtyBeforeEviction(ctx context.Context) {
+	if m.writeback == nil || m.dirtyIndex == nil {
+		return
+	}
+
+	if err := m.writeback.PauseNew(ctx); err != nil {
+		m.logger.Warnf("failsafe: pause new dirty writes for writeback flush failed: %v", err)
+		return
+	}
+	defer func() {
+		if err := m.writeback.ResumeNew(ctx); err != nil {
+			m.logger.Warnf("failsafe: resume new dirty writes after writeback flush failed: %v", err)
+		}
+	}()
+
+	metas, err := m.dirtyIndex.ListLRU(ctx, 0)
+	if err != nil {
+		m.logger.Warnf("failsafe: list writeback flush candidates failed: %v", err)
+		return
+	}
+
+	paths := largestDirtyPaths(metas, maxWritebackFlushCandidates)
+	if len(paths) == 0 {
+		return
+	}
+
+	if err := m.writeback.FlushHighPriority(ctx, paths); err != nil {
+		m.logger.Warnf("failsafe: flush %d dirty files before eviction failed: %v", len(paths), err)
+	}
+}
+
+// largestDirtyPaths returns up to limit paths from metas that have dirty
+// data pending upload, ordered by total dirty bytes descending.
+func largestDirtyPaths(metas []index.FileMeta, limit int) []string {
+	type candidate struct {
+		path  string
+		dirty int64
+	}
+
+	var candidates []candidate
+	for _, meta := range metas {
+		var dirty int64
+		for _, r := range meta.DirtyRanges {
+			dirty += r.Length
+		}
+		if dirty > 0 {
+			candidates = append(candidates, candidate{path: meta.Path, dirty: dirty})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].dirty > candidates[j].dirty })
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	paths := make([]string, len(candidates))
+	for i, c := range candidates {
+		paths[i] = c.path
+	}
+	return paths
+}
+
 func (m *Monitor) beginRecovery() bool {
 	m.mu.Lock()
 	if m.recovering {
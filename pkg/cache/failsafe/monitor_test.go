@@ -9,6 +9,8 @@ import (
 
 	"github.com/valandreev/tigrisfs/pkg/cache/cleaner"
 	"github.com/valandreev/tigrisfs/pkg/cache/failsafe"
+	"github.com/valandreev/tigrisfs/pkg/cache/files"
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
 )
 
 type stubUploader struct {
@@ -34,11 +36,18 @@ func (s *stubUploader) ResumeUploads(context.Context) error {
 }
 
 type stubCleaner struct {
-	mu        sync.Mutex
-	triggers  []cleaner.Trigger
-	reports   []cleaner.Report
-	err       error
-	blockChan chan struct{}
+	mu         sync.Mutex
+	triggers   []cleaner.Trigger
+	reports    []cleaner.Report
+	err        error
+	blockChan  chan struct{}
+	evictPaths []string
+	evictFreed int64
+	evictErr   error
+
+	// events, when set, records "evict" so callers can check ordering
+	// against events recorded elsewhere (e.g. a stubWriteback's "flush").
+	events *[]string
 }
 
 func (s *stubCleaner) RunOnce(ctx context.Context, trigger cleaner.Trigger) (cleaner.Report, error) {
@@ -51,6 +60,9 @@ func (s *stubCleaner) RunOnce(ctx context.Context, trigger cleaner.Trigger) (cle
 	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if s.events != nil {
+		*s.events = append(*s.events, "evict")
+	}
 	s.triggers = append(s.triggers, trigger)
 	var report cleaner.Report
 	if len(s.reports) > 0 {
@@ -60,6 +72,152 @@ func (s *stubCleaner) RunOnce(ctx context.Context, trigger cleaner.Trigger) (cle
 	return report, s.err
 }
 
+func (s *stubCleaner) EvictPath(ctx context.Context, path string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evictPaths = append(s.evictPaths, path)
+	return s.evictFreed, s.evictErr
+}
+
+type stubDirtyIndex struct {
+	metas []index.FileMeta
+	err   error
+}
+
+func (s *stubDirtyIndex) ListLRU(context.Context, int) ([]index.FileMeta, error) {
+	return s.metas, s.err
+}
+
+type stubWriteback struct {
+	mu          sync.Mutex
+	pauseCalls  int
+	resumeCalls int
+	flushed     [][]string
+	failFlush   error
+
+	// events, when set, records "flush" so callers can check ordering
+	// against events recorded elsewhere (e.g. a stubCleaner's "evict").
+	events *[]string
+}
+
+func (s *stubWriteback) PauseNew(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pauseCalls++
+	return nil
+}
+
+func (s *stubWriteback) ResumeNew(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resumeCalls++
+	return nil
+}
+
+func (s *stubWriteback) FlushHighPriority(_ context.Context, paths []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.events != nil {
+		*s.events = append(*s.events, "flush")
+	}
+	flushed := append([]string(nil), paths...)
+	s.flushed = append(s.flushed, flushed)
+	return s.failFlush
+}
+
+func TestMonitorHandleENOSPCFlushesDirtyFilesBeforeEviction(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	uploader := &stubUploader{}
+
+	var events []string
+	c := &stubCleaner{events: &events}
+	idx := &stubDirtyIndex{metas: []index.FileMeta{
+		{Path: "clean", DirtyRanges: nil},
+		{Path: "small-dirty", DirtyRanges: []index.Range{{Offset: 0, Length: 10}}},
+		{Path: "large-dirty", DirtyRanges: []index.Range{{Offset: 0, Length: 1000}}},
+	}}
+	wb := &stubWriteback{events: &events}
+
+	monitor, err := failsafe.NewMonitor(c, uploader, failsafe.WithWriteback(wb, idx))
+	if err != nil {
+		t.Fatalf("NewMonitor returned error: %v", err)
+	}
+
+	if err := monitor.HandleENOSPC(ctx); err != nil {
+		t.Fatalf("HandleENOSPC returned error: %v", err)
+	}
+
+	if wb.pauseCalls != 1 || wb.resumeCalls != 1 {
+		t.Fatalf("expected PauseNew/ResumeNew called once each, got %d/%d", wb.pauseCalls, wb.resumeCalls)
+	}
+	if len(wb.flushed) != 1 {
+		t.Fatalf("expected FlushHighPriority called once, got %d", len(wb.flushed))
+	}
+
+	want := []string{"large-dirty", "small-dirty"}
+	if len(wb.flushed[0]) != len(want) || wb.flushed[0][0] != want[0] || wb.flushed[0][1] != want[1] {
+		t.Fatalf("expected flush paths %v ordered by dirty bytes descending, got %v", want, wb.flushed[0])
+	}
+
+	if len(c.triggers) != 1 {
+		t.Fatalf("expected cleaner run once, got %d", len(c.triggers))
+	}
+	if len(events) != 2 || events[0] != "flush" || events[1] != "evict" {
+		t.Fatalf("expected flush before evict, got %v", events)
+	}
+}
+
+func TestMonitorHandleENOSPCSkipsFlushWithoutWriteback(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	uploader := &stubUploader{}
+	c := &stubCleaner{}
+
+	monitor, err := failsafe.NewMonitor(c, uploader)
+	if err != nil {
+		t.Fatalf("NewMonitor returned error: %v", err)
+	}
+
+	if err := monitor.HandleENOSPC(ctx); err != nil {
+		t.Fatalf("HandleENOSPC returned error: %v", err)
+	}
+
+	if len(c.triggers) != 1 {
+		t.Fatalf("expected cleaner still runs without a writeback controller, got %d", len(c.triggers))
+	}
+}
+
+func TestMonitorHandleENOSPCContinuesEvictionOnFlushError(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	uploader := &stubUploader{}
+	c := &stubCleaner{}
+	idx := &stubDirtyIndex{metas: []index.FileMeta{
+		{Path: "dirty", DirtyRanges: []index.Range{{Offset: 0, Length: 10}}},
+	}}
+	wb := &stubWriteback{failFlush: errors.New("upload backend unavailable")}
+
+	monitor, err := failsafe.NewMonitor(c, uploader, failsafe.WithWriteback(wb, idx))
+	if err != nil {
+		t.Fatalf("NewMonitor returned error: %v", err)
+	}
+
+	if err := monitor.HandleENOSPC(ctx); err != nil {
+		t.Fatalf("expected flush failure to be non-fatal, got %v", err)
+	}
+
+	if len(c.triggers) != 1 {
+		t.Fatalf("expected cleaner still runs after a flush error, got %d", len(c.triggers))
+	}
+	if wb.resumeCalls != 1 {
+		t.Fatalf("expected ResumeNew still called after a flush error, got %d", wb.resumeCalls)
+	}
+}
+
 func TestMonitorHandleENOSPCTriggersCleanerAndResumes(t *testing.T) {
 	t.Parallel()
 
@@ -156,6 +314,81 @@ func TestMonitorRejectsConcurrentRecovery(t *testing.T) {
 	}
 }
 
+func TestMonitorHandleENOSPCPausesAndResumesCategories(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	uploader := &stubUploader{}
+	c := &stubCleaner{}
+	pauser := failsafe.NewCategoryPauser()
+
+	monitor, err := failsafe.NewMonitor(c, uploader, failsafe.WithCategoryPauser(pauser))
+	if err != nil {
+		t.Fatalf("NewMonitor returned error: %v", err)
+	}
+
+	if !pauser.Allow(files.CategoryChunkData) {
+		t.Fatalf("expected chunk data allowed before recovery")
+	}
+
+	if err := monitor.HandleENOSPC(ctx); err != nil {
+		t.Fatalf("HandleENOSPC returned error: %v", err)
+	}
+
+	if !pauser.Allow(files.CategoryChunkData) {
+		t.Fatalf("expected chunk data allowed again after recovery completes")
+	}
+	if !pauser.Allow(files.CategoryCompaction) {
+		t.Fatalf("expected compaction allowed again after recovery completes")
+	}
+	if !pauser.Allow(files.CategoryJournal) {
+		t.Fatalf("expected journal never paused")
+	}
+}
+
+func TestMonitorHandleChunkAuthFailureEvictsPath(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	uploader := &stubUploader{}
+	c := &stubCleaner{evictFreed: 4096}
+
+	monitor, err := failsafe.NewMonitor(c, uploader)
+	if err != nil {
+		t.Fatalf("NewMonitor returned error: %v", err)
+	}
+
+	if err := monitor.HandleChunkAuthFailure(ctx, "objects/tampered"); err != nil {
+		t.Fatalf("HandleChunkAuthFailure returned error: %v", err)
+	}
+
+	if len(c.evictPaths) != 1 || c.evictPaths[0] != "objects/tampered" {
+		t.Fatalf("expected EvictPath called once with the bad path, got %v", c.evictPaths)
+	}
+	if uploader.pausedCalls != 0 {
+		t.Fatalf("expected uploads not paused for a chunk auth failure, got %d", uploader.pausedCalls)
+	}
+}
+
+func TestMonitorHandleChunkAuthFailurePropagatesEvictError(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	uploader := &stubUploader{}
+	evictErr := errors.New("remove failed")
+	c := &stubCleaner{evictErr: evictErr}
+
+	monitor, err := failsafe.NewMonitor(c, uploader)
+	if err != nil {
+		t.Fatalf("NewMonitor returned error: %v", err)
+	}
+
+	err = monitor.HandleChunkAuthFailure(ctx, "objects/tampered")
+	if !errors.Is(err, failsafe.ErrChunkAuthFailure) {
+		t.Fatalf("expected ErrChunkAuthFailure, got %v", err)
+	}
+}
+
 func waitUntil(cond func() bool, t *testing.T) {
 	t.Helper()
 	deadline := time.Now().Add(time.Second)
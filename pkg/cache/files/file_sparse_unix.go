@@ -0,0 +1,28 @@
+//go:build !windows
+// +build !windows
+
+package files
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// enableSparse is a no-op on unix: regular files on the filesystems this
+// cache targets (ext4, xfs, btrfs, apfs) already allocate blocks lazily, so
+// the staging file created by OpenContainer starts out sparse without any
+// extra syscall, unlike Windows which requires FSCTL_SET_SPARSE.
+func enableSparse(f *os.File) error {
+	return nil
+}
+
+// punchHole deallocates the blocks backing [off, off+length) in f without
+// changing its apparent size, so a later read of that range returns zeros
+// instead of the data previously written there.
+func punchHole(f *os.File, off, length int64) error {
+	if length <= 0 {
+		return nil
+	}
+	return unix.Fallocate(int(f.Fd()), unix.FALLOC_FL_PUNCH_HOLE|unix.FALLOC_FL_KEEP_SIZE, off, length)
+}
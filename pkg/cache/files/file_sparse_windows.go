@@ -5,6 +5,7 @@ package files
 
 import (
 	"os"
+	"unsafe"
 
 	"golang.org/x/sys/windows"
 )
@@ -21,3 +22,25 @@ func enableSparse(f *os.File) error {
 	}
 	return nil
 }
+
+// fileZeroDataInformation mirrors the Win32 FILE_ZERO_DATA_INFORMATION
+// struct passed to FSCTL_SET_ZERO_DATA.
+type fileZeroDataInformation struct {
+	FileOffset      int64
+	BeyondFinalZero int64
+}
+
+// punchHole deallocates the blocks backing [off, off+length) in f, same as
+// the unix fallocate(FALLOC_FL_PUNCH_HOLE) variant. f must already have been
+// marked sparse via enableSparse, or this zeroes the range without reclaiming
+// disk space.
+func punchHole(f *os.File, off, length int64) error {
+	if length <= 0 {
+		return nil
+	}
+	zeroRange := fileZeroDataInformation{FileOffset: off, BeyondFinalZero: off + length}
+	handle := windows.Handle(f.Fd())
+	var bytesReturned uint32
+	return windows.DeviceIoControl(handle, windows.FSCTL_SET_ZERO_DATA,
+		(*byte)(unsafe.Pointer(&zeroRange)), uint32(unsafe.Sizeof(zeroRange)), nil, 0, &bytesReturned, nil)
+}
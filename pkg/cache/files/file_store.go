@@ -1,12 +1,16 @@
 package files
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
+
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
 )
 
 var (
@@ -14,6 +18,56 @@ var (
 	ErrClosed = errors.New("cache file container is closed")
 )
 
+// ErrRangeMiss is returned by ReadRange when part of the requested range is
+// not yet resident in the container. Gaps lists the missing sub-ranges, in
+// ascending order, so the caller can fetch just those bytes (e.g. from the
+// remote backend) and retry with WriteRange.
+type ErrRangeMiss struct {
+	Gaps []index.Range
+}
+
+func (e *ErrRangeMiss) Error() string {
+	return fmt.Sprintf("cache file container: %d byte range(s) not resident", len(e.Gaps))
+}
+
+// RangeStore persists which byte ranges of a cache file are already staged
+// on disk, so OpenContainer can avoid materializing the whole object from
+// the existing cache file on every open. Implemented by
+// pkg/cache/index/bbolt.Index.
+type RangeStore interface {
+	// LoadResidentRanges returns the previously persisted resident ranges
+	// for path, or a nil/empty slice if none have been recorded.
+	LoadResidentRanges(ctx context.Context, path string) ([]index.Range, error)
+	// SaveResidentRanges overwrites the persisted resident ranges for path.
+	SaveResidentRanges(ctx context.Context, path string, ranges []index.Range) error
+}
+
+// Resetter reclaims a clean byte range of an otherwise-open cache file
+// without discarding the rest of it, so an ENOSPC recovery pass can free
+// space from a file that's mid-write instead of only being able to evict
+// files that are fully closed and clean. Implemented by *Container; see
+// pkg/cache/cleaner's reset path.
+type Resetter interface {
+	// ResetRange reclaims [off, off+length) and reports the number of bytes
+	// freed. The caller is responsible for only resetting ranges it knows
+	// are clean and for updating its own bookkeeping (e.g.
+	// index.ChunkMeta.Absent) afterward.
+	ResetRange(off, length int64) (int64, error)
+}
+
+// Option customises Container construction.
+type Option func(*Container)
+
+// WithRangeStore enables partial-range caching: OpenContainer stages only
+// the ranges already known to be resident instead of copying the whole
+// existing cache file, and Close persists the updated resident range set
+// back through store.
+func WithRangeStore(store RangeStore) Option {
+	return func(c *Container) {
+		c.store = store
+	}
+}
+
 // Container represents a mutable cache file; writes occur on a temporary file until Close commits atomically.
 type Container struct {
 	mu        sync.Mutex
@@ -21,10 +75,22 @@ type Container struct {
 	finalPath string
 	tempPath  string
 	closed    bool
+
+	store RangeStore
+	// present is the sorted, coalesced set of byte ranges currently backed
+	// by real data in file, as opposed to a sparse hole.
+	present []index.Range
+
+	category WriteCategory
+	metrics  Metrics
+	gate     WriteGate
 }
 
-// OpenContainer prepares a container for the given path, copying any existing data to a staging file.
-func OpenContainer(path string) (*Container, error) {
+// OpenContainer prepares a container for the given path. With no options,
+// any existing data at path is copied in full to the staging file. With
+// WithRangeStore, only the ranges already known to be resident are staged,
+// and ReadRange reports the rest as gaps instead of returning zeros.
+func OpenContainer(path string, opts ...Option) (*Container, error) {
 	if path == "" {
 		return nil, errors.New("cache file path must not be empty")
 	}
@@ -45,20 +111,46 @@ func OpenContainer(path string) (*Container, error) {
 		// For now we fall back to a regular file by ignoring this error.
 	}
 
-	if err := copyExisting(path, tempFile); err != nil {
+	c := &Container{
+		file:      tempFile,
+		finalPath: path,
+		tempPath:  tempFile.Name(),
+		metrics:   noopMetrics{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.store == nil {
+		if err := copyExisting(path, tempFile); err != nil {
+			tempFile.Close()
+			_ = os.Remove(tempFile.Name())
+			return nil, err
+		}
+		if info, statErr := os.Stat(path); statErr == nil && info.Size() > 0 {
+			c.present = []index.Range{{Offset: 0, Length: info.Size()}}
+		}
+		return c, nil
+	}
+
+	present, err := c.store.LoadResidentRanges(context.Background(), path)
+	if err != nil {
+		tempFile.Close()
+		_ = os.Remove(tempFile.Name())
+		return nil, fmt.Errorf("load resident ranges: %w", err)
+	}
+	if err := stagePresentRanges(path, tempFile, present); err != nil {
 		tempFile.Close()
 		_ = os.Remove(tempFile.Name())
 		return nil, err
 	}
+	c.present = present
 
-	return &Container{
-		file:      tempFile,
-		finalPath: path,
-		tempPath:  tempFile.Name(),
-	}, nil
+	return c, nil
 }
 
-// WriteAt writes data into the staged container at the given offset.
+// WriteAt writes data into the staged container at the given offset and
+// records [off, off+n) as resident.
 func (c *Container) WriteAt(p []byte, off int64) (int, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -66,7 +158,22 @@ func (c *Container) WriteAt(p []byte, off int64) (int, error) {
 	if c.closed {
 		return 0, ErrClosed
 	}
-	return c.file.WriteAt(p, off)
+	if c.gate != nil && !c.gate.Allow(c.category) {
+		return 0, ErrCategoryPaused
+	}
+	n, err := c.file.WriteAt(p, off)
+	if n > 0 {
+		c.present = index.MergeRanges(c.present, index.Range{Offset: off, Length: int64(n)})
+		c.metrics.RecordBytesWritten(c.category, n)
+	}
+	return n, err
+}
+
+// WriteRange writes data into the container at off, same as WriteAt. It
+// exists alongside ReadRange to name the range-aware read/write pair this
+// container supports when opened with WithRangeStore.
+func (c *Container) WriteRange(off int64, data []byte) (int, error) {
+	return c.WriteAt(data, off)
 }
 
 // ReadAt reads data from the staged container at the given offset.
@@ -80,6 +187,56 @@ func (c *Container) ReadAt(p []byte, off int64) (int, error) {
 	return c.file.ReadAt(p, off)
 }
 
+// ReadRange reads [off, off+length) from the container, returning
+// *ErrRangeMiss if any part of it is not yet resident rather than silently
+// returning zero bytes for a sparse hole.
+func (c *Container) ReadRange(ctx context.Context, off, length int64) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if c.closed {
+		return nil, ErrClosed
+	}
+
+	if gaps := missingRanges(c.present, index.Range{Offset: off, Length: length}); len(gaps) > 0 {
+		return nil, &ErrRangeMiss{Gaps: gaps}
+	}
+
+	buf := make([]byte, length)
+	if _, err := c.file.ReadAt(buf, off); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// ResetRange implements Resetter: it punches a hole over [off, off+length)
+// in the staged container, reclaiming the disk blocks it occupied, and
+// drops the range from the resident set so a subsequent ReadRange reports
+// it as a gap rather than returning zeros for what used to be real data.
+// Callers must only reset ranges they know are clean; ResetRange has no way
+// to tell a clean byte from a dirty one itself.
+func (c *Container) ResetRange(off, length int64) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return 0, ErrClosed
+	}
+	if length <= 0 {
+		return 0, nil
+	}
+
+	if err := punchHole(c.file, off, length); err != nil {
+		return 0, fmt.Errorf("punch hole: %w", err)
+	}
+
+	c.present = index.SubtractRange(c.present, index.Range{Offset: off, Length: length})
+	return length, nil
+}
+
 // Truncate resizes the staged container to the provided size.
 func (c *Container) Truncate(size int64) error {
 	c.mu.Lock()
@@ -88,7 +245,11 @@ func (c *Container) Truncate(size int64) error {
 	if c.closed {
 		return ErrClosed
 	}
-	return c.file.Truncate(size)
+	if err := c.file.Truncate(size); err != nil {
+		return err
+	}
+	c.metrics.RecordTruncate(c.category, size)
+	return nil
 }
 
 // Fsync flushes the staged container to disk.
@@ -99,10 +260,19 @@ func (c *Container) Fsync() error {
 	if c.closed {
 		return ErrClosed
 	}
-	return c.file.Sync()
+	start := time.Now()
+	err := c.file.Sync()
+	if err == nil {
+		c.metrics.RecordFsync(c.category, time.Since(start))
+	}
+	return err
 }
 
-// Close flushes and atomically renames the staged file into place.
+// Close flushes and atomically renames the staged file into place. When the
+// container was opened with WithRangeStore, the updated resident range set
+// is persisted right after the rename succeeds, so the on-disk sparse file
+// and the persisted bitmap never observably diverge for a caller that only
+// sees the container after a successful Close.
 func (c *Container) Close() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -128,11 +298,76 @@ func (c *Container) Close() error {
 		c.closed = true
 		return err
 	}
-
 	c.closed = true
+	c.metrics.RecordCommit(c.category)
+
+	if c.store != nil {
+		if err := c.store.SaveResidentRanges(context.Background(), c.finalPath, c.present); err != nil {
+			return fmt.Errorf("persist resident ranges: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// missingRanges returns the portions of want not covered by present, in
+// ascending order.
+func missingRanges(present []index.Range, want index.Range) []index.Range {
+	if want.Length <= 0 {
+		return nil
+	}
+	remaining := []index.Range{want}
+	for _, p := range present {
+		if p.End() <= want.Offset || p.Offset >= want.End() {
+			continue
+		}
+		remaining = index.SubtractRange(remaining, p)
+		if len(remaining) == 0 {
+			return nil
+		}
+	}
+	return remaining
+}
+
+// stagePresentRanges copies only the already-resident byte ranges from the
+// existing cache file at srcPath into dest, leaving the rest of dest sparse.
+// This is what lets OpenContainer skip materializing the whole object.
+func stagePresentRanges(srcPath string, dest *os.File, present []index.Range) error {
+	if len(present) == 0 {
+		return nil
+	}
+
+	source, err := os.Open(srcPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("open existing cache file: %w", err)
+	}
+	defer source.Close()
+
+	for _, r := range present {
+		w := &sectionWriter{dest: dest, off: r.Offset}
+		if _, err := io.CopyN(w, io.NewSectionReader(source, r.Offset, r.Length), r.Length); err != nil {
+			return fmt.Errorf("copy resident range [%d,%d): %w", r.Offset, r.End(), err)
+		}
+	}
 	return nil
 }
 
+// sectionWriter adapts os.File.WriteAt to the io.Writer interface required
+// by io.CopyN, advancing off as bytes are written.
+type sectionWriter struct {
+	dest *os.File
+	off  int64
+}
+
+func (w *sectionWriter) Write(p []byte) (int, error) {
+	n, err := w.dest.WriteAt(p, w.off)
+	w.off += int64(n)
+	return n, err
+}
+
 func copyExisting(srcPath string, dest *os.File) error {
 	source, err := os.Open(srcPath)
 	if err != nil {
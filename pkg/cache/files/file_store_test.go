@@ -1,10 +1,14 @@
 package files
 
 import (
+	"context"
+	"errors"
 	"io"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
 )
 
 func TestContainerWriteReadRoundTrip(t *testing.T) {
@@ -166,3 +170,167 @@ func TestContainerCloseIsIdempotent(t *testing.T) {
 		t.Fatalf("second Close failed: %v", err)
 	}
 }
+
+type fakeRangeStore struct {
+	ranges map[string][]index.Range
+}
+
+func newFakeRangeStore() *fakeRangeStore {
+	return &fakeRangeStore{ranges: make(map[string][]index.Range)}
+}
+
+func (f *fakeRangeStore) LoadResidentRanges(ctx context.Context, path string) ([]index.Range, error) {
+	return f.ranges[path], nil
+}
+
+func (f *fakeRangeStore) SaveResidentRanges(ctx context.Context, path string, ranges []index.Range) error {
+	f.ranges[path] = ranges
+	return nil
+}
+
+func TestContainerReadRangeReportsGaps(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sparse.bin")
+	store := newFakeRangeStore()
+
+	container, err := OpenContainer(path, WithRangeStore(store))
+	if err != nil {
+		t.Fatalf("OpenContainer returned error: %v", err)
+	}
+	defer func() {
+		_ = container.Close()
+	}()
+
+	if _, err := container.WriteRange(0, []byte("hello")); err != nil {
+		t.Fatalf("WriteRange failed: %v", err)
+	}
+
+	if got, err := container.ReadRange(context.Background(), 0, 5); err != nil {
+		t.Fatalf("ReadRange over resident data failed: %v", err)
+	} else if string(got) != "hello" {
+		t.Fatalf("ReadRange returned %q, want %q", string(got), "hello")
+	}
+
+	_, err = container.ReadRange(context.Background(), 0, 10)
+	var miss *ErrRangeMiss
+	if !errors.As(err, &miss) {
+		t.Fatalf("expected ErrRangeMiss reading past resident data, got %v", err)
+	}
+	if len(miss.Gaps) != 1 || miss.Gaps[0] != (index.Range{Offset: 5, Length: 5}) {
+		t.Fatalf("unexpected gaps: %+v", miss.Gaps)
+	}
+}
+
+func TestContainerPersistsResidentRangesAcrossReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "resident.bin")
+	store := newFakeRangeStore()
+
+	first, err := OpenContainer(path, WithRangeStore(store))
+	if err != nil {
+		t.Fatalf("OpenContainer returned error: %v", err)
+	}
+	if _, err := first.WriteRange(0, []byte("abc")); err != nil {
+		t.Fatalf("WriteRange failed: %v", err)
+	}
+	if _, err := first.WriteRange(100, []byte("xyz")); err != nil {
+		t.Fatalf("WriteRange failed: %v", err)
+	}
+	if err := first.Fsync(); err != nil {
+		t.Fatalf("Fsync failed: %v", err)
+	}
+	if err := first.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	want := []index.Range{{Offset: 0, Length: 3}, {Offset: 100, Length: 3}}
+	if got := store.ranges[path]; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("unexpected persisted ranges: %+v", got)
+	}
+
+	second, err := OpenContainer(path, WithRangeStore(store))
+	if err != nil {
+		t.Fatalf("reopen OpenContainer returned error: %v", err)
+	}
+	defer func() {
+		_ = second.Close()
+	}()
+
+	got, err := second.ReadRange(context.Background(), 100, 3)
+	if err != nil {
+		t.Fatalf("ReadRange after reopen failed: %v", err)
+	}
+	if string(got) != "xyz" {
+		t.Fatalf("ReadRange after reopen returned %q, want %q", string(got), "xyz")
+	}
+
+	if _, err := second.ReadRange(context.Background(), 3, 10); !errors.As(err, new(*ErrRangeMiss)) {
+		t.Fatalf("expected ErrRangeMiss for the never-written gap, got %v", err)
+	}
+}
+
+type fakeWriteGate struct {
+	blocked map[WriteCategory]bool
+}
+
+func (g *fakeWriteGate) Allow(category WriteCategory) bool {
+	return !g.blocked[category]
+}
+
+func TestContainerWriteGateBlocksPausedCategory(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gated.bin")
+
+	gate := &fakeWriteGate{blocked: map[WriteCategory]bool{CategoryChunkData: true}}
+	container, err := OpenContainer(path, WithCategory(CategoryChunkData), WithWriteGate(gate))
+	if err != nil {
+		t.Fatalf("OpenContainer returned error: %v", err)
+	}
+	defer func() {
+		_ = container.Close()
+	}()
+
+	if _, err := container.WriteAt([]byte("blocked"), 0); !errors.Is(err, ErrCategoryPaused) {
+		t.Fatalf("expected ErrCategoryPaused, got %v", err)
+	}
+
+	gate.blocked[CategoryChunkData] = false
+	if _, err := container.WriteAt([]byte("allowed"), 0); err != nil {
+		t.Fatalf("expected write to succeed once unpaused, got %v", err)
+	}
+}
+
+func TestContainerRecordsMetricsByCategory(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "metered.bin")
+
+	metrics := NewInMemoryMetrics()
+	container, err := OpenContainer(path, WithCategory(CategoryJournal), WithMetrics(metrics))
+	if err != nil {
+		t.Fatalf("OpenContainer returned error: %v", err)
+	}
+
+	if _, err := container.WriteAt([]byte("hello"), 0); err != nil {
+		t.Fatalf("WriteAt failed: %v", err)
+	}
+	if err := container.Fsync(); err != nil {
+		t.Fatalf("Fsync failed: %v", err)
+	}
+	if err := container.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	got := metrics.entries[CategoryJournal]
+	if got == nil {
+		t.Fatalf("expected counters recorded under CategoryJournal")
+	}
+	if got.bytesWritten != 5 {
+		t.Fatalf("expected 5 bytes written, got %d", got.bytesWritten)
+	}
+	if got.fsyncCount != 1 {
+		t.Fatalf("expected 1 fsync, got %d", got.fsyncCount)
+	}
+	if got.commitCount != 1 {
+		t.Fatalf("expected 1 commit, got %d", got.commitCount)
+	}
+}
@@ -0,0 +1,47 @@
+package index
+
+import (
+	"context"
+	"time"
+)
+
+// AdmissionGate decides whether a path that is not yet backed by a FileMeta
+// should be written to the on-disk cache, based on how many times it has
+// been observed. This lets a cache avoid thrashing its capacity on one-shot
+// large downloads that are never read again, at the cost of serving the
+// first Threshold-1 reads of any path pass-through (uncached).
+type AdmissionGate struct {
+	idx       CacheIndex
+	threshold int
+}
+
+// NewAdmissionGate returns a gate requiring threshold observations of a path
+// before Admit reports it eligible for caching. A threshold <= 1 admits
+// every path on first access, making the gate a no-op.
+func NewAdmissionGate(idx CacheIndex, threshold int) *AdmissionGate {
+	return &AdmissionGate{idx: idx, threshold: threshold}
+}
+
+// Admit records an access to path at now and reports whether it has now been
+// observed enough times to be admitted to the cache. Once admit is true, the
+// pending counter backing the decision is cleared, so a subsequent Admit
+// call for the same path (e.g. after it is evicted and re-downloaded) starts
+// counting from zero again.
+func (g *AdmissionGate) Admit(ctx context.Context, path string, now time.Time) (bool, error) {
+	if g.threshold <= 1 {
+		return true, nil
+	}
+
+	count, _, err := g.idx.RecordPendingAccess(ctx, path, now)
+	if err != nil {
+		return false, err
+	}
+	if count < g.threshold {
+		return false, nil
+	}
+
+	if err := g.idx.ClearPendingAccess(ctx, path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,53 @@
+package index
+
+// Backend is a minimal transactional key/value store abstraction, modeled
+// after bbolt's bucket/transaction semantics so a driver package (see
+// pkg/cache/index/bbolt, pkg/cache/index/badger) can expose its underlying
+// store this way if it chooses to. It exists as a documented extension
+// point for future drivers; neither shipped driver is currently routed
+// through it, since bbolt.Index and badger.Index each implement CacheIndex
+// directly against their own native transaction APIs.
+type Backend interface {
+	// View runs fn in a read-only transaction.
+	View(fn func(tx Tx) error) error
+	// Update runs fn in a read-write transaction.
+	Update(fn func(tx Tx) error) error
+	// Close releases the underlying store.
+	Close() error
+}
+
+// Tx is a single transaction against a Backend.
+type Tx interface {
+	// Bucket returns the named bucket, or nil if it does not exist.
+	Bucket(name string) Bucket
+	// CreateBucketIfNotExists returns the named bucket, creating it first
+	// if necessary. Only valid within a read-write transaction.
+	CreateBucketIfNotExists(name string) (Bucket, error)
+}
+
+// Bucket is a sorted key/value namespace within a Backend.
+type Bucket interface {
+	// Get returns the value stored at key, or nil if it is not present.
+	Get(key []byte) []byte
+	// Put inserts or replaces the value stored at key.
+	Put(key, value []byte) error
+	// Delete removes key. Missing keys are ignored.
+	Delete(key []byte) error
+	// ForEach calls fn for every key/value pair in key order, stopping (and
+	// returning fn's error) on the first non-nil error.
+	ForEach(fn func(k, v []byte) error) error
+	// Cursor returns a Cursor positioned before the first key.
+	Cursor() Cursor
+}
+
+// Cursor iterates a Bucket's keys in sorted order.
+type Cursor interface {
+	// Seek moves to the first key at or after key and returns it, or nil if
+	// none exists.
+	Seek(key []byte) (k, v []byte)
+	// Next advances to (and returns) the next key, or nil past the end.
+	Next() (k, v []byte)
+	// Prev moves to (and returns) the previous key, or nil before the
+	// start.
+	Prev() (k, v []byte)
+}
@@ -0,0 +1,954 @@
+// Package badger implements index.CacheIndex on top of dgraph-io/badger, an
+// LSM-tree key/value store. It is a sibling to pkg/cache/index/bbolt: pick
+// whichever driver suits the deployment via index.Open (see
+// pkg/cache/index/store). bbolt's B+tree rewrites every page touched by a
+// write, which includes bucketAtime whenever the atime bumps that
+// bbolt.Index.Get buffers are flushed; badger's LSM design instead appends
+// to a write-ahead value
+// log and compacts in the background, which suits that access pattern
+// much better for large, high-churn caches at the cost of a bigger
+// on-disk footprint and background compaction/GC to manage.
+//
+// badger has no notion of nested buckets the way bbolt does, so every key
+// here is prefixed with a short namespace byte string (prefixFiles,
+// prefixUploads, etc.) instead; see the prefix constants below for the
+// equivalent of bbolt's bucket list.
+package badger
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	bdg "github.com/dgraph-io/badger/v4"
+
+	"github.com/tigrisdata/tigrisfs/pkg/cache/index"
+)
+
+const (
+	prefixFiles         = "f/"
+	prefixUploads       = "u/"
+	prefixAtime         = "a/"
+	prefixPendingAccess = "p/"
+	prefixDigests       = "g/"
+	prefixChunks        = "c/"
+
+	uploadSeqKey    = "s/upload_seq"
+	uploadSeqBand   = 100
+	uploadIDFormat  = "upl-%020d"
+	valueLogGCRatio = 0.5
+)
+
+// Options configures Open behaviour.
+type Options struct {
+	// ValueLogGCInterval, if non-zero, runs badger.DB.RunValueLogGC on this
+	// schedule from a background goroutine for the lifetime of the Index.
+	// Zero disables automatic GC; callers that never set it are expected to
+	// invoke it themselves on their own schedule.
+	ValueLogGCInterval time.Duration
+}
+
+// Index implements index.CacheIndex backed by badger.
+type Index struct {
+	db        *bdg.DB
+	uploadSeq *bdg.Sequence
+
+	stopGC chan struct{}
+}
+
+// Open creates (or reopens) a badger-backed cache index at path.
+func Open(path string, opts Options) (*Index, error) {
+	bopts := bdg.DefaultOptions(path).WithLogger(nil)
+	db, err := bdg.Open(bopts)
+	if err != nil {
+		return nil, fmt.Errorf("open badger: %w", err)
+	}
+
+	seq, err := db.GetSequence([]byte(uploadSeqKey), uploadSeqBand)
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("open badger upload sequence: %w", err)
+	}
+
+	idx := &Index{db: db, uploadSeq: seq}
+	if opts.ValueLogGCInterval > 0 {
+		idx.stopGC = make(chan struct{})
+		go idx.runValueLogGC(opts.ValueLogGCInterval)
+	}
+	return idx, nil
+}
+
+// Close releases the underlying database handle and stops any background
+// value-log GC loop started by Open.
+func (i *Index) Close() error {
+	if i.stopGC != nil {
+		close(i.stopGC)
+	}
+	if i.uploadSeq != nil {
+		_ = i.uploadSeq.Release()
+	}
+	return i.db.Close()
+}
+
+func (i *Index) runValueLogGC(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-i.stopGC:
+			return
+		case <-ticker.C:
+			// RunValueLogGC reclaims at most one file per call; keep calling
+			// until it reports nothing left to do so one tick fully drains
+			// whatever backlog built up since the last one.
+			for i.db.RunValueLogGC(valueLogGCRatio) == nil {
+			}
+		}
+	}
+}
+
+// update runs fn in a read-write transaction, retrying on badger's
+// optimistic-concurrency conflict error since every CacheIndex method here
+// is a single logical operation with no caller-visible retry contract.
+func (i *Index) update(fn func(txn *bdg.Txn) error) error {
+	for {
+		err := i.db.Update(fn)
+		if errors.Is(err, bdg.ErrConflict) {
+			continue
+		}
+		return err
+	}
+}
+
+func getJSON(txn *bdg.Txn, key []byte, out interface{}) (bool, error) {
+	item, err := txn.Get(key)
+	if errors.Is(err, bdg.ErrKeyNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := item.Value(func(val []byte) error {
+		return json.Unmarshal(val, out)
+	}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func setJSON(txn *bdg.Txn, key []byte, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return txn.Set(key, data)
+}
+
+func fileKey(path string) []byte {
+	return append([]byte(prefixFiles), path...)
+}
+
+func uploadKey(id string) []byte {
+	return append([]byte(prefixUploads), id...)
+}
+
+func pendingKey(path string) []byte {
+	return append([]byte(prefixPendingAccess), path...)
+}
+
+// atimeKey orders entries by AtimeLocal ascending, breaking ties by path so
+// every entry gets a distinct key even when timestamps collide. Mirrors
+// bbolt.atimeKey, prefixed into badger's flat keyspace.
+func atimeKey(t time.Time, path string) []byte {
+	key := make([]byte, len(prefixAtime)+8+len(path))
+	n := copy(key, prefixAtime)
+	binary.BigEndian.PutUint64(key[n:], uint64(t.UnixNano()))
+	n += 8
+	copy(key[n:], path)
+	return key
+}
+
+func digestKey(digest string, length int64) []byte {
+	return []byte(prefixDigests + digest + ":" + strconv.FormatInt(length, 10))
+}
+
+// chunkKey orders chunk entries first by path and then by offset
+// (big-endian, so byte order matches numeric order), with a NUL separator
+// that can't appear in a path, so chunkKeyPrefix(path) bounds exactly that
+// path's entries for a prefix scan. Mirrors bbolt.chunkKey.
+func chunkKey(path string, offset int64) []byte {
+	key := make([]byte, len(prefixChunks)+len(path)+1+8)
+	n := copy(key, prefixChunks)
+	n += copy(key[n:], path)
+	key[n] = 0
+	n++
+	binary.BigEndian.PutUint64(key[n:], uint64(offset))
+	return key
+}
+
+func chunkKeyPrefix(path string) []byte {
+	key := make([]byte, len(prefixChunks)+len(path)+1)
+	n := copy(key, prefixChunks)
+	n += copy(key[n:], path)
+	key[n] = 0
+	return key
+}
+
+func (i *Index) Put(ctx context.Context, meta index.FileMeta) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if meta.Path == "" {
+		return errors.New("cache index: path must not be empty")
+	}
+
+	normalized := normalizeFileMeta(meta)
+	return i.update(func(txn *bdg.Txn) error {
+		return putFileMeta(txn, normalized)
+	})
+}
+
+func (i *Index) Get(ctx context.Context, path string) (index.FileMeta, error) {
+	if err := ctx.Err(); err != nil {
+		return index.FileMeta{}, err
+	}
+	if path == "" {
+		return index.FileMeta{}, errors.New("cache index: path must not be empty")
+	}
+
+	var result index.FileMeta
+	err := i.update(func(txn *bdg.Txn) error {
+		var meta index.FileMeta
+		ok, err := getJSON(txn, fileKey(path), &meta)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return index.ErrNotFound
+		}
+		meta.AtimeLocal = time.Now().UTC()
+		meta.HitCount++
+		if err := putFileMeta(txn, meta); err != nil {
+			return err
+		}
+		result = meta
+		return nil
+	})
+	if err != nil {
+		return index.FileMeta{}, err
+	}
+	return result, nil
+}
+
+func (i *Index) Update(ctx context.Context, path string, fn func(index.FileMeta) (index.FileMeta, error)) (index.FileMeta, error) {
+	if err := ctx.Err(); err != nil {
+		return index.FileMeta{}, err
+	}
+
+	var result index.FileMeta
+	err := i.update(func(txn *bdg.Txn) error {
+		var current index.FileMeta
+		ok, err := getJSON(txn, fileKey(path), &current)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return index.ErrNotFound
+		}
+		updated, err := fn(current)
+		if err != nil {
+			return err
+		}
+		updated.Path = path
+		if updated.AtimeLocal.IsZero() {
+			updated.AtimeLocal = time.Now().UTC()
+		}
+		if err := putFileMeta(txn, updated); err != nil {
+			return err
+		}
+		result = updated
+		return nil
+	})
+	if err != nil {
+		return index.FileMeta{}, err
+	}
+	return result, nil
+}
+
+func (i *Index) Delete(ctx context.Context, path string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return i.update(func(txn *bdg.Txn) error {
+		return deleteFileMeta(txn, path)
+	})
+}
+
+func (i *Index) ListLRU(ctx context.Context, limit int) ([]index.FileMeta, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	items := make([]index.FileMeta, 0)
+	err := i.db.View(func(txn *bdg.Txn) error {
+		opts := bdg.DefaultIteratorOptions
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		prefix := []byte(prefixAtime)
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			if limit > 0 && len(items) >= limit {
+				break
+			}
+			var path string
+			if err := it.Item().Value(func(val []byte) error {
+				path = string(val)
+				return nil
+			}); err != nil {
+				return err
+			}
+			var meta index.FileMeta
+			ok, err := getJSON(txn, fileKey(path), &meta)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			items = append(items, meta)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (i *Index) ListOlderThan(ctx context.Context, cutoff time.Time) ([]index.FileMeta, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	items := make([]index.FileMeta, 0)
+	// cutoffKey is the smallest possible atime key at exactly cutoff (empty
+	// path), so any real key (which always has a non-empty path suffix)
+	// compares strictly greater when its timestamp equals cutoff.
+	cutoffKey := atimeKey(cutoff, "")
+	err := i.db.View(func(txn *bdg.Txn) error {
+		opts := bdg.DefaultIteratorOptions
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		prefix := []byte(prefixAtime)
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			if bytes.Compare(it.Item().Key(), cutoffKey) >= 0 {
+				break
+			}
+			var path string
+			if err := it.Item().Value(func(val []byte) error {
+				path = string(val)
+				return nil
+			}); err != nil {
+				return err
+			}
+			var meta index.FileMeta
+			ok, err := getJSON(txn, fileKey(path), &meta)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			items = append(items, meta)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (i *Index) MarkDirty(ctx context.Context, path string, offset, length int64) (index.FileMeta, error) {
+	return i.Update(ctx, path, func(meta index.FileMeta) (index.FileMeta, error) {
+		meta.DirtyRanges = index.MergeRanges(meta.DirtyRanges, index.Range{Offset: offset, Length: length})
+		return meta, nil
+	})
+}
+
+func (i *Index) MarkClean(ctx context.Context, path string, offset, length int64) (index.FileMeta, error) {
+	return i.Update(ctx, path, func(meta index.FileMeta) (index.FileMeta, error) {
+		meta.DirtyRanges = index.SubtractRange(meta.DirtyRanges, index.Range{Offset: offset, Length: length})
+		return meta, nil
+	})
+}
+
+func (i *Index) DirtyRanges(ctx context.Context, path string) ([]index.Range, error) {
+	meta, err := i.Get(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return meta.DirtyRanges, nil
+}
+
+func (i *Index) PutChunk(ctx context.Context, path string, chunk index.ChunkMeta) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if path == "" {
+		return errors.New("cache index: path must not be empty")
+	}
+	return i.update(func(txn *bdg.Txn) error {
+		return setJSON(txn, chunkKey(path, chunk.Offset), chunk)
+	})
+}
+
+func (i *Index) DeleteChunk(ctx context.Context, path string, offset int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if path == "" {
+		return errors.New("cache index: path must not be empty")
+	}
+	return i.update(func(txn *bdg.Txn) error {
+		if err := txn.Delete(chunkKey(path, offset)); err != nil && !errors.Is(err, bdg.ErrKeyNotFound) {
+			return err
+		}
+		return nil
+	})
+}
+
+// ChunksInRange returns every chunk recorded for path that overlaps
+// [offset, offset+length). Unlike bbolt.Index.ChunksInRange, which can seek
+// its cursor directly to the first candidate key, badger's iterator has no
+// symmetrical "step back one" once positioned, so this walks every chunk
+// bucketed under path from its first offset, stopping once a chunk starts
+// at or past the end of the requested range.
+func (i *Index) ChunksInRange(ctx context.Context, path string, offset, length int64) ([]index.ChunkMeta, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, errors.New("cache index: path must not be empty")
+	}
+	if length <= 0 {
+		return nil, nil
+	}
+	end := offset + length
+
+	var result []index.ChunkMeta
+	err := i.db.View(func(txn *bdg.Txn) error {
+		opts := bdg.DefaultIteratorOptions
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		prefix := chunkKeyPrefix(path)
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var chunk index.ChunkMeta
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &chunk)
+			}); err != nil {
+				return err
+			}
+			if chunk.Offset >= end {
+				break
+			}
+			if chunk.Offset+chunk.Length > offset {
+				result = append(result, chunk)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (i *Index) AddUpload(ctx context.Context, entry index.UploadRecord) (index.UploadRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return index.UploadRecord{}, err
+	}
+
+	if entry.ID == "" {
+		seq, err := i.uploadSeq.Next()
+		if err != nil {
+			return index.UploadRecord{}, fmt.Errorf("cache index: assign upload id: %w", err)
+		}
+		entry.ID = fmt.Sprintf(uploadIDFormat, seq)
+	}
+	now := time.Now().UTC()
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = now
+	}
+	entry.UpdatedAt = now
+
+	err := i.update(func(txn *bdg.Txn) error {
+		return setJSON(txn, uploadKey(entry.ID), entry)
+	})
+	if err != nil {
+		return index.UploadRecord{}, err
+	}
+	return entry, nil
+}
+
+func (i *Index) ListUploads(ctx context.Context) ([]index.UploadRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	records := make([]index.UploadRecord, 0)
+	err := i.db.View(func(txn *bdg.Txn) error {
+		opts := bdg.DefaultIteratorOptions
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		prefix := []byte(prefixUploads)
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+			var rec index.UploadRecord
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &rec)
+			}); err != nil {
+				return err
+			}
+			records = append(records, rec)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// mutateUpload loads the upload record at id, applies fn, persists the
+// result, and returns it. Every Set* method below is a thin wrapper around
+// this, mirroring the equivalent sequence of Get-mutate-Put calls repeated
+// across bbolt.Index's own Set* methods.
+func (i *Index) mutateUpload(ctx context.Context, id string, fn func(*index.UploadRecord)) (index.UploadRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return index.UploadRecord{}, err
+	}
+	if id == "" {
+		return index.UploadRecord{}, errors.New("cache index: upload id must not be empty")
+	}
+
+	var result index.UploadRecord
+	err := i.update(func(txn *bdg.Txn) error {
+		var rec index.UploadRecord
+		ok, err := getJSON(txn, uploadKey(id), &rec)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return index.ErrNotFound
+		}
+		fn(&rec)
+		if err := setJSON(txn, uploadKey(id), rec); err != nil {
+			return err
+		}
+		result = rec
+		return nil
+	})
+	if err != nil {
+		return index.UploadRecord{}, err
+	}
+	return result, nil
+}
+
+func (i *Index) UpdateUploadStatus(ctx context.Context, id string, status index.UploadStatus, lastError string) (index.UploadRecord, error) {
+	return i.mutateUpload(ctx, id, func(rec *index.UploadRecord) {
+		rec.Status = status
+		rec.Attempts++
+		rec.LastError = lastError
+		now := time.Now().UTC()
+		if !now.After(rec.CreatedAt) {
+			now = rec.CreatedAt.Add(time.Nanosecond)
+		}
+		rec.UpdatedAt = now
+	})
+}
+
+func (i *Index) SetUploadID(ctx context.Context, id string, uploadID string) (index.UploadRecord, error) {
+	return i.mutateUpload(ctx, id, func(rec *index.UploadRecord) {
+		rec.UploadID = uploadID
+		rec.UpdatedAt = time.Now().UTC()
+	})
+}
+
+func (i *Index) SetTusURL(ctx context.Context, id string, tusURL string) (index.UploadRecord, error) {
+	return i.mutateUpload(ctx, id, func(rec *index.UploadRecord) {
+		rec.TusURL = tusURL
+		rec.UpdatedAt = time.Now().UTC()
+	})
+}
+
+func (i *Index) SetPartSize(ctx context.Context, id string, partSize int64) (index.UploadRecord, error) {
+	return i.mutateUpload(ctx, id, func(rec *index.UploadRecord) {
+		rec.PartSize = partSize
+		rec.UpdatedAt = time.Now().UTC()
+	})
+}
+
+func (i *Index) SetRetryDelay(ctx context.Context, id string, delay time.Duration) (index.UploadRecord, error) {
+	return i.mutateUpload(ctx, id, func(rec *index.UploadRecord) {
+		rec.PrevRetryDelay = delay
+		rec.UpdatedAt = time.Now().UTC()
+	})
+}
+
+func (i *Index) UpdateUploadProgress(ctx context.Context, id string, bytesTransferred int64, partETag string) (index.UploadRecord, error) {
+	return i.mutateUpload(ctx, id, func(rec *index.UploadRecord) {
+		rec.BytesTransferred = bytesTransferred
+		if partETag != "" {
+			rec.PartETags = append(rec.PartETags, partETag)
+		}
+		rec.UpdatedAt = time.Now().UTC()
+	})
+}
+
+func (i *Index) ClaimNextUpload(ctx context.Context, now time.Time, leaseDur time.Duration) (index.UploadRecord, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return index.UploadRecord{}, false, err
+	}
+
+	var result index.UploadRecord
+	found := false
+	err := i.update(func(txn *bdg.Txn) error {
+		found = false
+
+		var bestKey []byte
+		var bestRecord index.UploadRecord
+		var bestDue time.Time
+
+		opts := bdg.DefaultIteratorOptions
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		prefix := []byte(prefixUploads)
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var rec index.UploadRecord
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &rec)
+			}); err != nil {
+				return err
+			}
+
+			var due time.Time
+			switch rec.Status {
+			case index.UploadStatusQueued:
+				due = rec.NextAttemptAt
+			case index.UploadStatusInProgress:
+				if rec.LeaseExpiresAt.IsZero() || rec.LeaseExpiresAt.After(now) {
+					continue
+				}
+				due = rec.NextAttemptAt
+			default:
+				continue
+			}
+			if due.After(now) {
+				continue
+			}
+			if !found || due.Before(bestDue) {
+				bestKey = it.Item().KeyCopy(nil)
+				bestRecord = rec
+				bestDue = due
+				found = true
+			}
+		}
+
+		if !found {
+			return nil
+		}
+
+		bestRecord.Status = index.UploadStatusInProgress
+		bestRecord.Attempts++
+		bestRecord.LeaseExpiresAt = now.Add(leaseDur)
+		bestRecord.UpdatedAt = now
+		if err := setJSON(txn, bestKey, bestRecord); err != nil {
+			return err
+		}
+		result = bestRecord
+		return nil
+	})
+	if err != nil {
+		return index.UploadRecord{}, false, err
+	}
+	return result, found, nil
+}
+
+func (i *Index) Reschedule(ctx context.Context, id string, nextAttemptAt time.Time, lastError string) (index.UploadRecord, error) {
+	return i.mutateUpload(ctx, id, func(rec *index.UploadRecord) {
+		rec.Status = index.UploadStatusQueued
+		rec.NextAttemptAt = nextAttemptAt
+		rec.LastError = lastError
+		rec.LeaseExpiresAt = time.Time{}
+		rec.UpdatedAt = time.Now().UTC()
+	})
+}
+
+// RenewLease extends id's lease to now+leaseDur, for a worker still making
+// progress on an InProgress record that doesn't want another worker to
+// reclaim it out from under it via ClaimNextUpload.
+func (i *Index) RenewLease(ctx context.Context, id string, now time.Time, leaseDur time.Duration) (index.UploadRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return index.UploadRecord{}, err
+	}
+	if id == "" {
+		return index.UploadRecord{}, errors.New("cache index: upload id must not be empty")
+	}
+
+	var result index.UploadRecord
+	err := i.update(func(txn *bdg.Txn) error {
+		var rec index.UploadRecord
+		ok, err := getJSON(txn, uploadKey(id), &rec)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return index.ErrNotFound
+		}
+		if rec.Status != index.UploadStatusInProgress {
+			return fmt.Errorf("cache index: cannot renew lease for upload %s in status %s", id, rec.Status)
+		}
+		rec.LeaseExpiresAt = now.Add(leaseDur)
+		rec.UpdatedAt = now
+		if err := setJSON(txn, uploadKey(id), rec); err != nil {
+			return err
+		}
+		result = rec
+		return nil
+	})
+	if err != nil {
+		return index.UploadRecord{}, err
+	}
+	return result, nil
+}
+
+// ReapExpiredLeases resets every InProgress record whose LeaseExpiresAt is
+// at or before now back to Queued, scanning prefixUploads the same way
+// ClaimNextUpload and PurgeExpiredPendingAccess do.
+func (i *Index) ReapExpiredLeases(ctx context.Context, now time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var reaped int
+	err := i.update(func(txn *bdg.Txn) error {
+		opts := bdg.DefaultIteratorOptions
+		it := txn.NewIterator(opts)
+
+		prefix := []byte(prefixUploads)
+		var stale []index.UploadRecord
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var rec index.UploadRecord
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &rec)
+			}); err != nil {
+				it.Close()
+				return err
+			}
+			if rec.Status != index.UploadStatusInProgress || rec.LeaseExpiresAt.IsZero() || rec.LeaseExpiresAt.After(now) {
+				continue
+			}
+			stale = append(stale, rec)
+		}
+		it.Close()
+
+		for _, rec := range stale {
+			rec.Status = index.UploadStatusQueued
+			rec.LeaseExpiresAt = time.Time{}
+			rec.LastError = "lease expired: reclaimed by reaper"
+			rec.UpdatedAt = now
+			if err := setJSON(txn, uploadKey(rec.ID), rec); err != nil {
+				return err
+			}
+		}
+		reaped = len(stale)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return reaped, nil
+}
+
+type pendingAccessEntry struct {
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+}
+
+func (i *Index) RecordPendingAccess(ctx context.Context, path string, now time.Time) (int, time.Time, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, time.Time{}, err
+	}
+	if path == "" {
+		return 0, time.Time{}, errors.New("cache index: path must not be empty")
+	}
+
+	var result pendingAccessEntry
+	err := i.update(func(txn *bdg.Txn) error {
+		entry := pendingAccessEntry{FirstSeen: now}
+		if _, err := getJSON(txn, pendingKey(path), &entry); err != nil {
+			return err
+		}
+		entry.Count++
+		result = entry
+		return setJSON(txn, pendingKey(path), entry)
+	})
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return result.Count, result.FirstSeen, nil
+}
+
+func (i *Index) ClearPendingAccess(ctx context.Context, path string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if path == "" {
+		return errors.New("cache index: path must not be empty")
+	}
+	return i.update(func(txn *bdg.Txn) error {
+		if err := txn.Delete(pendingKey(path)); err != nil && !errors.Is(err, bdg.ErrKeyNotFound) {
+			return err
+		}
+		return nil
+	})
+}
+
+func (i *Index) PurgeExpiredPendingAccess(ctx context.Context, cutoff time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var removed int
+	err := i.update(func(txn *bdg.Txn) error {
+		opts := bdg.DefaultIteratorOptions
+		it := txn.NewIterator(opts)
+
+		prefix := []byte(prefixPendingAccess)
+		var stale [][]byte
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var entry pendingAccessEntry
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &entry)
+			}); err != nil {
+				it.Close()
+				return err
+			}
+			if entry.FirstSeen.Before(cutoff) {
+				stale = append(stale, it.Item().KeyCopy(nil))
+			}
+		}
+		it.Close()
+
+		for _, k := range stale {
+			if err := txn.Delete(k); err != nil {
+				return err
+			}
+		}
+		removed = len(stale)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+func (i *Index) LookupDigest(ctx context.Context, digest string, length int64) (string, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return "", false, err
+	}
+
+	var etag string
+	var found bool
+	err := i.db.View(func(txn *bdg.Txn) error {
+		item, err := txn.Get(digestKey(digest, length))
+		if errors.Is(err, bdg.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+		return item.Value(func(val []byte) error {
+			etag = string(val)
+			return nil
+		})
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return etag, found, nil
+}
+
+func (i *Index) RecordDigest(ctx context.Context, digest string, length int64, etag string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if digest == "" {
+		return errors.New("cache index: digest must not be empty")
+	}
+	return i.update(func(txn *bdg.Txn) error {
+		return txn.Set(digestKey(digest, length), []byte(etag))
+	})
+}
+
+func normalizeFileMeta(meta index.FileMeta) index.FileMeta {
+	if meta.AtimeLocal.IsZero() {
+		meta.AtimeLocal = time.Now().UTC()
+	}
+	if meta.MtimeRemote.IsZero() {
+		meta.MtimeRemote = time.Now().UTC()
+	}
+	return meta
+}
+
+// putFileMeta writes meta and keeps the atime index in sync, removing the
+// previous atime key (if the entry already existed under a different
+// AtimeLocal) before inserting the new one. Mirrors bbolt.putFileMeta.
+func putFileMeta(txn *bdg.Txn, meta index.FileMeta) error {
+	var existing index.FileMeta
+	ok, err := getJSON(txn, fileKey(meta.Path), &existing)
+	if err != nil {
+		return err
+	}
+	if ok {
+		if err := txn.Delete(atimeKey(existing.AtimeLocal, existing.Path)); err != nil && !errors.Is(err, bdg.ErrKeyNotFound) {
+			return err
+		}
+	}
+
+	if err := setJSON(txn, fileKey(meta.Path), meta); err != nil {
+		return err
+	}
+	return txn.Set(atimeKey(meta.AtimeLocal, meta.Path), []byte(meta.Path))
+}
+
+func deleteFileMeta(txn *bdg.Txn, path string) error {
+	var meta index.FileMeta
+	ok, err := getJSON(txn, fileKey(path), &meta)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	if err := txn.Delete(atimeKey(meta.AtimeLocal, path)); err != nil && !errors.Is(err, bdg.ErrKeyNotFound) {
+		return err
+	}
+	return txn.Delete(fileKey(path))
+}
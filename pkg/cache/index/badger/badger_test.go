@@ -0,0 +1,119 @@
+package badger
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
+	"github.com/valandreev/tigrisfs/pkg/cache/index/indextest"
+)
+
+func TestCacheIndexContractWithBadger(t *testing.T) {
+	indextest.RunCacheIndexContract(t, func(tb testing.TB) index.CacheIndex {
+		tb.Helper()
+
+		dir := tb.TempDir()
+		idx, err := Open(filepath.Join(dir, "index"), Options{})
+		if err != nil {
+			tb.Fatalf("failed to open badger index: %v", err)
+		}
+		tb.Cleanup(func() {
+			_ = idx.Close()
+		})
+		return idx
+	})
+}
+
+func TestUploadsPersistAcrossReopen(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index")
+
+	idx, err := Open(path, Options{})
+	if err != nil {
+		t.Fatalf("first Open returned error: %v", err)
+	}
+
+	upload := index.UploadRecord{
+		Path:   "/objects/video.mp4",
+		Offset: 1024,
+		Length: 2048,
+		Status: index.UploadStatusQueued,
+	}
+	created, err := idx.AddUpload(ctx, upload)
+	if err != nil {
+		t.Fatalf("AddUpload failed: %v", err)
+	}
+	if created.ID == "" {
+		t.Fatalf("expected AddUpload to assign ID")
+	}
+	progressed, err := idx.UpdateUploadStatus(ctx, created.ID, index.UploadStatusInProgress, "")
+	if err != nil {
+		t.Fatalf("UpdateUploadStatus failed: %v", err)
+	}
+	if progressed.Attempts != 1 {
+		t.Fatalf("expected attempts to be 1 after first update, got %d", progressed.Attempts)
+	}
+
+	if err := idx.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	idx, err = Open(path, Options{})
+	if err != nil {
+		t.Fatalf("re-open returned error: %v", err)
+	}
+	defer func() { _ = idx.Close() }()
+
+	uploads, err := idx.ListUploads(ctx)
+	if err != nil {
+		t.Fatalf("ListUploads failed: %v", err)
+	}
+	if len(uploads) != 1 {
+		t.Fatalf("expected 1 upload after reopen, got %d", len(uploads))
+	}
+	if uploads[0].ID != created.ID {
+		t.Fatalf("expected persisted ID %s, got %s", created.ID, uploads[0].ID)
+	}
+	if uploads[0].Status != index.UploadStatusInProgress {
+		t.Fatalf("expected status %s after reopen, got %s", index.UploadStatusInProgress, uploads[0].Status)
+	}
+}
+
+func TestListLRUOrdersByAtimeAndRespectsLimit(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	idx, err := Open(filepath.Join(dir, "index"), Options{})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer func() { _ = idx.Close() }()
+
+	older := index.FileMeta{Path: "objects/old.bin", Size: 1, AtimeLocal: time.Unix(10, 0).UTC()}
+	newer := index.FileMeta{Path: "objects/new.bin", Size: 1, AtimeLocal: time.Unix(20, 0).UTC()}
+	if err := idx.Put(ctx, newer); err != nil {
+		t.Fatalf("Put newer failed: %v", err)
+	}
+	if err := idx.Put(ctx, older); err != nil {
+		t.Fatalf("Put older failed: %v", err)
+	}
+
+	// Get() bumps AtimeLocal to now, so it must move to the back of the list.
+	if _, err := idx.Get(ctx, older.Path); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+
+	metas, err := idx.ListLRU(ctx, 1)
+	if err != nil {
+		t.Fatalf("ListLRU returned error: %v", err)
+	}
+	if len(metas) != 1 {
+		t.Fatalf("expected ListLRU to respect limit, got %d entries", len(metas))
+	}
+	if metas[0].Path != newer.Path {
+		t.Fatalf("expected %s to be least-recently-used after older was touched, got %s", newer.Path, metas[0].Path)
+	}
+}
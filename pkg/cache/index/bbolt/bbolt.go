@@ -1,14 +1,19 @@
 package bbolt
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/crc32"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
 	"strconv"
+	"sync"
 	"time"
 
 	bolt "go.etcd.io/bbolt"
@@ -17,10 +22,39 @@ import (
 )
 
 const (
-	currentSchemaVersion = 1
+	currentSchemaVersion = 7
 	bucketStats          = "stats"
 	bucketFiles          = "files"
 	bucketUploads        = "uploads"
+	// bucketUploadsByTime is a secondary index over bucketUploads, kept in
+	// sync by putUpload, keyed by uploadTimeKey(uploadDueKey(rec), rec.ID)
+	// for every record whose status is Queued or InProgress (see
+	// isSchedulable). It lets ClaimNextUpload seek straight to the
+	// earliest-due record in O(log N) instead of scanning every upload.
+	bucketUploadsByTime = "uploads_by_time"
+	// bucketAtime is a secondary index over bucketFiles keyed by
+	// atimeKey(AtimeLocal, Path) -> Path, kept in lockstep with bucketFiles so
+	// ListLRU can do an O(limit) cursor walk instead of a full scan and sort.
+	bucketAtime = "atime_idx"
+	// bucketRanges stores the persisted set of locally-resident byte ranges
+	// for a cache file, keyed by the same path used in bucketFiles, so
+	// files.Container can avoid re-materializing the whole object on open.
+	bucketRanges = "file_ranges"
+	// bucketPendingAccess stores admission-threshold bookkeeping for paths
+	// not yet backed by a FileMeta, keyed by path -> encoded
+	// pendingAccessEntry. Entries are removed once a path is admitted (or
+	// its counter expires), so this bucket only ever holds objects
+	// currently being "warmed up".
+	bucketPendingAccess = "pending_access"
+	// bucketDigests maps a content digest (keyed by digestKey(digest,
+	// length)) to the ETag of a previously uploaded object with that exact
+	// content, backing content-addressed chunk dedup in pkg/cache/uploader.
+	bucketDigests = "chunk_digests"
+	// bucketChunks holds per-chunk residency metadata, keyed by
+	// chunkKey(path, offset) rather than nested inside each path's FileMeta,
+	// so ChunksInRange can cursor-seek straight to the chunks overlapping a
+	// range instead of decoding (and scanning) every chunk a large file has.
+	bucketChunks = "chunks"
 
 	keySchemaVersion = "schema_version"
 	keyUploadSeq     = "upload_seq"
@@ -30,15 +64,80 @@ var (
 	errUnknownSchema = errors.New("cache index: unknown schema version")
 )
 
+// Codec selects how FileMeta/UploadRecord values are serialized on disk.
+type Codec int
+
+const (
+	// CodecBinary is the default: a compact, versioned binary encoding with
+	// varint integers and a trailing CRC32C (see encodeFileMeta,
+	// encodeUpload). It avoids JSON's per-field tag overhead, which matters
+	// most for a FileMeta with thousands of ChunkMeta entries since every
+	// write of it (including each flushed atime bump) still re-encodes the
+	// whole value.
+	CodecBinary Codec = iota
+	// CodecJSON keeps the legacy human-readable encoding, for inspecting a
+	// database with a generic bbolt viewer at the cost of size and CPU.
+	CodecJSON
+)
+
 // Options configures Open behaviour.
 type Options struct {
 	// Timeout controls bbolt file open timeout. If zero, a sensible default is used.
 	Timeout time.Duration
+
+	// Codec selects the on-disk encoding for new/rewritten FileMeta and
+	// UploadRecord values. Existing values are decoded by sniffing their
+	// leading byte regardless of this setting, so switching it is safe at
+	// any time: records only actually change format the next time they're
+	// written.
+	Codec Codec
+
+	// AtimeFlushInterval, if non-zero, starts a background goroutine that
+	// periodically flushes buffered atime bumps (see Index.Get) to disk in
+	// a single db.Update, instead of only flushing on Close or once
+	// AtimeBufferSize is reached.
+	AtimeFlushInterval time.Duration
+	// AtimeBufferSize bounds how many distinct paths' atime bumps are held
+	// in memory before a flush is forced. Zero means unbounded (flushing
+	// only on AtimeFlushInterval or Close).
+	AtimeBufferSize int
+	// SyncAtimeOnEvict, if true, makes ListLRU flush the atime buffer
+	// before reading, so an eviction sweep always sees fully up-to-date
+	// ordering on disk rather than merging in-memory overrides on top of
+	// it.
+	SyncAtimeOnEvict bool
+
+	// ReadOnly opens the database with bbolt's read-only mode instead of
+	// exclusively locking it for writes, for tooling that inspects a live
+	// mount's index (e.g. a "cache inspect" command) without contending
+	// with the daemon actually owning it. Every mutating method returns
+	// index.ErrReadOnly, and Get no longer buffers atime bumps since there
+	// will never be a write transaction to flush them through.
+	ReadOnly bool
+}
+
+// atimeBufEntry accumulates a path's pending Get-driven atime bump: the
+// most recent access time observed and how many HitCount increments (one
+// per Get) haven't made it to disk yet.
+type atimeBufEntry struct {
+	atime    time.Time
+	hitDelta uint64
 }
 
 // Index implements index.CacheIndex backed by bbolt.
 type Index struct {
-	db *bolt.DB
+	db       *bolt.DB
+	readOnly bool
+	codec    Codec
+
+	atimeBufferSize  int
+	syncAtimeOnEvict bool
+
+	atimeMu  sync.Mutex
+	atimeBuf map[string]atimeBufEntry
+
+	stopFlush chan struct{}
+	flushDone chan struct{}
 }
 
 // Open creates (or reopens) a bbolt-backed cache index at path.
@@ -52,26 +151,255 @@ func Open(path string, opts Options) (*Index, error) {
 		timeout = 100 * time.Millisecond
 	}
 
-	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: timeout})
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: timeout, ReadOnly: opts.ReadOnly})
 	if err != nil {
 		return nil, fmt.Errorf("open bbolt: %w", err)
 	}
 
-	idx := &Index{db: db}
-	if err := idx.ensureSchema(); err != nil {
-		_ = db.Close()
-		return nil, err
+	idx := &Index{
+		db:               db,
+		readOnly:         opts.ReadOnly,
+		codec:            opts.Codec,
+		atimeBufferSize:  opts.AtimeBufferSize,
+		syncAtimeOnEvict: opts.SyncAtimeOnEvict,
+		atimeBuf:         make(map[string]atimeBufEntry),
+	}
+
+	if opts.ReadOnly {
+		// A read-only bbolt handle can't run the write transaction
+		// ensureSchema needs to create buckets or migrate an older
+		// database, so a read-only Open only succeeds against a database
+		// already on currentSchemaVersion.
+		if err := idx.checkSchemaCurrent(); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	} else {
+		if err := idx.ensureSchema(); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+
+	if !opts.ReadOnly && opts.AtimeFlushInterval > 0 {
+		idx.stopFlush = make(chan struct{})
+		idx.flushDone = make(chan struct{})
+		go idx.runAtimeFlusher(opts.AtimeFlushInterval)
 	}
 
 	return idx, nil
 }
 
-// Close releases the underlying database handle.
+// update runs fn in a write transaction, rejecting it up front with
+// index.ErrReadOnly instead of handing it to bbolt (which would otherwise
+// fail the same way, but with its own internal error rather than the one
+// documented on CacheIndex's mutating methods).
+func (i *Index) update(fn func(tx *bolt.Tx) error) error {
+	if i.readOnly {
+		return index.ErrReadOnly
+	}
+	return i.db.Update(fn)
+}
+
+// checkSchemaCurrent verifies, without writing, that an already-initialized
+// database is on currentSchemaVersion. It's the read-only counterpart to
+// ensureSchema, which instead creates buckets and migrates forward.
+func (i *Index) checkSchemaCurrent() error {
+	return i.db.View(func(tx *bolt.Tx) error {
+		stats := tx.Bucket([]byte(bucketStats))
+		if stats == nil {
+			return fmt.Errorf("cache index: read-only open of an uninitialized database")
+		}
+		versionBytes := stats.Get([]byte(keySchemaVersion))
+		if len(versionBytes) == 0 {
+			return fmt.Errorf("cache index: read-only open of an uninitialized database")
+		}
+		version, err := strconv.Atoi(string(versionBytes))
+		if err != nil {
+			return fmt.Errorf("parse schema version: %w", err)
+		}
+		if version != currentSchemaVersion {
+			return fmt.Errorf("%w: %d (read-only open cannot migrate)", errUnknownSchema, version)
+		}
+		return nil
+	})
+}
+
+// sharedEntry is the registry's bookkeeping for one open database: the
+// *Index every sharer delegates to, and how many sharedHandles are still
+// holding it open.
+type sharedEntry struct {
+	idx  *Index
+	refs int
+}
+
+var (
+	sharedMu sync.Mutex
+	shared   = make(map[string]*sharedEntry)
+)
+
+// OpenShared returns a new *sharedHandle onto a process-wide singleton
+// *Index for the absolute form of path: the first call opens it with
+// Open(path, opts) and
+// every subsequent call (from elsewhere in the same process) for the same
+// path reuses that same underlying *Index instead of racing on bbolt's
+// exclusive file lock, ignoring opts since the database is already open.
+// Each returned handle's Close decrements a reference count; the underlying
+// database is only actually closed once every sharer has closed its handle.
+// It returns the concrete type, like Open, so callers can call Close
+// directly instead of needing a type assertion.
+func OpenShared(path string, opts Options) (*sharedHandle, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("resolve index path: %w", err)
+	}
+
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+
+	entry, ok := shared[abs]
+	if !ok {
+		idx, err := Open(abs, opts)
+		if err != nil {
+			return nil, err
+		}
+		entry = &sharedEntry{idx: idx}
+		shared[abs] = entry
+	}
+	entry.refs++
+	return &sharedHandle{path: abs, Index: entry.idx}, nil
+}
+
+// sharedHandle is the index.CacheIndex handed out by OpenShared. It embeds
+// *Index so every CacheIndex method delegates straight through, except
+// Close, which releases this sharer's reference instead of closing the
+// underlying database out from under everyone else using it.
+type sharedHandle struct {
+	*Index
+
+	path   string
+	mu     sync.Mutex
+	closed bool
+}
+
+// Close releases this handle's reference to the shared database, only
+// actually closing it once every other sharer has also closed theirs.
+// Calling Close more than once on the same handle is a no-op.
+func (h *sharedHandle) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+	h.closed = true
+
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+
+	entry, ok := shared[h.path]
+	if !ok {
+		return nil
+	}
+	entry.refs--
+	if entry.refs > 0 {
+		return nil
+	}
+	delete(shared, h.path)
+	return entry.idx.Close()
+}
+
+// Close stops the background atime flusher (if running), flushes any atime
+// bumps still buffered, and releases the underlying database handle.
 func (i *Index) Close() error {
+	if i.stopFlush != nil {
+		close(i.stopFlush)
+		<-i.flushDone
+	}
+	flushErr := i.flushAtimeBuffer()
+
 	if i.db == nil {
+		return flushErr
+	}
+	if closeErr := i.db.Close(); closeErr != nil {
+		return closeErr
+	}
+	return flushErr
+}
+
+func (i *Index) runAtimeFlusher(interval time.Duration) {
+	defer close(i.flushDone)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-i.stopFlush:
+			return
+		case <-ticker.C:
+			_ = i.flushAtimeBuffer()
+		}
+	}
+}
+
+// bufferAtime records path as accessed at now, returning how many Get calls
+// (including this one) have accumulated for it since the last flush. It
+// forces a flush first if recording this entry would grow the buffer past
+// AtimeBufferSize, so the map stays bounded.
+func (i *Index) bufferAtime(path string, now time.Time) uint64 {
+	i.atimeMu.Lock()
+	if _, exists := i.atimeBuf[path]; !exists && i.atimeBufferSize > 0 && len(i.atimeBuf) >= i.atimeBufferSize {
+		i.atimeMu.Unlock()
+		_ = i.flushAtimeBuffer()
+		i.atimeMu.Lock()
+	}
+	entry := i.atimeBuf[path]
+	entry.atime = now
+	entry.hitDelta++
+	i.atimeBuf[path] = entry
+	delta := entry.hitDelta
+	i.atimeMu.Unlock()
+	return delta
+}
+
+// flushAtimeBuffer applies every buffered atime bump to disk in a single
+// transaction and clears the buffer. Paths deleted since being buffered are
+// silently dropped.
+func (i *Index) flushAtimeBuffer() error {
+	if i.readOnly {
+		return nil
+	}
+
+	i.atimeMu.Lock()
+	if len(i.atimeBuf) == 0 {
+		i.atimeMu.Unlock()
 		return nil
 	}
-	return i.db.Close()
+	pending := i.atimeBuf
+	i.atimeBuf = make(map[string]atimeBufEntry)
+	i.atimeMu.Unlock()
+
+	return i.update(func(tx *bolt.Tx) error {
+		filesBucket := tx.Bucket([]byte(bucketFiles))
+		if filesBucket == nil {
+			return fmt.Errorf("missing bucket %s", bucketFiles)
+		}
+		for path, entry := range pending {
+			raw := filesBucket.Get([]byte(path))
+			if raw == nil {
+				continue
+			}
+			meta, err := decodeFileMeta(raw)
+			if err != nil {
+				return err
+			}
+			meta.AtimeLocal = entry.atime
+			meta.HitCount += entry.hitDelta
+			if err := putFileMeta(tx, meta, i.codec); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func (i *Index) Put(ctx context.Context, meta index.FileMeta) error {
@@ -83,19 +411,16 @@ func (i *Index) Put(ctx context.Context, meta index.FileMeta) error {
 	}
 
 	normalized := normalizeFileMeta(meta)
-	return i.db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(bucketFiles))
-		if bucket == nil {
-			return fmt.Errorf("missing bucket %s", bucketFiles)
-		}
-		data, err := encodeFileMeta(normalized)
-		if err != nil {
-			return err
-		}
-		return bucket.Put([]byte(normalized.Path), data)
+	return i.update(func(tx *bolt.Tx) error {
+		return putFileMeta(tx, normalized, i.codec)
 	})
 }
 
+// Get looks up path and, on success, bumps its recency via bufferAtime
+// instead of writing the bump straight to disk. This turns what used to be
+// a read-modify-write transaction into a plain db.View, with the atime bump
+// itself applied later (on a timer, once AtimeBufferSize is reached, or at
+// Close) by flushAtimeBuffer.
 func (i *Index) Get(ctx context.Context, path string) (index.FileMeta, error) {
 	if err := ctx.Err(); err != nil {
 		return index.FileMeta{}, err
@@ -105,7 +430,7 @@ func (i *Index) Get(ctx context.Context, path string) (index.FileMeta, error) {
 	}
 
 	var result index.FileMeta
-	err := i.db.Update(func(tx *bolt.Tx) error {
+	err := i.db.View(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(bucketFiles))
 		if bucket == nil {
 			return fmt.Errorf("missing bucket %s", bucketFiles)
@@ -118,18 +443,24 @@ func (i *Index) Get(ctx context.Context, path string) (index.FileMeta, error) {
 		if err != nil {
 			return err
 		}
-		meta.AtimeLocal = time.Now().UTC()
-		encoded, err := encodeFileMeta(meta)
-		if err != nil {
-			return err
-		}
-		if err := bucket.Put([]byte(path), encoded); err != nil {
-			return err
-		}
 		result = meta
 		return nil
 	})
-	return result, err
+	if err != nil {
+		return index.FileMeta{}, err
+	}
+
+	if i.readOnly {
+		// Nothing will ever flush a buffered bump on a read-only handle,
+		// so don't pretend the access happened; return what's on disk.
+		return result, nil
+	}
+
+	now := time.Now().UTC()
+	hitDelta := i.bufferAtime(path, now)
+	result.AtimeLocal = now
+	result.HitCount += hitDelta
+	return result, nil
 }
 
 func (i *Index) Update(ctx context.Context, path string, fn func(index.FileMeta) (index.FileMeta, error)) (index.FileMeta, error) {
@@ -141,7 +472,7 @@ func (i *Index) Update(ctx context.Context, path string, fn func(index.FileMeta)
 	}
 
 	var result index.FileMeta
-	err := i.db.Update(func(tx *bolt.Tx) error {
+	err := i.update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(bucketFiles))
 		if bucket == nil {
 			return fmt.Errorf("missing bucket %s", bucketFiles)
@@ -166,11 +497,7 @@ func (i *Index) Update(ctx context.Context, path string, fn func(index.FileMeta)
 			updated.Path = path
 		}
 		normalized := normalizeFileMeta(updated)
-		encoded, err := encodeFileMeta(normalized)
-		if err != nil {
-			return err
-		}
-		if err := bucket.Put(key, encoded); err != nil {
+		if err := putFileMeta(tx, normalized, i.codec); err != nil {
 			return err
 		}
 		result = normalized
@@ -186,167 +513,1098 @@ func (i *Index) Delete(ctx context.Context, path string) error {
 	if path == "" {
 		return errors.New("cache index: path must not be empty")
 	}
-	return i.db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(bucketFiles))
-		if bucket == nil {
-			return fmt.Errorf("missing bucket %s", bucketFiles)
-		}
-		return bucket.Delete([]byte(path))
+	return i.update(func(tx *bolt.Tx) error {
+		return deleteFileMeta(tx, path)
 	})
 }
 
+// ListLRU returns metadata ordered by least-recently-used. It walks
+// bucketAtime, whose keys are already sorted by AtimeLocal, so a bounded
+// limit only costs O(limit) bucketFiles lookups rather than a full scan and
+// sort of every entry.
+//
+// Buffered atime bumps (see bufferAtime) are not reflected in bucketAtime's
+// key order until flushed, so a path Get recently touched could otherwise
+// be evicted as if it were still cold. If syncAtimeOnEvict is set, ListLRU
+// flushes the buffer first and keeps the fast cursor walk. Otherwise it
+// only pays for a full scan-and-sort when the buffer is actually
+// non-empty, merging each path's buffered atime on top of its on-disk value
+// before ordering.
 func (i *Index) ListLRU(ctx context.Context, limit int) ([]index.FileMeta, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
+
+	if i.syncAtimeOnEvict {
+		if err := i.flushAtimeBuffer(); err != nil {
+			return nil, err
+		}
+		return i.listLRUFast(ctx, limit, nil)
+	}
+
+	i.atimeMu.Lock()
+	var overrides map[string]atimeBufEntry
+	if len(i.atimeBuf) > 0 {
+		overrides = make(map[string]atimeBufEntry, len(i.atimeBuf))
+		for path, entry := range i.atimeBuf {
+			overrides[path] = entry
+		}
+	}
+	i.atimeMu.Unlock()
+
+	if overrides == nil {
+		return i.listLRUFast(ctx, limit, nil)
+	}
+	return i.listLRUWithOverrides(ctx, limit, overrides)
+}
+
+// listLRUFast walks bucketAtime in key order (already sorted by
+// AtimeLocal), stopping as soon as limit entries are collected. overrides,
+// if non-nil, is applied to each entry's AtimeLocal/HitCount before it is
+// appended, without affecting iteration order.
+func (i *Index) listLRUFast(ctx context.Context, limit int, overrides map[string]atimeBufEntry) ([]index.FileMeta, error) {
 	metas := make([]index.FileMeta, 0)
 	err := i.db.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(bucketFiles))
-		if bucket == nil {
-			return fmt.Errorf("missing bucket %s", bucketFiles)
+		atimeBucket := tx.Bucket([]byte(bucketAtime))
+		filesBucket := tx.Bucket([]byte(bucketFiles))
+		if atimeBucket == nil || filesBucket == nil {
+			return fmt.Errorf("missing index buckets")
 		}
-		return bucket.ForEach(func(k, v []byte) error {
+
+		c := atimeBucket.Cursor()
+		for k, path := c.First(); k != nil; k, path = c.Next() {
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
 			default:
 			}
-			meta, err := decodeFileMeta(v)
+			raw := filesBucket.Get(path)
+			if raw == nil {
+				// Secondary index entry outlived its primary record; skip it
+				// rather than fail the whole listing.
+				continue
+			}
+			meta, err := decodeFileMeta(raw)
 			if err != nil {
 				return err
 			}
+			if override, ok := overrides[string(path)]; ok {
+				meta.AtimeLocal = override.atime
+				meta.HitCount += override.hitDelta
+			}
 			metas = append(metas, meta)
-			return nil
-		})
+			if limit > 0 && len(metas) >= limit {
+				return nil
+			}
+		}
+		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
-	sortFileMetasByAtime(metas)
-	if limit > 0 && limit < len(metas) {
-		metas = metas[:limit]
-	}
 	return metas, nil
 }
 
-func (i *Index) AddUpload(ctx context.Context, entry index.UploadRecord) (index.UploadRecord, error) {
-	if err := ctx.Err(); err != nil {
-		return index.UploadRecord{}, err
-	}
-	var result index.UploadRecord
-	err := i.db.Update(func(tx *bolt.Tx) error {
-		uploads := tx.Bucket([]byte(bucketUploads))
-		stats := tx.Bucket([]byte(bucketStats))
-		if uploads == nil || stats == nil {
-			return fmt.Errorf("missing upload buckets")
-		}
-		now := time.Now().UTC()
-		if entry.CreatedAt.IsZero() {
-			entry.CreatedAt = now
+// listLRUWithOverrides scans every bucketAtime entry (since buffered atime
+// bumps can move a path anywhere in the order), applies overrides, sorts by
+// the resulting AtimeLocal, and then truncates to limit.
+func (i *Index) listLRUWithOverrides(ctx context.Context, limit int, overrides map[string]atimeBufEntry) ([]index.FileMeta, error) {
+	metas := make([]index.FileMeta, 0)
+	err := i.db.View(func(tx *bolt.Tx) error {
+		atimeBucket := tx.Bucket([]byte(bucketAtime))
+		filesBucket := tx.Bucket([]byte(bucketFiles))
+		if atimeBucket == nil || filesBucket == nil {
+			return fmt.Errorf("missing index buckets")
 		}
-		entry.UpdatedAt = now
-		if entry.ID == "" {
-			seq, err := nextSequence(stats)
+
+		c := atimeBucket.Cursor()
+		for k, path := c.First(); k != nil; k, path = c.Next() {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+			raw := filesBucket.Get(path)
+			if raw == nil {
+				continue
+			}
+			meta, err := decodeFileMeta(raw)
 			if err != nil {
 				return err
 			}
-			entry.ID = formatUploadID(seq)
-		}
-		data, err := encodeUpload(entry)
-		if err != nil {
-			return err
-		}
-		if err := uploads.Put([]byte(entry.ID), data); err != nil {
-			return err
+			if override, ok := overrides[string(path)]; ok {
+				meta.AtimeLocal = override.atime
+				meta.HitCount += override.hitDelta
+			}
+			metas = append(metas, meta)
 		}
-		result = entry
 		return nil
 	})
-	return result, err
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(metas, func(a, b int) bool {
+		return metas[a].AtimeLocal.Before(metas[b].AtimeLocal)
+	})
+	if limit > 0 && len(metas) > limit {
+		metas = metas[:limit]
+	}
+	return metas, nil
 }
 
-func (i *Index) ListUploads(ctx context.Context) ([]index.UploadRecord, error) {
+// ListOlderThan returns metadata for every entry whose AtimeLocal is
+// strictly before cutoff, ordered oldest first. Like ListLRU it walks
+// bucketAtime, stopping as soon as it reaches an entry at or past cutoff
+// rather than scanning the whole index.
+func (i *Index) ListOlderThan(ctx context.Context, cutoff time.Time) ([]index.FileMeta, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	records := make([]index.UploadRecord, 0)
+	cutoffNanos := uint64(cutoff.UnixNano())
+	metas := make([]index.FileMeta, 0)
 	err := i.db.View(func(tx *bolt.Tx) error {
-		uploads := tx.Bucket([]byte(bucketUploads))
-		if uploads == nil {
-			return fmt.Errorf("missing bucket %s", bucketUploads)
+		atimeBucket := tx.Bucket([]byte(bucketAtime))
+		filesBucket := tx.Bucket([]byte(bucketFiles))
+		if atimeBucket == nil || filesBucket == nil {
+			return fmt.Errorf("missing index buckets")
 		}
-		c := uploads.Cursor()
-		for k, v := c.First(); k != nil; k, v = c.Next() {
+
+		c := atimeBucket.Cursor()
+		for k, path := c.First(); k != nil; k, path = c.Next() {
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
 			default:
 			}
-			rec, err := decodeUpload(v)
+			if len(k) < 8 || binary.BigEndian.Uint64(k[:8]) >= cutoffNanos {
+				break
+			}
+			raw := filesBucket.Get(path)
+			if raw == nil {
+				// Secondary index entry outlived its primary record; skip it
+				// rather than fail the whole listing.
+				continue
+			}
+			meta, err := decodeFileMeta(raw)
 			if err != nil {
 				return err
 			}
-			records = append(records, rec)
+			metas = append(metas, meta)
 		}
 		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
-	return records, nil
+	return metas, nil
 }
 
-func (i *Index) UpdateUploadStatus(ctx context.Context, id string, status index.UploadStatus, lastError string) (index.UploadRecord, error) {
+// MarkDirty records [offset, offset+length) as dirty for path.
+func (i *Index) MarkDirty(ctx context.Context, path string, offset, length int64) (index.FileMeta, error) {
+	return i.Update(ctx, path, func(meta index.FileMeta) (index.FileMeta, error) {
+		meta.DirtyRanges = index.MergeRanges(meta.DirtyRanges, index.Range{Offset: offset, Length: length})
+		return meta, nil
+	})
+}
+
+// MarkClean removes [offset, offset+length) from path's dirty ranges.
+func (i *Index) MarkClean(ctx context.Context, path string, offset, length int64) (index.FileMeta, error) {
+	return i.Update(ctx, path, func(meta index.FileMeta) (index.FileMeta, error) {
+		meta.DirtyRanges = index.SubtractRange(meta.DirtyRanges, index.Range{Offset: offset, Length: length})
+		return meta, nil
+	})
+}
+
+// DirtyRanges returns path's sorted, coalesced dirty byte ranges.
+func (i *Index) DirtyRanges(ctx context.Context, path string) ([]index.Range, error) {
+	meta, err := i.Get(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return meta.DirtyRanges, nil
+}
+
+// LoadResidentRanges returns the persisted set of locally-resident byte
+// ranges for path, or nil if none have been recorded yet.
+func (i *Index) LoadResidentRanges(ctx context.Context, path string) ([]index.Range, error) {
 	if err := ctx.Err(); err != nil {
-		return index.UploadRecord{}, err
+		return nil, err
 	}
-	if id == "" {
-		return index.UploadRecord{}, errors.New("cache index: upload id must not be empty")
+	if path == "" {
+		return nil, errors.New("cache index: path must not be empty")
 	}
 
-	var result index.UploadRecord
-	err := i.db.Update(func(tx *bolt.Tx) error {
-		uploads := tx.Bucket([]byte(bucketUploads))
-		if uploads == nil {
-			return fmt.Errorf("missing bucket %s", bucketUploads)
+	var ranges []index.Range
+	err := i.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketRanges))
+		if bucket == nil {
+			return fmt.Errorf("missing bucket %s", bucketRanges)
 		}
-		raw := uploads.Get([]byte(id))
+		raw := bucket.Get([]byte(path))
 		if raw == nil {
-			return index.ErrNotFound
-		}
-		rec, err := decodeUpload(raw)
-		if err != nil {
-			return err
-		}
-		rec.Status = status
-		rec.Attempts++
-		rec.LastError = lastError
-		now := time.Now().UTC()
-		if !now.After(rec.CreatedAt) {
-			now = rec.CreatedAt.Add(time.Nanosecond)
-		}
-		rec.UpdatedAt = now
-		data, err := encodeUpload(rec)
-		if err != nil {
-			return err
-		}
-		if err := uploads.Put([]byte(id), data); err != nil {
-			return err
+			return nil
 		}
-		result = rec
-		return nil
+		return json.Unmarshal(raw, &ranges)
 	})
-	return result, err
+	if err != nil {
+		return nil, err
+	}
+	return ranges, nil
 }
 
-func (i *Index) ensureSchema() error {
-	return i.db.Update(func(tx *bolt.Tx) error {
-		if _, err := tx.CreateBucketIfNotExists([]byte(bucketFiles)); err != nil {
-			return fmt.Errorf("ensure files bucket: %w", err)
+// SaveResidentRanges overwrites the persisted resident byte ranges for path.
+func (i *Index) SaveResidentRanges(ctx context.Context, path string, ranges []index.Range) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if path == "" {
+		return errors.New("cache index: path must not be empty")
+	}
+
+	data, err := json.Marshal(ranges)
+	if err != nil {
+		return err
+	}
+	return i.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketRanges))
+		if bucket == nil {
+			return fmt.Errorf("missing bucket %s", bucketRanges)
+		}
+		return bucket.Put([]byte(path), data)
+	})
+}
+
+// pendingAccessEntry is the bucketPendingAccess payload.
+type pendingAccessEntry struct {
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+}
+
+// RecordPendingAccess increments path's pre-admission access counter,
+// creating it with FirstSeen set to now if this is the first observation.
+func (i *Index) RecordPendingAccess(ctx context.Context, path string, now time.Time) (int, time.Time, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, time.Time{}, err
+	}
+	if path == "" {
+		return 0, time.Time{}, errors.New("cache index: path must not be empty")
+	}
+
+	var result pendingAccessEntry
+	err := i.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketPendingAccess))
+		if bucket == nil {
+			return fmt.Errorf("missing bucket %s", bucketPendingAccess)
+		}
+
+		entry := pendingAccessEntry{FirstSeen: now}
+		if raw := bucket.Get([]byte(path)); raw != nil {
+			if err := json.Unmarshal(raw, &entry); err != nil {
+				return err
+			}
+		}
+		entry.Count++
+
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		result = entry
+		return bucket.Put([]byte(path), data)
+	})
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return result.Count, result.FirstSeen, nil
+}
+
+// ClearPendingAccess removes path's pre-admission access counter, if any.
+func (i *Index) ClearPendingAccess(ctx context.Context, path string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if path == "" {
+		return errors.New("cache index: path must not be empty")
+	}
+
+	return i.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketPendingAccess))
+		if bucket == nil {
+			return fmt.Errorf("missing bucket %s", bucketPendingAccess)
+		}
+		return bucket.Delete([]byte(path))
+	})
+}
+
+// PurgeExpiredPendingAccess removes every pending-access counter whose
+// FirstSeen is strictly before cutoff, returning how many were removed.
+func (i *Index) PurgeExpiredPendingAccess(ctx context.Context, cutoff time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var removed int
+	err := i.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketPendingAccess))
+		if bucket == nil {
+			return fmt.Errorf("missing bucket %s", bucketPendingAccess)
+		}
+
+		var stale [][]byte
+		if err := bucket.ForEach(func(k, v []byte) error {
+			var entry pendingAccessEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return err
+			}
+			if entry.FirstSeen.Before(cutoff) {
+				stale = append(stale, append([]byte(nil), k...))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for _, k := range stale {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		removed = len(stale)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+// digestKey combines digest and length into a single bucketDigests key, so
+// two objects that happen to hash the same but differ in length (a
+// theoretical SHA-256 collision aside, this guards against comparing chunks
+// of different lengths that share a truncated digest) are never conflated.
+func digestKey(digest string, length int64) []byte {
+	key := make([]byte, len(digest)+1+19)
+	n := copy(key, digest)
+	key[n] = ':'
+	n++
+	n += copy(key[n:], strconv.FormatInt(length, 10))
+	return key[:n]
+}
+
+// LookupDigest returns the ETag recorded for digest+length, if any.
+func (i *Index) LookupDigest(ctx context.Context, digest string, length int64) (string, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return "", false, err
+	}
+
+	var etag string
+	var found bool
+	err := i.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketDigests))
+		if bucket == nil {
+			return fmt.Errorf("missing bucket %s", bucketDigests)
+		}
+		raw := bucket.Get(digestKey(digest, length))
+		if raw == nil {
+			return nil
+		}
+		found = true
+		etag = string(raw)
+		return nil
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return etag, found, nil
+}
+
+// RecordDigest persists digest+length -> etag, overwriting any previously
+// recorded ETag for the same digest+length.
+func (i *Index) RecordDigest(ctx context.Context, digest string, length int64, etag string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if digest == "" {
+		return errors.New("cache index: digest must not be empty")
+	}
+
+	return i.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketDigests))
+		if bucket == nil {
+			return fmt.Errorf("missing bucket %s", bucketDigests)
+		}
+		return bucket.Put(digestKey(digest, length), []byte(etag))
+	})
+}
+
+// chunkKey orders bucketChunks entries first by path and then by offset
+// (big-endian, so byte order matches numeric order), with a NUL separator
+// that can't appear in a path, so chunkKeyPrefix(path) bounds exactly that
+// path's entries for a cursor scan.
+func chunkKey(path string, offset int64) []byte {
+	key := make([]byte, len(path)+1+8)
+	n := copy(key, path)
+	key[n] = 0
+	n++
+	binary.BigEndian.PutUint64(key[n:], uint64(offset))
+	return key
+}
+
+func chunkKeyPrefix(path string) []byte {
+	key := make([]byte, len(path)+1)
+	n := copy(key, path)
+	key[n] = 0
+	return key
+}
+
+// PutChunk records or replaces per-chunk residency metadata for path at
+// chunk.Offset.
+func (i *Index) PutChunk(ctx context.Context, path string, chunk index.ChunkMeta) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if path == "" {
+		return errors.New("cache index: path must not be empty")
+	}
+
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		return err
+	}
+	return i.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketChunks))
+		if bucket == nil {
+			return fmt.Errorf("missing bucket %s", bucketChunks)
+		}
+		return bucket.Put(chunkKey(path, chunk.Offset), data)
+	})
+}
+
+// DeleteChunk removes the chunk residency entry for path at offset. Missing
+// entries are ignored.
+func (i *Index) DeleteChunk(ctx context.Context, path string, offset int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if path == "" {
+		return errors.New("cache index: path must not be empty")
+	}
+
+	return i.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketChunks))
+		if bucket == nil {
+			return fmt.Errorf("missing bucket %s", bucketChunks)
+		}
+		return bucket.Delete(chunkKey(path, offset))
+	})
+}
+
+// ChunksInRange returns every chunk recorded for path whose byte range
+// overlaps [offset, offset+length), ordered by Offset. It seeks the bbolt
+// cursor directly to the first chunk at or after offset (O(log N)), checks
+// the one entry immediately before it for overlap (a chunk can start before
+// offset and still extend past it), and then walks forward only as far as
+// chunks that start before the end of the range (O(k)), rather than
+// decoding every chunk the path has.
+func (i *Index) ChunksInRange(ctx context.Context, path string, offset, length int64) ([]index.ChunkMeta, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, errors.New("cache index: path must not be empty")
+	}
+	if length <= 0 {
+		return nil, nil
+	}
+	end := offset + length
+
+	var result []index.ChunkMeta
+	err := i.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketChunks))
+		if bucket == nil {
+			return fmt.Errorf("missing bucket %s", bucketChunks)
+		}
+		prefix := chunkKeyPrefix(path)
+		cur := bucket.Cursor()
+
+		k, _ := cur.Seek(chunkKey(path, offset))
+		if pk, pv := cur.Prev(); bytes.HasPrefix(pk, prefix) {
+			chunk, err := decodeChunkMeta(pv)
+			if err != nil {
+				return err
+			}
+			if chunk.Offset+chunk.Length > offset {
+				result = append(result, chunk)
+			}
+		}
+		// cur.Prev() can't be trusted to leave the cursor one entry back: when
+		// there is nothing earlier in the whole bucket, bbolt instead leaves
+		// it sitting on the first element and returns nil, so a cur.Next()
+		// here would skip that first element. Re-seeking is the only way to
+		// reliably restore the position Seek originally landed on.
+		k, v := cur.Seek(chunkKey(path, offset))
+		for ; bytes.HasPrefix(k, prefix); k, v = cur.Next() {
+			chunk, err := decodeChunkMeta(v)
+			if err != nil {
+				return err
+			}
+			if chunk.Offset >= end {
+				break
+			}
+			result = append(result, chunk)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (i *Index) AddUpload(ctx context.Context, entry index.UploadRecord) (index.UploadRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return index.UploadRecord{}, err
+	}
+	var result index.UploadRecord
+	err := i.update(func(tx *bolt.Tx) error {
+		stats := tx.Bucket([]byte(bucketStats))
+		if stats == nil {
+			return fmt.Errorf("missing bucket %s", bucketStats)
+		}
+		now := time.Now().UTC()
+		if entry.CreatedAt.IsZero() {
+			entry.CreatedAt = now
+		}
+		entry.UpdatedAt = now
+		if entry.ID == "" {
+			seq, err := nextSequence(stats)
+			if err != nil {
+				return err
+			}
+			entry.ID = formatUploadID(seq)
+		}
+		if err := putUpload(tx, entry, i.codec); err != nil {
+			return err
+		}
+		result = entry
+		return nil
+	})
+	return result, err
+}
+
+func (i *Index) ListUploads(ctx context.Context) ([]index.UploadRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	records := make([]index.UploadRecord, 0)
+	err := i.db.View(func(tx *bolt.Tx) error {
+		uploads := tx.Bucket([]byte(bucketUploads))
+		if uploads == nil {
+			return fmt.Errorf("missing bucket %s", bucketUploads)
+		}
+		c := uploads.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+			rec, err := decodeUpload(v)
+			if err != nil {
+				return err
+			}
+			records = append(records, rec)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (i *Index) UpdateUploadStatus(ctx context.Context, id string, status index.UploadStatus, lastError string) (index.UploadRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return index.UploadRecord{}, err
+	}
+	if id == "" {
+		return index.UploadRecord{}, errors.New("cache index: upload id must not be empty")
+	}
+
+	var result index.UploadRecord
+	err := i.update(func(tx *bolt.Tx) error {
+		uploads := tx.Bucket([]byte(bucketUploads))
+		if uploads == nil {
+			return fmt.Errorf("missing bucket %s", bucketUploads)
+		}
+		raw := uploads.Get([]byte(id))
+		if raw == nil {
+			return index.ErrNotFound
+		}
+		rec, err := decodeUpload(raw)
+		if err != nil {
+			return err
+		}
+		rec.Status = status
+		rec.Attempts++
+		rec.LastError = lastError
+		now := time.Now().UTC()
+		if !now.After(rec.CreatedAt) {
+			now = rec.CreatedAt.Add(time.Nanosecond)
+		}
+		rec.UpdatedAt = now
+		if err := putUpload(tx, rec, i.codec); err != nil {
+			return err
+		}
+		result = rec
+		return nil
+	})
+	return result, err
+}
+
+func (i *Index) SetUploadID(ctx context.Context, id string, uploadID string) (index.UploadRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return index.UploadRecord{}, err
+	}
+	if id == "" {
+		return index.UploadRecord{}, errors.New("cache index: upload id must not be empty")
+	}
+
+	var result index.UploadRecord
+	err := i.update(func(tx *bolt.Tx) error {
+		uploads := tx.Bucket([]byte(bucketUploads))
+		if uploads == nil {
+			return fmt.Errorf("missing bucket %s", bucketUploads)
+		}
+		raw := uploads.Get([]byte(id))
+		if raw == nil {
+			return index.ErrNotFound
+		}
+		rec, err := decodeUpload(raw)
+		if err != nil {
+			return err
+		}
+		rec.UploadID = uploadID
+		rec.UpdatedAt = time.Now().UTC()
+		if err := putUpload(tx, rec, i.codec); err != nil {
+			return err
+		}
+		result = rec
+		return nil
+	})
+	return result, err
+}
+
+func (i *Index) SetPartSize(ctx context.Context, id string, partSize int64) (index.UploadRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return index.UploadRecord{}, err
+	}
+	if id == "" {
+		return index.UploadRecord{}, errors.New("cache index: upload id must not be empty")
+	}
+
+	var result index.UploadRecord
+	err := i.update(func(tx *bolt.Tx) error {
+		uploads := tx.Bucket([]byte(bucketUploads))
+		if uploads == nil {
+			return fmt.Errorf("missing bucket %s", bucketUploads)
+		}
+		raw := uploads.Get([]byte(id))
+		if raw == nil {
+			return index.ErrNotFound
+		}
+		rec, err := decodeUpload(raw)
+		if err != nil {
+			return err
+		}
+		rec.PartSize = partSize
+		rec.UpdatedAt = time.Now().UTC()
+		if err := putUpload(tx, rec, i.codec); err != nil {
+			return err
+		}
+		result = rec
+		return nil
+	})
+	return result, err
+}
+
+func (i *Index) SetRetryDelay(ctx context.Context, id string, delay time.Duration) (index.UploadRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return index.UploadRecord{}, err
+	}
+	if id == "" {
+		return index.UploadRecord{}, errors.New("cache index: upload id must not be empty")
+	}
+
+	var result index.UploadRecord
+	err := i.update(func(tx *bolt.Tx) error {
+		uploads := tx.Bucket([]byte(bucketUploads))
+		if uploads == nil {
+			return fmt.Errorf("missing bucket %s", bucketUploads)
+		}
+		raw := uploads.Get([]byte(id))
+		if raw == nil {
+			return index.ErrNotFound
+		}
+		rec, err := decodeUpload(raw)
+		if err != nil {
+			return err
+		}
+		rec.PrevRetryDelay = delay
+		rec.UpdatedAt = time.Now().UTC()
+		if err := putUpload(tx, rec, i.codec); err != nil {
+			return err
+		}
+		result = rec
+		return nil
+	})
+	return result, err
+}
+
+func (i *Index) SetTusURL(ctx context.Context, id string, tusURL string) (index.UploadRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return index.UploadRecord{}, err
+	}
+	if id == "" {
+		return index.UploadRecord{}, errors.New("cache index: upload id must not be empty")
+	}
+
+	var result index.UploadRecord
+	err := i.update(func(tx *bolt.Tx) error {
+		uploads := tx.Bucket([]byte(bucketUploads))
+		if uploads == nil {
+			return fmt.Errorf("missing bucket %s", bucketUploads)
+		}
+		raw := uploads.Get([]byte(id))
+		if raw == nil {
+			return index.ErrNotFound
+		}
+		rec, err := decodeUpload(raw)
+		if err != nil {
+			return err
+		}
+		rec.TusURL = tusURL
+		rec.UpdatedAt = time.Now().UTC()
+		if err := putUpload(tx, rec, i.codec); err != nil {
+			return err
+		}
+		result = rec
+		return nil
+	})
+	return result, err
+}
+
+func (i *Index) UpdateUploadProgress(ctx context.Context, id string, bytesTransferred int64, partETag string) (index.UploadRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return index.UploadRecord{}, err
+	}
+	if id == "" {
+		return index.UploadRecord{}, errors.New("cache index: upload id must not be empty")
+	}
+
+	var result index.UploadRecord
+	err := i.update(func(tx *bolt.Tx) error {
+		uploads := tx.Bucket([]byte(bucketUploads))
+		if uploads == nil {
+			return fmt.Errorf("missing bucket %s", bucketUploads)
+		}
+		raw := uploads.Get([]byte(id))
+		if raw == nil {
+			return index.ErrNotFound
+		}
+		rec, err := decodeUpload(raw)
+		if err != nil {
+			return err
+		}
+		rec.BytesTransferred = bytesTransferred
+		if partETag != "" {
+			rec.PartETags = append(rec.PartETags, partETag)
+		}
+		rec.UpdatedAt = time.Now().UTC()
+		if err := putUpload(tx, rec, i.codec); err != nil {
+			return err
+		}
+		result = rec
+		return nil
+	})
+	return result, err
+}
+
+// ClaimNextUpload picks the earliest-due schedulable record off
+// bucketUploadsByTime. Because that bucket is keyed by due time ascending
+// (see uploadDueKey), the very first cursor entry is either the answer (its
+// due time is at or before now) or proof nothing is due yet (no entry
+// later in the bucket can have an earlier due time) — no scan required.
+func (i *Index) ClaimNextUpload(ctx context.Context, now time.Time, leaseDur time.Duration) (index.UploadRecord, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return index.UploadRecord{}, false, err
+	}
+
+	var result index.UploadRecord
+	found := false
+	err := i.update(func(tx *bolt.Tx) error {
+		uploads := tx.Bucket([]byte(bucketUploads))
+		byTime := tx.Bucket([]byte(bucketUploadsByTime))
+		if uploads == nil || byTime == nil {
+			return fmt.Errorf("missing upload buckets")
+		}
+
+		k, v := byTime.Cursor().First()
+		if k == nil || len(k) < 8 {
+			return nil
+		}
+		if binary.BigEndian.Uint64(k[:8]) > orderedUnixNano(now) {
+			return nil
+		}
+
+		raw := uploads.Get(v)
+		if raw == nil {
+			return fmt.Errorf("uploads_by_time entry for missing upload %s", string(v))
+		}
+		rec, err := decodeUpload(raw)
+		if err != nil {
+			return err
+		}
+
+		rec.Status = index.UploadStatusInProgress
+		rec.Attempts++
+		rec.LeaseExpiresAt = now.Add(leaseDur)
+		rec.UpdatedAt = now
+		if err := putUpload(tx, rec, i.codec); err != nil {
+			return err
+		}
+		result = rec
+		found = true
+		return nil
+	})
+	if err != nil {
+		return index.UploadRecord{}, false, err
+	}
+	return result, found, nil
+}
+
+func (i *Index) Reschedule(ctx context.Context, id string, nextAttemptAt time.Time, lastError string) (index.UploadRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return index.UploadRecord{}, err
+	}
+	if id == "" {
+		return index.UploadRecord{}, errors.New("cache index: upload id must not be empty")
+	}
+
+	var result index.UploadRecord
+	err := i.update(func(tx *bolt.Tx) error {
+		uploads := tx.Bucket([]byte(bucketUploads))
+		if uploads == nil {
+			return fmt.Errorf("missing bucket %s", bucketUploads)
+		}
+		raw := uploads.Get([]byte(id))
+		if raw == nil {
+			return index.ErrNotFound
+		}
+		rec, err := decodeUpload(raw)
+		if err != nil {
+			return err
+		}
+		rec.Status = index.UploadStatusQueued
+		rec.NextAttemptAt = nextAttemptAt
+		rec.LastError = lastError
+		rec.LeaseExpiresAt = time.Time{}
+		rec.UpdatedAt = time.Now().UTC()
+		if err := putUpload(tx, rec, i.codec); err != nil {
+			return err
+		}
+		result = rec
+		return nil
+	})
+	return result, err
+}
+
+// RenewLease extends id's lease to now+leaseDur, for a worker still making
+// progress on an InProgress record that doesn't want another worker to
+// reclaim it out from under it via ClaimNextUpload.
+func (i *Index) RenewLease(ctx context.Context, id string, now time.Time, leaseDur time.Duration) (index.UploadRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return index.UploadRecord{}, err
+	}
+	if id == "" {
+		return index.UploadRecord{}, errors.New("cache index: upload id must not be empty")
+	}
+
+	var result index.UploadRecord
+	err := i.update(func(tx *bolt.Tx) error {
+		uploads := tx.Bucket([]byte(bucketUploads))
+		if uploads == nil {
+			return fmt.Errorf("missing bucket %s", bucketUploads)
+		}
+		raw := uploads.Get([]byte(id))
+		if raw == nil {
+			return index.ErrNotFound
+		}
+		rec, err := decodeUpload(raw)
+		if err != nil {
+			return err
+		}
+		if rec.Status != index.UploadStatusInProgress {
+			return fmt.Errorf("cache index: cannot renew lease for upload %s in status %s", id, rec.Status)
+		}
+		rec.LeaseExpiresAt = now.Add(leaseDur)
+		rec.UpdatedAt = now
+		if err := putUpload(tx, rec, i.codec); err != nil {
+			return err
+		}
+		result = rec
+		return nil
+	})
+	return result, err
+}
+
+// ReapExpiredLeases resets every InProgress record whose LeaseExpiresAt is
+// at or before now back to Queued. It collects candidates in a first pass
+// (bucketUploadsByTime is sorted by due time, so it can stop at the first
+// entry not yet due) and applies them in a second, mirroring
+// PurgeExpiredPendingAccess's two-phase approach since bbolt forbids
+// mutating a bucket while a cursor over it is still in use.
+func (i *Index) ReapExpiredLeases(ctx context.Context, now time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var reaped int
+	err := i.update(func(tx *bolt.Tx) error {
+		uploads := tx.Bucket([]byte(bucketUploads))
+		byTime := tx.Bucket([]byte(bucketUploadsByTime))
+		if uploads == nil || byTime == nil {
+			return fmt.Errorf("missing upload buckets")
+		}
+
+		var stale []index.UploadRecord
+		c := byTime.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if len(k) < 8 || binary.BigEndian.Uint64(k[:8]) > orderedUnixNano(now) {
+				break
+			}
+			raw := uploads.Get(v)
+			if raw == nil {
+				continue
+			}
+			rec, err := decodeUpload(raw)
+			if err != nil {
+				return err
+			}
+			if rec.Status != index.UploadStatusInProgress {
+				continue
+			}
+			stale = append(stale, rec)
+		}
+
+		for _, rec := range stale {
+			rec.Status = index.UploadStatusQueued
+			rec.LeaseExpiresAt = time.Time{}
+			rec.LastError = "lease expired: reclaimed by reaper"
+			rec.UpdatedAt = now
+			if err := putUpload(tx, rec, i.codec); err != nil {
+				return err
+			}
+		}
+		reaped = len(stale)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return reaped, nil
+}
+
+// putFileMeta writes meta to bucketFiles and keeps bucketAtime in sync,
+// removing the previous atime key (if the entry already existed under a
+// different AtimeLocal) before inserting the new one. Callers run this
+// inside a single write transaction alongside any other mutation so the
+// two buckets never observably diverge.
+func putFileMeta(tx *bolt.Tx, meta index.FileMeta, codec Codec) error {
+	filesBucket := tx.Bucket([]byte(bucketFiles))
+	atimeBucket := tx.Bucket([]byte(bucketAtime))
+	if filesBucket == nil || atimeBucket == nil {
+		return fmt.Errorf("missing index buckets")
+	}
+
+	path := []byte(meta.Path)
+	if existing := filesBucket.Get(path); existing != nil {
+		previous, err := decodeFileMeta(existing)
+		if err != nil {
+			return err
+		}
+		if err := atimeBucket.Delete(atimeKey(previous.AtimeLocal, previous.Path)); err != nil {
+			return err
+		}
+	}
+
+	data, err := encodeFileMeta(meta, codec)
+	if err != nil {
+		return err
+	}
+	if err := filesBucket.Put(path, data); err != nil {
+		return err
+	}
+	return atimeBucket.Put(atimeKey(meta.AtimeLocal, meta.Path), path)
+}
+
+// deleteFileMeta removes path from both bucketFiles and bucketAtime.
+func deleteFileMeta(tx *bolt.Tx, path string) error {
+	filesBucket := tx.Bucket([]byte(bucketFiles))
+	atimeBucket := tx.Bucket([]byte(bucketAtime))
+	if filesBucket == nil || atimeBucket == nil {
+		return fmt.Errorf("missing index buckets")
+	}
+
+	existing := filesBucket.Get([]byte(path))
+	if existing != nil {
+		meta, err := decodeFileMeta(existing)
+		if err != nil {
+			return err
+		}
+		if err := atimeBucket.Delete(atimeKey(meta.AtimeLocal, meta.Path)); err != nil {
+			return err
+		}
+	}
+	return filesBucket.Delete([]byte(path))
+}
+
+// atimeKey orders entries by AtimeLocal ascending, breaking ties by path so
+// every entry gets a distinct key even when timestamps collide.
+func atimeKey(t time.Time, path string) []byte {
+	key := make([]byte, 8+len(path))
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	copy(key[8:], path)
+	return key
+}
+
+func (i *Index) ensureSchema() error {
+	return i.db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists([]byte(bucketFiles)); err != nil {
+			return fmt.Errorf("ensure files bucket: %w", err)
 		}
 		if _, err := tx.CreateBucketIfNotExists([]byte(bucketUploads)); err != nil {
 			return fmt.Errorf("ensure uploads bucket: %w", err)
 		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(bucketAtime)); err != nil {
+			return fmt.Errorf("ensure atime index bucket: %w", err)
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(bucketRanges)); err != nil {
+			return fmt.Errorf("ensure file ranges bucket: %w", err)
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(bucketPendingAccess)); err != nil {
+			return fmt.Errorf("ensure pending access bucket: %w", err)
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(bucketDigests)); err != nil {
+			return fmt.Errorf("ensure chunk digests bucket: %w", err)
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(bucketChunks)); err != nil {
+			return fmt.Errorf("ensure chunks bucket: %w", err)
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(bucketUploadsByTime)); err != nil {
+			return fmt.Errorf("ensure uploads by time bucket: %w", err)
+		}
 		stats, err := tx.CreateBucketIfNotExists([]byte(bucketStats))
 		if err != nil {
 			return fmt.Errorf("ensure stats bucket: %w", err)
@@ -384,6 +1642,60 @@ func migrate(tx *bolt.Tx, from, to int) error {
 				return fmt.Errorf("migrate v0 uploads: %w", err)
 			}
 			version = 1
+		case 1:
+			if err := backfillAtimeIndex(tx); err != nil {
+				return fmt.Errorf("migrate v1 atime index: %w", err)
+			}
+			version = 2
+		case 2:
+			// bucketRanges is new in v3; existing databases simply start
+			// with no persisted resident ranges for any path, which is
+			// equivalent to treating every cached file as not yet staged
+			// for partial reads until it is next written or reopened.
+			if _, err := tx.CreateBucketIfNotExists([]byte(bucketRanges)); err != nil {
+				return fmt.Errorf("migrate v2 file ranges: %w", err)
+			}
+			version = 3
+		case 3:
+			// bucketPendingAccess is new in v4; existing databases simply
+			// start with no in-progress admission counters, equivalent to
+			// every path being eligible for immediate admission again.
+			if _, err := tx.CreateBucketIfNotExists([]byte(bucketPendingAccess)); err != nil {
+				return fmt.Errorf("migrate v3 pending access: %w", err)
+			}
+			version = 4
+		case 4:
+			// bucketDigests is new in v5; existing databases simply start
+			// with no recorded digests, equivalent to every chunk dedup
+			// lookup missing until the corresponding content is next
+			// uploaded.
+			if _, err := tx.CreateBucketIfNotExists([]byte(bucketDigests)); err != nil {
+				return fmt.Errorf("migrate v4 chunk digests: %w", err)
+			}
+			version = 5
+		case 5:
+			// bucketChunks is new in v6: per-chunk residency metadata moves
+			// out of each FileMeta.Chunks blob into its own bucket keyed by
+			// chunkKey(path, offset), so ChunksInRange can seek straight to
+			// the chunks overlapping a range. Existing records are migrated
+			// by walking bucketFiles and splitting each FileMeta.Chunks
+			// entry into bucketChunks; FileMeta.Chunks itself is left
+			// populated (readers that haven't switched over yet keep
+			// working), so this is additive rather than destructive.
+			if err := splitChunksIntoBucket(tx); err != nil {
+				return fmt.Errorf("migrate v5 chunks: %w", err)
+			}
+			version = 6
+		case 6:
+			// bucketUploadsByTime is new in v7: a secondary index over
+			// bucketUploads so ClaimNextUpload can seek to due work instead
+			// of scanning every upload record. Existing databases are
+			// backfilled by walking bucketUploads and indexing every
+			// Queued/InProgress record under its due key.
+			if err := backfillUploadsByTime(tx); err != nil {
+				return fmt.Errorf("migrate v6 uploads by time: %w", err)
+			}
+			version = 7
 		default:
 			return fmt.Errorf("%w: %d", errUnknownSchema, version)
 		}
@@ -391,6 +1703,157 @@ func migrate(tx *bolt.Tx, from, to int) error {
 	return nil
 }
 
+// backfillAtimeIndex populates bucketAtime from every existing bucketFiles
+// entry, for databases created before the secondary index existed.
+func backfillAtimeIndex(tx *bolt.Tx) error {
+	filesBucket := tx.Bucket([]byte(bucketFiles))
+	atimeBucket, err := tx.CreateBucketIfNotExists([]byte(bucketAtime))
+	if err != nil {
+		return err
+	}
+	if filesBucket == nil {
+		return nil
+	}
+	return filesBucket.ForEach(func(k, v []byte) error {
+		meta, err := decodeFileMeta(v)
+		if err != nil {
+			return err
+		}
+		return atimeBucket.Put(atimeKey(meta.AtimeLocal, meta.Path), []byte(meta.Path))
+	})
+}
+
+// splitChunksIntoBucket populates bucketChunks from every existing
+// FileMeta.Chunks entry, for databases created before the dedicated
+// per-chunk bucket existed.
+func splitChunksIntoBucket(tx *bolt.Tx) error {
+	filesBucket := tx.Bucket([]byte(bucketFiles))
+	chunksBucket, err := tx.CreateBucketIfNotExists([]byte(bucketChunks))
+	if err != nil {
+		return err
+	}
+	if filesBucket == nil {
+		return nil
+	}
+	return filesBucket.ForEach(func(k, v []byte) error {
+		meta, err := decodeFileMeta(v)
+		if err != nil {
+			return err
+		}
+		for _, chunk := range meta.Chunks {
+			data, err := json.Marshal(chunk)
+			if err != nil {
+				return err
+			}
+			if err := chunksBucket.Put(chunkKey(meta.Path, chunk.Offset), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// backfillUploadsByTime populates bucketUploadsByTime from every existing
+// bucketUploads entry, for databases created before the secondary index
+// existed.
+func backfillUploadsByTime(tx *bolt.Tx) error {
+	uploads := tx.Bucket([]byte(bucketUploads))
+	byTime, err := tx.CreateBucketIfNotExists([]byte(bucketUploadsByTime))
+	if err != nil {
+		return err
+	}
+	if uploads == nil {
+		return nil
+	}
+	return uploads.ForEach(func(k, v []byte) error {
+		rec, err := decodeUpload(v)
+		if err != nil {
+			return err
+		}
+		if !isSchedulable(rec.Status) {
+			return nil
+		}
+		return byTime.Put(uploadTimeKey(uploadDueKey(rec), rec.ID), []byte(rec.ID))
+	})
+}
+
+// isSchedulable reports whether status means the record still needs to
+// appear in bucketUploadsByTime; Complete and Failed records are terminal
+// and drop out of the time index once reached.
+func isSchedulable(status index.UploadStatus) bool {
+	return status == index.UploadStatusQueued || status == index.UploadStatusInProgress
+}
+
+// uploadDueKey is the time bucketUploadsByTime orders rec by: a Queued
+// record is due at NextAttemptAt, while an InProgress record is only
+// reclaimable once its lease expires, so it's keyed by LeaseExpiresAt
+// instead. Other statuses aren't indexed at all (see isSchedulable).
+func uploadDueKey(rec index.UploadRecord) time.Time {
+	if rec.Status == index.UploadStatusInProgress {
+		return rec.LeaseExpiresAt
+	}
+	return rec.NextAttemptAt
+}
+
+// uploadTimeKey orders bucketUploadsByTime entries by due time ascending,
+// breaking ties by id so every entry gets a distinct key even when two
+// records become due at the same instant.
+func uploadTimeKey(due time.Time, id string) []byte {
+	key := make([]byte, 8+len(id))
+	binary.BigEndian.PutUint64(key, orderedUnixNano(due))
+	copy(key[8:], id)
+	return key
+}
+
+// orderedUnixNano maps t to a uint64 whose unsigned byte order matches t's
+// chronological order, including for zero-value times (an unset
+// NextAttemptAt, meaning "due immediately"): UnixNano() is a signed
+// int64 that a plain uint64 conversion would wrap into a huge value for
+// any time before 1970, including time.Time{}, which would sort it after
+// every real due time instead of before all of them. Flipping the sign
+// bit is the standard trick for making two's-complement integers compare
+// correctly as unsigned.
+func orderedUnixNano(t time.Time) uint64 {
+	return uint64(t.UnixNano()) ^ (1 << 63)
+}
+
+// putUpload writes rec to bucketUploads and keeps bucketUploadsByTime in
+// sync, removing whatever time-index entry the previous version of this
+// record held (if it was schedulable) before inserting the new one (if
+// rec is). Callers run this inside a single write transaction alongside
+// any other mutation so the two buckets never observably diverge.
+func putUpload(tx *bolt.Tx, rec index.UploadRecord, codec Codec) error {
+	uploads := tx.Bucket([]byte(bucketUploads))
+	byTime := tx.Bucket([]byte(bucketUploadsByTime))
+	if uploads == nil || byTime == nil {
+		return fmt.Errorf("missing upload buckets")
+	}
+
+	if existing := uploads.Get([]byte(rec.ID)); existing != nil {
+		previous, err := decodeUpload(existing)
+		if err != nil {
+			return err
+		}
+		if isSchedulable(previous.Status) {
+			if err := byTime.Delete(uploadTimeKey(uploadDueKey(previous), previous.ID)); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := encodeUpload(rec, codec)
+	if err != nil {
+		return err
+	}
+	if err := uploads.Put([]byte(rec.ID), data); err != nil {
+		return err
+	}
+	if isSchedulable(rec.Status) {
+		return byTime.Put(uploadTimeKey(uploadDueKey(rec), rec.ID), []byte(rec.ID))
+	}
+	return nil
+}
+
 func nextSequence(stats *bolt.Bucket) (int, error) {
 	raw := stats.Get([]byte(keyUploadSeq))
 	var seq int
@@ -429,35 +1892,407 @@ func cloneFileMeta(meta index.FileMeta) index.FileMeta {
 		clone.Chunks = make([]index.ChunkMeta, len(meta.Chunks))
 		copy(clone.Chunks, meta.Chunks)
 	}
+	if len(meta.DirtyRanges) > 0 {
+		clone.DirtyRanges = make([]index.Range, len(meta.DirtyRanges))
+		copy(clone.DirtyRanges, meta.DirtyRanges)
+	}
 	return clone
 }
 
-func encodeFileMeta(meta index.FileMeta) ([]byte, error) {
-	return json.Marshal(meta)
+// binaryCodecVersion is the only binary encoding version so far. It is
+// chosen so it can never collide with '{' (0x7B), the first byte of any
+// JSON-encoded record, which is how decode tells the two formats apart.
+const binaryCodecVersion = 0x01
+
+// Chunk flag bits packed into the single flags byte written by
+// writeChunkMeta/readChunkMeta.
+const (
+	chunkFlagDirty byte = 1 << iota
+	chunkFlagAbsent
+	chunkFlagPinned
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+func encodeFileMeta(meta index.FileMeta, codec Codec) ([]byte, error) {
+	if codec == CodecJSON {
+		return json.Marshal(meta)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(binaryCodecVersion)
+	writeString(&buf, meta.Path)
+	writeString(&buf, meta.ETag)
+	writeVarint(&buf, meta.Size)
+	writeUvarint(&buf, uint64(len(meta.Chunks)))
+	for _, chunk := range meta.Chunks {
+		writeChunkMeta(&buf, chunk)
+	}
+	writeTime(&buf, meta.MtimeRemote)
+	writeTime(&buf, meta.AtimeLocal)
+	writeUvarint(&buf, meta.HitCount)
+	writeUvarint(&buf, uint64(len(meta.DirtyRanges)))
+	for _, r := range meta.DirtyRanges {
+		writeVarint(&buf, r.Offset)
+		writeVarint(&buf, r.Length)
+	}
+	return appendCRC(buf.Bytes()), nil
 }
 
+// decodeFileMeta auto-detects the encoding by sniffing the leading byte: a
+// database written before this binary codec existed has JSON records
+// starting with '{', which upgrade to the binary format lazily the next
+// time that path is written (see putFileMeta).
 func decodeFileMeta(data []byte) (index.FileMeta, error) {
+	if len(data) > 0 && data[0] == '{' {
+		var meta index.FileMeta
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return index.FileMeta{}, err
+		}
+		return meta, nil
+	}
+
+	body, err := verifyAndStripCRC(data)
+	if err != nil {
+		return index.FileMeta{}, err
+	}
+	r := bytes.NewReader(body)
+	if _, err := r.ReadByte(); err != nil {
+		return index.FileMeta{}, err
+	}
+
 	var meta index.FileMeta
-	if err := json.Unmarshal(data, &meta); err != nil {
+	if meta.Path, err = readString(r); err != nil {
+		return index.FileMeta{}, err
+	}
+	if meta.ETag, err = readString(r); err != nil {
+		return index.FileMeta{}, err
+	}
+	if meta.Size, err = readVarint(r); err != nil {
+		return index.FileMeta{}, err
+	}
+	chunkCount, err := readUvarint(r)
+	if err != nil {
+		return index.FileMeta{}, err
+	}
+	if chunkCount > 0 {
+		meta.Chunks = make([]index.ChunkMeta, chunkCount)
+		for i := range meta.Chunks {
+			if meta.Chunks[i], err = readChunkMeta(r); err != nil {
+				return index.FileMeta{}, err
+			}
+		}
+	}
+	if meta.MtimeRemote, err = readTime(r); err != nil {
+		return index.FileMeta{}, err
+	}
+	if meta.AtimeLocal, err = readTime(r); err != nil {
+		return index.FileMeta{}, err
+	}
+	if meta.HitCount, err = readUvarint(r); err != nil {
 		return index.FileMeta{}, err
 	}
+	rangeCount, err := readUvarint(r)
+	if err != nil {
+		return index.FileMeta{}, err
+	}
+	if rangeCount > 0 {
+		meta.DirtyRanges = make([]index.Range, rangeCount)
+		for i := range meta.DirtyRanges {
+			offset, err := readVarint(r)
+			if err != nil {
+				return index.FileMeta{}, err
+			}
+			length, err := readVarint(r)
+			if err != nil {
+				return index.FileMeta{}, err
+			}
+			meta.DirtyRanges[i] = index.Range{Offset: offset, Length: length}
+		}
+	}
 	return meta, nil
 }
 
-func encodeUpload(entry index.UploadRecord) ([]byte, error) {
-	return json.Marshal(entry)
+func writeChunkMeta(buf *bytes.Buffer, chunk index.ChunkMeta) {
+	writeVarint(buf, chunk.Offset)
+	writeVarint(buf, chunk.Length)
+
+	var flags byte
+	if chunk.Dirty {
+		flags |= chunkFlagDirty
+	}
+	if chunk.Absent {
+		flags |= chunkFlagAbsent
+	}
+	if chunk.Pinned {
+		flags |= chunkFlagPinned
+	}
+	buf.WriteByte(flags)
+
+	writeUvarint(buf, uint64(chunk.KeyGeneration))
+	writeTime(buf, chunk.DownloadedAt)
+	writeTime(buf, chunk.AccessedAt)
+}
+
+func readChunkMeta(r *bytes.Reader) (index.ChunkMeta, error) {
+	var chunk index.ChunkMeta
+	var err error
+	if chunk.Offset, err = readVarint(r); err != nil {
+		return index.ChunkMeta{}, err
+	}
+	if chunk.Length, err = readVarint(r); err != nil {
+		return index.ChunkMeta{}, err
+	}
+	flags, err := r.ReadByte()
+	if err != nil {
+		return index.ChunkMeta{}, err
+	}
+	chunk.Dirty = flags&chunkFlagDirty != 0
+	chunk.Absent = flags&chunkFlagAbsent != 0
+	chunk.Pinned = flags&chunkFlagPinned != 0
+
+	keyGeneration, err := readUvarint(r)
+	if err != nil {
+		return index.ChunkMeta{}, err
+	}
+	chunk.KeyGeneration = uint32(keyGeneration)
+
+	if chunk.DownloadedAt, err = readTime(r); err != nil {
+		return index.ChunkMeta{}, err
+	}
+	if chunk.AccessedAt, err = readTime(r); err != nil {
+		return index.ChunkMeta{}, err
+	}
+	return chunk, nil
+}
+
+func encodeUpload(entry index.UploadRecord, codec Codec) ([]byte, error) {
+	if codec == CodecJSON {
+		return json.Marshal(entry)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(binaryCodecVersion)
+	writeString(&buf, entry.ID)
+	writeString(&buf, entry.Path)
+	writeVarint(&buf, entry.Offset)
+	writeVarint(&buf, entry.Length)
+	writeString(&buf, string(entry.Status))
+	writeVarint(&buf, int64(entry.Attempts))
+	writeString(&buf, entry.LastError)
+	writeTime(&buf, entry.CreatedAt)
+	writeTime(&buf, entry.UpdatedAt)
+	writeString(&buf, entry.UploadID)
+	writeUvarint(&buf, uint64(len(entry.PartETags)))
+	for _, etag := range entry.PartETags {
+		writeString(&buf, etag)
+	}
+	writeVarint(&buf, entry.PartSize)
+	writeVarint(&buf, entry.BytesTransferred)
+	writeUvarint(&buf, uint64(entry.KeyGeneration))
+	writeString(&buf, entry.TusURL)
+	writeVarint(&buf, entry.ServerOffset)
+	writeTime(&buf, entry.NextAttemptAt)
+	writeVarint(&buf, int64(entry.MaxAttempts))
+	writeTime(&buf, entry.LeaseExpiresAt)
+	writeVarint(&buf, int64(entry.PrevRetryDelay))
+	return appendCRC(buf.Bytes()), nil
 }
 
+// decodeUpload auto-detects the encoding the same way decodeFileMeta does.
 func decodeUpload(data []byte) (index.UploadRecord, error) {
+	if len(data) > 0 && data[0] == '{' {
+		var entry index.UploadRecord
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return index.UploadRecord{}, err
+		}
+		return entry, nil
+	}
+
+	body, err := verifyAndStripCRC(data)
+	if err != nil {
+		return index.UploadRecord{}, err
+	}
+	r := bytes.NewReader(body)
+	if _, err := r.ReadByte(); err != nil {
+		return index.UploadRecord{}, err
+	}
+
 	var entry index.UploadRecord
-	if err := json.Unmarshal(data, &entry); err != nil {
+	if entry.ID, err = readString(r); err != nil {
+		return index.UploadRecord{}, err
+	}
+	if entry.Path, err = readString(r); err != nil {
+		return index.UploadRecord{}, err
+	}
+	if entry.Offset, err = readVarint(r); err != nil {
+		return index.UploadRecord{}, err
+	}
+	if entry.Length, err = readVarint(r); err != nil {
+		return index.UploadRecord{}, err
+	}
+	status, err := readString(r)
+	if err != nil {
+		return index.UploadRecord{}, err
+	}
+	entry.Status = index.UploadStatus(status)
+	attempts, err := readVarint(r)
+	if err != nil {
+		return index.UploadRecord{}, err
+	}
+	entry.Attempts = int(attempts)
+	if entry.LastError, err = readString(r); err != nil {
+		return index.UploadRecord{}, err
+	}
+	if entry.CreatedAt, err = readTime(r); err != nil {
 		return index.UploadRecord{}, err
 	}
+	if entry.UpdatedAt, err = readTime(r); err != nil {
+		return index.UploadRecord{}, err
+	}
+	if entry.UploadID, err = readString(r); err != nil {
+		return index.UploadRecord{}, err
+	}
+	partCount, err := readUvarint(r)
+	if err != nil {
+		return index.UploadRecord{}, err
+	}
+	if partCount > 0 {
+		entry.PartETags = make([]string, partCount)
+		for i := range entry.PartETags {
+			if entry.PartETags[i], err = readString(r); err != nil {
+				return index.UploadRecord{}, err
+			}
+		}
+	}
+	if entry.PartSize, err = readVarint(r); err != nil {
+		return index.UploadRecord{}, err
+	}
+	if entry.BytesTransferred, err = readVarint(r); err != nil {
+		return index.UploadRecord{}, err
+	}
+	keyGeneration, err := readUvarint(r)
+	if err != nil {
+		return index.UploadRecord{}, err
+	}
+	entry.KeyGeneration = uint32(keyGeneration)
+	if entry.TusURL, err = readString(r); err != nil {
+		return index.UploadRecord{}, err
+	}
+	if entry.ServerOffset, err = readVarint(r); err != nil {
+		return index.UploadRecord{}, err
+	}
+	if entry.NextAttemptAt, err = readTime(r); err != nil {
+		return index.UploadRecord{}, err
+	}
+	maxAttempts, err := readVarint(r)
+	if err != nil {
+		return index.UploadRecord{}, err
+	}
+	entry.MaxAttempts = int(maxAttempts)
+	if entry.LeaseExpiresAt, err = readTime(r); err != nil {
+		return index.UploadRecord{}, err
+	}
+	prevRetryDelay, err := readVarint(r)
+	if err != nil {
+		return index.UploadRecord{}, err
+	}
+	entry.PrevRetryDelay = time.Duration(prevRetryDelay)
 	return entry, nil
 }
 
-func sortFileMetasByAtime(metas []index.FileMeta) {
-	sort.Slice(metas, func(i, j int) bool {
-		return metas[i].AtimeLocal.Before(metas[j].AtimeLocal)
-	})
+// appendCRC appends a trailing CRC32C (Castagnoli) checksum over body, the
+// format every binary-encoded record ends with so decode can detect disk
+// corruption instead of silently returning garbage field values.
+func appendCRC(body []byte) []byte {
+	out := make([]byte, len(body)+4)
+	copy(out, body)
+	binary.BigEndian.PutUint32(out[len(body):], crc32.Checksum(body, crcTable))
+	return out
+}
+
+// verifyAndStripCRC checks data's trailing CRC32C and returns the body with
+// it removed, or index.ErrCorrupt if the checksum doesn't match.
+func verifyAndStripCRC(data []byte) ([]byte, error) {
+	if len(data) < 5 {
+		return nil, fmt.Errorf("%w: record too short to contain a checksum", index.ErrCorrupt)
+	}
+	body := data[:len(data)-4]
+	want := binary.BigEndian.Uint32(data[len(data)-4:])
+	if got := crc32.Checksum(body, crcTable); got != want {
+		return nil, fmt.Errorf("%w: checksum mismatch", index.ErrCorrupt)
+	}
+	return body, nil
+}
+
+func writeUvarint(buf *bytes.Buffer, v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	buf.Write(tmp[:n])
+}
+
+func writeVarint(buf *bytes.Buffer, v int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(tmp[:], v)
+	buf.Write(tmp[:n])
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	writeUvarint(buf, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+// writeTime encodes t as a presence byte followed by a varint UnixNano, so
+// the zero time.Time (which UnixNano doesn't represent exactly as 0) still
+// round-trips through readTime as IsZero().
+func writeTime(buf *bytes.Buffer, t time.Time) {
+	if t.IsZero() {
+		buf.WriteByte(0)
+		return
+	}
+	buf.WriteByte(1)
+	writeVarint(buf, t.UTC().UnixNano())
+}
+
+func readUvarint(r *bytes.Reader) (uint64, error) {
+	return binary.ReadUvarint(r)
+}
+
+func readVarint(r *bytes.Reader) (int64, error) {
+	return binary.ReadVarint(r)
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	n, err := readUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func readTime(r *bytes.Reader) (time.Time, error) {
+	present, err := r.ReadByte()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if present == 0 {
+		return time.Time{}, nil
+	}
+	ns, err := readVarint(r)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, ns).UTC(), nil
+}
+
+func decodeChunkMeta(data []byte) (index.ChunkMeta, error) {
+	var chunk index.ChunkMeta
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return index.ChunkMeta{}, err
+	}
+	return chunk, nil
 }
@@ -2,6 +2,8 @@ package bbolt
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"path/filepath"
 	"strconv"
 	"testing"
@@ -68,6 +70,162 @@ func TestOpenUpgradesLegacySchema(t *testing.T) {
 	}
 }
 
+func TestOpenBackfillsAtimeIndexFromLegacySchema(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.db")
+
+	createV1SchemaWithFiles(t, path, []index.FileMeta{
+		{Path: "objects/a.bin", Size: 1, AtimeLocal: time.Unix(100, 0).UTC()},
+		{Path: "objects/b.bin", Size: 2, AtimeLocal: time.Unix(50, 0).UTC()},
+	})
+
+	idx, err := Open(path, Options{})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer func() { _ = idx.Close() }()
+
+	metas, err := idx.ListLRU(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("ListLRU returned error: %v", err)
+	}
+	if len(metas) != 2 {
+		t.Fatalf("expected 2 entries after backfill, got %d", len(metas))
+	}
+	if metas[0].Path != "objects/b.bin" || metas[1].Path != "objects/a.bin" {
+		t.Fatalf("expected backfilled atime index to order by AtimeLocal, got %v", metas)
+	}
+}
+
+func TestListLRUOrdersByAtimeAndRespectsLimit(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.db")
+
+	idx, err := Open(path, Options{})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer func() { _ = idx.Close() }()
+
+	older := index.FileMeta{Path: "objects/old.bin", Size: 1, AtimeLocal: time.Unix(10, 0).UTC()}
+	newer := index.FileMeta{Path: "objects/new.bin", Size: 1, AtimeLocal: time.Unix(20, 0).UTC()}
+	if err := idx.Put(ctx, newer); err != nil {
+		t.Fatalf("Put newer failed: %v", err)
+	}
+	if err := idx.Put(ctx, older); err != nil {
+		t.Fatalf("Put older failed: %v", err)
+	}
+
+	// Get() bumps AtimeLocal to now, so it must move to the back of the list.
+	if _, err := idx.Get(ctx, older.Path); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+
+	metas, err := idx.ListLRU(ctx, 1)
+	if err != nil {
+		t.Fatalf("ListLRU returned error: %v", err)
+	}
+	if len(metas) != 1 {
+		t.Fatalf("expected ListLRU to respect limit, got %d entries", len(metas))
+	}
+	if metas[0].Path != newer.Path {
+		t.Fatalf("expected %s to be least-recently-used after older was touched, got %s", newer.Path, metas[0].Path)
+	}
+}
+
+func TestGetCoalescesAtimeBumpsUntilFlush(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.db")
+
+	idx, err := Open(path, Options{})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer func() { _ = idx.Close() }()
+
+	meta := index.FileMeta{Path: "objects/hot.bin", Size: 1, AtimeLocal: time.Unix(1, 0).UTC()}
+	if err := idx.Put(ctx, meta); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := idx.Get(ctx, meta.Path); err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+	}
+
+	// Before a flush, the bump lives only in the in-memory buffer: a fresh
+	// read transaction against bucketFiles must still see the pre-Get
+	// HitCount.
+	err = idx.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket([]byte(bucketFiles)).Get([]byte(meta.Path))
+		onDisk, decodeErr := decodeFileMeta(raw)
+		if decodeErr != nil {
+			return decodeErr
+		}
+		if onDisk.HitCount != 0 {
+			t.Fatalf("expected on-disk HitCount to stay 0 before flush, got %d", onDisk.HitCount)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("view failed: %v", err)
+	}
+
+	if err := idx.flushAtimeBuffer(); err != nil {
+		t.Fatalf("flushAtimeBuffer failed: %v", err)
+	}
+
+	flushed, err := idx.Get(ctx, meta.Path)
+	if err != nil {
+		t.Fatalf("Get after flush failed: %v", err)
+	}
+	if flushed.HitCount != 4 {
+		t.Fatalf("expected HitCount 4 after flush and one more Get, got %d", flushed.HitCount)
+	}
+}
+
+func createV1SchemaWithFiles(t *testing.T, path string, metas []index.FileMeta) {
+	t.Helper()
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		stats, err := tx.CreateBucketIfNotExists([]byte(bucketStats))
+		if err != nil {
+			return err
+		}
+		if err := stats.Put([]byte(keySchemaVersion), []byte("1")); err != nil {
+			return err
+		}
+		files, err := tx.CreateBucketIfNotExists([]byte(bucketFiles))
+		if err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(bucketUploads)); err != nil {
+			return err
+		}
+		for _, meta := range metas {
+			data, err := encodeFileMeta(meta, CodecJSON)
+			if err != nil {
+				return err
+			}
+			if err := files.Put([]byte(meta.Path), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to write v1 schema: %v", err)
+	}
+}
+
 func TestUploadsPersistAcrossReopen(t *testing.T) {
 	ctx := context.Background()
 	dir := t.TempDir()
@@ -154,6 +312,186 @@ func TestUploadsPersistAcrossReopen(t *testing.T) {
 	}
 }
 
+func TestClaimNextUploadUsesTimeIndexOrdering(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.db")
+
+	idx, err := Open(path, Options{})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer func() { _ = idx.Close() }()
+
+	earlier := time.Unix(100, 0).UTC()
+	later := time.Unix(200, 0).UTC()
+	if _, err := idx.AddUpload(ctx, index.UploadRecord{Path: "/uploads/later.bin", Status: index.UploadStatusQueued, NextAttemptAt: later}); err != nil {
+		t.Fatalf("AddUpload later failed: %v", err)
+	}
+	soonest, err := idx.AddUpload(ctx, index.UploadRecord{Path: "/uploads/earlier.bin", Status: index.UploadStatusQueued, NextAttemptAt: earlier})
+	if err != nil {
+		t.Fatalf("AddUpload earlier failed: %v", err)
+	}
+
+	claimed, ok, err := idx.ClaimNextUpload(ctx, later, time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("expected a claim to succeed, ok=%v err=%v", ok, err)
+	}
+	if claimed.ID != soonest.ID {
+		t.Fatalf("expected to claim earliest-due upload %s, got %s", soonest.ID, claimed.ID)
+	}
+
+	// bucketUploadsByTime must have dropped the claimed record's Queued
+	// entry and re-indexed it under its new InProgress/LeaseExpiresAt key,
+	// rather than leaving a stale entry behind.
+	var count int
+	err = idx.db.View(func(tx *bolt.Tx) error {
+		byTime := tx.Bucket([]byte(bucketUploadsByTime))
+		return byTime.ForEach(func(k, v []byte) error {
+			count++
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("view failed: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected exactly 2 entries in uploads_by_time, got %d", count)
+	}
+}
+
+func TestReapExpiredLeasesRequeuesStrandedUploads(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.db")
+
+	idx, err := Open(path, Options{})
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer func() { _ = idx.Close() }()
+
+	created, err := idx.AddUpload(ctx, index.UploadRecord{Path: "/uploads/crashed.bin", Status: index.UploadStatusQueued})
+	if err != nil {
+		t.Fatalf("AddUpload failed: %v", err)
+	}
+	now := time.Unix(1000, 0).UTC()
+	if _, ok, err := idx.ClaimNextUpload(ctx, now, time.Second); err != nil || !ok {
+		t.Fatalf("expected claim to succeed, ok=%v err=%v", ok, err)
+	}
+
+	reaped, err := idx.ReapExpiredLeases(ctx, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("ReapExpiredLeases returned error: %v", err)
+	}
+	if reaped != 1 {
+		t.Fatalf("expected 1 lease reaped, got %d", reaped)
+	}
+
+	uploads, err := idx.ListUploads(ctx)
+	if err != nil {
+		t.Fatalf("ListUploads failed: %v", err)
+	}
+	if len(uploads) != 1 || uploads[0].ID != created.ID {
+		t.Fatalf("expected to find reaped upload %s, got %v", created.ID, uploads)
+	}
+	if uploads[0].Status != index.UploadStatusQueued {
+		t.Fatalf("expected reaped upload to be Queued, got %s", uploads[0].Status)
+	}
+}
+
+func TestReadOnlyOpenRejectsMutationsButAllowsReads(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.db")
+
+	idx, err := Open(path, Options{})
+	if err != nil {
+		t.Fatalf("first Open returned error: %v", err)
+	}
+	meta := index.FileMeta{Path: "/objects/a", Size: 10}
+	if err := idx.Put(ctx, meta); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := idx.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	ro, err := Open(path, Options{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("read-only Open returned error: %v", err)
+	}
+	defer func() { _ = ro.Close() }()
+
+	got, err := ro.Get(ctx, meta.Path)
+	if err != nil {
+		t.Fatalf("Get on read-only index failed: %v", err)
+	}
+	if got.Path != meta.Path {
+		t.Fatalf("expected path %s, got %s", meta.Path, got.Path)
+	}
+
+	if err := ro.Put(ctx, index.FileMeta{Path: "/objects/b", Size: 1}); !errors.Is(err, index.ErrReadOnly) {
+		t.Fatalf("expected Put on read-only index to return ErrReadOnly, got %v", err)
+	}
+	if err := ro.Delete(ctx, meta.Path); !errors.Is(err, index.ErrReadOnly) {
+		t.Fatalf("expected Delete on read-only index to return ErrReadOnly, got %v", err)
+	}
+	if _, err := ro.AddUpload(ctx, index.UploadRecord{Path: "/objects/b"}); !errors.Is(err, index.ErrReadOnly) {
+		t.Fatalf("expected AddUpload on read-only index to return ErrReadOnly, got %v", err)
+	}
+}
+
+func TestReadOnlyOpenRejectsUninitializedDatabase(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.db")
+
+	if _, err := Open(path, Options{ReadOnly: true}); err == nil {
+		t.Fatalf("expected read-only Open of a nonexistent database to fail")
+	}
+}
+
+func TestOpenSharedReusesHandleAndRefcountsClose(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.db")
+
+	first, err := OpenShared(path, Options{})
+	if err != nil {
+		t.Fatalf("first OpenShared returned error: %v", err)
+	}
+	second, err := OpenShared(path, Options{})
+	if err != nil {
+		t.Fatalf("second OpenShared returned error: %v", err)
+	}
+
+	if err := first.Put(ctx, index.FileMeta{Path: "/objects/shared", Size: 1}); err != nil {
+		t.Fatalf("Put through first handle failed: %v", err)
+	}
+	if _, err := second.Get(ctx, "/objects/shared"); err != nil {
+		t.Fatalf("expected second handle to see the first handle's write: %v", err)
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatalf("closing first handle returned error: %v", err)
+	}
+
+	// The underlying database must still be open: second hasn't closed yet.
+	if _, err := second.Get(ctx, "/objects/shared"); err != nil {
+		t.Fatalf("expected second handle to still work after first closed: %v", err)
+	}
+
+	if err := second.Close(); err != nil {
+		t.Fatalf("closing second handle returned error: %v", err)
+	}
+
+	reopened, err := Open(path, Options{})
+	if err != nil {
+		t.Fatalf("expected database to be closed and reopenable, got error: %v", err)
+	}
+	defer func() { _ = reopened.Close() }()
+}
+
 func readSchemaVersion(t *testing.T, path string) int {
 	t.Helper()
 
@@ -211,3 +549,310 @@ func createLegacySchema(t *testing.T, path string) {
 		t.Fatalf("failed to write legacy schema: %v", err)
 	}
 }
+
+func fileMetaWithChunks(path string, chunkCount int) index.FileMeta {
+	chunks := make([]index.ChunkMeta, chunkCount)
+	now := time.Now().UTC()
+	for i := range chunks {
+		chunks[i] = index.ChunkMeta{
+			Offset:        int64(i) * 4096,
+			Length:        4096,
+			Dirty:         i%7 == 0,
+			KeyGeneration: uint32(i % 3),
+			DownloadedAt:  now,
+			AccessedAt:    now,
+		}
+	}
+	return index.FileMeta{
+		Path:        path,
+		ETag:        "etag-value",
+		Size:        int64(chunkCount) * 4096,
+		Chunks:      chunks,
+		MtimeRemote: now,
+		AtimeLocal:  now,
+		HitCount:    42,
+	}
+}
+
+func TestBinaryCodecRoundTripsFileMeta(t *testing.T) {
+	meta := fileMetaWithChunks("/objects/big", 50)
+	meta.DirtyRanges = []index.Range{{Offset: 0, Length: 100}, {Offset: 4096, Length: 10}}
+
+	data, err := encodeFileMeta(meta, CodecBinary)
+	if err != nil {
+		t.Fatalf("encodeFileMeta failed: %v", err)
+	}
+	if len(data) == 0 || data[0] == '{' {
+		t.Fatalf("expected a binary-tagged record, got leading byte %q", data[:1])
+	}
+
+	got, err := decodeFileMeta(data)
+	if err != nil {
+		t.Fatalf("decodeFileMeta failed: %v", err)
+	}
+	if got.Path != meta.Path || got.ETag != meta.ETag || got.Size != meta.Size || got.HitCount != meta.HitCount {
+		t.Fatalf("scalar fields did not round-trip: got %+v, want %+v", got, meta)
+	}
+	if !got.MtimeRemote.Equal(meta.MtimeRemote) || !got.AtimeLocal.Equal(meta.AtimeLocal) {
+		t.Fatalf("timestamps did not round-trip: got %+v, want %+v", got, meta)
+	}
+	if len(got.Chunks) != len(meta.Chunks) {
+		t.Fatalf("expected %d chunks, got %d", len(meta.Chunks), len(got.Chunks))
+	}
+	for i, chunk := range got.Chunks {
+		want := meta.Chunks[i]
+		if chunk.Offset != want.Offset || chunk.Length != want.Length || chunk.Dirty != want.Dirty ||
+			chunk.KeyGeneration != want.KeyGeneration || !chunk.DownloadedAt.Equal(want.DownloadedAt) {
+			t.Fatalf("chunk %d did not round-trip: got %+v, want %+v", i, chunk, want)
+		}
+	}
+	if len(got.DirtyRanges) != len(meta.DirtyRanges) || got.DirtyRanges[1].Offset != 4096 {
+		t.Fatalf("dirty ranges did not round-trip: got %v, want %v", got.DirtyRanges, meta.DirtyRanges)
+	}
+}
+
+func TestBinaryCodecRoundTripsFileMetaWithZeroTimes(t *testing.T) {
+	meta := index.FileMeta{Path: "/objects/fresh"}
+
+	data, err := encodeFileMeta(meta, CodecBinary)
+	if err != nil {
+		t.Fatalf("encodeFileMeta failed: %v", err)
+	}
+	got, err := decodeFileMeta(data)
+	if err != nil {
+		t.Fatalf("decodeFileMeta failed: %v", err)
+	}
+	if !got.MtimeRemote.IsZero() || !got.AtimeLocal.IsZero() {
+		t.Fatalf("expected zero times to round-trip as zero, got %+v", got)
+	}
+}
+
+func TestBinaryCodecRoundTripsUploadRecord(t *testing.T) {
+	now := time.Now().UTC()
+	rec := index.UploadRecord{
+		ID:               "upload-1",
+		Path:             "/objects/video.mp4",
+		Offset:           1024,
+		Length:           2048,
+		Status:           index.UploadStatusInProgress,
+		Attempts:         3,
+		LastError:        "timeout",
+		CreatedAt:        now,
+		UpdatedAt:        now,
+		UploadID:         "multipart-1",
+		PartETags:        []string{"etag-a", "etag-b"},
+		PartSize:         512,
+		BytesTransferred: 1536,
+		KeyGeneration:    2,
+		TusURL:           "https://tus.example/uploads/1",
+		ServerOffset:     1536,
+		NextAttemptAt:    now,
+		MaxAttempts:      5,
+		LeaseExpiresAt:   now,
+		PrevRetryDelay:   3 * time.Second,
+	}
+
+	data, err := encodeUpload(rec, CodecBinary)
+	if err != nil {
+		t.Fatalf("encodeUpload failed: %v", err)
+	}
+	if len(data) == 0 || data[0] == '{' {
+		t.Fatalf("expected a binary-tagged record, got leading byte %q", data[:1])
+	}
+
+	got, err := decodeUpload(data)
+	if err != nil {
+		t.Fatalf("decodeUpload failed: %v", err)
+	}
+	if got.ID != rec.ID || got.Path != rec.Path || got.Offset != rec.Offset || got.Length != rec.Length ||
+		got.Status != rec.Status || got.Attempts != rec.Attempts || got.LastError != rec.LastError ||
+		got.UploadID != rec.UploadID || got.PartSize != rec.PartSize ||
+		got.BytesTransferred != rec.BytesTransferred || got.KeyGeneration != rec.KeyGeneration ||
+		got.TusURL != rec.TusURL || got.ServerOffset != rec.ServerOffset ||
+		got.MaxAttempts != rec.MaxAttempts || got.PrevRetryDelay != rec.PrevRetryDelay {
+		t.Fatalf("upload record did not round-trip: got %+v, want %+v", got, rec)
+	}
+	if !got.CreatedAt.Equal(rec.CreatedAt) || !got.UpdatedAt.Equal(rec.UpdatedAt) ||
+		!got.NextAttemptAt.Equal(rec.NextAttemptAt) || !got.LeaseExpiresAt.Equal(rec.LeaseExpiresAt) {
+		t.Fatalf("upload record timestamps did not round-trip: got %+v, want %+v", got, rec)
+	}
+	if len(got.PartETags) != len(rec.PartETags) {
+		t.Fatalf("expected %d part etags, got %d", len(rec.PartETags), len(got.PartETags))
+	}
+	for i, etag := range got.PartETags {
+		if etag != rec.PartETags[i] {
+			t.Fatalf("part etag %d mismatch: got %s, want %s", i, etag, rec.PartETags[i])
+		}
+	}
+}
+
+func TestDecodeFileMetaFallsBackToLegacyJSON(t *testing.T) {
+	meta := fileMetaWithChunks("/objects/legacy", 3)
+
+	data, err := encodeFileMeta(meta, CodecJSON)
+	if err != nil {
+		t.Fatalf("encodeFileMeta(CodecJSON) failed: %v", err)
+	}
+	if data[0] != '{' {
+		t.Fatalf("expected JSON encoding to start with '{', got %q", data[:1])
+	}
+
+	got, err := decodeFileMeta(data)
+	if err != nil {
+		t.Fatalf("decodeFileMeta failed to fall back to JSON: %v", err)
+	}
+	if got.Path != meta.Path || len(got.Chunks) != len(meta.Chunks) {
+		t.Fatalf("legacy JSON record did not decode correctly: got %+v", got)
+	}
+}
+
+func TestOpenRewritesLegacyJSONRecordsInBinaryOnNextPut(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.db")
+
+	meta := fileMetaWithChunks("/objects/legacy", 2)
+	createV1SchemaWithFiles(t, path, []index.FileMeta{meta})
+
+	idx, err := Open(path, Options{})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	got, err := idx.Get(ctx, meta.Path)
+	if err != nil {
+		t.Fatalf("Get on a legacy JSON record failed: %v", err)
+	}
+	if got.Path != meta.Path {
+		t.Fatalf("expected path %s, got %s", meta.Path, got.Path)
+	}
+
+	if err := idx.Put(ctx, got); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	if err := idx.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	raw := readRawFileMeta(t, path, meta.Path)
+	if len(raw) == 0 || raw[0] == '{' {
+		t.Fatalf("expected record to upgrade to binary encoding after rewrite, got leading byte %q", raw[:1])
+	}
+}
+
+func TestDecodeFileMetaRejectsCorruptRecord(t *testing.T) {
+	meta := fileMetaWithChunks("/objects/corrupt", 2)
+	data, err := encodeFileMeta(meta, CodecBinary)
+	if err != nil {
+		t.Fatalf("encodeFileMeta failed: %v", err)
+	}
+	data[len(data)-1] ^= 0xFF
+
+	if _, err := decodeFileMeta(data); !errors.Is(err, index.ErrCorrupt) {
+		t.Fatalf("expected index.ErrCorrupt for a tampered record, got %v", err)
+	}
+}
+
+func TestDecodeUploadRejectsCorruptRecord(t *testing.T) {
+	rec := index.UploadRecord{ID: "upload-1", Path: "/objects/a", Status: index.UploadStatusQueued}
+	data, err := encodeUpload(rec, CodecBinary)
+	if err != nil {
+		t.Fatalf("encodeUpload failed: %v", err)
+	}
+	data[0] ^= 0xFF
+
+	if _, err := decodeUpload(data); !errors.Is(err, index.ErrCorrupt) {
+		t.Fatalf("expected index.ErrCorrupt for a tampered record, got %v", err)
+	}
+}
+
+func TestBinaryCodecIsSmallerThanJSONFor10kChunks(t *testing.T) {
+	meta := fileMetaWithChunks("/objects/huge", 10000)
+
+	binaryData, err := encodeFileMeta(meta, CodecBinary)
+	if err != nil {
+		t.Fatalf("encodeFileMeta(CodecBinary) failed: %v", err)
+	}
+	jsonData, err := encodeFileMeta(meta, CodecJSON)
+	if err != nil {
+		t.Fatalf("encodeFileMeta(CodecJSON) failed: %v", err)
+	}
+
+	t.Logf("10k chunks: binary=%d bytes, json=%d bytes (%.1f%% of json)",
+		len(binaryData), len(jsonData), 100*float64(len(binaryData))/float64(len(jsonData)))
+	if len(binaryData) >= len(jsonData) {
+		t.Fatalf("expected binary encoding to be smaller than JSON: binary=%d, json=%d", len(binaryData), len(jsonData))
+	}
+}
+
+func readRawFileMeta(t *testing.T, path, metaPath string) []byte {
+	t.Helper()
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("failed to open db for inspection: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	var raw []byte
+	if err := db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketFiles))
+		if bucket == nil {
+			return fmt.Errorf("missing bucket %s", bucketFiles)
+		}
+		raw = append([]byte(nil), bucket.Get([]byte(metaPath))...)
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to read raw record: %v", err)
+	}
+	return raw
+}
+
+func BenchmarkEncodeFileMetaBinary(b *testing.B) {
+	meta := fileMetaWithChunks("/objects/huge", 10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := encodeFileMeta(meta, CodecBinary); err != nil {
+			b.Fatalf("encodeFileMeta failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkEncodeFileMetaJSON(b *testing.B) {
+	meta := fileMetaWithChunks("/objects/huge", 10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := encodeFileMeta(meta, CodecJSON); err != nil {
+			b.Fatalf("encodeFileMeta failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkDecodeFileMetaBinary(b *testing.B) {
+	meta := fileMetaWithChunks("/objects/huge", 10000)
+	data, err := encodeFileMeta(meta, CodecBinary)
+	if err != nil {
+		b.Fatalf("encodeFileMeta failed: %v", err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := decodeFileMeta(data); err != nil {
+			b.Fatalf("decodeFileMeta failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkDecodeFileMetaJSON(b *testing.B) {
+	meta := fileMetaWithChunks("/objects/huge", 10000)
+	data, err := encodeFileMeta(meta, CodecJSON)
+	if err != nil {
+		b.Fatalf("encodeFileMeta failed: %v", err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := decodeFileMeta(data); err != nil {
+			b.Fatalf("decodeFileMeta failed: %v", err)
+		}
+	}
+}
@@ -3,17 +3,124 @@ package index
 import (
 	"context"
 	"errors"
+	"sort"
 	"time"
 )
 
 // ErrNotFound is returned when a requested entry is not present in the index.
 var ErrNotFound = errors.New("cache index: entry not found")
 
+// ErrReadOnly is returned by mutating methods on an index opened read-only.
+var ErrReadOnly = errors.New("cache index: index is read-only")
+
+// ErrCorrupt is returned when a stored record fails its integrity check
+// (e.g. a CRC mismatch), so disk corruption surfaces as an error instead of
+// silently returning garbage field values that could poison an eviction
+// policy or a retry loop.
+var ErrCorrupt = errors.New("cache index: record failed integrity check")
+
+// Range describes a half-open byte interval [Offset, Offset+Length).
+type Range struct {
+	Offset int64
+	Length int64
+}
+
+// End returns the exclusive end of the range.
+func (r Range) End() int64 {
+	return r.Offset + r.Length
+}
+
+// MergeRanges returns ranges with add inserted, coalescing it with any
+// ranges it overlaps or directly abuts. ranges must already be sorted by
+// Offset and non-overlapping; the result maintains that invariant.
+func MergeRanges(ranges []Range, add Range) []Range {
+	if add.Length <= 0 {
+		return ranges
+	}
+
+	merged := make([]Range, 0, len(ranges)+1)
+	inserted := false
+	for _, r := range ranges {
+		switch {
+		case inserted, r.End() < add.Offset:
+			merged = append(merged, r)
+		case r.Offset > add.End():
+			merged = append(merged, add)
+			inserted = true
+			merged = append(merged, r)
+		default:
+			start := add.Offset
+			if r.Offset < start {
+				start = r.Offset
+			}
+			end := add.End()
+			if r.End() > end {
+				end = r.End()
+			}
+			add = Range{Offset: start, Length: end - start}
+		}
+	}
+	if !inserted {
+		merged = append(merged, add)
+	}
+	return merged
+}
+
+// SubtractRange removes remove from ranges, splitting any range that only
+// partially overlaps it. ranges must already be sorted by Offset and
+// non-overlapping; the result maintains that invariant.
+func SubtractRange(ranges []Range, remove Range) []Range {
+	if remove.Length <= 0 {
+		return ranges
+	}
+
+	result := make([]Range, 0, len(ranges)+1)
+	for _, r := range ranges {
+		if r.End() <= remove.Offset || r.Offset >= remove.End() {
+			result = append(result, r)
+			continue
+		}
+		if r.Offset < remove.Offset {
+			result = append(result, Range{Offset: r.Offset, Length: remove.Offset - r.Offset})
+		}
+		if r.End() > remove.End() {
+			result = append(result, Range{Offset: remove.End(), Length: r.End() - remove.End()})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Offset < result[j].Offset })
+	return result
+}
+
 // ChunkMeta describes a contiguous byte range cached on disk.
 type ChunkMeta struct {
 	Offset int64
 	Length int64
 	Dirty  bool
+
+	// Absent marks a clean chunk whose bytes were reclaimed by a cleaner
+	// reset (see cleaner.Cleaner's ENOSPC recovery path) rather than a full
+	// eviction: the index entry and any other chunk still describe this
+	// file, but a read over [Offset, Offset+Length) must be treated as a
+	// cache miss and re-fetched from the backend. Never set on a Dirty
+	// chunk, since dirty bytes only exist locally.
+	Absent bool
+
+	// KeyGeneration identifies which master-key generation this chunk was
+	// encrypted under, so a key rotation can re-encrypt chunks lazily instead
+	// of all at once.
+	KeyGeneration uint32
+
+	// DownloadedAt is when this chunk's bytes were last fetched from the
+	// backend (zero for a chunk that has only ever been written locally,
+	// i.e. Dirty).
+	DownloadedAt time.Time
+	// AccessedAt is when this chunk was last read, tracked independently of
+	// FileMeta.AtimeLocal so a per-chunk (rather than whole-file) eviction
+	// policy can tell which ranges of a large file are actually still hot.
+	AccessedAt time.Time
+	// Pinned marks a chunk that must survive eviction regardless of age or
+	// access frequency, e.g. one a cleaner policy has decided to keep warm.
+	Pinned bool
 }
 
 // FileMeta stores metadata for a cached object, including chunk layout and timestamps.
@@ -24,6 +131,18 @@ type FileMeta struct {
 	Chunks      []ChunkMeta
 	MtimeRemote time.Time
 	AtimeLocal  time.Time
+
+	// HitCount counts how many times Get has returned this entry, for
+	// frequency-aware eviction policies (cleaner.PolicyLFU,
+	// cleaner.PolicyGDSF). Incremented alongside AtimeLocal on every Get.
+	HitCount uint64
+
+	// DirtyRanges is the sorted, non-overlapping set of byte ranges written
+	// locally since the last successful upload of this path. It is coarser
+	// than per-chunk Dirty flags: a write can mark part of a chunk dirty
+	// without requiring the whole chunk to be re-uploaded. Maintained via
+	// MergeRanges/SubtractRange by MarkDirty/MarkClean.
+	DirtyRanges []Range
 }
 
 // UploadStatus represents the lifecycle state for a pending background upload.
@@ -38,6 +157,10 @@ const (
 	UploadStatusComplete UploadStatus = "complete"
 	// UploadStatusFailed marks an upload that exhausted retries and requires intervention.
 	UploadStatusFailed UploadStatus = "failed"
+	// UploadStatusResumable marks an upload that was interrupted mid-transfer
+	// but has a live server-side session (UploadID or TusURL) it can resume
+	// from, as opposed to UploadStatusQueued which starts from byte 0.
+	UploadStatusResumable UploadStatus = "resumable"
 )
 
 // UploadRecord tracks a pending or completed chunk upload stored in the index.
@@ -51,6 +174,51 @@ type UploadRecord struct {
 	LastError string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+
+	// UploadID is the backend's multipart upload session identifier, set
+	// once the first part is staged so a crashed upload can be resumed
+	// instead of restarted from byte 0.
+	UploadID string
+	// PartETags holds the ETag of every part checkpointed so far, in order.
+	PartETags []string
+	// PartSize is the size each part of a multipart upload was split into,
+	// decided once when the upload session was created (see
+	// uploader.Config.MultipartPartSize) and persisted so a resumed upload
+	// keeps using the same part boundaries even if the configured part size
+	// changes afterward. Zero means this record was never split: a single
+	// part spans the whole chunk.
+	PartSize int64
+	// BytesTransferred is the number of bytes from Offset already durably
+	// accepted by the backend.
+	BytesTransferred int64
+	// KeyGeneration identifies which master-key generation the cached chunk
+	// backing this upload was encrypted under.
+	KeyGeneration uint32
+
+	// TusURL is the server-assigned tus.io upload URL for this record, set
+	// once creation succeeds so a crashed upload resumes against the same
+	// session instead of creating a new one.
+	TusURL string
+	// ServerOffset is the last offset the tus server reported owning via
+	// HEAD, used to resume a PATCH from the right position.
+	ServerOffset int64
+
+	// NextAttemptAt is when this record becomes eligible for ClaimNextUpload.
+	// Zero means eligible immediately.
+	NextAttemptAt time.Time
+	// MaxAttempts bounds how many times the scheduler will retry this record
+	// before giving up; zero means the caller enforces its own limit.
+	MaxAttempts int
+	// LeaseExpiresAt is set by ClaimNextUpload and marks when an InProgress
+	// claim is considered abandoned and eligible to be reclaimed by another
+	// worker.
+	LeaseExpiresAt time.Time
+
+	// PrevRetryDelay is the delay actually used the last time this record
+	// was retried, persisted so a decorrelated-jitter backoff sequence
+	// resumes from here after a crash or restart instead of restarting at
+	// the configured base delay.
+	PrevRetryDelay time.Duration
 }
 
 // CacheIndex expresses the minimal persistence requirements for the cache metadata store.
@@ -65,6 +233,36 @@ type CacheIndex interface {
 	Delete(ctx context.Context, path string) error
 	// ListLRU returns metadata ordered by least-recently-used (AtimeLocal ascending).
 	ListLRU(ctx context.Context, limit int) ([]FileMeta, error)
+	// ListOlderThan returns metadata for every entry whose AtimeLocal is
+	// strictly before cutoff, ordered oldest first, so an age-based sweep
+	// doesn't need to rescan (and filter) the whole index the way a
+	// ListLRU(ctx, 0) call would.
+	ListOlderThan(ctx context.Context, cutoff time.Time) ([]FileMeta, error)
+
+	// MarkDirty records [offset, offset+length) as dirty for path, coalescing
+	// it with any dirty range already recorded that it overlaps or abuts.
+	MarkDirty(ctx context.Context, path string, offset, length int64) (FileMeta, error)
+	// MarkClean removes [offset, offset+length) from path's dirty ranges,
+	// splitting any range that only partially overlaps it. Typically called
+	// once the corresponding bytes have been durably uploaded.
+	MarkClean(ctx context.Context, path string, offset, length int64) (FileMeta, error)
+	// DirtyRanges returns path's sorted, coalesced dirty byte ranges.
+	DirtyRanges(ctx context.Context, path string) ([]Range, error)
+
+	// PutChunk records or replaces per-chunk residency metadata for path at
+	// chunk.Offset, independent of the FileMeta.Chunks slice stored with
+	// Put/Update. Implementations are expected to index these by
+	// (path, offset) so ChunksInRange can answer "which chunks overlap this
+	// range" without decoding the whole FileMeta.
+	PutChunk(ctx context.Context, path string, chunk ChunkMeta) error
+	// DeleteChunk removes the chunk residency entry for path at offset.
+	// Missing entries are ignored.
+	DeleteChunk(ctx context.Context, path string, offset int64) error
+	// ChunksInRange returns every chunk recorded for path that overlaps
+	// [offset, offset+length), ordered by Offset, letting a large file's
+	// read path cheaply answer which byte ranges are already local without
+	// scanning every chunk the file has.
+	ChunksInRange(ctx context.Context, path string, offset, length int64) ([]ChunkMeta, error)
 
 	// AddUpload records a new upload entry. If entry.ID is empty, an ID must be assigned.
 	AddUpload(ctx context.Context, entry UploadRecord) (UploadRecord, error)
@@ -72,4 +270,69 @@ type CacheIndex interface {
 	ListUploads(ctx context.Context) ([]UploadRecord, error)
 	// UpdateUploadStatus updates status information for an existing upload entry.
 	UpdateUploadStatus(ctx context.Context, id string, status UploadStatus, lastError string) (UploadRecord, error)
+	// SetUploadID persists the backend multipart session identifier for an
+	// existing upload entry, enabling resumption across restarts.
+	SetUploadID(ctx context.Context, id string, uploadID string) (UploadRecord, error)
+	// SetTusURL persists the backend tus.io upload URL for an existing
+	// upload entry, enabling resumption across restarts.
+	SetTusURL(ctx context.Context, id string, tusURL string) (UploadRecord, error)
+	// SetPartSize persists the part size a multipart upload session was
+	// split into, so a restart resumes with the same part boundaries
+	// instead of recomputing them from the uploader's current configuration.
+	SetPartSize(ctx context.Context, id string, partSize int64) (UploadRecord, error)
+	// SetRetryDelay persists the backoff delay actually used for an existing
+	// upload entry's most recent retry, so a resumed decorrelated-jitter
+	// sequence (see uploader.Uploader.backoffDelay) continues from here
+	// rather than restarting at the configured base delay.
+	SetRetryDelay(ctx context.Context, id string, delay time.Duration) (UploadRecord, error)
+	// UpdateUploadProgress atomically appends a checkpointed part ETag (when
+	// non-empty) and records the total bytes transferred so far.
+	UpdateUploadProgress(ctx context.Context, id string, bytesTransferred int64, partETag string) (UploadRecord, error)
+	// ClaimNextUpload atomically transitions the earliest-due Queued record
+	// (or an InProgress record whose lease has expired) to InProgress with a
+	// fresh lease of leaseDur from now, so multiple worker goroutines or
+	// nodes can share the uploads table without double-dispatching the same
+	// record. ok is false when nothing is currently due.
+	ClaimNextUpload(ctx context.Context, now time.Time, leaseDur time.Duration) (record UploadRecord, ok bool, err error)
+	// Reschedule requeues id for a later attempt at nextAttemptAt, recording
+	// lastError and releasing any lease held on it.
+	Reschedule(ctx context.Context, id string, nextAttemptAt time.Time, lastError string) (UploadRecord, error)
+	// RenewLease extends an already-claimed (InProgress) record's lease to
+	// now+leaseDur, for a worker that is still making progress and wants to
+	// keep other workers from reclaiming the record out from under it. It
+	// fails if id is not currently InProgress.
+	RenewLease(ctx context.Context, id string, now time.Time, leaseDur time.Duration) (UploadRecord, error)
+	// ReapExpiredLeases resets every InProgress record whose LeaseExpiresAt
+	// is at or before now back to Queued, for a crashed worker that never
+	// got the chance to fail or reschedule its claim. It returns how many
+	// records were reclaimed. ClaimNextUpload already reclaims an expired
+	// lease opportunistically when another worker asks for work, so calling
+	// this periodically is only needed to make stranded records visible as
+	// Queued (rather than stuck InProgress) before anyone next polls.
+	ReapExpiredLeases(ctx context.Context, now time.Time) (int, error)
+
+	// RecordPendingAccess increments the pre-admission access counter for a
+	// path not yet backed by a FileMeta, creating it (with FirstSeen set to
+	// now) on first observation. Used by AdmissionGate to implement a
+	// minimum-access threshold before a path is cached on disk.
+	RecordPendingAccess(ctx context.Context, path string, now time.Time) (count int, firstSeen time.Time, err error)
+	// ClearPendingAccess removes path's pre-admission access counter, if
+	// any, typically once it has been admitted (or evicted without ever
+	// being admitted).
+	ClearPendingAccess(ctx context.Context, path string) error
+	// PurgeExpiredPendingAccess removes every pending-access counter whose
+	// FirstSeen is strictly before cutoff, returning how many were removed,
+	// so a sweep can forget about one-shot reads instead of accumulating
+	// counters for paths that are never revisited.
+	PurgeExpiredPendingAccess(ctx context.Context, cutoff time.Time) (int, error)
+
+	// LookupDigest returns the ETag of a previously uploaded object whose
+	// content hash and length match digest and length, for content-addressed
+	// chunk dedup. ok is false when no such mapping is recorded.
+	LookupDigest(ctx context.Context, digest string, length int64) (etag string, ok bool, err error)
+	// RecordDigest persists digest+length -> etag so a future upload of
+	// identical chunk content can be skipped in favor of reusing etag.
+	// Calling it again for the same digest+length overwrites the stored
+	// ETag with the latest one.
+	RecordDigest(ctx context.Context, digest string, length int64, etag string) error
 }
@@ -3,8 +3,10 @@ package indextest
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sort"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -41,6 +43,28 @@ func RunCacheIndexContract(t *testing.T, factory CacheIndexFactory) {
 				assertMetasEqual(t, meta, fetched, withDynamicTimes())
 			},
 		},
+		{
+			name: "get increments HitCount on every call",
+			testFn: func(t *testing.T, idx index.CacheIndex) {
+				t.Helper()
+
+				ctx := context.Background()
+				meta := sampleMeta("/docs/hot.pdf", "etag-hot", 2048, time.Unix(20, 0))
+				if err := idx.Put(ctx, meta); err != nil {
+					t.Fatalf("Put returned error: %v", err)
+				}
+
+				for i, want := range []uint64{1, 2, 3} {
+					fetched, err := idx.Get(ctx, meta.Path)
+					if err != nil {
+						t.Fatalf("Get #%d returned error: %v", i, err)
+					}
+					if fetched.HitCount != want {
+						t.Fatalf("Get #%d: expected HitCount %d, got %d", i, want, fetched.HitCount)
+					}
+				}
+			},
+		},
 		{
 			name: "get missing returns ErrNotFound",
 			testFn: func(t *testing.T, idx index.CacheIndex) {
@@ -174,82 +198,760 @@ func RunCacheIndexContract(t *testing.T, factory CacheIndexFactory) {
 				if err != nil {
 					t.Fatalf("ListLRU returned error: %v", err)
 				}
-				if len(results) != 2 {
-					t.Fatalf("expected 2 entries, got %d", len(results))
+				if len(results) != 2 {
+					t.Fatalf("expected 2 entries, got %d", len(results))
+				}
+				if results[0].Path != "/a" || results[1].Path != "/b" {
+					t.Fatalf("expected [/a /b], got [%s %s]", results[0].Path, results[1].Path)
+				}
+
+				if results[0].AtimeLocal.After(results[1].AtimeLocal) {
+					t.Fatalf("expected first result to be least recently used")
+				}
+			},
+		},
+		{
+			name: "list older than returns entries before cutoff ordered oldest first",
+			testFn: func(t *testing.T, idx index.CacheIndex) {
+				t.Helper()
+
+				ctx := context.Background()
+				base := time.Unix(200, 0)
+				metas := []index.FileMeta{
+					sampleMeta("/old-a", "etag-a", 100, base.Add(time.Second)),
+					sampleMeta("/old-b", "etag-b", 200, base.Add(2*time.Second)),
+					sampleMeta("/fresh", "etag-c", 300, base.Add(10*time.Second)),
+				}
+				for _, meta := range metas {
+					if err := idx.Put(ctx, meta); err != nil {
+						t.Fatalf("Put failed: %v", err)
+					}
+				}
+
+				results, err := idx.ListOlderThan(ctx, base.Add(5*time.Second))
+				if err != nil {
+					t.Fatalf("ListOlderThan returned error: %v", err)
+				}
+				if len(results) != 2 {
+					t.Fatalf("expected 2 entries older than cutoff, got %d", len(results))
+				}
+				if results[0].Path != "/old-a" || results[1].Path != "/old-b" {
+					t.Fatalf("expected [/old-a /old-b], got [%s %s]", results[0].Path, results[1].Path)
+				}
+
+				if results, err := idx.ListOlderThan(ctx, base); err != nil {
+					t.Fatalf("ListOlderThan returned error: %v", err)
+				} else if len(results) != 0 {
+					t.Fatalf("expected no entries older than base, got %d", len(results))
+				}
+			},
+		},
+		{
+			name: "pending access counts, clears, and expires",
+			testFn: func(t *testing.T, idx index.CacheIndex) {
+				t.Helper()
+
+				ctx := context.Background()
+				first := time.Unix(300, 0)
+
+				for i, want := range []int{1, 2, 3} {
+					count, firstSeen, err := idx.RecordPendingAccess(ctx, "/warming.bin", first.Add(time.Duration(i)*time.Second))
+					if err != nil {
+						t.Fatalf("RecordPendingAccess #%d returned error: %v", i, err)
+					}
+					if count != want {
+						t.Fatalf("RecordPendingAccess #%d: expected count %d, got %d", i, want, count)
+					}
+					if !firstSeen.Equal(first) {
+						t.Fatalf("RecordPendingAccess #%d: expected firstSeen to stay %v, got %v", i, first, firstSeen)
+					}
+				}
+
+				if err := idx.ClearPendingAccess(ctx, "/warming.bin"); err != nil {
+					t.Fatalf("ClearPendingAccess returned error: %v", err)
+				}
+				count, firstSeen, err := idx.RecordPendingAccess(ctx, "/warming.bin", first.Add(time.Minute))
+				if err != nil {
+					t.Fatalf("RecordPendingAccess after clear returned error: %v", err)
+				}
+				if count != 1 {
+					t.Fatalf("expected counter to restart at 1 after clear, got %d", count)
+				}
+				if !firstSeen.Equal(first.Add(time.Minute)) {
+					t.Fatalf("expected firstSeen to reset after clear, got %v", firstSeen)
+				}
+
+				if _, _, err := idx.RecordPendingAccess(ctx, "/stale.bin", first); err != nil {
+					t.Fatalf("RecordPendingAccess for /stale.bin returned error: %v", err)
+				}
+
+				removed, err := idx.PurgeExpiredPendingAccess(ctx, first.Add(30*time.Second))
+				if err != nil {
+					t.Fatalf("PurgeExpiredPendingAccess returned error: %v", err)
+				}
+				if removed != 1 {
+					t.Fatalf("expected 1 expired entry purged, got %d", removed)
+				}
+
+				if count, _, err := idx.RecordPendingAccess(ctx, "/stale.bin", first.Add(time.Hour)); err != nil {
+					t.Fatalf("RecordPendingAccess for /stale.bin after purge returned error: %v", err)
+				} else if count != 1 {
+					t.Fatalf("expected /stale.bin counter to restart at 1 after purge, got %d", count)
+				}
+				if count, _, err := idx.RecordPendingAccess(ctx, "/warming.bin", first.Add(time.Hour)); err != nil {
+					t.Fatalf("RecordPendingAccess for /warming.bin after purge returned error: %v", err)
+				} else if count != 2 {
+					t.Fatalf("expected unexpired /warming.bin counter to survive purge, got %d", count)
+				}
+			},
+		},
+		{
+			name: "digest lookup records and overwrites",
+			testFn: func(t *testing.T, idx index.CacheIndex) {
+				t.Helper()
+
+				ctx := context.Background()
+
+				if _, ok, err := idx.LookupDigest(ctx, "deadbeef", 4096); err != nil {
+					t.Fatalf("LookupDigest returned error: %v", err)
+				} else if ok {
+					t.Fatalf("expected no mapping before RecordDigest")
+				}
+
+				if err := idx.RecordDigest(ctx, "deadbeef", 4096, "etag-1"); err != nil {
+					t.Fatalf("RecordDigest returned error: %v", err)
+				}
+
+				etag, ok, err := idx.LookupDigest(ctx, "deadbeef", 4096)
+				if err != nil {
+					t.Fatalf("LookupDigest returned error: %v", err)
+				}
+				if !ok || etag != "etag-1" {
+					t.Fatalf("expected (etag-1, true), got (%s, %v)", etag, ok)
+				}
+
+				// Same digest, different length must not collide.
+				if _, ok, err := idx.LookupDigest(ctx, "deadbeef", 8192); err != nil {
+					t.Fatalf("LookupDigest returned error: %v", err)
+				} else if ok {
+					t.Fatalf("expected no mapping for a different length")
+				}
+
+				if err := idx.RecordDigest(ctx, "deadbeef", 4096, "etag-2"); err != nil {
+					t.Fatalf("RecordDigest overwrite returned error: %v", err)
+				}
+				if etag, ok, err := idx.LookupDigest(ctx, "deadbeef", 4096); err != nil {
+					t.Fatalf("LookupDigest returned error: %v", err)
+				} else if !ok || etag != "etag-2" {
+					t.Fatalf("expected overwritten mapping (etag-2, true), got (%s, %v)", etag, ok)
+				}
+			},
+		},
+		{
+			name: "uploads lifecycle",
+			testFn: func(t *testing.T, idx index.CacheIndex) {
+				t.Helper()
+
+				ctx := context.Background()
+				upload := index.UploadRecord{
+					Path:   "/uploads/video.mp4",
+					Offset: 0,
+					Length: 64 << 20,
+					Status: index.UploadStatusQueued,
+				}
+				created, err := idx.AddUpload(ctx, upload)
+				if err != nil {
+					t.Fatalf("AddUpload failed: %v", err)
+				}
+				if created.ID == "" {
+					t.Fatalf("expected AddUpload to assign ID")
+				}
+				if created.CreatedAt.IsZero() || created.UpdatedAt.IsZero() {
+					t.Fatalf("expected timestamps set on AddUpload")
+				}
+
+				uploads, err := idx.ListUploads(ctx)
+				if err != nil {
+					t.Fatalf("ListUploads failed: %v", err)
+				}
+				if len(uploads) != 1 {
+					t.Fatalf("expected 1 upload, got %d", len(uploads))
+				}
+				if uploads[0].ID != created.ID {
+					t.Fatalf("expected upload ID %s, got %s", created.ID, uploads[0].ID)
+				}
+
+				progressed, err := idx.UpdateUploadStatus(ctx, created.ID, index.UploadStatusInProgress, "")
+				if err != nil {
+					t.Fatalf("UpdateUploadStatus failed: %v", err)
+				}
+				if progressed.Status != index.UploadStatusInProgress {
+					t.Fatalf("expected status %s, got %s", index.UploadStatusInProgress, progressed.Status)
+				}
+				if progressed.Attempts != 1 {
+					t.Fatalf("expected attempts to increment, got %d", progressed.Attempts)
+				}
+
+				failed, err := idx.UpdateUploadStatus(ctx, created.ID, index.UploadStatusFailed, "network err")
+				if err != nil {
+					t.Fatalf("UpdateUploadStatus failed: %v", err)
+				}
+				if failed.Status != index.UploadStatusFailed {
+					t.Fatalf("expected failed status, got %s", failed.Status)
+				}
+				if failed.LastError != "network err" {
+					t.Fatalf("expected last error recorded")
+				}
+				if failed.Attempts != 2 {
+					t.Fatalf("expected attempts to increment again, got %d", failed.Attempts)
+				}
+				if !failed.UpdatedAt.After(failed.CreatedAt) {
+					t.Fatalf("expected updated timestamp to be newer than created")
+				}
+
+				if _, err := idx.UpdateUploadStatus(ctx, "missing", index.UploadStatusQueued, ""); !errors.Is(err, index.ErrNotFound) {
+					t.Fatalf("expected ErrNotFound on missing upload, got %v", err)
+				}
+			},
+		},
+		{
+			name: "upload progress checkpoints resumably",
+			testFn: func(t *testing.T, idx index.CacheIndex) {
+				t.Helper()
+
+				ctx := context.Background()
+				created, err := idx.AddUpload(ctx, index.UploadRecord{
+					Path:   "/uploads/big.bin",
+					Offset: 0,
+					Length: 128 << 20,
+					Status: index.UploadStatusQueued,
+				})
+				if err != nil {
+					t.Fatalf("AddUpload failed: %v", err)
+				}
+
+				withID, err := idx.SetUploadID(ctx, created.ID, "mpu-1")
+				if err != nil {
+					t.Fatalf("SetUploadID failed: %v", err)
+				}
+				if withID.UploadID != "mpu-1" {
+					t.Fatalf("expected UploadID mpu-1, got %q", withID.UploadID)
+				}
+
+				withPartSize, err := idx.SetPartSize(ctx, created.ID, 8<<20)
+				if err != nil {
+					t.Fatalf("SetPartSize failed: %v", err)
+				}
+				if withPartSize.PartSize != 8<<20 {
+					t.Fatalf("expected PartSize 8MiB, got %d", withPartSize.PartSize)
+				}
+
+				afterPart1, err := idx.UpdateUploadProgress(ctx, created.ID, 8<<20, "etag-1")
+				if err != nil {
+					t.Fatalf("UpdateUploadProgress failed: %v", err)
+				}
+				if afterPart1.BytesTransferred != 8<<20 {
+					t.Fatalf("expected BytesTransferred 8MiB, got %d", afterPart1.BytesTransferred)
+				}
+				if len(afterPart1.PartETags) != 1 || afterPart1.PartETags[0] != "etag-1" {
+					t.Fatalf("expected PartETags [etag-1], got %v", afterPart1.PartETags)
+				}
+
+				afterPart2, err := idx.UpdateUploadProgress(ctx, created.ID, 16<<20, "etag-2")
+				if err != nil {
+					t.Fatalf("UpdateUploadProgress failed: %v", err)
+				}
+				if len(afterPart2.PartETags) != 2 || afterPart2.PartETags[1] != "etag-2" {
+					t.Fatalf("expected PartETags to append, got %v", afterPart2.PartETags)
+				}
+
+				if _, err := idx.UpdateUploadProgress(ctx, "missing", 0, "etag"); !errors.Is(err, index.ErrNotFound) {
+					t.Fatalf("expected ErrNotFound on missing upload, got %v", err)
+				}
+				if _, err := idx.SetPartSize(ctx, "missing", 1); !errors.Is(err, index.ErrNotFound) {
+					t.Fatalf("expected ErrNotFound on missing upload, got %v", err)
+				}
+			},
+		},
+		{
+			name: "retry delay persists across updates",
+			testFn: func(t *testing.T, idx index.CacheIndex) {
+				t.Helper()
+
+				ctx := context.Background()
+				created, err := idx.AddUpload(ctx, index.UploadRecord{
+					Path:   "/uploads/flaky.bin",
+					Offset: 0,
+					Length: 4 << 20,
+					Status: index.UploadStatusQueued,
+				})
+				if err != nil {
+					t.Fatalf("AddUpload failed: %v", err)
+				}
+
+				withDelay, err := idx.SetRetryDelay(ctx, created.ID, 750*time.Millisecond)
+				if err != nil {
+					t.Fatalf("SetRetryDelay failed: %v", err)
+				}
+				if withDelay.PrevRetryDelay != 750*time.Millisecond {
+					t.Fatalf("expected PrevRetryDelay 750ms, got %s", withDelay.PrevRetryDelay)
+				}
+
+				requeued, err := idx.UpdateUploadStatus(ctx, created.ID, index.UploadStatusQueued, "retrying")
+				if err != nil {
+					t.Fatalf("UpdateUploadStatus failed: %v", err)
+				}
+				if requeued.PrevRetryDelay != 750*time.Millisecond {
+					t.Fatalf("expected PrevRetryDelay to survive status update, got %s", requeued.PrevRetryDelay)
+				}
+
+				if _, err := idx.SetRetryDelay(ctx, "missing", time.Second); !errors.Is(err, index.ErrNotFound) {
+					t.Fatalf("expected ErrNotFound on missing upload, got %v", err)
+				}
+			},
+		},
+		{
+			name: "tus url persists and marks the record resumable",
+			testFn: func(t *testing.T, idx index.CacheIndex) {
+				t.Helper()
+
+				ctx := context.Background()
+				created, err := idx.AddUpload(ctx, index.UploadRecord{
+					Path:   "/uploads/resumable.bin",
+					Offset: 0,
+					Length: 4 << 20,
+					Status: index.UploadStatusQueued,
+				})
+				if err != nil {
+					t.Fatalf("AddUpload failed: %v", err)
+				}
+
+				withURL, err := idx.SetTusURL(ctx, created.ID, "https://tus.example.com/files/abc")
+				if err != nil {
+					t.Fatalf("SetTusURL failed: %v", err)
+				}
+				if withURL.TusURL != "https://tus.example.com/files/abc" {
+					t.Fatalf("expected TusURL to persist, got %q", withURL.TusURL)
+				}
+
+				resumable, err := idx.UpdateUploadStatus(ctx, created.ID, index.UploadStatusResumable, "connection dropped")
+				if err != nil {
+					t.Fatalf("UpdateUploadStatus failed: %v", err)
+				}
+				if resumable.Status != index.UploadStatusResumable {
+					t.Fatalf("expected resumable status, got %s", resumable.Status)
+				}
+				if resumable.TusURL != "https://tus.example.com/files/abc" {
+					t.Fatalf("expected TusURL to survive status update, got %q", resumable.TusURL)
+				}
+
+				if _, err := idx.SetTusURL(ctx, "missing", "https://tus.example.com/files/missing"); !errors.Is(err, index.ErrNotFound) {
+					t.Fatalf("expected ErrNotFound on missing upload, got %v", err)
+				}
+			},
+		},
+		{
+			name: "claim next upload picks earliest due and excludes future work",
+			testFn: func(t *testing.T, idx index.CacheIndex) {
+				t.Helper()
+
+				ctx := context.Background()
+				now := time.Unix(1000, 0).UTC()
+
+				future, err := idx.AddUpload(ctx, index.UploadRecord{
+					Path: "/uploads/later.bin", Status: index.UploadStatusQueued, NextAttemptAt: now.Add(time.Hour),
+				})
+				if err != nil {
+					t.Fatalf("AddUpload future failed: %v", err)
+				}
+				due, err := idx.AddUpload(ctx, index.UploadRecord{
+					Path: "/uploads/due.bin", Status: index.UploadStatusQueued,
+				})
+				if err != nil {
+					t.Fatalf("AddUpload due failed: %v", err)
+				}
+
+				claimed, ok, err := idx.ClaimNextUpload(ctx, now, time.Minute)
+				if err != nil {
+					t.Fatalf("ClaimNextUpload returned error: %v", err)
+				}
+				if !ok {
+					t.Fatalf("expected a claimable upload")
+				}
+				if claimed.ID != due.ID {
+					t.Fatalf("expected to claim %s, got %s", due.ID, claimed.ID)
+				}
+				if claimed.Status != index.UploadStatusInProgress {
+					t.Fatalf("expected claimed record to be InProgress, got %s", claimed.Status)
+				}
+				if !claimed.LeaseExpiresAt.Equal(now.Add(time.Minute)) {
+					t.Fatalf("expected lease to expire at %s, got %s", now.Add(time.Minute), claimed.LeaseExpiresAt)
+				}
+
+				_, ok, err = idx.ClaimNextUpload(ctx, now, time.Minute)
+				if err != nil {
+					t.Fatalf("second ClaimNextUpload returned error: %v", err)
+				}
+				if ok {
+					t.Fatalf("expected no further claimable uploads before the future one is due")
+				}
+
+				_ = future
+			},
+		},
+		{
+			name: "claim next upload reclaims an expired lease",
+			testFn: func(t *testing.T, idx index.CacheIndex) {
+				t.Helper()
+
+				ctx := context.Background()
+				now := time.Unix(2000, 0).UTC()
+
+				created, err := idx.AddUpload(ctx, index.UploadRecord{
+					Path: "/uploads/stuck.bin", Status: index.UploadStatusQueued,
+				})
+				if err != nil {
+					t.Fatalf("AddUpload failed: %v", err)
+				}
+
+				claimed, ok, err := idx.ClaimNextUpload(ctx, now, time.Second)
+				if err != nil || !ok {
+					t.Fatalf("expected first claim to succeed, ok=%v err=%v", ok, err)
+				}
+
+				later := now.Add(time.Hour)
+				reclaimed, ok, err := idx.ClaimNextUpload(ctx, later, time.Minute)
+				if err != nil {
+					t.Fatalf("ClaimNextUpload returned error: %v", err)
+				}
+				if !ok {
+					t.Fatalf("expected expired lease to be reclaimable")
+				}
+				if reclaimed.ID != claimed.ID || reclaimed.ID != created.ID {
+					t.Fatalf("expected to reclaim %s, got %s", created.ID, reclaimed.ID)
+				}
+				if reclaimed.Attempts != 2 {
+					t.Fatalf("expected attempts to increment across claims, got %d", reclaimed.Attempts)
+				}
+			},
+		},
+		{
+			name: "reschedule requeues with a future attempt time",
+			testFn: func(t *testing.T, idx index.CacheIndex) {
+				t.Helper()
+
+				ctx := context.Background()
+				created, err := idx.AddUpload(ctx, index.UploadRecord{
+					Path: "/uploads/retry.bin", Status: index.UploadStatusQueued,
+				})
+				if err != nil {
+					t.Fatalf("AddUpload failed: %v", err)
+				}
+				now := time.Unix(3000, 0).UTC()
+				if _, _, err := idx.ClaimNextUpload(ctx, now, time.Minute); err != nil {
+					t.Fatalf("ClaimNextUpload failed: %v", err)
+				}
+
+				nextAttempt := now.Add(5 * time.Second)
+				rescheduled, err := idx.Reschedule(ctx, created.ID, nextAttempt, "transient error")
+				if err != nil {
+					t.Fatalf("Reschedule returned error: %v", err)
+				}
+				if rescheduled.Status != index.UploadStatusQueued {
+					t.Fatalf("expected status queued after reschedule, got %s", rescheduled.Status)
+				}
+				if !rescheduled.NextAttemptAt.Equal(nextAttempt) {
+					t.Fatalf("expected NextAttemptAt %s, got %s", nextAttempt, rescheduled.NextAttemptAt)
+				}
+				if rescheduled.LastError != "transient error" {
+					t.Fatalf("expected last error recorded")
+				}
+				if !rescheduled.LeaseExpiresAt.IsZero() {
+					t.Fatalf("expected lease to be released on reschedule")
+				}
+
+				if _, ok, err := idx.ClaimNextUpload(ctx, nextAttempt.Add(-time.Second), time.Minute); err != nil || ok {
+					t.Fatalf("expected rescheduled upload to stay ineligible before its NextAttemptAt, ok=%v err=%v", ok, err)
+				}
+				claimedAgain, ok, err := idx.ClaimNextUpload(ctx, nextAttempt, time.Minute)
+				if err != nil || !ok {
+					t.Fatalf("expected rescheduled upload to become claimable at its NextAttemptAt, ok=%v err=%v", ok, err)
+				}
+				if claimedAgain.ID != created.ID {
+					t.Fatalf("expected to claim %s again, got %s", created.ID, claimedAgain.ID)
+				}
+
+				if _, err := idx.Reschedule(ctx, "missing", now, ""); !errors.Is(err, index.ErrNotFound) {
+					t.Fatalf("expected ErrNotFound on missing upload, got %v", err)
+				}
+			},
+		},
+		{
+			name: "renew lease extends an in-progress claim",
+			testFn: func(t *testing.T, idx index.CacheIndex) {
+				t.Helper()
+
+				ctx := context.Background()
+				created, err := idx.AddUpload(ctx, index.UploadRecord{
+					Path: "/uploads/renew.bin", Status: index.UploadStatusQueued,
+				})
+				if err != nil {
+					t.Fatalf("AddUpload failed: %v", err)
+				}
+
+				now := time.Unix(4000, 0).UTC()
+				if _, ok, err := idx.ClaimNextUpload(ctx, now, time.Second); err != nil || !ok {
+					t.Fatalf("expected claim to succeed, ok=%v err=%v", ok, err)
+				}
+
+				later := now.Add(30 * time.Second)
+				renewed, err := idx.RenewLease(ctx, created.ID, later, time.Minute)
+				if err != nil {
+					t.Fatalf("RenewLease returned error: %v", err)
+				}
+				if !renewed.LeaseExpiresAt.Equal(later.Add(time.Minute)) {
+					t.Fatalf("expected lease extended to %s, got %s", later.Add(time.Minute), renewed.LeaseExpiresAt)
+				}
+
+				// The original, un-renewed lease would have expired by now;
+				// renewal must have kept it from being reclaimed.
+				if _, ok, err := idx.ClaimNextUpload(ctx, now.Add(2*time.Second), time.Minute); err != nil || ok {
+					t.Fatalf("expected renewed lease to stay held, ok=%v err=%v", ok, err)
+				}
+
+				if _, err := idx.RenewLease(ctx, "missing", later, time.Minute); !errors.Is(err, index.ErrNotFound) {
+					t.Fatalf("expected ErrNotFound on missing upload, got %v", err)
+				}
+
+				queued, err := idx.AddUpload(ctx, index.UploadRecord{
+					Path: "/uploads/not-claimed.bin", Status: index.UploadStatusQueued,
+				})
+				if err != nil {
+					t.Fatalf("AddUpload failed: %v", err)
+				}
+				if _, err := idx.RenewLease(ctx, queued.ID, later, time.Minute); err == nil {
+					t.Fatalf("expected RenewLease to reject a record that was never claimed")
+				}
+			},
+		},
+		{
+			name: "reap expired leases requeues stranded uploads",
+			testFn: func(t *testing.T, idx index.CacheIndex) {
+				t.Helper()
+
+				ctx := context.Background()
+				created, err := idx.AddUpload(ctx, index.UploadRecord{
+					Path: "/uploads/stranded.bin", Status: index.UploadStatusQueued,
+				})
+				if err != nil {
+					t.Fatalf("AddUpload failed: %v", err)
+				}
+
+				now := time.Unix(5000, 0).UTC()
+				if _, ok, err := idx.ClaimNextUpload(ctx, now, time.Second); err != nil || !ok {
+					t.Fatalf("expected claim to succeed, ok=%v err=%v", ok, err)
+				}
+
+				later := now.Add(time.Hour)
+				reaped, err := idx.ReapExpiredLeases(ctx, later)
+				if err != nil {
+					t.Fatalf("ReapExpiredLeases returned error: %v", err)
+				}
+				if reaped != 1 {
+					t.Fatalf("expected 1 lease reaped, got %d", reaped)
+				}
+
+				uploads, err := idx.ListUploads(ctx)
+				if err != nil {
+					t.Fatalf("ListUploads failed: %v", err)
+				}
+				var found bool
+				for _, rec := range uploads {
+					if rec.ID != created.ID {
+						continue
+					}
+					found = true
+					if rec.Status != index.UploadStatusQueued {
+						t.Fatalf("expected reaped upload to be Queued, got %s", rec.Status)
+					}
+					if !rec.LeaseExpiresAt.IsZero() {
+						t.Fatalf("expected reaped upload's lease to be cleared")
+					}
+				}
+				if !found {
+					t.Fatalf("expected to find upload %s after reaping", created.ID)
+				}
+
+				// Nothing left to reap a second time.
+				reaped, err = idx.ReapExpiredLeases(ctx, later)
+				if err != nil {
+					t.Fatalf("ReapExpiredLeases returned error: %v", err)
+				}
+				if reaped != 0 {
+					t.Fatalf("expected 0 leases reaped on second call, got %d", reaped)
+				}
+			},
+		},
+		{
+			name: "chunks in range finds overlapping entries including one starting before offset",
+			testFn: func(t *testing.T, idx index.CacheIndex) {
+				t.Helper()
+
+				ctx := context.Background()
+				path := "/chunks/big.bin"
+
+				chunks := []index.ChunkMeta{
+					{Offset: 0, Length: 100},
+					{Offset: 100, Length: 100},
+					{Offset: 300, Length: 100},
+				}
+				for _, c := range chunks {
+					if err := idx.PutChunk(ctx, path, c); err != nil {
+						t.Fatalf("PutChunk failed: %v", err)
+					}
+				}
+
+				// [150, 250) overlaps the chunk at 100 (which starts before 150) and
+				// does not reach the chunk at 300.
+				found, err := idx.ChunksInRange(ctx, path, 150, 100)
+				if err != nil {
+					t.Fatalf("ChunksInRange failed: %v", err)
+				}
+				if len(found) != 1 || found[0].Offset != 100 {
+					t.Fatalf("expected [chunk@100], got %+v", found)
+				}
+
+				all, err := idx.ChunksInRange(ctx, path, 0, 400)
+				if err != nil {
+					t.Fatalf("ChunksInRange failed: %v", err)
+				}
+				if len(all) != 3 {
+					t.Fatalf("expected 3 chunks, got %d", len(all))
+				}
+
+				if err := idx.DeleteChunk(ctx, path, 100); err != nil {
+					t.Fatalf("DeleteChunk failed: %v", err)
+				}
+				if err := idx.DeleteChunk(ctx, path, 100); err != nil {
+					t.Fatalf("DeleteChunk should be idempotent, got error: %v", err)
+				}
+
+				afterDelete, err := idx.ChunksInRange(ctx, path, 0, 400)
+				if err != nil {
+					t.Fatalf("ChunksInRange failed: %v", err)
+				}
+				if len(afterDelete) != 2 {
+					t.Fatalf("expected 2 chunks after delete, got %d", len(afterDelete))
+				}
+			},
+		},
+		{
+			name: "mark dirty coalesces overlapping and adjacent ranges",
+			testFn: func(t *testing.T, idx index.CacheIndex) {
+				t.Helper()
+
+				ctx := context.Background()
+				meta := sampleMeta("/dirty/a.bin", "etag-a", 4096, time.Unix(20, 0))
+				if err := idx.Put(ctx, meta); err != nil {
+					t.Fatalf("Put failed: %v", err)
+				}
+
+				if _, err := idx.MarkDirty(ctx, meta.Path, 0, 100); err != nil {
+					t.Fatalf("MarkDirty failed: %v", err)
+				}
+				// Overlaps [0,100).
+				if _, err := idx.MarkDirty(ctx, meta.Path, 50, 100); err != nil {
+					t.Fatalf("MarkDirty failed: %v", err)
+				}
+				// Directly abuts [0,150) at offset 150, so it coalesces too.
+				if _, err := idx.MarkDirty(ctx, meta.Path, 150, 50); err != nil {
+					t.Fatalf("MarkDirty failed: %v", err)
 				}
-				if results[0].Path != "/a" || results[1].Path != "/b" {
-					t.Fatalf("expected [/a /b], got [%s %s]", results[0].Path, results[1].Path)
+				// Disjoint range, stays separate.
+				if _, err := idx.MarkDirty(ctx, meta.Path, 1000, 10); err != nil {
+					t.Fatalf("MarkDirty failed: %v", err)
 				}
 
-				if results[0].AtimeLocal.After(results[1].AtimeLocal) {
-					t.Fatalf("expected first result to be least recently used")
+				ranges, err := idx.DirtyRanges(ctx, meta.Path)
+				if err != nil {
+					t.Fatalf("DirtyRanges failed: %v", err)
 				}
+				want := []index.Range{{Offset: 0, Length: 200}, {Offset: 1000, Length: 10}}
+				assertRangesEqual(t, want, ranges)
 			},
 		},
 		{
-			name: "uploads lifecycle",
+			name: "mark dirty coalesces a range spanning multiple prior ranges",
 			testFn: func(t *testing.T, idx index.CacheIndex) {
 				t.Helper()
 
 				ctx := context.Background()
-				upload := index.UploadRecord{
-					Path:   "/uploads/video.mp4",
-					Offset: 0,
-					Length: 64 << 20,
-					Status: index.UploadStatusQueued,
+				meta := sampleMeta("/dirty/b.bin", "etag-b", 4096, time.Unix(21, 0))
+				if err := idx.Put(ctx, meta); err != nil {
+					t.Fatalf("Put failed: %v", err)
 				}
-				created, err := idx.AddUpload(ctx, upload)
-				if err != nil {
-					t.Fatalf("AddUpload failed: %v", err)
+
+				if _, err := idx.MarkDirty(ctx, meta.Path, 0, 10); err != nil {
+					t.Fatalf("MarkDirty failed: %v", err)
 				}
-				if created.ID == "" {
-					t.Fatalf("expected AddUpload to assign ID")
+				if _, err := idx.MarkDirty(ctx, meta.Path, 100, 10); err != nil {
+					t.Fatalf("MarkDirty failed: %v", err)
 				}
-				if created.CreatedAt.IsZero() || created.UpdatedAt.IsZero() {
-					t.Fatalf("expected timestamps set on AddUpload")
+				if _, err := idx.MarkDirty(ctx, meta.Path, 200, 10); err != nil {
+					t.Fatalf("MarkDirty failed: %v", err)
+				}
+				// Spans across all three previously disjoint ranges.
+				if _, err := idx.MarkDirty(ctx, meta.Path, 5, 200); err != nil {
+					t.Fatalf("MarkDirty failed: %v", err)
 				}
 
-				uploads, err := idx.ListUploads(ctx)
+				ranges, err := idx.DirtyRanges(ctx, meta.Path)
 				if err != nil {
-					t.Fatalf("ListUploads failed: %v", err)
-				}
-				if len(uploads) != 1 {
-					t.Fatalf("expected 1 upload, got %d", len(uploads))
-				}
-				if uploads[0].ID != created.ID {
-					t.Fatalf("expected upload ID %s, got %s", created.ID, uploads[0].ID)
+					t.Fatalf("DirtyRanges failed: %v", err)
 				}
+				want := []index.Range{{Offset: 0, Length: 210}}
+				assertRangesEqual(t, want, ranges)
+			},
+		},
+		{
+			name: "mark clean splits a range and clears it after a full flush",
+			testFn: func(t *testing.T, idx index.CacheIndex) {
+				t.Helper()
 
-				progressed, err := idx.UpdateUploadStatus(ctx, created.ID, index.UploadStatusInProgress, "")
-				if err != nil {
-					t.Fatalf("UpdateUploadStatus failed: %v", err)
+				ctx := context.Background()
+				meta := sampleMeta("/dirty/c.bin", "etag-c", 4096, time.Unix(22, 0))
+				if err := idx.Put(ctx, meta); err != nil {
+					t.Fatalf("Put failed: %v", err)
 				}
-				if progressed.Status != index.UploadStatusInProgress {
-					t.Fatalf("expected status %s, got %s", index.UploadStatusInProgress, progressed.Status)
+
+				if _, err := idx.MarkDirty(ctx, meta.Path, 0, 100); err != nil {
+					t.Fatalf("MarkDirty failed: %v", err)
 				}
-				if progressed.Attempts != 1 {
-					t.Fatalf("expected attempts to increment, got %d", progressed.Attempts)
+				// Clean the middle, leaving two ranges behind.
+				if _, err := idx.MarkClean(ctx, meta.Path, 40, 20); err != nil {
+					t.Fatalf("MarkClean failed: %v", err)
 				}
-
-				failed, err := idx.UpdateUploadStatus(ctx, created.ID, index.UploadStatusFailed, "network err")
+				ranges, err := idx.DirtyRanges(ctx, meta.Path)
 				if err != nil {
-					t.Fatalf("UpdateUploadStatus failed: %v", err)
-				}
-				if failed.Status != index.UploadStatusFailed {
-					t.Fatalf("expected failed status, got %s", failed.Status)
+					t.Fatalf("DirtyRanges failed: %v", err)
 				}
-				if failed.LastError != "network err" {
-					t.Fatalf("expected last error recorded")
+				want := []index.Range{{Offset: 0, Length: 40}, {Offset: 60, Length: 40}}
+				assertRangesEqual(t, want, ranges)
+
+				// Flushing the whole file clears all remaining dirty ranges.
+				if _, err := idx.MarkClean(ctx, meta.Path, 0, 100); err != nil {
+					t.Fatalf("MarkClean failed: %v", err)
 				}
-				if failed.Attempts != 2 {
-					t.Fatalf("expected attempts to increment again, got %d", failed.Attempts)
+				ranges, err = idx.DirtyRanges(ctx, meta.Path)
+				if err != nil {
+					t.Fatalf("DirtyRanges failed: %v", err)
 				}
-				if !failed.UpdatedAt.After(failed.CreatedAt) {
-					t.Fatalf("expected updated timestamp to be newer than created")
+				if len(ranges) != 0 {
+					t.Fatalf("expected no dirty ranges after full flush, got %v", ranges)
 				}
 
-				if _, err := idx.UpdateUploadStatus(ctx, "missing", index.UploadStatusQueued, ""); !errors.Is(err, index.ErrNotFound) {
-					t.Fatalf("expected ErrNotFound on missing upload, got %v", err)
+				if _, err := idx.MarkDirty(ctx, "/missing", 0, 1); !errors.Is(err, index.ErrNotFound) {
+					t.Fatalf("expected ErrNotFound marking dirty on missing path, got %v", err)
 				}
 			},
 		},
@@ -330,6 +1032,19 @@ func assertMetasEqual(t *testing.T, expected, actual index.FileMeta, opts ...cmp
 	}
 }
 
+func assertRangesEqual(t *testing.T, expected, actual []index.Range) {
+	t.Helper()
+
+	if len(expected) != len(actual) {
+		t.Fatalf("dirty ranges mismatch: expected %+v got %+v", expected, actual)
+	}
+	for i := range expected {
+		if expected[i] != actual[i] {
+			t.Fatalf("dirty ranges mismatch: expected %+v got %+v", expected, actual)
+		}
+	}
+}
+
 func sampleMeta(path, etag string, size int64, atime time.Time) index.FileMeta {
 	return index.FileMeta{
 		Path:        path,
@@ -348,17 +1063,61 @@ func sampleMeta(path, etag string, size int64, atime time.Time) index.FileMeta {
 }
 
 type memoryIndex struct {
-	files       map[string]index.FileMeta
-	uploads     map[string]index.UploadRecord
-	uploadOrder []string
-	nextUpload  int
+	mu            sync.Mutex
+	files         map[string]index.FileMeta
+	uploads       map[string]index.UploadRecord
+	uploadOrder   []string
+	nextUpload    int
+	pendingAccess map[string]pendingAccessEntry
+	digests       map[string]string
+	chunks        map[string]map[int64]index.ChunkMeta
+}
+
+type pendingAccessEntry struct {
+	count     int
+	firstSeen time.Time
 }
 
 func newMemoryIndex() *memoryIndex {
 	return &memoryIndex{
-		files:   make(map[string]index.FileMeta),
-		uploads: make(map[string]index.UploadRecord),
+		files:         make(map[string]index.FileMeta),
+		uploads:       make(map[string]index.UploadRecord),
+		pendingAccess: make(map[string]pendingAccessEntry),
+		digests:       make(map[string]string),
+		chunks:        make(map[string]map[int64]index.ChunkMeta),
+	}
+}
+
+// digestMapKey combines digest and length into a single memoryIndex key, so
+// two objects that happen to hash the same but differ in length are never
+// conflated, mirroring bbolt.digestKey.
+func digestMapKey(digest string, length int64) string {
+	return digest + ":" + strconv.FormatInt(length, 10)
+}
+
+func (m *memoryIndex) LookupDigest(ctx context.Context, digest string, length int64) (string, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return "", false, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	etag, ok := m.digests[digestMapKey(digest, length)]
+	return etag, ok, nil
+}
+
+func (m *memoryIndex) RecordDigest(ctx context.Context, digest string, length int64, etag string) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
+	if digest == "" {
+		return errors.New("digest must not be empty")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.digests[digestMapKey(digest, length)] = etag
+	return nil
 }
 
 func (m *memoryIndex) Close() error {
@@ -366,6 +1125,9 @@ func (m *memoryIndex) Close() error {
 }
 
 func (m *memoryIndex) Put(ctx context.Context, meta index.FileMeta) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if meta.Path == "" {
 		return errors.New("path must not be empty")
 	}
@@ -380,16 +1142,23 @@ func (m *memoryIndex) Put(ctx context.Context, meta index.FileMeta) error {
 }
 
 func (m *memoryIndex) Get(ctx context.Context, path string) (index.FileMeta, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	meta, ok := m.files[path]
 	if !ok {
 		return index.FileMeta{}, index.ErrNotFound
 	}
 	meta.AtimeLocal = time.Now().UTC()
+	meta.HitCount++
 	m.files[path] = cloneMeta(meta)
 	return cloneMeta(meta), nil
 }
 
 func (m *memoryIndex) Update(ctx context.Context, path string, fn func(index.FileMeta) (index.FileMeta, error)) (index.FileMeta, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	current, ok := m.files[path]
 	if !ok {
 		return index.FileMeta{}, index.ErrNotFound
@@ -409,11 +1178,17 @@ func (m *memoryIndex) Update(ctx context.Context, path string, fn func(index.Fil
 }
 
 func (m *memoryIndex) Delete(ctx context.Context, path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	delete(m.files, path)
 	return nil
 }
 
 func (m *memoryIndex) ListLRU(ctx context.Context, limit int) ([]index.FileMeta, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	items := make([]index.FileMeta, 0, len(m.files))
 	for _, meta := range m.files {
 		items = append(items, cloneMeta(meta))
@@ -430,7 +1205,152 @@ func (m *memoryIndex) ListLRU(ctx context.Context, limit int) ([]index.FileMeta,
 	return items, nil
 }
 
+func (m *memoryIndex) ListOlderThan(ctx context.Context, cutoff time.Time) ([]index.FileMeta, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	items := make([]index.FileMeta, 0)
+	for _, meta := range m.files {
+		if meta.AtimeLocal.Before(cutoff) {
+			items = append(items, cloneMeta(meta))
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].AtimeLocal.Before(items[j].AtimeLocal)
+	})
+
+	return items, nil
+}
+
+func (m *memoryIndex) RecordPendingAccess(ctx context.Context, path string, now time.Time) (int, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.pendingAccess[path]
+	if !ok {
+		entry = pendingAccessEntry{firstSeen: now}
+	}
+	entry.count++
+	m.pendingAccess[path] = entry
+
+	return entry.count, entry.firstSeen, nil
+}
+
+func (m *memoryIndex) ClearPendingAccess(ctx context.Context, path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.pendingAccess, path)
+	return nil
+}
+
+func (m *memoryIndex) PurgeExpiredPendingAccess(ctx context.Context, cutoff time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := 0
+	for path, entry := range m.pendingAccess {
+		if entry.firstSeen.Before(cutoff) {
+			delete(m.pendingAccess, path)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (m *memoryIndex) MarkDirty(ctx context.Context, path string, offset, length int64) (index.FileMeta, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	meta, ok := m.files[path]
+	if !ok {
+		return index.FileMeta{}, index.ErrNotFound
+	}
+	meta.DirtyRanges = index.MergeRanges(meta.DirtyRanges, index.Range{Offset: offset, Length: length})
+	m.files[path] = cloneMeta(meta)
+	return cloneMeta(meta), nil
+}
+
+func (m *memoryIndex) MarkClean(ctx context.Context, path string, offset, length int64) (index.FileMeta, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	meta, ok := m.files[path]
+	if !ok {
+		return index.FileMeta{}, index.ErrNotFound
+	}
+	meta.DirtyRanges = index.SubtractRange(meta.DirtyRanges, index.Range{Offset: offset, Length: length})
+	m.files[path] = cloneMeta(meta)
+	return cloneMeta(meta), nil
+}
+
+func (m *memoryIndex) DirtyRanges(ctx context.Context, path string) ([]index.Range, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	meta, ok := m.files[path]
+	if !ok {
+		return nil, index.ErrNotFound
+	}
+	return append([]index.Range(nil), meta.DirtyRanges...), nil
+}
+
+func (m *memoryIndex) PutChunk(ctx context.Context, path string, chunk index.ChunkMeta) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if path == "" {
+		return errors.New("path must not be empty")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.chunks[path] == nil {
+		m.chunks[path] = make(map[int64]index.ChunkMeta)
+	}
+	m.chunks[path][chunk.Offset] = chunk
+	return nil
+}
+
+func (m *memoryIndex) DeleteChunk(ctx context.Context, path string, offset int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.chunks[path], offset)
+	return nil
+}
+
+func (m *memoryIndex) ChunksInRange(ctx context.Context, path string, offset, length int64) ([]index.ChunkMeta, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if length <= 0 {
+		return nil, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	end := offset + length
+	var result []index.ChunkMeta
+	for _, chunk := range m.chunks[path] {
+		if chunk.Offset < end && chunk.Offset+chunk.Length > offset {
+			result = append(result, chunk)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Offset < result[j].Offset
+	})
+	return result, nil
+}
+
 func (m *memoryIndex) AddUpload(ctx context.Context, entry index.UploadRecord) (index.UploadRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if entry.ID == "" {
 		m.nextUpload++
 		entry.ID = makeUploadID(m.nextUpload)
@@ -446,6 +1366,9 @@ func (m *memoryIndex) AddUpload(ctx context.Context, entry index.UploadRecord) (
 }
 
 func (m *memoryIndex) ListUploads(ctx context.Context) ([]index.UploadRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	items := make([]index.UploadRecord, 0, len(m.uploads))
 	for _, id := range m.uploadOrder {
 		if entry, ok := m.uploads[id]; ok {
@@ -456,6 +1379,9 @@ func (m *memoryIndex) ListUploads(ctx context.Context) ([]index.UploadRecord, er
 }
 
 func (m *memoryIndex) UpdateUploadStatus(ctx context.Context, id string, status index.UploadStatus, lastError string) (index.UploadRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	entry, ok := m.uploads[id]
 	if !ok {
 		return index.UploadRecord{}, index.ErrNotFound
@@ -472,12 +1398,195 @@ func (m *memoryIndex) UpdateUploadStatus(ctx context.Context, id string, status
 	return cloneUpload(entry), nil
 }
 
+func (m *memoryIndex) SetUploadID(ctx context.Context, id string, uploadID string) (index.UploadRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.uploads[id]
+	if !ok {
+		return index.UploadRecord{}, index.ErrNotFound
+	}
+	entry.UploadID = uploadID
+	entry.UpdatedAt = time.Now().UTC()
+	m.uploads[id] = cloneUpload(entry)
+	return cloneUpload(entry), nil
+}
+
+func (m *memoryIndex) SetTusURL(ctx context.Context, id string, tusURL string) (index.UploadRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.uploads[id]
+	if !ok {
+		return index.UploadRecord{}, index.ErrNotFound
+	}
+	entry.TusURL = tusURL
+	entry.UpdatedAt = time.Now().UTC()
+	m.uploads[id] = cloneUpload(entry)
+	return cloneUpload(entry), nil
+}
+
+func (m *memoryIndex) SetPartSize(ctx context.Context, id string, partSize int64) (index.UploadRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.uploads[id]
+	if !ok {
+		return index.UploadRecord{}, index.ErrNotFound
+	}
+	entry.PartSize = partSize
+	entry.UpdatedAt = time.Now().UTC()
+	m.uploads[id] = cloneUpload(entry)
+	return cloneUpload(entry), nil
+}
+
+func (m *memoryIndex) SetRetryDelay(ctx context.Context, id string, delay time.Duration) (index.UploadRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.uploads[id]
+	if !ok {
+		return index.UploadRecord{}, index.ErrNotFound
+	}
+	entry.PrevRetryDelay = delay
+	entry.UpdatedAt = time.Now().UTC()
+	m.uploads[id] = cloneUpload(entry)
+	return cloneUpload(entry), nil
+}
+
+func (m *memoryIndex) UpdateUploadProgress(ctx context.Context, id string, bytesTransferred int64, partETag string) (index.UploadRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.uploads[id]
+	if !ok {
+		return index.UploadRecord{}, index.ErrNotFound
+	}
+	entry.BytesTransferred = bytesTransferred
+	if partETag != "" {
+		entry.PartETags = append(append([]string(nil), entry.PartETags...), partETag)
+	}
+	entry.UpdatedAt = time.Now().UTC()
+	m.uploads[id] = cloneUpload(entry)
+	return cloneUpload(entry), nil
+}
+
+func (m *memoryIndex) ClaimNextUpload(ctx context.Context, now time.Time, leaseDur time.Duration) (index.UploadRecord, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var bestID string
+	var bestDue time.Time
+	found := false
+
+	for _, id := range m.uploadOrder {
+		entry, ok := m.uploads[id]
+		if !ok {
+			continue
+		}
+
+		var due time.Time
+		switch entry.Status {
+		case index.UploadStatusQueued:
+			due = entry.NextAttemptAt
+		case index.UploadStatusInProgress:
+			if entry.LeaseExpiresAt.IsZero() || entry.LeaseExpiresAt.After(now) {
+				continue
+			}
+			due = entry.NextAttemptAt
+		default:
+			continue
+		}
+		if due.After(now) {
+			continue
+		}
+		if !found || due.Before(bestDue) {
+			bestID = id
+			bestDue = due
+			found = true
+		}
+	}
+
+	if !found {
+		return index.UploadRecord{}, false, nil
+	}
+
+	entry := m.uploads[bestID]
+	entry.Status = index.UploadStatusInProgress
+	entry.Attempts++
+	entry.LeaseExpiresAt = now.Add(leaseDur)
+	entry.UpdatedAt = now
+	m.uploads[bestID] = cloneUpload(entry)
+	return cloneUpload(entry), true, nil
+}
+
+func (m *memoryIndex) Reschedule(ctx context.Context, id string, nextAttemptAt time.Time, lastError string) (index.UploadRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.uploads[id]
+	if !ok {
+		return index.UploadRecord{}, index.ErrNotFound
+	}
+	entry.Status = index.UploadStatusQueued
+	entry.NextAttemptAt = nextAttemptAt
+	entry.LastError = lastError
+	entry.LeaseExpiresAt = time.Time{}
+	entry.UpdatedAt = time.Now().UTC()
+	m.uploads[id] = cloneUpload(entry)
+	return cloneUpload(entry), nil
+}
+
+func (m *memoryIndex) RenewLease(ctx context.Context, id string, now time.Time, leaseDur time.Duration) (index.UploadRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.uploads[id]
+	if !ok {
+		return index.UploadRecord{}, index.ErrNotFound
+	}
+	if entry.Status != index.UploadStatusInProgress {
+		return index.UploadRecord{}, fmt.Errorf("cache index: cannot renew lease for upload %s in status %s", id, entry.Status)
+	}
+	entry.LeaseExpiresAt = now.Add(leaseDur)
+	entry.UpdatedAt = now
+	m.uploads[id] = cloneUpload(entry)
+	return cloneUpload(entry), nil
+}
+
+func (m *memoryIndex) ReapExpiredLeases(ctx context.Context, now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reaped := 0
+	for _, id := range m.uploadOrder {
+		entry, ok := m.uploads[id]
+		if !ok || entry.Status != index.UploadStatusInProgress {
+			continue
+		}
+		if entry.LeaseExpiresAt.IsZero() || entry.LeaseExpiresAt.After(now) {
+			continue
+		}
+		entry.Status = index.UploadStatusQueued
+		entry.LeaseExpiresAt = time.Time{}
+		entry.LastError = "lease expired: reclaimed by reaper"
+		entry.UpdatedAt = now
+		m.uploads[id] = cloneUpload(entry)
+		reaped++
+	}
+	return reaped, nil
+}
+
 func cloneMeta(meta index.FileMeta) index.FileMeta {
 	clone := meta
 	if len(meta.Chunks) > 0 {
 		clone.Chunks = make([]index.ChunkMeta, len(meta.Chunks))
 		copy(clone.Chunks, meta.Chunks)
 	}
+	if len(meta.DirtyRanges) > 0 {
+		clone.DirtyRanges = make([]index.Range, len(meta.DirtyRanges))
+		copy(clone.DirtyRanges, meta.DirtyRanges)
+	}
 	return clone
 }
 
@@ -0,0 +1,49 @@
+// Package store selects and constructs a pkg/cache/index.CacheIndex driver
+// by name, similar to how frostfs-node makes its metabase storage engine
+// pluggable. It is a separate package from pkg/cache/index so that package
+// can stay driver-agnostic: index imports nothing from bbolt or badger, and
+// both of those import index, so the factory gluing them together has to
+// live above all three.
+package store
+
+import (
+	"fmt"
+
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
+	"github.com/valandreev/tigrisfs/pkg/cache/index/badger"
+	"github.com/valandreev/tigrisfs/pkg/cache/index/bbolt"
+)
+
+// Driver names a CacheIndex backend implementation.
+type Driver string
+
+const (
+	// DriverBBolt selects pkg/cache/index/bbolt: a single-file B+tree store
+	// with a small footprint, suited to small-to-medium caches. This is the
+	// default.
+	DriverBBolt Driver = "bbolt"
+	// DriverBadger selects pkg/cache/index/badger: an LSM-tree store with
+	// better write throughput and background compaction, suited to large,
+	// high-churn caches at the cost of a bigger on-disk footprint.
+	DriverBadger Driver = "badger"
+)
+
+// Options configures Open. Exactly one of BBolt/Badger is consulted,
+// selected by the driver argument; the other is ignored.
+type Options struct {
+	BBolt  bbolt.Options
+	Badger badger.Options
+}
+
+// Open constructs a CacheIndex at path using the named driver. An empty
+// driver defaults to DriverBBolt.
+func Open(driver Driver, path string, opts Options) (index.CacheIndex, error) {
+	switch driver {
+	case "", DriverBBolt:
+		return bbolt.Open(path, opts.BBolt)
+	case DriverBadger:
+		return badger.Open(path, opts.Badger)
+	default:
+		return nil, fmt.Errorf("cache index: unknown driver %q", driver)
+	}
+}
@@ -0,0 +1,204 @@
+package invalidate_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
+	"github.com/valandreev/tigrisfs/pkg/cache/index/indextest"
+	"github.com/valandreev/tigrisfs/pkg/cache/invalidate"
+)
+
+// fakeNotifyChannel records every NOTIFY_INVAL_ENTRY/NOTIFY_INVAL_INODE push
+// it's asked to make, standing in for the kernel-facing *fuse.Server this
+// tree has no mount path to construct yet.
+type fakeNotifyChannel struct {
+	mu        sync.Mutex
+	entries   []string
+	inodes    []uint64
+	failEntry bool
+	failInode bool
+}
+
+func (f *fakeNotifyChannel) InvalidateEntry(parentIno uint64, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failEntry {
+		return errors.New("fake notify channel: entry invalidation failed")
+	}
+	f.entries = append(f.entries, name)
+	return nil
+}
+
+func (f *fakeNotifyChannel) InvalidateInode(ino uint64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failInode {
+		return errors.New("fake notify channel: inode invalidation failed")
+	}
+	f.inodes = append(f.inodes, ino)
+	return nil
+}
+
+func (f *fakeNotifyChannel) invalidatedEntries() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]string, len(f.entries))
+	copy(out, f.entries)
+	return out
+}
+
+func (f *fakeNotifyChannel) invalidatedInodes() []uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]uint64, len(f.inodes))
+	copy(out, f.inodes)
+	return out
+}
+
+// fakeResolver maps keys to parent/name/ino triples set up by the test.
+type fakeResolver struct {
+	entries map[string]resolvedEntry
+}
+
+type resolvedEntry struct {
+	parentIno uint64
+	name      string
+	ino       uint64
+}
+
+func (r *fakeResolver) ResolveEntry(key string) (uint64, string, uint64, bool) {
+	e, ok := r.entries[key]
+	if !ok {
+		return 0, "", 0, false
+	}
+	return e.parentIno, e.name, e.ino, true
+}
+
+func TestFUSEInvalidatorDropsCacheAndPushesKernelNotifications(t *testing.T) {
+	ctx := context.Background()
+	idx := indextest.MemoryIndexFactory()(t)
+
+	if err := idx.Put(ctx, index.FileMeta{Path: "/objects/a", Size: 3}); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	channel := &fakeNotifyChannel{}
+	resolver := &fakeResolver{entries: map[string]resolvedEntry{
+		"/objects/a": {parentIno: 1, name: "a", ino: 42},
+	}}
+
+	inv, err := invalidate.NewFUSEInvalidator(idx, channel, resolver)
+	if err != nil {
+		t.Fatalf("NewFUSEInvalidator failed: %v", err)
+	}
+
+	if err := inv.Invalidate(ctx, "/objects/a"); err != nil {
+		t.Fatalf("Invalidate failed: %v", err)
+	}
+
+	if _, err := idx.Get(ctx, "/objects/a"); !errors.Is(err, index.ErrNotFound) {
+		t.Fatalf("expected cache entry to be gone, got err=%v", err)
+	}
+	if got := channel.invalidatedEntries(); len(got) != 1 || got[0] != "a" {
+		t.Fatalf("expected entry invalidation for \"a\", got %+v", got)
+	}
+	if got := channel.invalidatedInodes(); len(got) != 1 || got[0] != 42 {
+		t.Fatalf("expected inode invalidation for ino 42, got %+v", got)
+	}
+}
+
+func TestFUSEInvalidatorSkipsNotifyWhenKeyUnresolved(t *testing.T) {
+	ctx := context.Background()
+	idx := indextest.MemoryIndexFactory()(t)
+
+	if err := idx.Put(ctx, index.FileMeta{Path: "/objects/unmapped", Size: 1}); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	channel := &fakeNotifyChannel{}
+	resolver := &fakeResolver{entries: map[string]resolvedEntry{}}
+
+	inv, err := invalidate.NewFUSEInvalidator(idx, channel, resolver)
+	if err != nil {
+		t.Fatalf("NewFUSEInvalidator failed: %v", err)
+	}
+
+	if err := inv.Invalidate(ctx, "/objects/unmapped"); err != nil {
+		t.Fatalf("Invalidate failed: %v", err)
+	}
+
+	if _, err := idx.Get(ctx, "/objects/unmapped"); !errors.Is(err, index.ErrNotFound) {
+		t.Fatalf("expected cache entry to be gone regardless of kernel resolution, got err=%v", err)
+	}
+	if got := channel.invalidatedEntries(); len(got) != 0 {
+		t.Fatalf("expected no entry invalidation for an unresolved key, got %+v", got)
+	}
+}
+
+func TestFUSEInvalidatorSkipsInodeNotifyWhenUnknown(t *testing.T) {
+	ctx := context.Background()
+	idx := indextest.MemoryIndexFactory()(t)
+
+	if err := idx.Put(ctx, index.FileMeta{Path: "/objects/b", Size: 1}); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	channel := &fakeNotifyChannel{}
+	resolver := &fakeResolver{entries: map[string]resolvedEntry{
+		"/objects/b": {parentIno: 1, name: "b", ino: 0},
+	}}
+
+	inv, err := invalidate.NewFUSEInvalidator(idx, channel, resolver)
+	if err != nil {
+		t.Fatalf("NewFUSEInvalidator failed: %v", err)
+	}
+
+	if err := inv.Invalidate(ctx, "/objects/b"); err != nil {
+		t.Fatalf("Invalidate failed: %v", err)
+	}
+	if got := channel.invalidatedEntries(); len(got) != 1 {
+		t.Fatalf("expected entry invalidation still to fire, got %+v", got)
+	}
+	if got := channel.invalidatedInodes(); len(got) != 0 {
+		t.Fatalf("expected no inode invalidation when ino is unknown, got %+v", got)
+	}
+}
+
+func TestFUSEInvalidatorReturnsEntryNotifyError(t *testing.T) {
+	ctx := context.Background()
+	idx := indextest.MemoryIndexFactory()(t)
+
+	if err := idx.Put(ctx, index.FileMeta{Path: "/objects/c", Size: 1}); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	channel := &fakeNotifyChannel{failEntry: true}
+	resolver := &fakeResolver{entries: map[string]resolvedEntry{
+		"/objects/c": {parentIno: 1, name: "c", ino: 7},
+	}}
+
+	inv, err := invalidate.NewFUSEInvalidator(idx, channel, resolver)
+	if err != nil {
+		t.Fatalf("NewFUSEInvalidator failed: %v", err)
+	}
+
+	if err := inv.Invalidate(ctx, "/objects/c"); err == nil {
+		t.Fatalf("expected an error when the notify channel fails")
+	}
+}
+
+func TestNewFUSEInvalidatorRejectsMissingDependencies(t *testing.T) {
+	idx := indextest.MemoryIndexFactory()(t)
+	channel := &fakeNotifyChannel{}
+	resolver := &fakeResolver{entries: map[string]resolvedEntry{}}
+
+	if _, err := invalidate.NewFUSEInvalidator(idx, nil, resolver); err == nil {
+		t.Fatalf("expected error for nil notify channel")
+	}
+	if _, err := invalidate.NewFUSEInvalidator(idx, channel, nil); err == nil {
+		t.Fatalf("expected error for nil inode resolver")
+	}
+}
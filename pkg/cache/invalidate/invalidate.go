@@ -0,0 +1,346 @@
+// Package invalidate drives cache invalidation from a pluggable stream of
+// object-change events (S3 event notifications relayed through SQS, or a
+// Tigris/GCS equivalent) instead of waiting for a user to trigger a refresh
+// by hand. It polls an EventSource for batches of changed keys, coalesces
+// duplicates seen within a short window, and calls an Invalidator for each
+// survivor — the same cache-invalidation step a manual per-path refresh
+// would perform — so a bucket change becomes visible without a round trip
+// through a magic xattr.
+//
+// Invalidator is the boundary Poller depends on; CacheIndexInvalidator below
+// drops the stale entry from the CacheIndex so the next lookup refetches it,
+// and FUSEInvalidator (fuse_invalidator.go) composes that with a push down
+// NotifyChannel so a kernel that already has the entry or its pages cached
+// drops them too, instead of waiting for the next lookup. Either can be
+// passed to New without any change to Poller itself.
+//
+// Note on scope: this tree's FUSE mount layer - the code that would
+// construct a NotifyChannel backed by a real go-fuse *fuse.Server, and the
+// name-cache an InodeResolver would be backed by - isn't present in this
+// snapshot, so NotifyChannel and InodeResolver are the seams a real mount
+// would implement, not wrappers around implementations that already exist
+// here.
+package invalidate
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valandreev/tigrisfs/log"
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
+)
+
+// ChangeType classifies what happened to an object's key.
+type ChangeType int
+
+const (
+	// ChangeUnknown means the source didn't distinguish create from
+	// remove; the event is still worth invalidating on.
+	ChangeUnknown ChangeType = iota
+	ChangeCreatedOrUpdated
+	ChangeRemoved
+)
+
+// Event is a single object-change notification, identified well enough for
+// an EventSource to later acknowledge it.
+type Event struct {
+	// ID identifies this event to the source (e.g. an SQS receipt handle)
+	// so Delete can acknowledge exactly the events that were applied.
+	ID string
+	// Key is the object key that changed, relative to the bucket root.
+	Key    string
+	Change ChangeType
+}
+
+// EventSource is a pluggable source of object-change events. An SQS-backed
+// implementation would long-poll ReceiveMessage and translate S3 event
+// records into Events; tests use a fake.
+type EventSource interface {
+	// Receive returns a batch of pending events (possibly empty) without
+	// blocking past the source's own internal timeout. A non-nil error
+	// means the source is unreachable right now (e.g. the queue can't be
+	// reached), not that any individual event is malformed.
+	Receive(ctx context.Context) ([]Event, error)
+	// Delete acknowledges that events have been applied, so a redelivery
+	// doesn't invalidate the same key again. Implementations should treat
+	// this best-effort: a failure here is logged, not fatal, since the
+	// worst outcome is a harmless duplicate invalidation later.
+	Delete(ctx context.Context, events []Event) error
+}
+
+// Invalidator drops whatever cached state the poller is protecting for a
+// single key, in response to a change event.
+type Invalidator interface {
+	Invalidate(ctx context.Context, key string) error
+}
+
+// FullRefresher performs a full cache refresh, used as a fallback when the
+// EventSource has been unreachable for too long to trust incremental
+// invalidation alone.
+type FullRefresher interface {
+	RefreshAll(ctx context.Context) error
+}
+
+// Logger captures structured output for the poller.
+type Logger interface {
+	Debugf(format string, args ...any)
+	Infof(format string, args ...any)
+	Warnf(format string, args ...any)
+	Errorf(format string, args ...any)
+}
+
+// Config controls poller runtime behaviour.
+type Config struct {
+	// Prefix restricts invalidation to keys with this prefix; empty means
+	// every key is eligible. Applied here in addition to whatever
+	// server-side filter the EventSource itself uses.
+	Prefix string
+	// PollInterval is how long Run waits between successive Receive calls,
+	// whether or not the previous call returned events.
+	PollInterval time.Duration
+	// CoalesceWindow suppresses a repeat Invalidate call for a key that
+	// was already invalidated more recently than this, so a burst of
+	// events for the same hot key (e.g. several PUTs in a row) triggers
+	// one invalidation instead of one per event.
+	CoalesceWindow time.Duration
+	// FallbackAfter is how long the source may go unreachable before Run
+	// falls back to a full refresh via FullRefresher, instead of silently
+	// relying on stale cached data indefinitely.
+	FallbackAfter time.Duration
+}
+
+// Option customises Poller construction.
+type Option func(*Poller)
+
+// WithLogger overrides the default logger.
+func WithLogger(logger Logger) Option {
+	return func(p *Poller) {
+		p.logger = logger
+	}
+}
+
+// WithFallback installs a FullRefresher to call once the EventSource has
+// been unreachable for longer than Config.FallbackAfter. Without one, Run
+// just logs a warning and keeps retrying instead of refreshing.
+func WithFallback(fallback FullRefresher) Option {
+	return func(p *Poller) {
+		p.fallback = fallback
+	}
+}
+
+// Poller polls an EventSource and invalidates cached entries for every
+// changed key it reports.
+type Poller struct {
+	cfg         Config
+	source      EventSource
+	invalidator Invalidator
+	fallback    FullRefresher
+	logger      Logger
+
+	mu           sync.Mutex
+	recentlyDone map[string]time.Time
+}
+
+// New constructs a Poller.
+func New(cfg Config, source EventSource, invalidator Invalidator, opts ...Option) (*Poller, error) {
+	if source == nil {
+		return nil, errors.New("cache invalidate: event source is required")
+	}
+	if invalidator == nil {
+		return nil, errors.New("cache invalidate: invalidator is required")
+	}
+
+	cfg = applyDefaults(cfg)
+
+	p := &Poller{
+		cfg:          cfg,
+		source:       source,
+		invalidator:  invalidator,
+		logger:       defaultLogger(),
+		recentlyDone: make(map[string]time.Time),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.logger == nil {
+		p.logger = defaultLogger()
+	}
+
+	return p, nil
+}
+
+// Run polls for change events and invalidates affected keys until ctx is
+// cancelled.
+func (p *Poller) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.cfg.PollInterval)
+	defer ticker.Stop()
+
+	var lastSuccess time.Time = time.Now()
+	var fallbackTriggered bool
+
+	for {
+		events, err := p.source.Receive(ctx)
+		if err != nil {
+			p.logger.Warnf("cache invalidate: receive failed: %v", err)
+			if !fallbackTriggered && p.fallback != nil && time.Since(lastSuccess) > p.cfg.FallbackAfter {
+				p.logger.Warnf("cache invalidate: event source unreachable for over %s, falling back to a full refresh", p.cfg.FallbackAfter)
+				if refreshErr := p.fallback.RefreshAll(ctx); refreshErr != nil {
+					p.logger.Errorf("cache invalidate: fallback refresh failed: %v", refreshErr)
+				} else {
+					fallbackTriggered = true
+				}
+			}
+		} else {
+			lastSuccess = time.Now()
+			fallbackTriggered = false
+			p.processBatch(ctx, events)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// processBatch invalidates every eligible, non-coalesced key in events and
+// acknowledges the ones it successfully applied.
+func (p *Poller) processBatch(ctx context.Context, events []Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	now := time.Now()
+	var applied []Event
+	seenThisBatch := make(map[string]struct{}, len(events))
+
+	for _, ev := range events {
+		if p.cfg.Prefix != "" && !strings.HasPrefix(ev.Key, p.cfg.Prefix) {
+			applied = append(applied, ev)
+			continue
+		}
+		if _, dup := seenThisBatch[ev.Key]; dup {
+			applied = append(applied, ev)
+			continue
+		}
+		seenThisBatch[ev.Key] = struct{}{}
+
+		if p.recentlyInvalidated(ev.Key, now) {
+			applied = append(applied, ev)
+			continue
+		}
+
+		if err := p.invalidator.Invalidate(ctx, ev.Key); err != nil {
+			p.logger.Errorf("cache invalidate: invalidate %s failed: %v", ev.Key, err)
+			continue
+		}
+		p.markInvalidated(ev.Key, now)
+		applied = append(applied, ev)
+	}
+
+	if len(applied) == 0 {
+		return
+	}
+	if err := p.source.Delete(ctx, applied); err != nil {
+		p.logger.Warnf("cache invalidate: acknowledging %d event(s) failed: %v", len(applied), err)
+	}
+}
+
+// recentlyInvalidated reports whether key was already invalidated more
+// recently than CoalesceWindow, pruning expired entries as it goes so
+// recentlyDone doesn't grow unbounded under a steady stream of distinct keys.
+func (p *Poller) recentlyInvalidated(key string, now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for k, at := range p.recentlyDone {
+		if now.Sub(at) > p.cfg.CoalesceWindow {
+			delete(p.recentlyDone, k)
+		}
+	}
+
+	at, ok := p.recentlyDone[key]
+	return ok && now.Sub(at) <= p.cfg.CoalesceWindow
+}
+
+func (p *Poller) markInvalidated(key string, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.recentlyDone[key] = now
+}
+
+func applyDefaults(cfg Config) Config {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.CoalesceWindow <= 0 {
+		cfg.CoalesceWindow = 2 * time.Second
+	}
+	if cfg.FallbackAfter <= 0 {
+		cfg.FallbackAfter = 5 * time.Minute
+	}
+	return cfg
+}
+
+func defaultLogger() Logger {
+	return logHandleAdapter{handle: log.GetLogger("cache-invalidate")}
+}
+
+type logHandleAdapter struct {
+	handle *log.LogHandle
+}
+
+func (l logHandleAdapter) Debugf(format string, args ...any) {
+	if l.handle != nil {
+		l.handle.Debug().Msgf(format, args...)
+	}
+}
+
+func (l logHandleAdapter) Infof(format string, args ...any) {
+	if l.handle != nil {
+		l.handle.Info().Msgf(format, args...)
+	}
+}
+
+func (l logHandleAdapter) Warnf(format string, args ...any) {
+	if l.handle != nil {
+		l.handle.Warn().Msgf(format, args...)
+	}
+}
+
+func (l logHandleAdapter) Errorf(format string, args ...any) {
+	if l.handle != nil {
+		l.handle.Error().Msgf(format, args...)
+	}
+}
+
+// CacheIndexInvalidator is the Invalidator this tree can actually back: it
+// drops key's FileMeta (and therefore its chunk records) from idx, so the
+// next Get refetches metadata and the next read re-downloads chunks instead
+// of serving what's now known to be stale. It does not (and, without a
+// mount layer, cannot) push a kernel dentry/attr invalidation; wire a
+// FUSE-aware Invalidator in front of this one once that layer exists.
+type CacheIndexInvalidator struct {
+	idx index.CacheIndex
+}
+
+// NewCacheIndexInvalidator constructs a CacheIndexInvalidator.
+func NewCacheIndexInvalidator(idx index.CacheIndex) *CacheIndexInvalidator {
+	return &CacheIndexInvalidator{idx: idx}
+}
+
+// Invalidate deletes key's cached metadata. A key with no cached entry is
+// not an error: it just means there was nothing to invalidate.
+func (c *CacheIndexInvalidator) Invalidate(ctx context.Context, key string) error {
+	err := c.idx.Delete(ctx, key)
+	if err != nil && errors.Is(err, index.ErrNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,294 @@
+package invalidate_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
+	"github.com/valandreev/tigrisfs/pkg/cache/index/indextest"
+	"github.com/valandreev/tigrisfs/pkg/cache/invalidate"
+)
+
+// fakeSource is an EventSource whose Receive pulls from a channel of
+// pre-queued batches, so a test can control exactly what Run observes on
+// each poll without a real queue.
+type fakeSource struct {
+	mu      sync.Mutex
+	batches chan []invalidate.Event
+	failAll bool
+	deleted [][]invalidate.Event
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{batches: make(chan []invalidate.Event, 16)}
+}
+
+func (f *fakeSource) push(events ...invalidate.Event) {
+	f.batches <- events
+}
+
+func (f *fakeSource) Receive(ctx context.Context) ([]invalidate.Event, error) {
+	f.mu.Lock()
+	fail := f.failAll
+	f.mu.Unlock()
+	if fail {
+		return nil, errors.New("fake source: unreachable")
+	}
+	select {
+	case batch := <-f.batches:
+		return batch, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (f *fakeSource) Delete(ctx context.Context, events []invalidate.Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleted = append(f.deleted, events)
+	return nil
+}
+
+func (f *fakeSource) setFail(fail bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failAll = fail
+}
+
+// fakeInvalidator records every key it's asked to invalidate.
+type fakeInvalidator struct {
+	mu   sync.Mutex
+	seen []string
+}
+
+func (f *fakeInvalidator) Invalidate(ctx context.Context, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seen = append(f.seen, key)
+	return nil
+}
+
+func (f *fakeInvalidator) keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]string, len(f.seen))
+	copy(out, f.seen)
+	return out
+}
+
+// fakeFullRefresher counts how many times RefreshAll was called.
+type fakeFullRefresher struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (f *fakeFullRefresher) RefreshAll(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	return nil
+}
+
+func (f *fakeFullRefresher) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("condition not met within %s", timeout)
+}
+
+func TestPollerInvalidatesEventKey(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := newFakeSource()
+	inv := &fakeInvalidator{}
+
+	p, err := invalidate.New(invalidate.Config{PollInterval: 10 * time.Millisecond}, source, inv)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	go func() { _ = p.Run(ctx) }()
+
+	source.push(invalidate.Event{ID: "1", Key: "/objects/a", Change: invalidate.ChangeCreatedOrUpdated})
+
+	waitFor(t, 2*time.Second, func() bool { return len(inv.keys()) == 1 })
+	if got := inv.keys(); len(got) != 1 || got[0] != "/objects/a" {
+		t.Fatalf("expected invalidation of /objects/a, got %+v", got)
+	}
+}
+
+func TestPollerFiltersByPrefix(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := newFakeSource()
+	inv := &fakeInvalidator{}
+
+	p, err := invalidate.New(invalidate.Config{PollInterval: 10 * time.Millisecond, Prefix: "/keep/"}, source, inv)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	go func() { _ = p.Run(ctx) }()
+
+	source.push(
+		invalidate.Event{ID: "1", Key: "/skip/a"},
+		invalidate.Event{ID: "2", Key: "/keep/b"},
+	)
+	// Make sure Run has had a chance to drain the batch before asserting
+	// on the full key list (there's only ever one key to wait for here).
+	waitFor(t, 2*time.Second, func() bool { return len(inv.keys()) == 1 })
+
+	if got := inv.keys(); len(got) != 1 || got[0] != "/keep/b" {
+		t.Fatalf("expected only /keep/b invalidated, got %+v", got)
+	}
+}
+
+func TestPollerCoalescesRepeatedKeyWithinWindow(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := newFakeSource()
+	inv := &fakeInvalidator{}
+
+	p, err := invalidate.New(invalidate.Config{
+		PollInterval:   10 * time.Millisecond,
+		CoalesceWindow: time.Hour,
+	}, source, inv)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	go func() { _ = p.Run(ctx) }()
+
+	source.push(invalidate.Event{ID: "1", Key: "/hot"})
+	waitFor(t, 2*time.Second, func() bool { return len(inv.keys()) == 1 })
+
+	source.push(invalidate.Event{ID: "2", Key: "/hot"})
+	// Give Run several poll cycles to (wrongly, if buggy) re-invalidate.
+	time.Sleep(100 * time.Millisecond)
+
+	if got := inv.keys(); len(got) != 1 {
+		t.Fatalf("expected the repeat within the coalesce window to be suppressed, got %+v", got)
+	}
+}
+
+func TestPollerAcknowledgesAppliedEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := newFakeSource()
+	inv := &fakeInvalidator{}
+
+	p, err := invalidate.New(invalidate.Config{PollInterval: 10 * time.Millisecond}, source, inv)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	go func() { _ = p.Run(ctx) }()
+
+	source.push(invalidate.Event{ID: "1", Key: "/objects/a"})
+	waitFor(t, 2*time.Second, func() bool {
+		source.mu.Lock()
+		defer source.mu.Unlock()
+		return len(source.deleted) == 1
+	})
+}
+
+func TestPollerFallsBackToFullRefreshWhenSourceUnreachable(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := newFakeSource()
+	source.setFail(true)
+	inv := &fakeInvalidator{}
+	fallback := &fakeFullRefresher{}
+
+	p, err := invalidate.New(invalidate.Config{
+		PollInterval:  10 * time.Millisecond,
+		FallbackAfter: 30 * time.Millisecond,
+	}, source, inv, invalidate.WithFallback(fallback))
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	go func() { _ = p.Run(ctx) }()
+
+	waitFor(t, 2*time.Second, func() bool { return fallback.callCount() >= 1 })
+}
+
+func TestPollerSkipsFallbackWhenSourceRecovers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := newFakeSource()
+	inv := &fakeInvalidator{}
+	fallback := &fakeFullRefresher{}
+
+	p, err := invalidate.New(invalidate.Config{
+		PollInterval:  10 * time.Millisecond,
+		FallbackAfter: time.Hour,
+	}, source, inv, invalidate.WithFallback(fallback))
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	go func() { _ = p.Run(ctx) }()
+
+	source.push(invalidate.Event{ID: "1", Key: "/objects/a"})
+	waitFor(t, 2*time.Second, func() bool { return len(inv.keys()) == 1 })
+
+	time.Sleep(50 * time.Millisecond)
+	if fallback.callCount() != 0 {
+		t.Fatalf("expected no fallback refresh while the source is healthy, got %d calls", fallback.callCount())
+	}
+}
+
+func TestNewRejectsMissingDependencies(t *testing.T) {
+	source := newFakeSource()
+	inv := &fakeInvalidator{}
+
+	if _, err := invalidate.New(invalidate.Config{}, nil, inv); err == nil {
+		t.Fatalf("expected error for nil event source")
+	}
+	if _, err := invalidate.New(invalidate.Config{}, source, nil); err == nil {
+		t.Fatalf("expected error for nil invalidator")
+	}
+}
+
+func TestCacheIndexInvalidatorDeletesEntry(t *testing.T) {
+	ctx := context.Background()
+	idx := indextest.MemoryIndexFactory()(t)
+
+	if err := idx.Put(ctx, index.FileMeta{Path: "/objects/a", Size: 3}); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	inv := invalidate.NewCacheIndexInvalidator(idx)
+	if err := inv.Invalidate(ctx, "/objects/a"); err != nil {
+		t.Fatalf("Invalidate failed: %v", err)
+	}
+
+	if _, err := idx.Get(ctx, "/objects/a"); !errors.Is(err, index.ErrNotFound) {
+		t.Fatalf("expected entry to be gone after invalidation, got err=%v", err)
+	}
+}
+
+func TestCacheIndexInvalidatorIgnoresMissingEntry(t *testing.T) {
+	ctx := context.Background()
+	idx := indextest.MemoryIndexFactory()(t)
+
+	inv := invalidate.NewCacheIndexInvalidator(idx)
+	if err := inv.Invalidate(ctx, "/objects/never-existed"); err != nil {
+		t.Fatalf("expected invalidating a missing entry to be a no-op, got %v", err)
+	}
+}
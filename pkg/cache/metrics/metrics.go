@@ -0,0 +1,154 @@
+// Package metrics provides dependency-free collectors for cleaner and
+// failsafe telemetry (eviction counts/bytes, run duration, ENOSPC recovery
+// outcomes, upload pause duration). There is no vendored Prometheus client
+// in this tree, so InMemoryMetrics follows the same pattern as
+// files.InMemoryMetrics: cumulative in-memory counters served as plain text,
+// which can be mounted on an existing debug mux or scraped and relabelled
+// by an external exporter without pulling in a client library here.
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/valandreev/tigrisfs/pkg/cache/cleaner"
+	"github.com/valandreev/tigrisfs/pkg/cache/failsafe"
+)
+
+// InMemoryMetrics implements both cleaner.Metrics and failsafe.Metrics,
+// since in practice a single process wires one collector into both.
+type InMemoryMetrics struct {
+	mu sync.Mutex
+
+	evictions               map[cleaner.EvictionReason]int64
+	evictedBytes            map[cleaner.EvictionReason]int64
+	resetCount              int64
+	resetBytes              int64
+	runCount                map[cleaner.TriggerReason]int64
+	runDurationSum          map[cleaner.TriggerReason]time.Duration
+	emergencyCount          int64
+	capacityNotReducedCount int64
+
+	enospcEvents           int64
+	recoveryCount          map[failsafe.RecoveryOutcome]int64
+	recoveryDurationSum    map[failsafe.RecoveryOutcome]time.Duration
+	uploadPauseCount       int64
+	uploadPauseDurationSum time.Duration
+}
+
+// New constructs an empty InMemoryMetrics.
+func New() *InMemoryMetrics {
+	return &InMemoryMetrics{
+		evictions:           make(map[cleaner.EvictionReason]int64),
+		evictedBytes:        make(map[cleaner.EvictionReason]int64),
+		runCount:            make(map[cleaner.TriggerReason]int64),
+		runDurationSum:      make(map[cleaner.TriggerReason]time.Duration),
+		recoveryCount:       make(map[failsafe.RecoveryOutcome]int64),
+		recoveryDurationSum: make(map[failsafe.RecoveryOutcome]time.Duration),
+	}
+}
+
+// RecordEviction implements cleaner.Metrics.
+func (m *InMemoryMetrics) RecordEviction(reason cleaner.EvictionReason, bytesFreed int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.evictions[reason]++
+	m.evictedBytes[reason] += bytesFreed
+}
+
+// RecordReset implements cleaner.Metrics.
+func (m *InMemoryMetrics) RecordReset(bytesFreed int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.resetCount++
+	m.resetBytes += bytesFreed
+}
+
+// RecordRunDuration implements cleaner.Metrics.
+func (m *InMemoryMetrics) RecordRunDuration(trigger cleaner.TriggerReason, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runCount[trigger]++
+	m.runDurationSum[trigger] += d
+}
+
+// RecordEmergencyTriggered implements cleaner.Metrics.
+func (m *InMemoryMetrics) RecordEmergencyTriggered() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.emergencyCount++
+}
+
+// RecordCapacityNotReduced implements cleaner.Metrics.
+func (m *InMemoryMetrics) RecordCapacityNotReduced() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.capacityNotReducedCount++
+}
+
+// RecordENOSPCEvent implements failsafe.Metrics.
+func (m *InMemoryMetrics) RecordENOSPCEvent() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enospcEvents++
+}
+
+// RecordRecoveryDuration implements failsafe.Metrics.
+func (m *InMemoryMetrics) RecordRecoveryDuration(outcome failsafe.RecoveryOutcome, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recoveryCount[outcome]++
+	m.recoveryDurationSum[outcome] += d
+}
+
+// RecordUploadPauseDuration implements failsafe.Metrics.
+func (m *InMemoryMetrics) RecordUploadPauseDuration(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.uploadPauseCount++
+	m.uploadPauseDurationSum += d
+}
+
+// ServeHTTP dumps cumulative counters as plain text, intended to be mounted
+// at /debug/cache/cleaner alongside files.InMemoryMetrics' /debug/cache/io.
+func (m *InMemoryMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	fmt.Fprintln(w, "# cleaner")
+	fmt.Fprintln(w, "eviction_reason\tcount\tbytes_freed")
+	for reason, count := range m.evictions {
+		fmt.Fprintf(w, "%s\t%d\t%d\n", reason, count, m.evictedBytes[reason])
+	}
+	fmt.Fprintf(w, "reset\t%d\t%d\n", m.resetCount, m.resetBytes)
+	fmt.Fprintf(w, "emergency_triggered\t%d\n", m.emergencyCount)
+	fmt.Fprintf(w, "capacity_not_reduced\t%d\n", m.capacityNotReducedCount)
+	fmt.Fprintln(w, "run_trigger\tcount\tavg_duration")
+	for trigger, count := range m.runCount {
+		avg := time.Duration(0)
+		if count > 0 {
+			avg = m.runDurationSum[trigger] / time.Duration(count)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\n", trigger, count, avg)
+	}
+
+	fmt.Fprintln(w, "# failsafe")
+	fmt.Fprintf(w, "enospc_events\t%d\n", m.enospcEvents)
+	avgPause := time.Duration(0)
+	if m.uploadPauseCount > 0 {
+		avgPause = m.uploadPauseDurationSum / time.Duration(m.uploadPauseCount)
+	}
+	fmt.Fprintf(w, "upload_pauses\t%d\t%s\n", m.uploadPauseCount, avgPause)
+	fmt.Fprintln(w, "recovery_outcome\tcount\tavg_duration")
+	for outcome, count := range m.recoveryCount {
+		avg := time.Duration(0)
+		if count > 0 {
+			avg = m.recoveryDurationSum[outcome] / time.Duration(count)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\n", outcome, count, avg)
+	}
+}
@@ -0,0 +1,339 @@
+// Package reconcile repairs drift between the persistent CacheIndex and the
+// on-disk cache directory left behind by a crash or hard kill during
+// writeback, so the cleaner and fail-safe monitor have reliable ground truth
+// to act on after a restart.
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/valandreev/tigrisfs/log"
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
+)
+
+const defaultInProgressThreshold = 10 * time.Minute
+
+// Logger captures structured output for the reconciler.
+type Logger interface {
+	Debugf(format string, args ...any)
+	Infof(format string, args ...any)
+	Warnf(format string, args ...any)
+	Errorf(format string, args ...any)
+}
+
+// Report summarises the repairs a reconciliation pass made.
+type Report struct {
+	OrphansRemoved int
+	MetaPruned     int
+	ChunksDemoted  int
+	UploadsRequed  int
+	Errors         []string
+}
+
+// Option customises Reconciler construction.
+type Option func(*Reconciler)
+
+// WithLogger overrides the default logger.
+func WithLogger(logger Logger) Option {
+	return func(r *Reconciler) {
+		r.logger = logger
+	}
+}
+
+// WithInProgressThreshold overrides how long an in_progress upload may sit
+// before a restart considers it abandoned and requeues it.
+func WithInProgressThreshold(d time.Duration) Option {
+	return func(r *Reconciler) {
+		r.inProgressThreshold = d
+	}
+}
+
+// Reconciler walks the cache directory and CacheIndex, reconciling one
+// against the other.
+type Reconciler struct {
+	idx                 index.CacheIndex
+	cacheDir            string
+	parallelism         int
+	inProgressThreshold time.Duration
+	logger              Logger
+}
+
+// New constructs a Reconciler. parallelism bounds how many cached files are
+// inspected concurrently during the per-file pass; values <= 0 fall back to 1.
+func New(idx index.CacheIndex, cacheDir string, parallelism int, opts ...Option) (*Reconciler, error) {
+	if idx == nil {
+		return nil, errors.New("cache reconcile: cache index is required")
+	}
+	if cacheDir == "" {
+		return nil, errors.New("cache reconcile: cache directory is required")
+	}
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+
+	r := &Reconciler{
+		idx:                 idx,
+		cacheDir:            cacheDir,
+		parallelism:         parallelism,
+		inProgressThreshold: defaultInProgressThreshold,
+		logger:              defaultLogger(),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.logger == nil {
+		r.logger = defaultLogger()
+	}
+
+	return r, nil
+}
+
+// Run performs a single reconciliation pass and returns a summary of the
+// repairs it made. It never returns an error for per-entry repair failures;
+// those are counted in Report.Errors and logged instead, so one bad entry
+// doesn't abort the whole pass.
+func (r *Reconciler) Run(ctx context.Context) (Report, error) {
+	var report Report
+
+	metas, err := r.idx.ListLRU(ctx, 0)
+	if err != nil {
+		return report, fmt.Errorf("cache reconcile: list index entries: %w", err)
+	}
+
+	onDisk, err := r.walkCacheDir()
+	if err != nil {
+		return report, fmt.Errorf("cache reconcile: walk cache directory: %w", err)
+	}
+
+	indexed := make(map[string]struct{}, len(metas))
+	for _, meta := range metas {
+		indexed[meta.Path] = struct{}{}
+	}
+
+	r.reconcileFiles(ctx, metas, &report)
+	r.removeOrphans(onDisk, indexed, &report)
+	r.requeueStaleUploads(ctx, &report)
+
+	r.logger.Infof("cache reconcile: pruned=%d orphans_removed=%d chunks_demoted=%d uploads_requeued=%d errors=%d",
+		report.MetaPruned, report.OrphansRemoved, report.ChunksDemoted, report.UploadsRequed, len(report.Errors))
+
+	return report, nil
+}
+
+// reconcileFiles checks every indexed FileMeta against the file it points
+// at: entries whose file has vanished are pruned, and Dirty chunks whose
+// range is still an unallocated sparse hole (never actually written, as
+// reported by isSparseHole) are demoted so the uploader doesn't try to ship
+// empty ranges.
+func (r *Reconciler) reconcileFiles(ctx context.Context, metas []index.FileMeta, report *Report) {
+	var mu sync.Mutex
+	sem := make(chan struct{}, r.parallelism)
+	var wg sync.WaitGroup
+
+	for _, meta := range metas {
+		meta := meta
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			fullPath := filepath.Join(r.cacheDir, filepath.FromSlash(meta.Path))
+			file, err := os.Open(fullPath)
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					if delErr := r.idx.Delete(ctx, meta.Path); delErr != nil && !errors.Is(delErr, index.ErrNotFound) {
+						mu.Lock()
+						report.Errors = append(report.Errors, fmt.Sprintf("prune %s: %v", meta.Path, delErr))
+						mu.Unlock()
+						return
+					}
+					mu.Lock()
+					report.MetaPruned++
+					mu.Unlock()
+					return
+				}
+				mu.Lock()
+				report.Errors = append(report.Errors, fmt.Sprintf("stat %s: %v", meta.Path, err))
+				mu.Unlock()
+				return
+			}
+			defer file.Close()
+
+			demoted, errs := demoteZeroDirtyChunks(file, meta.Chunks)
+			if len(errs) > 0 {
+				mu.Lock()
+				for _, e := range errs {
+					report.Errors = append(report.Errors, fmt.Sprintf("check sparse holes %s: %v", meta.Path, e))
+				}
+				mu.Unlock()
+			}
+			if len(demoted) == 0 {
+				return
+			}
+
+			_, err = r.idx.Update(ctx, meta.Path, func(fm index.FileMeta) (index.FileMeta, error) {
+				for _, offset := range demoted {
+					for i := range fm.Chunks {
+						if fm.Chunks[i].Offset == offset {
+							fm.Chunks[i].Dirty = false
+						}
+					}
+				}
+				return fm, nil
+			})
+			if err != nil {
+				mu.Lock()
+				report.Errors = append(report.Errors, fmt.Sprintf("demote %s: %v", meta.Path, err))
+				mu.Unlock()
+				return
+			}
+
+			mu.Lock()
+			report.ChunksDemoted += len(demoted)
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+}
+
+// demoteZeroDirtyChunks returns the offsets of Dirty chunks that are
+// genuinely unwritten sparse holes, meaning the region was never actually
+// written despite being marked dirty (e.g. the process was killed right
+// after planning the chunk layout but before any PATCH/write landed).
+//
+// Whether a range is a hole is decided via isSparseHole (lseek SEEK_HOLE/
+// SEEK_DATA on unix, FSCTL_QUERY_ALLOCATED_RANGES on Windows), not by
+// reading the bytes and checking they're zero: a legitimate write of zeros
+// over data already staged for upload reads identically to an unwritten
+// hole, and demoting that chunk would silently drop it from the dirty set,
+// leaving stale non-zero content on the backend forever. Only the
+// filesystem's own allocation map can tell the two apart.
+func demoteZeroDirtyChunks(file *os.File, chunks []index.ChunkMeta) (demoted []int64, errs []error) {
+	for _, chunk := range chunks {
+		if !chunk.Dirty || chunk.Length <= 0 {
+			continue
+		}
+		hole, err := isSparseHole(file, chunk.Offset, chunk.Length)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("offset %d: %w", chunk.Offset, err))
+			continue
+		}
+		if hole {
+			demoted = append(demoted, chunk.Offset)
+		}
+	}
+	return demoted, errs
+}
+
+// removeOrphans deletes on-disk files that have no corresponding FileMeta
+// entry at all, left behind by a crash between staging data and recording
+// its metadata.
+func (r *Reconciler) removeOrphans(onDisk []string, indexed map[string]struct{}, report *Report) {
+	for _, relPath := range onDisk {
+		if _, ok := indexed[relPath]; ok {
+			continue
+		}
+		fullPath := filepath.Join(r.cacheDir, filepath.FromSlash(relPath))
+		if err := os.Remove(fullPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			report.Errors = append(report.Errors, fmt.Sprintf("remove orphan %s: %v", relPath, err))
+			continue
+		}
+		report.OrphansRemoved++
+	}
+}
+
+// requeueStaleUploads transitions in_progress uploads older than
+// inProgressThreshold back to queued, since the process that was driving
+// them is gone and the uploader's in-memory retry state went with it.
+func (r *Reconciler) requeueStaleUploads(ctx context.Context, report *Report) {
+	uploads, err := r.idx.ListUploads(ctx)
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("list uploads: %v", err))
+		return
+	}
+
+	cutoff := time.Now().Add(-r.inProgressThreshold)
+	for _, upload := range uploads {
+		if upload.Status != index.UploadStatusInProgress || upload.UpdatedAt.After(cutoff) {
+			continue
+		}
+		if _, err := r.idx.UpdateUploadStatus(ctx, upload.ID, index.UploadStatusQueued, ""); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("requeue upload %s: %v", upload.ID, err))
+			continue
+		}
+		report.UploadsRequed++
+	}
+}
+
+// walkCacheDir returns every regular file under cacheDir as a slash-separated
+// path relative to it.
+func (r *Reconciler) walkCacheDir() ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(r.cacheDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, relErr := filepath.Rel(r.cacheDir, p)
+		if relErr != nil {
+			return relErr
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+func defaultLogger() Logger {
+	return logHandleAdapter{handle: log.GetLogger("cache-reconcile")}
+}
+
+type logHandleAdapter struct {
+	handle *log.LogHandle
+}
+
+func (l logHandleAdapter) Debugf(format string, args ...any) {
+	if l.handle != nil {
+		l.handle.Debug().Msgf(format, args...)
+	}
+}
+
+func (l logHandleAdapter) Infof(format string, args ...any) {
+	if l.handle != nil {
+		l.handle.Info().Msgf(format, args...)
+	}
+}
+
+func (l logHandleAdapter) Warnf(format string, args ...any) {
+	if l.handle != nil {
+		l.handle.Warn().Msgf(format, args...)
+	}
+}
+
+func (l logHandleAdapter) Errorf(format string, args ...any) {
+	if l.handle != nil {
+		l.handle.Error().Msgf(format, args...)
+	}
+}
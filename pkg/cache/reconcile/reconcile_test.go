@@ -0,0 +1,220 @@
+package reconcile_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
+	"github.com/valandreev/tigrisfs/pkg/cache/index/indextest"
+	"github.com/valandreev/tigrisfs/pkg/cache/reconcile"
+)
+
+func TestReconcilePrunesMissingFiles(t *testing.T) {
+	ctx := context.Background()
+	cacheDir := t.TempDir()
+	idx := indextest.MemoryIndexFactory()(t)
+
+	if err := idx.Put(ctx, index.FileMeta{Path: "objects/gone.bin", Size: 10}); err != nil {
+		t.Fatalf("put meta: %v", err)
+	}
+
+	r, err := reconcile.New(idx, cacheDir, 2)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+
+	report, err := r.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if report.MetaPruned != 1 {
+		t.Fatalf("expected 1 pruned entry, got %d", report.MetaPruned)
+	}
+
+	if _, err := idx.Get(ctx, "objects/gone.bin"); err == nil {
+		t.Fatalf("expected metadata to be pruned")
+	}
+}
+
+func TestReconcileRemovesOrphanFiles(t *testing.T) {
+	ctx := context.Background()
+	cacheDir := t.TempDir()
+	idx := indextest.MemoryIndexFactory()(t)
+
+	orphanPath := filepath.Join(cacheDir, "objects", "orphan.bin")
+	if err := os.MkdirAll(filepath.Dir(orphanPath), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(orphanPath, []byte("leftover"), 0o644); err != nil {
+		t.Fatalf("write orphan: %v", err)
+	}
+
+	r, err := reconcile.New(idx, cacheDir, 2)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+
+	report, err := r.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if report.OrphansRemoved != 1 {
+		t.Fatalf("expected 1 orphan removed, got %d", report.OrphansRemoved)
+	}
+	if _, err := os.Stat(orphanPath); !os.IsNotExist(err) {
+		t.Fatalf("expected orphan file to be removed, stat err: %v", err)
+	}
+}
+
+func TestReconcileDemotesAllZeroDirtyChunks(t *testing.T) {
+	ctx := context.Background()
+	cacheDir := t.TempDir()
+	idx := indextest.MemoryIndexFactory()(t)
+
+	filePath := filepath.Join(cacheDir, "objects", "sparse.bin")
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	// Truncate extends the file to 16 bytes without writing anything, so the
+	// whole range is a genuine, unallocated sparse hole rather than merely
+	// reading as zero.
+	f, err := os.Create(filePath)
+	if err != nil {
+		t.Fatalf("create sparse file: %v", err)
+	}
+	if err := f.Truncate(16); err != nil {
+		f.Close()
+		t.Fatalf("truncate sparse file: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("close sparse file: %v", err)
+	}
+
+	meta := index.FileMeta{
+		Path: "objects/sparse.bin",
+		Size: 16,
+		Chunks: []index.ChunkMeta{
+			{Offset: 0, Length: 16, Dirty: true},
+		},
+	}
+	if err := idx.Put(ctx, meta); err != nil {
+		t.Fatalf("put meta: %v", err)
+	}
+
+	r, err := reconcile.New(idx, cacheDir, 2)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+
+	report, err := r.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if report.ChunksDemoted != 1 {
+		t.Fatalf("expected 1 chunk demoted, got %d", report.ChunksDemoted)
+	}
+
+	loaded, err := idx.Get(ctx, meta.Path)
+	if err != nil {
+		t.Fatalf("get meta: %v", err)
+	}
+	if loaded.Chunks[0].Dirty {
+		t.Fatalf("expected chunk to be demoted to non-dirty")
+	}
+}
+
+func TestReconcileDoesNotDemoteLegitimateZeroWrite(t *testing.T) {
+	ctx := context.Background()
+	cacheDir := t.TempDir()
+	idx := indextest.MemoryIndexFactory()(t)
+
+	filePath := filepath.Join(cacheDir, "objects", "zero-overwrite.bin")
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	// Unlike the sparse-hole case above, this actually writes the zero
+	// bytes, allocating the range instead of leaving it a hole - the
+	// scenario of an app overwriting already-uploaded data with zeros.
+	if err := os.WriteFile(filePath, make([]byte, 16), 0o644); err != nil {
+		t.Fatalf("write zero-filled file: %v", err)
+	}
+
+	meta := index.FileMeta{
+		Path: "objects/zero-overwrite.bin",
+		Size: 16,
+		Chunks: []index.ChunkMeta{
+			{Offset: 0, Length: 16, Dirty: true},
+		},
+	}
+	if err := idx.Put(ctx, meta); err != nil {
+		t.Fatalf("put meta: %v", err)
+	}
+
+	r, err := reconcile.New(idx, cacheDir, 2)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+
+	report, err := r.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if report.ChunksDemoted != 0 {
+		t.Fatalf("expected no chunks demoted for an actually-written zero range, got %d", report.ChunksDemoted)
+	}
+
+	loaded, err := idx.Get(ctx, meta.Path)
+	if err != nil {
+		t.Fatalf("get meta: %v", err)
+	}
+	if !loaded.Chunks[0].Dirty {
+		t.Fatalf("expected chunk to remain dirty so it still gets uploaded")
+	}
+}
+
+func TestReconcileRequeuesStaleInProgressUploads(t *testing.T) {
+	ctx := context.Background()
+	cacheDir := t.TempDir()
+	idx := indextest.MemoryIndexFactory()(t)
+
+	created, err := idx.AddUpload(ctx, index.UploadRecord{Path: "objects/a.bin", Offset: 0, Length: 10, Status: index.UploadStatusInProgress})
+	if err != nil {
+		t.Fatalf("AddUpload returned error: %v", err)
+	}
+
+	r, err := reconcile.New(idx, cacheDir, 2, reconcile.WithInProgressThreshold(0))
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+
+	// Ensure the upload is observably older than the zero threshold.
+	time.Sleep(time.Millisecond)
+
+	report, err := r.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if report.UploadsRequed != 1 {
+		t.Fatalf("expected 1 upload requeued, got %d", report.UploadsRequed)
+	}
+
+	uploads, err := idx.ListUploads(ctx)
+	if err != nil {
+		t.Fatalf("ListUploads returned error: %v", err)
+	}
+	var found bool
+	for _, u := range uploads {
+		if u.ID == created.ID {
+			found = true
+			if u.Status != index.UploadStatusQueued {
+				t.Fatalf("expected status queued, got %s", u.Status)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("expected to find requeued upload %s", created.ID)
+	}
+}
@@ -0,0 +1,37 @@
+//go:build !windows
+// +build !windows
+
+package reconcile
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// isSparseHole reports whether [offset, offset+length) in file is backed
+// entirely by a filesystem hole, via lseek(SEEK_DATA) rather than inspecting
+// the bytes themselves: a legitimate write of zeros over already-allocated
+// data reads identically to an unwritten sparse region, and only
+// SEEK_HOLE/SEEK_DATA can tell the two apart.
+func isSparseHole(file *os.File, offset, length int64) (bool, error) {
+	if length <= 0 {
+		return true, nil
+	}
+
+	dataOffset, err := unix.Seek(int(file.Fd()), offset, unix.SEEK_DATA)
+	if err != nil {
+		if errors.Is(err, unix.ENXIO) {
+			// No data extent anywhere from offset to EOF: the whole range
+			// is a hole.
+			return true, nil
+		}
+		return false, err
+	}
+
+	// dataOffset is the start of the next data extent at or after offset;
+	// the queried range is entirely a hole only if that extent starts at or
+	// past the end of it.
+	return dataOffset >= offset+length, nil
+}
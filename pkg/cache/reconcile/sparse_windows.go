@@ -0,0 +1,49 @@
+//go:build windows
+// +build windows
+
+package reconcile
+
+import (
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// fileAllocatedRangeBuffer mirrors the Win32 FILE_ALLOCATED_RANGE_BUFFER
+// struct FSCTL_QUERY_ALLOCATED_RANGES takes as both its input query range
+// and its output allocated-range records.
+type fileAllocatedRangeBuffer struct {
+	FileOffset int64
+	Length     int64
+}
+
+// isSparseHole reports whether [offset, offset+length) in file has no
+// allocated ranges anywhere within it, via FSCTL_QUERY_ALLOCATED_RANGES —
+// the same distinction SEEK_HOLE/SEEK_DATA makes on unix: a legitimate
+// write of zeros over already-allocated data reads identically to an
+// unwritten sparse region, and only querying the filesystem's own
+// allocation map can tell the two apart.
+func isSparseHole(file *os.File, offset, length int64) (bool, error) {
+	if length <= 0 {
+		return true, nil
+	}
+
+	query := fileAllocatedRangeBuffer{FileOffset: offset, Length: length}
+	var out fileAllocatedRangeBuffer
+	handle := windows.Handle(file.Fd())
+	var bytesReturned uint32
+	err := windows.DeviceIoControl(handle, windows.FSCTL_QUERY_ALLOCATED_RANGES,
+		(*byte)(unsafe.Pointer(&query)), uint32(unsafe.Sizeof(query)),
+		(*byte)(unsafe.Pointer(&out)), uint32(unsafe.Sizeof(out)), &bytesReturned, nil)
+	if err != nil {
+		if err == windows.ERROR_MORE_DATA {
+			// At least one allocated range intersects the query; that's
+			// enough to know the range isn't entirely a hole.
+			return false, nil
+		}
+		return false, err
+	}
+	// bytesReturned is 0 when no allocated range intersects the query.
+	return bytesReturned == 0, nil
+}
@@ -0,0 +1,488 @@
+// Package tus implements a minimal tus 1.0.0 resumable upload server (Core,
+// Creation, Checksum and Termination extensions) that lands uploaded bytes
+// directly into the on-disk cache and the background upload queue, so large
+// objects can be pushed into a mounted bucket without going through FUSE.
+package tus
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valandreev/tigrisfs/log"
+	"github.com/valandreev/tigrisfs/pkg/cache/files"
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
+)
+
+// protocolVersion is the tus protocol version this server implements.
+const protocolVersion = "1.0.0"
+
+// extensions advertised in the Tus-Extension header.
+const extensions = "creation,checksum,termination"
+
+var (
+	// ErrUploadNotFound is returned when a request references an upload ID
+	// the server has no record of.
+	ErrUploadNotFound = errors.New("tus: upload not found")
+	// ErrOffsetMismatch is returned when a PATCH's Upload-Offset header does
+	// not match the server's recorded offset for the upload.
+	ErrOffsetMismatch = errors.New("tus: offset mismatch")
+	// ErrChecksumMismatch is returned when an Upload-Checksum header does not
+	// match the bytes received in a PATCH request body.
+	ErrChecksumMismatch = errors.New("tus: checksum mismatch")
+)
+
+// Logger captures structured output for the tus server.
+type Logger interface {
+	Debugf(format string, args ...any)
+	Infof(format string, args ...any)
+	Warnf(format string, args ...any)
+	Errorf(format string, args ...any)
+}
+
+// Option customises Server construction.
+type Option func(*Server)
+
+// WithLogger overrides the default logger.
+func WithLogger(logger Logger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// Server implements the tus HTTP protocol against a CacheIndex and the cache
+// directory. Each upload is addressed by an opaque ID minted on creation and
+// mapped to a path inside cacheDir.
+type Server struct {
+	idx       index.CacheIndex
+	cacheDir  string
+	chunkSize int64
+	logger    Logger
+
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+// session tracks the in-progress state for a single upload. The container is
+// kept open across PATCH requests so bytes can be appended without re-copying
+// the file already staged to disk.
+type session struct {
+	mu        sync.Mutex
+	path      string
+	container *files.Container
+	length    int64
+	offset    int64
+	chunkSize int64
+}
+
+// NewServer constructs a tus Server. chunkMB controls the ChunkMeta layout
+// planned for newly created uploads and should match cache.Config.ChunkMB.
+func NewServer(idx index.CacheIndex, cacheDir string, chunkMB int, opts ...Option) (*Server, error) {
+	if idx == nil {
+		return nil, errors.New("tus: cache index is required")
+	}
+	if cacheDir == "" {
+		return nil, errors.New("tus: cache directory is required")
+	}
+	if chunkMB <= 0 {
+		return nil, errors.New("tus: chunk size must be > 0")
+	}
+
+	s := &Server{
+		idx:       idx,
+		cacheDir:  cacheDir,
+		chunkSize: int64(chunkMB) * 1024 * 1024,
+		logger:    defaultLogger(),
+		sessions:  make(map[string]*session),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.logger == nil {
+		s.logger = defaultLogger()
+	}
+
+	return s, nil
+}
+
+// ServeHTTP dispatches tus requests. Callers typically mount it under a
+// dedicated prefix (e.g. "/tus/") on the address configured for ingest.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", protocolVersion)
+
+	switch r.Method {
+	case http.MethodOptions:
+		s.handleOptions(w)
+	case http.MethodPost:
+		s.handleCreate(w, r)
+	case http.MethodHead:
+		s.handleHead(w, r)
+	case http.MethodPatch:
+		s.handlePatch(w, r)
+	case http.MethodDelete:
+		s.handleTerminate(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleOptions(w http.ResponseWriter) {
+	w.Header().Set("Tus-Version", protocolVersion)
+	w.Header().Set("Tus-Extension", extensions)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleCreate implements POST: it plans a FileMeta chunk layout for
+// Upload-Length, allocates a sparse staging file, and returns the new
+// resource's Location.
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	length, err := parseInt64Header(r.Header, "Upload-Length")
+	if err != nil || length < 0 {
+		http.Error(w, "missing or invalid Upload-Length", http.StatusBadRequest)
+		return
+	}
+
+	relPath, err := uploadPathFromMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	meta := index.FileMeta{
+		Path:   relPath,
+		Size:   length,
+		Chunks: plannedChunks(length, s.chunkSize),
+	}
+	if err := s.idx.Put(r.Context(), meta); err != nil {
+		s.logger.Errorf("tus: put initial metadata for %s failed: %v", relPath, err)
+		http.Error(w, "failed to create upload", http.StatusInternalServerError)
+		return
+	}
+
+	var containerOpts []files.Option
+	if store, ok := s.idx.(files.RangeStore); ok {
+		containerOpts = append(containerOpts, files.WithRangeStore(store))
+	}
+	container, err := files.OpenContainer(filepath.Join(s.cacheDir, filepath.FromSlash(relPath)), containerOpts...)
+	if err != nil {
+		s.logger.Errorf("tus: open container for %s failed: %v", relPath, err)
+		http.Error(w, "failed to allocate storage", http.StatusInternalServerError)
+		return
+	}
+	if err := container.Truncate(length); err != nil {
+		s.logger.Errorf("tus: preallocate %s failed: %v", relPath, err)
+		http.Error(w, "failed to allocate storage", http.StatusInternalServerError)
+		return
+	}
+
+	id := newUploadID()
+	s.mu.Lock()
+	s.sessions[id] = &session{path: relPath, container: container, length: length, chunkSize: s.chunkSize}
+	s.mu.Unlock()
+
+	w.Header().Set("Location", id)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// handleHead implements HEAD: it reports the offset the client should resume
+// from, answered from the in-process session's memory. An ID with no
+// in-process session (including one created before a restart) gets 404; the
+// offset is not reconstructed from the persisted chunk layout.
+func (s *Server) handleHead(w http.ResponseWriter, r *http.Request) {
+	id := uploadIDFromPath(r.URL.Path)
+	sess := s.lookupSession(id)
+	if sess != nil {
+		sess.mu.Lock()
+		offset, length := sess.offset, sess.length
+		sess.mu.Unlock()
+		w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+		w.Header().Set("Upload-Length", strconv.FormatInt(length, 10))
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	http.Error(w, ErrUploadNotFound.Error(), http.StatusNotFound)
+}
+
+// handlePatch implements PATCH: it appends body bytes at Upload-Offset,
+// verifies the Upload-Checksum extension header when present, and records a
+// queued UploadRecord for every chunk the write completes.
+func (s *Server) handlePatch(w http.ResponseWriter, r *http.Request) {
+	id := uploadIDFromPath(r.URL.Path)
+	sess := s.lookupSession(id)
+	if sess == nil {
+		http.Error(w, ErrUploadNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	offset, err := parseInt64Header(r.Header, "Upload-Offset")
+	if err != nil {
+		http.Error(w, "missing or invalid Upload-Offset", http.StatusBadRequest)
+		return
+	}
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	if offset != sess.offset {
+		http.Error(w, ErrOffsetMismatch.Error(), http.StatusConflict)
+		return
+	}
+
+	body := io.Reader(r.Body)
+	var checker hash.Hash
+	var wantSum []byte
+	if header := r.Header.Get("Upload-Checksum"); header != "" {
+		checker, wantSum, err = newChecksumVerifier(header)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = io.TeeReader(body, checker)
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	if checker != nil && !bytesEqual(checker.Sum(nil), wantSum) {
+		http.Error(w, ErrChecksumMismatch.Error(), http.StatusExpectationFailed)
+		return
+	}
+
+	if _, err := sess.container.WriteAt(data, offset); err != nil {
+		s.logger.Errorf("tus: write %s at offset %d failed: %v", sess.path, offset, err)
+		http.Error(w, "failed to persist upload", http.StatusInternalServerError)
+		return
+	}
+
+	newOffset := offset + int64(len(data))
+	if err := s.completeChunks(r.Context(), sess, sess.offset, newOffset); err != nil {
+		s.logger.Errorf("tus: queue completed chunks for %s failed: %v", sess.path, err)
+		http.Error(w, "failed to queue upload", http.StatusInternalServerError)
+		return
+	}
+	sess.offset = newOffset
+
+	if sess.offset >= sess.length {
+		if err := sess.container.Fsync(); err != nil {
+			s.logger.Warnf("tus: fsync %s failed: %v", sess.path, err)
+		}
+		if err := sess.container.Close(); err != nil {
+			s.logger.Errorf("tus: commit %s failed: %v", sess.path, err)
+		}
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(sess.offset, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleTerminate implements DELETE (Termination extension): it abandons an
+// in-progress upload and removes its index entry.
+func (s *Server) handleTerminate(w http.ResponseWriter, r *http.Request) {
+	id := uploadIDFromPath(r.URL.Path)
+
+	s.mu.Lock()
+	sess, ok := s.sessions[id]
+	delete(s.sessions, id)
+	s.mu.Unlock()
+
+	if !ok {
+		http.Error(w, ErrUploadNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	if err := s.idx.Delete(r.Context(), sess.path); err != nil && !errors.Is(err, index.ErrNotFound) {
+		s.logger.Warnf("tus: delete metadata for %s failed: %v", sess.path, err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// completeChunks marks every planned chunk fully covered by [0, newOffset)
+// that wasn't already covered by [0, prevOffset) as Dirty and enqueues a
+// queued UploadRecord for it, so the background uploader picks it up without
+// waiting for the whole object to finish.
+func (s *Server) completeChunks(ctx context.Context, sess *session, prevOffset, newOffset int64) error {
+	updated, err := s.idx.Update(ctx, sess.path, func(meta index.FileMeta) (index.FileMeta, error) {
+		for i := range meta.Chunks {
+			chunkEnd := meta.Chunks[i].Offset + meta.Chunks[i].Length
+			if chunkEnd <= prevOffset || chunkEnd > newOffset {
+				continue
+			}
+			meta.Chunks[i].Dirty = true
+		}
+		return meta, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, chunk := range updated.Chunks {
+		chunkEnd := chunk.Offset + chunk.Length
+		if chunkEnd <= prevOffset || chunkEnd > newOffset || !chunk.Dirty {
+			continue
+		}
+		record := index.UploadRecord{
+			Path:   sess.path,
+			Offset: chunk.Offset,
+			Length: chunk.Length,
+			Status: index.UploadStatusQueued,
+		}
+		if _, err := s.idx.AddUpload(ctx, record); err != nil {
+			return fmt.Errorf("queue chunk at offset %d: %w", chunk.Offset, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) lookupSession(id string) *session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions[id]
+}
+
+func plannedChunks(length, chunkSize int64) []index.ChunkMeta {
+	if length <= 0 {
+		return nil
+	}
+	count := length / chunkSize
+	if length%chunkSize != 0 {
+		count++
+	}
+	chunks := make([]index.ChunkMeta, 0, count)
+	for offset := int64(0); offset < length; offset += chunkSize {
+		size := chunkSize
+		if offset+size > length {
+			size = length - offset
+		}
+		chunks = append(chunks, index.ChunkMeta{Offset: offset, Length: size})
+	}
+	return chunks
+}
+
+func uploadPathFromMetadata(encoded string) (string, error) {
+	for _, pair := range strings.Split(encoded, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, " ", 2)
+		if parts[0] != "filename" || len(parts) != 2 {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return "", fmt.Errorf("tus: invalid Upload-Metadata filename encoding: %w", err)
+		}
+		cleaned := filepath.ToSlash(filepath.Clean(string(decoded)))
+		if cleaned == "" || cleaned == "." || strings.HasPrefix(cleaned, "../") || filepath.IsAbs(cleaned) {
+			return "", fmt.Errorf("tus: invalid target path %q", decoded)
+		}
+		return cleaned, nil
+	}
+	return "", errors.New("tus: Upload-Metadata must include a filename entry")
+}
+
+func newChecksumVerifier(header string) (hash.Hash, []byte, error) {
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) != 2 {
+		return nil, nil, errors.New("tus: invalid Upload-Checksum header")
+	}
+	want, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, fmt.Errorf("tus: invalid Upload-Checksum encoding: %w", err)
+	}
+	switch strings.ToLower(parts[0]) {
+	case "sha1":
+		return sha1.New(), want, nil
+	case "md5":
+		return md5.New(), want, nil
+	default:
+		return nil, nil, fmt.Errorf("tus: unsupported checksum algorithm %q", parts[0])
+	}
+}
+
+func bytesEqual(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func parseInt64Header(header http.Header, name string) (int64, error) {
+	v := header.Get(name)
+	if v == "" {
+		return 0, fmt.Errorf("header %s is required", name)
+	}
+	return strconv.ParseInt(v, 10, 64)
+}
+
+func uploadIDFromPath(p string) string {
+	return path.Base(p)
+}
+
+func newUploadID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return hex.EncodeToString([]byte(time.Now().String()))
+	}
+	return hex.EncodeToString(buf)
+}
+
+func defaultLogger() Logger {
+	return logHandleAdapter{handle: log.GetLogger("cache-tus")}
+}
+
+type logHandleAdapter struct {
+	handle *log.LogHandle
+}
+
+func (l logHandleAdapter) Debugf(format string, args ...any) {
+	if l.handle != nil {
+		l.handle.Debug().Msgf(format, args...)
+	}
+}
+
+func (l logHandleAdapter) Infof(format string, args ...any) {
+	if l.handle != nil {
+		l.handle.Info().Msgf(format, args...)
+	}
+}
+
+func (l logHandleAdapter) Warnf(format string, args ...any) {
+	if l.handle != nil {
+		l.handle.Warn().Msgf(format, args...)
+	}
+}
+
+func (l logHandleAdapter) Errorf(format string, args ...any) {
+	if l.handle != nil {
+		l.handle.Error().Msgf(format, args...)
+	}
+}
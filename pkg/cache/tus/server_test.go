@@ -0,0 +1,114 @@
+package tus_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/valandreev/tigrisfs/pkg/cache/index/indextest"
+	"github.com/valandreev/tigrisfs/pkg/cache/tus"
+)
+
+func newTestServer(t *testing.T) (*tus.Server, string) {
+	t.Helper()
+	cacheDir := t.TempDir()
+	idx := indextest.MemoryIndexFactory()(t)
+	srv, err := tus.NewServer(idx, cacheDir, 1, tus.WithLogger(noopLogger{}))
+	if err != nil {
+		t.Fatalf("NewServer returned error: %v", err)
+	}
+	return srv, cacheDir
+}
+
+type noopLogger struct{}
+
+func (noopLogger) Debugf(string, ...any) {}
+func (noopLogger) Infof(string, ...any)  {}
+func (noopLogger) Warnf(string, ...any)  {}
+func (noopLogger) Errorf(string, ...any) {}
+
+func TestTusCreatePatchHeadLifecycle(t *testing.T) {
+	srv, cacheDir := newTestServer(t)
+
+	createReq := httptest.NewRequest(http.MethodPost, "/", nil)
+	createReq.Header.Set("Upload-Length", "11")
+	createReq.Header.Set("Upload-Metadata", "filename aGVsbG8udHh0")
+	createRec := httptest.NewRecorder()
+	srv.ServeHTTP(createRec, createReq)
+
+	if createRec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 Created, got %d: %s", createRec.Code, createRec.Body.String())
+	}
+	id := createRec.Header().Get("Location")
+	if id == "" {
+		t.Fatalf("expected Location header with upload id")
+	}
+
+	patchReq := httptest.NewRequest(http.MethodPatch, "/"+id, strings.NewReader("hello world"))
+	patchReq.Header.Set("Upload-Offset", "0")
+	patchRec := httptest.NewRecorder()
+	srv.ServeHTTP(patchRec, patchReq)
+
+	if patchRec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 No Content, got %d: %s", patchRec.Code, patchRec.Body.String())
+	}
+	if got := patchRec.Header().Get("Upload-Offset"); got != "11" {
+		t.Fatalf("expected Upload-Offset 11, got %q", got)
+	}
+
+	data, err := os.ReadFile(filepath.Join(cacheDir, "hello.txt"))
+	if err != nil {
+		t.Fatalf("read committed file: %v", err)
+	}
+	if string(data) != "hello world" {
+		t.Fatalf("expected committed contents %q, got %q", "hello world", string(data))
+	}
+}
+
+func TestTusPatchRejectsOffsetMismatch(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	createReq := httptest.NewRequest(http.MethodPost, "/", nil)
+	createReq.Header.Set("Upload-Length", "11")
+	createReq.Header.Set("Upload-Metadata", "filename aGVsbG8udHh0")
+	createRec := httptest.NewRecorder()
+	srv.ServeHTTP(createRec, createReq)
+	id := createRec.Header().Get("Location")
+
+	patchReq := httptest.NewRequest(http.MethodPatch, "/"+id, strings.NewReader("world"))
+	patchReq.Header.Set("Upload-Offset", "6")
+	patchRec := httptest.NewRecorder()
+	srv.ServeHTTP(patchRec, patchReq)
+
+	if patchRec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 Conflict for offset mismatch, got %d", patchRec.Code)
+	}
+}
+
+func TestTusTerminateRemovesUpload(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	createReq := httptest.NewRequest(http.MethodPost, "/", nil)
+	createReq.Header.Set("Upload-Length", "11")
+	createReq.Header.Set("Upload-Metadata", "filename aGVsbG8udHh0")
+	createRec := httptest.NewRecorder()
+	srv.ServeHTTP(createRec, createReq)
+	id := createRec.Header().Get("Location")
+
+	delReq := httptest.NewRequest(http.MethodDelete, "/"+id, nil)
+	delRec := httptest.NewRecorder()
+	srv.ServeHTTP(delRec, delReq)
+	if delRec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 No Content for termination, got %d", delRec.Code)
+	}
+
+	headReq := httptest.NewRequest(http.MethodHead, "/"+id, nil)
+	headRec := httptest.NewRecorder()
+	srv.ServeHTTP(headRec, headReq)
+	if headRec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 Not Found after termination, got %d", headRec.Code)
+	}
+}
@@ -0,0 +1,260 @@
+// Package upload schedules work from the CacheIndex uploads table onto a
+// Dispatcher, using CacheIndex.ClaimNextUpload so multiple worker goroutines
+// (or multiple nodes sharing the same persistent index) can drain the queue
+// without double-dispatching the same record.
+package upload
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/valandreev/tigrisfs/log"
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
+)
+
+// Dispatcher executes a claimed upload. A non-nil error causes the record to
+// be rescheduled (or marked Failed once its attempt budget is exhausted).
+type Dispatcher func(ctx context.Context, record index.UploadRecord) error
+
+// Logger captures structured output for the scheduler.
+type Logger interface {
+	Debugf(format string, args ...any)
+	Infof(format string, args ...any)
+	Warnf(format string, args ...any)
+	Errorf(format string, args ...any)
+}
+
+// Config controls scheduler runtime behaviour.
+type Config struct {
+	// Parallelism bounds how many claimed uploads are dispatched concurrently.
+	Parallelism int
+	// PollInterval is how often the scheduler polls for claimable work when
+	// the previous poll found nothing due.
+	PollInterval time.Duration
+	// LeaseDuration is how long a claimed record is held InProgress before
+	// another worker is allowed to reclaim it.
+	LeaseDuration time.Duration
+	// ReapInterval is how often Run calls CacheIndex.ReapExpiredLeases to
+	// requeue records whose worker crashed mid-upload, instead of leaving
+	// them to be noticed only when another ClaimNextUpload happens to land
+	// on them.
+	ReapInterval time.Duration
+	// MaxAttempts bounds retries for records that don't set their own
+	// UploadRecord.MaxAttempts.
+	MaxAttempts int
+	// BaseRetryDelay and MaxRetryDelay bound the exponential backoff applied
+	// between a failed attempt and the next claimable NextAttemptAt.
+	BaseRetryDelay time.Duration
+	MaxRetryDelay  time.Duration
+}
+
+// Option customises Scheduler construction.
+type Option func(*Scheduler)
+
+// WithLogger overrides the default logger.
+func WithLogger(logger Logger) Option {
+	return func(s *Scheduler) {
+		s.logger = logger
+	}
+}
+
+// Scheduler polls a CacheIndex uploads table and dispatches due records.
+type Scheduler struct {
+	cfg      Config
+	idx      index.CacheIndex
+	dispatch Dispatcher
+	logger   Logger
+}
+
+// New constructs a Scheduler.
+func New(cfg Config, idx index.CacheIndex, dispatch Dispatcher, opts ...Option) (*Scheduler, error) {
+	if idx == nil {
+		return nil, errors.New("cache upload: cache index is required")
+	}
+	if dispatch == nil {
+		return nil, errors.New("cache upload: dispatcher is required")
+	}
+
+	cfg = applyDefaults(cfg)
+
+	s := &Scheduler{
+		cfg:      cfg,
+		idx:      idx,
+		dispatch: dispatch,
+		logger:   defaultLogger(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.logger == nil {
+		s.logger = defaultLogger()
+	}
+
+	return s, nil
+}
+
+// Run claims and dispatches work until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) error {
+	sem := make(chan struct{}, s.cfg.Parallelism)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	reapTicker := time.NewTicker(s.cfg.ReapInterval)
+	defer reapTicker.Stop()
+
+	for {
+		for {
+			record, ok, err := s.idx.ClaimNextUpload(ctx, time.Now().UTC(), s.cfg.LeaseDuration)
+			if err != nil {
+				s.logger.Warnf("claim next upload failed: %v", err)
+				break
+			}
+			if !ok {
+				break
+			}
+
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(record index.UploadRecord) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				s.process(ctx, record)
+			}(record)
+		}
+
+		// Nothing claimable (or the index errored) right now; wait for the
+		// next poll or reap tick instead of busy-looping.
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-reapTicker.C:
+			s.reapExpiredLeases(ctx)
+		}
+	}
+}
+
+// reapExpiredLeases requeues records whose worker crashed mid-upload
+// (LeaseExpiresAt passed without a completing UpdateUploadStatus or a
+// Reschedule ever landing),
+// so they show up as Queued instead of staying stuck InProgress until
+// another ClaimNextUpload happens to notice and reclaim them.
+func (s *Scheduler) reapExpiredLeases(ctx context.Context) {
+	reaped, err := s.idx.ReapExpiredLeases(ctx, time.Now().UTC())
+	if err != nil {
+		s.logger.Warnf("reap expired leases failed: %v", err)
+		return
+	}
+	if reaped > 0 {
+		s.logger.Infof("reaped %d stranded upload(s) back to queued", reaped)
+	}
+}
+
+func (s *Scheduler) process(ctx context.Context, record index.UploadRecord) {
+	if err := s.dispatch(ctx, record); err != nil {
+		s.retry(ctx, record, err)
+		return
+	}
+	if _, err := s.idx.UpdateUploadStatus(ctx, record.ID, index.UploadStatusComplete, ""); err != nil {
+		s.logger.Errorf("mark upload %s complete failed: %v", record.ID, err)
+	}
+}
+
+func (s *Scheduler) retry(ctx context.Context, record index.UploadRecord, cause error) {
+	maxAttempts := record.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = s.cfg.MaxAttempts
+	}
+	if maxAttempts > 0 && record.Attempts >= maxAttempts {
+		s.logger.Warnf("upload %s exhausted retries: %v", record.ID, cause)
+		if _, err := s.idx.UpdateUploadStatus(ctx, record.ID, index.UploadStatusFailed, cause.Error()); err != nil {
+			s.logger.Errorf("mark upload %s failed: %v", record.ID, err)
+		}
+		return
+	}
+
+	delay := s.backoffDelay(record.Attempts)
+	s.logger.Warnf("upload %s failed, retrying in %s: %v", record.ID, delay, cause)
+	if _, err := s.idx.Reschedule(ctx, record.ID, time.Now().UTC().Add(delay), cause.Error()); err != nil {
+		s.logger.Errorf("reschedule upload %s failed: %v", record.ID, err)
+	}
+}
+
+func (s *Scheduler) backoffDelay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := time.Duration(float64(s.cfg.BaseRetryDelay) * math.Pow(2, float64(attempt-1)))
+	if delay > s.cfg.MaxRetryDelay {
+		return s.cfg.MaxRetryDelay
+	}
+	if delay < s.cfg.BaseRetryDelay {
+		return s.cfg.BaseRetryDelay
+	}
+	return delay
+}
+
+func applyDefaults(cfg Config) Config {
+	if cfg.Parallelism <= 0 {
+		cfg.Parallelism = 2
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 200 * time.Millisecond
+	}
+	if cfg.LeaseDuration <= 0 {
+		cfg.LeaseDuration = 5 * time.Minute
+	}
+	if cfg.ReapInterval <= 0 {
+		cfg.ReapInterval = 5 * cfg.LeaseDuration
+	}
+	if cfg.BaseRetryDelay <= 0 {
+		cfg.BaseRetryDelay = 100 * time.Millisecond
+	}
+	if cfg.MaxRetryDelay <= 0 {
+		cfg.MaxRetryDelay = 5 * time.Second
+	}
+	if cfg.MaxRetryDelay < cfg.BaseRetryDelay {
+		cfg.MaxRetryDelay = cfg.BaseRetryDelay
+	}
+	return cfg
+}
+
+func defaultLogger() Logger {
+	return logHandleAdapter{handle: log.GetLogger("cache-upload-scheduler")}
+}
+
+type logHandleAdapter struct {
+	handle *log.LogHandle
+}
+
+func (l logHandleAdapter) Debugf(format string, args ...any) {
+	if l.handle != nil {
+		l.handle.Debug().Msgf(format, args...)
+	}
+}
+
+func (l logHandleAdapter) Infof(format string, args ...any) {
+	if l.handle != nil {
+		l.handle.Info().Msgf(format, args...)
+	}
+}
+
+func (l logHandleAdapter) Warnf(format string, args ...any) {
+	if l.handle != nil {
+		l.handle.Warn().Msgf(format, args...)
+	}
+}
+
+func (l logHandleAdapter) Errorf(format string, args ...any) {
+	if l.handle != nil {
+		l.handle.Error().Msgf(format, args...)
+	}
+}
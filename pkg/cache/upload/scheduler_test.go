@@ -0,0 +1,151 @@
+package upload_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
+	"github.com/valandreev/tigrisfs/pkg/cache/index/indextest"
+	"github.com/valandreev/tigrisfs/pkg/cache/upload"
+)
+
+func TestSchedulerDispatchesQueuedUpload(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	idx := indextest.MemoryIndexFactory()(t)
+	created, err := idx.AddUpload(ctx, index.UploadRecord{Path: "/uploads/a.bin", Status: index.UploadStatusQueued})
+	if err != nil {
+		t.Fatalf("AddUpload failed: %v", err)
+	}
+
+	var dispatched atomic.Int32
+	var mu sync.Mutex
+	var seenID string
+	done := make(chan struct{})
+
+	sched, err := upload.New(upload.Config{PollInterval: 10 * time.Millisecond}, idx, func(ctx context.Context, record index.UploadRecord) error {
+		dispatched.Add(1)
+		mu.Lock()
+		seenID = record.ID
+		mu.Unlock()
+		close(done)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+
+	go func() { _ = sched.Run(ctx) }()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for dispatch")
+	}
+	cancel()
+
+	if dispatched.Load() != 1 {
+		t.Fatalf("expected exactly 1 dispatch, got %d", dispatched.Load())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if seenID != created.ID {
+		t.Fatalf("expected to dispatch %s, got %s", created.ID, seenID)
+	}
+
+	final, err := idx.ListUploads(context.Background())
+	if err != nil {
+		t.Fatalf("ListUploads failed: %v", err)
+	}
+	if len(final) != 1 || final[0].Status != index.UploadStatusComplete {
+		t.Fatalf("expected upload marked complete, got %+v", final)
+	}
+}
+
+func TestSchedulerReschedulesOnFailureThenSucceeds(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	idx := indextest.MemoryIndexFactory()(t)
+	if _, err := idx.AddUpload(ctx, index.UploadRecord{Path: "/uploads/flaky.bin", Status: index.UploadStatusQueued}); err != nil {
+		t.Fatalf("AddUpload failed: %v", err)
+	}
+
+	var attempts atomic.Int32
+	done := make(chan struct{})
+
+	sched, err := upload.New(upload.Config{
+		PollInterval:   5 * time.Millisecond,
+		BaseRetryDelay: 5 * time.Millisecond,
+		MaxRetryDelay:  10 * time.Millisecond,
+	}, idx, func(ctx context.Context, record index.UploadRecord) error {
+		n := attempts.Add(1)
+		if n == 1 {
+			return errors.New("transient failure")
+		}
+		close(done)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+
+	go func() { _ = sched.Run(ctx) }()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for retry to succeed")
+	}
+	cancel()
+
+	if attempts.Load() < 2 {
+		t.Fatalf("expected at least 2 attempts, got %d", attempts.Load())
+	}
+}
+
+func TestSchedulerFailsPermanentlyAfterMaxAttempts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	idx := indextest.MemoryIndexFactory()(t)
+	created, err := idx.AddUpload(ctx, index.UploadRecord{Path: "/uploads/doomed.bin", Status: index.UploadStatusQueued, MaxAttempts: 1})
+	if err != nil {
+		t.Fatalf("AddUpload failed: %v", err)
+	}
+
+	sched, err := upload.New(upload.Config{
+		PollInterval:   5 * time.Millisecond,
+		BaseRetryDelay: 5 * time.Millisecond,
+	}, idx, func(ctx context.Context, record index.UploadRecord) error {
+		return errors.New("permanent failure")
+	})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+
+	go func() { _ = sched.Run(ctx) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		uploads, err := idx.ListUploads(context.Background())
+		if err != nil {
+			t.Fatalf("ListUploads failed: %v", err)
+		}
+		if len(uploads) == 1 && uploads[0].Status == index.UploadStatusFailed {
+			cancel()
+			if uploads[0].ID != created.ID {
+				t.Fatalf("expected failed record to be %s, got %s", created.ID, uploads[0].ID)
+			}
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	t.Fatalf("expected upload to be marked failed after exhausting MaxAttempts")
+}
@@ -0,0 +1,93 @@
+package uploader
+
+import (
+	"time"
+
+	"github.com/valandreev/tigrisfs/log"
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
+)
+
+// AuditEvent is one structured lifecycle transition reported to an
+// AuditSink, matching the stable schema log.NewAuditLogger's JSON output
+// uses: ts (added by the logger itself), event, upload_id, path, etag,
+// attempt, bytes, duration_ms, reason.
+type AuditEvent struct {
+	Event    string
+	UploadID string
+	Path     string
+	ETag     string
+	Attempt  int
+	Bytes    int64
+	Duration time.Duration
+	Reason   string
+}
+
+// AuditSink receives an AuditEvent for every queued/started/retried/
+// completed/failed transition, alongside the existing Metrics hooks.
+// Metrics is for aggregate counters; AuditSink is for a machine-parsable
+// trail suitable for SIEM ingestion, typically backed by a
+// log.NewAuditLogger writer (see NewZerologAuditSink).
+type AuditSink interface {
+	Audit(event AuditEvent)
+}
+
+// noopAuditSink is the default AuditSink: Uploader always has one to call,
+// the same way it always has a noopMetrics.
+type noopAuditSink struct{}
+
+func (noopAuditSink) Audit(AuditEvent) {}
+
+// zerologAuditSink adapts a *log.LogHandle (normally one built with
+// log.NewAuditLogger, so it always emits JSON) into an AuditSink.
+type zerologAuditSink struct {
+	handle *log.LogHandle
+}
+
+// NewZerologAuditSink returns an AuditSink that logs each AuditEvent as one
+// JSON line via handle, under the field names log.NewAuditLogger documents.
+func NewZerologAuditSink(handle *log.LogHandle) AuditSink {
+	return zerologAuditSink{handle: handle}
+}
+
+// audit reports one lifecycle transition for record to u.auditSink. attempt
+// and reason follow the same conventions the corresponding Metrics call
+// uses; duration is the time since processRecord started handling this
+// attempt, zero for transitions (queued, started) that haven't done any
+// work yet.
+func (u *Uploader) audit(event string, record index.UploadRecord, attempt int, reason string, duration time.Duration) {
+	u.auditSink.Audit(AuditEvent{
+		Event:    event,
+		UploadID: record.ID,
+		Path:     record.Path,
+		ETag:     lastETag(record),
+		Attempt:  attempt,
+		Bytes:    record.BytesTransferred,
+		Duration: duration,
+		Reason:   reason,
+	})
+}
+
+// lastETag returns the most recently checkpointed part ETag for record, or
+// "" if it hasn't completed any part (or isn't a multipart upload) yet.
+func lastETag(record index.UploadRecord) string {
+	if len(record.PartETags) == 0 {
+		return ""
+	}
+	return record.PartETags[len(record.PartETags)-1]
+}
+
+func (s zerologAuditSink) Audit(event AuditEvent) {
+	if s.handle == nil {
+		return
+	}
+	s.handle.Info().
+		Str("event", event.Event).
+		Str("upload_id", event.UploadID).
+		Str("path", event.Path).
+		Str("etag", event.ETag).
+		Int("attempt", event.Attempt).
+		Int64("bytes", event.Bytes).
+		Int64("duration_ms", event.Duration.Milliseconds()).
+		Str("reason", event.Reason).
+		Msg("")
+}
@@ -0,0 +1,163 @@
+package uploader
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// breakerState is one of circuitBreaker's three states, following the
+// standard circuit-breaker pattern: closed lets every upload through open
+// short-circuits them all, and halfOpen lets exactly one probe through to
+// decide whether to close again.
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerClosed:
+		return "closed"
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// circuitBreaker tracks a rolling window of backend.Upload failures and
+// trips open once BreakerThreshold failures land within BreakerWindow,
+// short-circuiting processRecord instead of calling an already-unhealthy
+// backend. A zero threshold disables the breaker entirely: Allow always
+// reports true and RecordFailure/RecordSuccess are no-ops.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+
+	state         breakerState
+	failures      []time.Time
+	openedAt      time.Time
+	probeInFlight bool
+}
+
+// newCircuitBreaker returns a circuitBreaker enforcing threshold failures
+// within window before tripping, staying open for cooldown before allowing
+// a half-open probe. threshold <= 0 disables the breaker.
+func newCircuitBreaker(threshold int, window, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, window: window, cooldown: cooldown}
+}
+
+// Cooldown returns how long processRecord should sleep after Allow refuses
+// a record due to the breaker being open, before requeuing it.
+func (b *circuitBreaker) Cooldown() time.Duration {
+	return b.cooldown
+}
+
+// Allow reports whether an upload attempt should proceed to the backend
+// right now. When open, it reports false until cooldown has elapsed since
+// the trip, at which point it transitions to half-open and allows exactly
+// one caller through as a probe (subsequent concurrent callers are refused
+// until the probe resolves via RecordSuccess/RecordFailure).
+func (b *circuitBreaker) Allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.threshold <= 0 {
+		return true
+	}
+
+	switch b.state {
+	case breakerOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.probeInFlight = true
+		return true
+	case breakerHalfOpen:
+		if b.probeInFlight {
+			return false
+		}
+		b.probeInFlight = true
+		return true
+	default:
+		return true
+	}
+}
+
+// RecordFailure counts a backend failure toward the rolling window,
+// tripping the breaker if it crosses threshold, or reopening it
+// immediately if the failure was the half-open probe.
+func (b *circuitBreaker) RecordFailure(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.threshold <= 0 {
+		return
+	}
+
+	if b.state == breakerHalfOpen {
+		b.trip(now)
+		return
+	}
+
+	b.failures = append(b.failures, now)
+	b.pruneLocked(now)
+	if b.state == breakerClosed && len(b.failures) >= b.threshold {
+		b.trip(now)
+	}
+}
+
+// RecordSuccess clears the failure window and, if this was the half-open
+// probe, closes the breaker.
+func (b *circuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.state = breakerClosed
+	b.probeInFlight = false
+	b.failures = nil
+}
+
+func (b *circuitBreaker) trip(now time.Time) {
+	b.state = breakerOpen
+	b.openedAt = now
+	b.probeInFlight = false
+	b.failures = nil
+}
+
+// pruneLocked drops failures older than window; mu must already be held.
+func (b *circuitBreaker) pruneLocked(now time.Time) {
+	cutoff := now.Add(-b.window)
+	i := 0
+	for i < len(b.failures) && b.failures[i].Before(cutoff) {
+		i++
+	}
+	if i > 0 {
+		b.failures = b.failures[i:]
+	}
+}
+
+// String reports the breaker's current state for DebugString, "disabled"
+// when threshold <= 0.
+func (b *circuitBreaker) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.threshold <= 0 {
+		return "disabled"
+	}
+	return fmt.Sprintf("%s(failures=%d)", b.state, len(b.failures))
+}
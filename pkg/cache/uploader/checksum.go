@@ -0,0 +1,113 @@
+package uploader
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
+)
+
+// PartDescriptor describes one part of a content-addressed multipart
+// upload: its position within the record and the SHA-256 of its bytes.
+type PartDescriptor struct {
+	PartNumber int
+	Offset     int64
+	Length     int64
+	SHA256     string
+}
+
+// PartitionedChunkProvider is optionally implemented by a ChunkProvider that
+// already knows a record's content-addressed part layout and per-part
+// SHA-256 (for example because it hashed each part while writing it to local
+// cache), so uploadResumable can skip re-reading a part just to hash it
+// before upload.
+type PartitionedChunkProvider interface {
+	// Partitions returns record's parts in order. uploadResumable looks up
+	// each part's SHA256 by PartNumber; a provider that can't derive a
+	// digest for a given part should omit it rather than return a wrong one,
+	// so uploadResumable falls back to hashing that part itself.
+	Partitions(ctx context.Context, record index.UploadRecord) ([]PartDescriptor, error)
+}
+
+// ErrChecksumMismatch indicates a part's server-reported checksum didn't
+// match the SHA-256 computed locally before upload. This is distinct from
+// ErrETagMismatch, which means the whole remote object changed underneath a
+// queued upload: a checksum mismatch means a single part was corrupted in
+// transit (or at rest), and processRecord treats it as a non-retryable
+// failure rather than queuing a doomed retry of the same bytes.
+var ErrChecksumMismatch = errors.New("cache uploader: part checksum mismatch")
+
+// ChecksummedMultipartBackend is optionally implemented by a MultipartBackend
+// that can verify part integrity content-addressed: UploadPartChecksummed
+// sends sha256Hex alongside the part body (as Content-MD5/
+// x-amz-checksum-sha256, backend-specific) and returns the checksum the
+// server actually stored the part under, so mismatches surface before
+// CompleteMultipartUpload rather than as a silently corrupted object.
+type ChecksummedMultipartBackend interface {
+	MultipartBackend
+
+	// UploadPartChecksummed uploads data as partNumber, passing sha256Hex
+	// (the hex-encoded SHA-256 of data's bytes) to the backend for transit
+	// verification. serverSHA256 is the checksum the backend reports back
+	// once it has received and verified the part; it may be empty if the
+	// backend has no way to report one, in which case no local mismatch
+	// check is possible beyond whatever the backend itself enforced.
+	UploadPartChecksummed(ctx context.Context, record index.UploadRecord, partNumber int, sha256Hex string, data ReadSeekCloser) (etag, serverSHA256 string, err error)
+}
+
+// checksumMismatchError pairs ErrChecksumMismatch with the part it happened
+// on. It deliberately implements neither Retryable nor Resumable: resending
+// the exact bytes that already failed a checksum won't fix a corrupted
+// local read, so processRecord falls through to its generic failure path.
+type checksumMismatchError struct {
+	partNumber int
+	want, got  string
+}
+
+func (e *checksumMismatchError) Error() string {
+	return fmt.Sprintf("%s: part %d: want %s, got %s", ErrChecksumMismatch, e.partNumber, e.want, e.got)
+}
+
+func (e *checksumMismatchError) Unwrap() error { return ErrChecksumMismatch }
+
+// sha256Hex hashes the entirety of rs and seeks it back to the start, so it
+// can still be uploaded afterward. rs must support Seek(0, io.SeekStart);
+// every ReadSeekCloser returned by a ChunkProvider does.
+func sha256Hex(rs ReadSeekCloser) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, rs); err != nil {
+		return "", fmt.Errorf("hash part: %w", err)
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("rewind part after hash: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// partChecksums asks provider for record's part layout, when it implements
+// PartitionedChunkProvider, and returns the SHA-256 digests it already knows
+// keyed by part number. A nil result (provider doesn't implement the
+// interface, or it errored) just means every part falls back to being hashed
+// on the fly in uploadResumable.
+func partChecksums(ctx context.Context, provider ChunkProvider, record index.UploadRecord, logger Logger) map[int]string {
+	pp, ok := provider.(PartitionedChunkProvider)
+	if !ok {
+		return nil
+	}
+	descriptors, err := pp.Partitions(ctx, record)
+	if err != nil {
+		logger.Warnf("upload %s: partition descriptors unavailable, hashing parts on the fly: %v", record.ID, err)
+		return nil
+	}
+	digests := make(map[int]string, len(descriptors))
+	for _, d := range descriptors {
+		if d.SHA256 != "" {
+			digests[d.PartNumber] = d.SHA256
+		}
+	}
+	return digests
+}
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/valandreev/tigrisfs/pkg/cache/files"
 	"github.com/valandreev/tigrisfs/pkg/cache/index"
 )
 
@@ -16,22 +17,48 @@ import (
 type LocalFileChunkProvider struct {
 	// Root is the base directory that contains cached file data.
 	Root string
+
+	// Category tags reads from this provider for Metrics and Gate, in
+	// terms of the same files.WriteCategory used to label Container I/O.
+	// The zero value is files.CategoryUnspecified.
+	Category files.WriteCategory
+	// Metrics, if set, is told how many bytes were read per OpenChunk
+	// caller, tagged with Category.
+	Metrics files.Metrics
+	// Gate, if set, is consulted before opening a chunk; when it blocks
+	// Category (e.g. the failsafe monitor paused it during ENOSPC
+	// recovery), OpenChunk fails with a retryable error instead of reading.
+	Gate files.WriteGate
 }
 
 // OpenChunk opens a reader for the requested upload record, constrained to the
 // specified offset and length.
 func (p LocalFileChunkProvider) OpenChunk(ctx context.Context, record index.UploadRecord) (ReadSeekCloser, error) {
+	return p.openSection(ctx, record, record.Offset, record.Length)
+}
+
+// OpenPart implements PartProvider, serving exactly partSize bytes starting
+// partOffset into record's data, so a multipart upload resuming past earlier
+// parts doesn't have to open anything before them.
+func (p LocalFileChunkProvider) OpenPart(ctx context.Context, record index.UploadRecord, partNumber int, partOffset, partSize int64) (ReadSeekCloser, error) {
+	return p.openSection(ctx, record, record.Offset+partOffset, partSize)
+}
+
+func (p LocalFileChunkProvider) openSection(ctx context.Context, record index.UploadRecord, offset, length int64) (ReadSeekCloser, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 	if p.Root == "" {
 		return nil, errors.New("chunk provider: root directory is not configured")
 	}
+	if p.Gate != nil && !p.Gate.Allow(p.Category) {
+		return nil, RetryableError{Err: fmt.Errorf("chunk provider: %w", files.ErrCategoryPaused)}
+	}
 	if record.Path == "" {
 		return nil, errors.New("chunk provider: record path is empty")
 	}
-	if record.Offset < 0 {
-		return nil, fmt.Errorf("chunk provider: negative offset %d", record.Offset)
+	if offset < 0 {
+		return nil, fmt.Errorf("chunk provider: negative offset %d", offset)
 	}
 
 	cleanPath := filepath.Clean(record.Path)
@@ -45,22 +72,25 @@ func (p LocalFileChunkProvider) OpenChunk(ctx context.Context, record index.Uplo
 		return nil, fmt.Errorf("chunk provider: open file %q: %w", fullPath, err)
 	}
 
-	length := record.Length
 	if length <= 0 {
 		info, statErr := file.Stat()
 		if statErr != nil {
 			_ = file.Close()
 			return nil, fmt.Errorf("chunk provider: stat file %q: %w", fullPath, statErr)
 		}
-		length = info.Size() - record.Offset
+		length = info.Size() - offset
 		if length < 0 {
 			_ = file.Close()
 			return nil, fmt.Errorf("chunk provider: invalid length derived from file size")
 		}
 	}
 
-	section := io.NewSectionReader(file, record.Offset, length)
-	return &fileSectionReadCloser{SectionReader: section, file: file}, nil
+	section := io.NewSectionReader(file, offset, length)
+	reader := ReadSeekCloser(&fileSectionReadCloser{SectionReader: section, file: file})
+	if p.Metrics != nil {
+		reader = &countingReadSeekCloser{ReadSeekCloser: reader, metrics: p.Metrics, category: p.Category}
+	}
+	return reader, nil
 }
 
 type fileSectionReadCloser struct {
@@ -71,3 +101,19 @@ type fileSectionReadCloser struct {
 func (f *fileSectionReadCloser) Close() error {
 	return f.file.Close()
 }
+
+// countingReadSeekCloser reports every Read through to Metrics, tagged with
+// category, so read-side cache pressure is visible alongside writes.
+type countingReadSeekCloser struct {
+	ReadSeekCloser
+	metrics  files.Metrics
+	category files.WriteCategory
+}
+
+func (c *countingReadSeekCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadSeekCloser.Read(p)
+	if n > 0 {
+		c.metrics.RecordBytesRead(c.category, n)
+	}
+	return n, err
+}
@@ -0,0 +1,132 @@
+package uploader
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrChunkAuthFailed is returned when a cached chunk's AEAD tag does not
+// verify, meaning the on-disk data is corrupt or has been tampered with.
+var ErrChunkAuthFailed = errors.New("cache uploader: chunk authentication failed")
+
+// nonceSize is the AES-GCM standard nonce length in bytes (96 bits).
+const nonceSize = 12
+
+// masterKeySize is the required length of an AES-256 key.
+const masterKeySize = 32
+
+// LoadMasterKey resolves a cache encryption key URI into a raw 32-byte
+// AES-256 key. Supported schemes are file:// (read the key from a file) and
+// env:// (read the key from an environment variable); both expect the key
+// contents to be exactly 32 bytes once trimmed of surrounding whitespace.
+func LoadMasterKey(uri string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(uri, "file://"):
+		data, err := os.ReadFile(strings.TrimPrefix(uri, "file://"))
+		if err != nil {
+			return nil, fmt.Errorf("load master key: %w", err)
+		}
+		return normalizeMasterKey(data)
+	case strings.HasPrefix(uri, "env://"):
+		name := strings.TrimPrefix(uri, "env://")
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("load master key: environment variable %q is not set", name)
+		}
+		return normalizeMasterKey([]byte(value))
+	default:
+		return nil, fmt.Errorf("load master key: unsupported key URI %q", uri)
+	}
+}
+
+func normalizeMasterKey(data []byte) ([]byte, error) {
+	trimmed := strings.TrimSpace(string(data))
+	if len(trimmed) != masterKeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", masterKeySize, len(trimmed))
+	}
+	return []byte(trimmed), nil
+}
+
+// sealContext builds the AEAD associated data binding a seal to the path,
+// offset and key generation it belongs to, so swapping the sealed bytes of
+// one chunk onto another chunk's trailer fails authentication instead of
+// silently decrypting under the wrong context. Unlike the nonce, this does
+// not need to be unique: it is authenticated, not used as cipher state.
+func sealContext(path string, offset int64, generation uint32) []byte {
+	ctx := make([]byte, 0, len(path)+12)
+	ctx = append(ctx, path...)
+	var tmp [8]byte
+	binary.BigEndian.PutUint64(tmp[:], uint64(offset))
+	ctx = append(ctx, tmp[:]...)
+	binary.BigEndian.PutUint32(tmp[:4], generation)
+	ctx = append(ctx, tmp[:4]...)
+	return ctx
+}
+
+// EncryptChunk seals plaintext with AES-256-GCM under a fresh random nonce,
+// returning nonce||ciphertext||tag ready to write to the cache directory
+// as-is. The nonce must be random and unique per seal, not derived from path
+// or generation: a write-back cache reseals the same path under the same
+// generation on every rewrite, and AES-GCM catastrophically leaks both
+// plaintexts (and the authentication key) if the same (key, nonce) pair is
+// ever reused for two different plaintexts. Storing the nonce as a prefix
+// of each seal, rather than recomputing it, is what keeps every seal
+// independent regardless of how many times a path is rewritten.
+func EncryptChunk(masterKey []byte, path string, offset int64, generation uint32, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var nonce [nonceSize]byte
+	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
+		return nil, fmt.Errorf("cache uploader: generate nonce: %w", err)
+	}
+
+	sealed := gcm.Seal(nil, nonce[:], plaintext, sealContext(path, offset, generation))
+	return append(nonce[:], sealed...), nil
+}
+
+// DecryptChunk opens a chunk previously sealed by EncryptChunk, reading the
+// nonce back from its first nonceSize bytes instead of recomputing it. A failure here
+// means the on-disk chunk is corrupt or has been tampered with, and wraps
+// ErrChunkAuthFailed so callers can route it to the fail-safe monitor
+// instead of treating it as a transient I/O error.
+func DecryptChunk(masterKey []byte, path string, offset int64, generation uint32, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < nonceSize {
+		return nil, fmt.Errorf("%w: %s: sealed data shorter than nonce", ErrChunkAuthFailed, path)
+	}
+	nonce, ciphertext := sealed[:nonceSize], sealed[nonceSize:]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, sealContext(path, offset, generation))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrChunkAuthFailed, path, err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(masterKey []byte) (cipher.AEAD, error) {
+	if len(masterKey) != masterKeySize {
+		return nil, fmt.Errorf("cache uploader: master key must be %d bytes, got %d", masterKeySize, len(masterKey))
+	}
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, fmt.Errorf("cache uploader: init cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("cache uploader: init GCM: %w", err)
+	}
+	return gcm, nil
+}
@@ -0,0 +1,144 @@
+package uploader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
+)
+
+func testMasterKey() []byte {
+	return []byte("01234567890123456789012345678901"[:32])
+}
+
+func TestEncryptDecryptChunkRoundTrips(t *testing.T) {
+	key := testMasterKey()
+	plaintext := []byte("the quick brown fox jumps over the lazy dog")
+
+	sealed, err := EncryptChunk(key, "objects/file.bin", 0, 1, plaintext)
+	if err != nil {
+		t.Fatalf("EncryptChunk returned error: %v", err)
+	}
+
+	decrypted, err := DecryptChunk(key, "objects/file.bin", 0, 1, sealed)
+	if err != nil {
+		t.Fatalf("DecryptChunk returned error: %v", err)
+	}
+	if string(decrypted) != string(plaintext) {
+		t.Fatalf("expected %q, got %q", plaintext, decrypted)
+	}
+}
+
+func TestDecryptChunkRejectsTamperedData(t *testing.T) {
+	key := testMasterKey()
+	sealed, err := EncryptChunk(key, "objects/file.bin", 0, 1, []byte("payload"))
+	if err != nil {
+		t.Fatalf("EncryptChunk returned error: %v", err)
+	}
+	sealed[0] ^= 0xff
+
+	_, err = DecryptChunk(key, "objects/file.bin", 0, 1, sealed)
+	if !errors.Is(err, ErrChunkAuthFailed) {
+		t.Fatalf("expected ErrChunkAuthFailed, got %v", err)
+	}
+}
+
+func TestDecryptChunkRejectsWrongGeneration(t *testing.T) {
+	key := testMasterKey()
+	sealed, err := EncryptChunk(key, "objects/file.bin", 0, 1, []byte("payload"))
+	if err != nil {
+		t.Fatalf("EncryptChunk returned error: %v", err)
+	}
+
+	_, err = DecryptChunk(key, "objects/file.bin", 0, 2, sealed)
+	if !errors.Is(err, ErrChunkAuthFailed) {
+		t.Fatalf("expected ErrChunkAuthFailed for mismatched generation, got %v", err)
+	}
+}
+
+func TestLoadMasterKeyFromFile(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "cache.key")
+	if err := os.WriteFile(keyPath, testMasterKey(), 0o600); err != nil {
+		t.Fatalf("write key file: %v", err)
+	}
+
+	key, err := LoadMasterKey("file://" + keyPath)
+	if err != nil {
+		t.Fatalf("LoadMasterKey returned error: %v", err)
+	}
+	if string(key) != string(testMasterKey()) {
+		t.Fatalf("loaded key does not match file contents")
+	}
+}
+
+func TestLoadMasterKeyFromEnv(t *testing.T) {
+	t.Setenv("TIGRISFS_TEST_CACHE_KEY", string(testMasterKey()))
+
+	key, err := LoadMasterKey("env://TIGRISFS_TEST_CACHE_KEY")
+	if err != nil {
+		t.Fatalf("LoadMasterKey returned error: %v", err)
+	}
+	if string(key) != string(testMasterKey()) {
+		t.Fatalf("loaded key does not match environment value")
+	}
+}
+
+func TestLoadMasterKeyRejectsUnknownScheme(t *testing.T) {
+	if _, err := LoadMasterKey("vault://secret/cache-key"); err == nil {
+		t.Fatalf("expected error for unsupported scheme, got nil")
+	}
+}
+
+func TestEncryptingChunkProviderRoundTrips(t *testing.T) {
+	dir := t.TempDir()
+	provider := EncryptingChunkProvider{Root: dir, MasterKey: testMasterKey()}
+
+	if err := provider.EncryptAndWrite("objects/file.bin", 1, []byte("hello world")); err != nil {
+		t.Fatalf("EncryptAndWrite returned error: %v", err)
+	}
+
+	record := index.UploadRecord{Path: "objects/file.bin", Offset: 6, Length: 5, KeyGeneration: 1}
+	chunk, err := provider.OpenChunk(context.Background(), record)
+	if err != nil {
+		t.Fatalf("OpenChunk returned error: %v", err)
+	}
+	defer chunk.Close()
+
+	data, err := io.ReadAll(chunk)
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	if string(data) != "world" {
+		t.Fatalf("expected \"world\", got %q", string(data))
+	}
+}
+
+func TestEncryptingChunkProviderDetectsTampering(t *testing.T) {
+	dir := t.TempDir()
+	provider := EncryptingChunkProvider{Root: dir, MasterKey: testMasterKey()}
+
+	if err := provider.EncryptAndWrite("objects/file.bin", 1, []byte("hello world")); err != nil {
+		t.Fatalf("EncryptAndWrite returned error: %v", err)
+	}
+
+	fullPath := filepath.Join(dir, "objects", "file.bin")
+	sealed, err := os.ReadFile(fullPath)
+	if err != nil {
+		t.Fatalf("read sealed file: %v", err)
+	}
+	sealed[len(sealed)-1] ^= 0xff
+	if err := os.WriteFile(fullPath, sealed, 0o600); err != nil {
+		t.Fatalf("rewrite sealed file: %v", err)
+	}
+
+	record := index.UploadRecord{Path: "objects/file.bin", Offset: 0, Length: 11, KeyGeneration: 1}
+	_, err = provider.OpenChunk(context.Background(), record)
+	if !errors.Is(err, ErrChunkAuthFailed) {
+		t.Fatalf("expected ErrChunkAuthFailed, got %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package uploader
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
+)
+
+// DedupBackend is optionally implemented by a Backend to support
+// content-addressed chunk dedup: before a chunk is uploaded, its payload is
+// hashed and checked against index.CacheIndex's digest map (see
+// index.CacheIndex.LookupDigest/RecordDigest). If a prior upload already put
+// identical content at a still-live object, Head lets the uploader confirm
+// that and skip the PUT entirely.
+//
+// Dedup only applies to the plain Backend.Upload path; MultipartBackend and
+// ResumableBackend already checkpoint progress and have their own resume
+// semantics, so they are dispatched to directly by upload() instead.
+type DedupBackend interface {
+	Backend
+
+	// Head reports whether an object with the given ETag still exists
+	// remotely, so a stale digest->object mapping falls back to a normal
+	// upload instead of silently reusing a deleted object.
+	Head(ctx context.Context, record index.UploadRecord, etag string) (bool, error)
+	// UploadReturningETag behaves exactly like Upload, but additionally
+	// reports the resulting object's ETag, so a successful upload's digest
+	// can be recorded for future dedup lookups.
+	UploadReturningETag(ctx context.Context, record index.UploadRecord, data ReadSeekCloser) (etag string, err error)
+}
+
+// digestFlight lets concurrent workers uploading identical chunk content
+// single-flight the actual PUT: whichever worker claims the digest first
+// uploads it, and the rest wait on that result instead of each paying for
+// their own copy.
+type digestFlight struct {
+	wg sync.WaitGroup
+}
+
+// enterDigestFlight claims digest for the caller. If the caller is the
+// leader, it must call u.leaveDigestFlight(digest) once its upload attempt
+// (successful or not) is done. If not the leader, wait blocks until the
+// leader has done so.
+func (u *Uploader) enterDigestFlight(digest string) (leader bool, wait func()) {
+	u.dedupMu.Lock()
+	defer u.dedupMu.Unlock()
+
+	if u.digestFlights == nil {
+		u.digestFlights = make(map[string]*digestFlight)
+	}
+	if flight, ok := u.digestFlights[digest]; ok {
+		return false, flight.wg.Wait
+	}
+
+	flight := &digestFlight{}
+	flight.wg.Add(1)
+	u.digestFlights[digest] = flight
+	return true, flight.wg.Wait
+}
+
+func (u *Uploader) leaveDigestFlight(digest string) {
+	u.dedupMu.Lock()
+	flight, ok := u.digestFlights[digest]
+	if ok {
+		delete(u.digestFlights, digest)
+	}
+	u.dedupMu.Unlock()
+	if ok {
+		flight.wg.Done()
+	}
+}
+
+// hashChunk streams data through SHA-256 without buffering it whole, then
+// rewinds data to the start so a caller can still upload it afterward.
+func hashChunk(data ReadSeekCloser) (digest string, length int64, err error) {
+	hasher := sha256.New()
+	n, err := io.Copy(hasher, data)
+	if err != nil {
+		return "", 0, fmt.Errorf("hash chunk: %w", err)
+	}
+	if _, err := data.Seek(0, io.SeekStart); err != nil {
+		return "", 0, fmt.Errorf("rewind chunk after hashing: %w", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), n, nil
+}
+
+// tryReuseDigest reports whether digest+length already maps to a live
+// remote object, probing it with Head so a stale mapping (the object was
+// since deleted or overwritten out from under the digest map) falls back to
+// a normal upload rather than silently skipping one that never happened.
+func (u *Uploader) tryReuseDigest(ctx context.Context, backend DedupBackend, record index.UploadRecord, digest string, length int64) (etag string, reused bool) {
+	existing, ok, err := u.idx.LookupDigest(ctx, digest, length)
+	if err != nil {
+		u.logger.Warnf("upload %s: lookup digest failed, uploading normally: %v", record.ID, err)
+		return "", false
+	}
+	if !ok {
+		return "", false
+	}
+
+	alive, err := backend.Head(ctx, record, existing)
+	if err != nil {
+		u.logger.Warnf("upload %s: HEAD probe for reused object %s failed, uploading normally: %v", record.ID, existing, err)
+		return "", false
+	}
+	if !alive {
+		return "", false
+	}
+	return existing, true
+}
+
+// uploadWithDedup hashes chunk and, if identical content was already
+// uploaded to a still-live object, reuses it instead of uploading again.
+// Concurrent uploads of the same digest are single-flighted so only one of
+// them actually reaches the backend.
+func (u *Uploader) uploadWithDedup(ctx context.Context, backend DedupBackend, record index.UploadRecord, chunk ReadSeekCloser) error {
+	digest, length, err := hashChunk(chunk)
+	if err != nil {
+		u.logger.Warnf("upload %s: hash chunk for dedup failed, uploading without dedup: %v", record.ID, err)
+		return backend.Upload(ctx, record, chunk)
+	}
+
+	// Two single-flight rounds without becoming the leader or finding a
+	// reusable object is vanishingly unlikely (it means another worker won
+	// the slot twice in a row); fall through to a direct upload rather than
+	// looping forever.
+	for attempt := 0; attempt < 2; attempt++ {
+		if etag, reused := u.tryReuseDigest(ctx, backend, record, digest, length); reused {
+			u.metrics.RecordDeduped(record, length)
+			u.logger.Debugf("upload %s: deduped against existing object %s (%d bytes saved)", record.ID, etag, length)
+			return nil
+		}
+
+		leader, wait := u.enterDigestFlight(digest)
+		if !leader {
+			wait()
+			continue
+		}
+
+		etag, err := backend.UploadReturningETag(ctx, record, chunk)
+		if err != nil {
+			u.leaveDigestFlight(digest)
+			return err
+		}
+		if err := u.idx.RecordDigest(ctx, digest, length, etag); err != nil {
+			u.logger.Warnf("upload %s: record digest after upload failed: %v", record.ID, err)
+		}
+		u.leaveDigestFlight(digest)
+		return nil
+	}
+
+	return backend.Upload(ctx, record, chunk)
+}
@@ -0,0 +1,182 @@
+package uploader
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
+)
+
+// directorySourceQueueSize bounds how many discovered-but-not-yet-
+// registered file paths DirectorySource buffers between its sweep
+// goroutine and its registration workers, so a directory holding far more
+// files than SweepWorkers can register doesn't grow memory without bound;
+// once full, the sweep just blocks until a worker catches up.
+const directorySourceQueueSize = 256
+
+// DirectorySource periodically walks a local directory for files not yet
+// tracked in idx and registers each as a new UploadRecord, letting any
+// process that drops files into that directory get them uploaded without
+// calling into Uploader directly. This mirrors the interval-sweep pattern
+// Uploader itself already uses for scanAndQueue, one layer further out:
+// discovering records to create, rather than dispatching ones that already
+// exist.
+type DirectorySource struct {
+	root       string
+	interval   time.Duration
+	quiescence time.Duration
+	workers    int
+	idx        index.CacheIndex
+	logger     Logger
+}
+
+// NewDirectorySource returns a DirectorySource sweeping root every
+// interval. A file is skipped until it hasn't been modified for at least
+// quiescence, so one still being written isn't registered mid-write.
+// workers bounds how many discovered files are registered concurrently.
+func NewDirectorySource(root string, interval, quiescence time.Duration, workers int, idx index.CacheIndex, logger Logger) *DirectorySource {
+	if workers <= 0 {
+		workers = 1
+	}
+	if logger == nil {
+		logger = defaultLogger()
+	}
+	return &DirectorySource{
+		root:       root,
+		interval:   interval,
+		quiescence: quiescence,
+		workers:    workers,
+		idx:        idx,
+		logger:     logger,
+	}
+}
+
+// Run sweeps root immediately and then every interval, until ctx is done.
+func (d *DirectorySource) Run(ctx context.Context) error {
+	work := make(chan string, directorySourceQueueSize)
+
+	var wg sync.WaitGroup
+	for i := 0; i < d.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.registerWorker(ctx, work)
+		}()
+	}
+	defer func() {
+		close(work)
+		wg.Wait()
+	}()
+
+	if err := d.sweep(ctx, work); err != nil && !isContextError(err) {
+		d.logger.Warnf("directory source: initial sweep of %s failed: %v", d.root, err)
+	}
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := d.sweep(ctx, work); err != nil && !isContextError(err) {
+				d.logger.Warnf("directory source: sweep of %s failed: %v", d.root, err)
+			}
+		}
+	}
+}
+
+// sweep walks root once, sending every file that's both unknown to idx and
+// outside the quiescence window onto work. known is rebuilt from
+// idx.ListUploads every sweep, since CacheIndex has no cheaper way to ask
+// "is this path already tracked" than listing every upload entry.
+func (d *DirectorySource) sweep(ctx context.Context, work chan<- string) error {
+	uploads, err := d.idx.ListUploads(ctx)
+	if err != nil {
+		return fmt.Errorf("list existing uploads: %w", err)
+	}
+	known := make(map[string]struct{}, len(uploads))
+	for _, u := range uploads {
+		known[u.Path] = struct{}{}
+	}
+
+	cutoff := time.Now().Add(-d.quiescence)
+	return filepath.WalkDir(d.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// An unreadable entry (permissions, a race with deletion) is
+			// not fatal to the rest of the sweep.
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		if _, tracked := known[path]; tracked {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			// Disappeared between WalkDir listing it and Info(); the next
+			// sweep will simply not see it either.
+			return nil
+		}
+		if info.ModTime().After(cutoff) {
+			return nil // still being written
+		}
+
+		select {
+		case work <- path:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		return nil
+	})
+}
+
+// registerWorker drains work until it's closed or ctx ends, registering
+// each path it receives.
+func (d *DirectorySource) registerWorker(ctx context.Context, work <-chan string) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case path, ok := <-work:
+			if !ok {
+				return
+			}
+			d.register(ctx, path)
+		}
+	}
+}
+
+// register adds path to idx as a new queued upload. A file that vanished
+// between discovery and registration (os.Stat failing with IsNotExist) is
+// silently dropped rather than treated as an error: the next sweep simply
+// won't see it either.
+func (d *DirectorySource) register(ctx context.Context, path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			d.logger.Warnf("directory source: stat %s failed: %v", path, err)
+		}
+		return
+	}
+
+	record, err := d.idx.AddUpload(ctx, index.UploadRecord{
+		Path:   path,
+		Length: info.Size(),
+		Status: index.UploadStatusQueued,
+	})
+	if err != nil {
+		d.logger.Errorf("directory source: register %s failed: %v", path, err)
+		return
+	}
+	d.logger.Debugf("directory source: registered %s (%d bytes, mtime %s) as upload %s",
+		path, info.Size(), info.ModTime(), record.ID)
+}
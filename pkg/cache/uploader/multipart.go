@@ -0,0 +1,74 @@
+package uploader
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
+)
+
+// PartProvider is optionally implemented by a ChunkProvider that can serve a
+// single part of a large record's data directly, without the caller having
+// to open the whole chunk and seek past bytes it doesn't need. uploadResumable
+// prefers it over seeking within the already-open whole-chunk reader
+// whenever the configured provider supports it.
+type PartProvider interface {
+	OpenPart(ctx context.Context, record index.UploadRecord, partNumber int, partOffset, partSize int64) (ReadSeekCloser, error)
+}
+
+// partReader bounds src to [base, base+size) of its own coordinate space, so
+// a backend reading one multipart part can't run past it into the next
+// part's bytes. Close never closes src, since src is the whole-chunk reader
+// shared across every part and is owned (and closed) by its real caller.
+type partReader struct {
+	src  ReadSeekCloser
+	base int64
+	size int64
+	pos  int64
+}
+
+func newPartReader(src ReadSeekCloser, base, size int64) (*partReader, error) {
+	if _, err := src.Seek(base, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("seek to part offset: %w", err)
+	}
+	return &partReader{src: src, base: base, size: size}, nil
+}
+
+func (p *partReader) Read(b []byte) (int, error) {
+	if p.pos >= p.size {
+		return 0, io.EOF
+	}
+	if remaining := p.size - p.pos; int64(len(b)) > remaining {
+		b = b[:remaining]
+	}
+	n, err := p.src.Read(b)
+	p.pos += int64(n)
+	return n, err
+}
+
+func (p *partReader) Seek(offset int64, whence int) (int64, error) {
+	var target int64
+	switch whence {
+	case io.SeekStart:
+		target = offset
+	case io.SeekCurrent:
+		target = p.pos + offset
+	case io.SeekEnd:
+		target = p.size + offset
+	default:
+		return 0, fmt.Errorf("part reader: invalid whence %d", whence)
+	}
+	if target < 0 || target > p.size {
+		return 0, fmt.Errorf("part reader: seek %d out of bounds [0, %d]", target, p.size)
+	}
+	if _, err := p.src.Seek(p.base+target, io.SeekStart); err != nil {
+		return 0, err
+	}
+	p.pos = target
+	return target, nil
+}
+
+func (p *partReader) Close() error {
+	return nil
+}
@@ -0,0 +1,65 @@
+package uploader
+
+import (
+	"io"
+
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
+)
+
+// PageCache pushes the bytes of a just-completed upload into the kernel's
+// FUSE page cache, so a read issued right after close is served from cache
+// instead of re-fetching the object the uploader just wrote. A
+// *pagecoherency.Coherency satisfies this.
+type PageCache interface {
+	StoreUploaded(ino uint64, offset int64, data []byte) error
+}
+
+// InodeResolver maps an upload's cache path to the inode number PageCache
+// operates on. pkg/cache has no inode concept of its own - it is one layer
+// below the FUSE mount that assigns them - so this is the seam a real mount
+// would implement; WithPageCache is a no-op until both it and a PageCache
+// are supplied.
+type InodeResolver interface {
+	ResolveInode(path string) (ino uint64, ok bool)
+}
+
+// WithPageCache wires a PageCache into the uploader: after every
+// successfully completed upload, the just-uploaded chunk is pushed into
+// cache via resolver's inode for that path before Run moves on to the next
+// record. Leaving this unset (the default) skips page-cache notification
+// entirely.
+func WithPageCache(cache PageCache, resolver InodeResolver) Option {
+	return func(u *Uploader) {
+		u.pageCache = cache
+		u.inodeResolver = resolver
+	}
+}
+
+// storeInPageCache is best-effort: the upload it's reporting on already
+// completed successfully, so a failure here only costs the next reader a
+// round trip to the backend it otherwise wouldn't have paid, not data
+// durability.
+func (u *Uploader) storeInPageCache(record index.UploadRecord, chunk ReadSeekCloser) {
+	if u.pageCache == nil || u.inodeResolver == nil {
+		return
+	}
+
+	ino, ok := u.inodeResolver.ResolveInode(record.Path)
+	if !ok {
+		return
+	}
+
+	if _, err := chunk.Seek(0, io.SeekStart); err != nil {
+		u.logger.Warnf("page cache update for %s: rewind chunk: %v", record.Path, err)
+		return
+	}
+	data, err := io.ReadAll(chunk)
+	if err != nil {
+		u.logger.Warnf("page cache update for %s: read chunk: %v", record.Path, err)
+		return
+	}
+
+	if err := u.pageCache.StoreUploaded(ino, record.Offset, data); err != nil {
+		u.logger.Warnf("page cache update for %s: %v", record.Path, err)
+	}
+}
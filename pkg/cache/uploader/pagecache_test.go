@@ -0,0 +1,179 @@
+package uploader
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	indexpkg "github.com/valandreev/tigrisfs/pkg/cache/index"
+)
+
+type fakePageCache struct {
+	mu     sync.Mutex
+	stored map[uint64][]byte
+}
+
+func newFakePageCache() *fakePageCache {
+	return &fakePageCache{stored: make(map[uint64][]byte)}
+}
+
+func (f *fakePageCache) StoreUploaded(ino uint64, offset int64, data []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cp := make([]byte, len(data))
+	copy(cp, data)
+	f.stored[ino] = cp
+	return nil
+}
+
+func (f *fakePageCache) Get(ino uint64) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.stored[ino]
+	return data, ok
+}
+
+type fakeInodeResolver struct {
+	byPath map[string]uint64
+}
+
+func (r *fakeInodeResolver) ResolveInode(path string) (uint64, bool) {
+	ino, ok := r.byPath[path]
+	return ino, ok
+}
+
+// countingChunkProvider wraps stubChunkProvider to count how many times a
+// path's chunk is re-opened after the upload that produced it completes -
+// standing in for a backend re-fetch a cache hit should make unnecessary.
+type countingChunkProvider struct {
+	*stubChunkProvider
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func newCountingChunkProvider(inner *stubChunkProvider) *countingChunkProvider {
+	return &countingChunkProvider{stubChunkProvider: inner, counts: make(map[string]int)}
+}
+
+func (c *countingChunkProvider) OpenChunk(ctx context.Context, record indexpkg.UploadRecord) (ReadSeekCloser, error) {
+	c.mu.Lock()
+	c.counts[record.Path]++
+	c.mu.Unlock()
+	return c.stubChunkProvider.OpenChunk(ctx, record)
+}
+
+func (c *countingChunkProvider) opensFor(path string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[path]
+}
+
+func TestUploaderStoresCompletedUploadInPageCache(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	idx := newTestIndex(t)
+
+	record, err := idx.AddUpload(ctx, indexpkg.UploadRecord{
+		Path:   "objects/cached.bin",
+		Offset: 0,
+		Length: 256,
+		Status: indexpkg.UploadStatusQueued,
+	})
+	if err != nil {
+		t.Fatalf("AddUpload failed: %v", err)
+	}
+
+	payload := bytes.Repeat([]byte{'z'}, int(record.Length))
+	chunkData := map[string][]byte{record.Path: payload}
+	backend := &stubBackend{responses: []error{nil}}
+	provider := newCountingChunkProvider(newStubChunkProvider(chunkData))
+	pageCache := newFakePageCache()
+	resolver := &fakeInodeResolver{byPath: map[string]uint64{record.Path: 42}}
+
+	cfg := Config{
+		MaxConcurrentUploads: 1,
+		MaxAttempts:          3,
+		BaseRetryDelay:       5 * time.Millisecond,
+		MaxRetryDelay:        50 * time.Millisecond,
+		PollInterval:         5 * time.Millisecond,
+	}
+
+	uploader, err := New(cfg, idx, backend, WithChunkProvider(provider), WithPageCache(pageCache, resolver))
+	if err != nil {
+		t.Fatalf("New uploader failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_ = uploader.Run(ctx)
+		close(done)
+	}()
+
+	waitForStatus(t, idx, record.ID, indexpkg.UploadStatusComplete, 500*time.Millisecond)
+	cancel()
+	<-done
+
+	stored, ok := pageCache.Get(42)
+	if !ok {
+		t.Fatalf("expected upload to be stored in the page cache")
+	}
+	if !bytes.Equal(stored, payload) {
+		t.Fatalf("cached payload mismatch: got %d bytes, want %d", len(stored), len(payload))
+	}
+	if opens := provider.opensFor(record.Path); opens != 1 {
+		t.Fatalf("expected exactly 1 backend chunk open (none triggered by the page-cache write), got %d", opens)
+	}
+}
+
+func TestUploaderSkipsPageCacheWithoutResolvedInode(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	idx := newTestIndex(t)
+
+	record, err := idx.AddUpload(ctx, indexpkg.UploadRecord{
+		Path:   "objects/unresolved.bin",
+		Offset: 0,
+		Length: 64,
+		Status: indexpkg.UploadStatusQueued,
+	})
+	if err != nil {
+		t.Fatalf("AddUpload failed: %v", err)
+	}
+
+	chunkData := map[string][]byte{record.Path: bytes.Repeat([]byte{'q'}, int(record.Length))}
+	backend := &stubBackend{responses: []error{nil}}
+	provider := newStubChunkProvider(chunkData)
+	pageCache := newFakePageCache()
+	resolver := &fakeInodeResolver{byPath: map[string]uint64{}}
+
+	cfg := Config{
+		MaxConcurrentUploads: 1,
+		MaxAttempts:          3,
+		BaseRetryDelay:       5 * time.Millisecond,
+		MaxRetryDelay:        50 * time.Millisecond,
+		PollInterval:         5 * time.Millisecond,
+	}
+
+	uploader, err := New(cfg, idx, backend, WithChunkProvider(provider), WithPageCache(pageCache, resolver))
+	if err != nil {
+		t.Fatalf("New uploader failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_ = uploader.Run(ctx)
+		close(done)
+	}()
+
+	waitForStatus(t, idx, record.ID, indexpkg.UploadStatusComplete, 500*time.Millisecond)
+	cancel()
+	<-done
+
+	if _, ok := pageCache.Get(0); ok {
+		t.Fatalf("expected no page-cache entry when the inode can't be resolved")
+	}
+}
@@ -0,0 +1,63 @@
+package uploader
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
+)
+
+// Rekey re-encrypts every chunk of path still sealed under an older
+// KeyGeneration, advancing it to newGeneration under the provider's current
+// MasterKey. It is the library-level building block for a lazy "cache
+// rekey" admin operation driven after a master key rotation; callers
+// typically walk ListLRU (or a targeted path list) and invoke this per file.
+func Rekey(ctx context.Context, idx index.CacheIndex, provider EncryptingChunkProvider, path string, newGeneration uint32) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	meta, err := idx.Get(ctx, path)
+	if err != nil {
+		return fmt.Errorf("rekey %s: %w", path, err)
+	}
+
+	upToDate := true
+	for _, chunk := range meta.Chunks {
+		if chunk.KeyGeneration != newGeneration {
+			upToDate = false
+			break
+		}
+	}
+	if upToDate {
+		return nil
+	}
+
+	reader, err := provider.OpenChunk(ctx, index.UploadRecord{Path: path, KeyGeneration: meta.Chunks[0].KeyGeneration})
+	if err != nil {
+		return fmt.Errorf("rekey %s: open: %w", path, err)
+	}
+	defer reader.Close()
+
+	buf := make([]byte, meta.Size)
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		return fmt.Errorf("rekey %s: read: %w", path, err)
+	}
+
+	if err := provider.EncryptAndWrite(path, newGeneration, buf); err != nil {
+		return fmt.Errorf("rekey %s: write: %w", path, err)
+	}
+
+	_, err = idx.Update(ctx, path, func(fm index.FileMeta) (index.FileMeta, error) {
+		for i := range fm.Chunks {
+			fm.Chunks[i].KeyGeneration = newGeneration
+		}
+		return fm, nil
+	})
+	if err != nil {
+		return fmt.Errorf("rekey %s: update index: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package uploader
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RetryPolicy computes how long to wait before the next retry of a failed
+// upload attempt. attempt is 1-based: 1 is the delay before the first
+// retry. lastErr is the error that caused the retry, which a policy may
+// inspect to special-case particular backends or error shapes.
+//
+// RetryPolicy is only consulted for errors that are already retryable and
+// under Config.MaxAttempts; ErrETagMismatch and similar terminal errors
+// never reach it (see processRecord).
+type RetryPolicy interface {
+	NextDelay(attempt int, lastErr error) time.Duration
+}
+
+// RetryAfter is implemented by errors that know exactly how long the caller
+// should wait before retrying, such as one parsed from a backend's 429/503
+// Retry-After header. When lastErr satisfies this, the uploader honors the
+// reported delay directly instead of consulting the configured RetryPolicy.
+type RetryAfter interface {
+	RetryAfter() time.Duration
+}
+
+// ExponentialPolicy doubles the delay on every attempt, capped at Cap. It
+// reproduces Uploader's built-in default backoff (see backoffDelay) as a
+// standalone RetryPolicy, for callers who want that exact curve without
+// relying on the unconfigured default.
+type ExponentialPolicy struct {
+	Base time.Duration
+	Cap  time.Duration
+}
+
+// NextDelay implements RetryPolicy.
+func (p ExponentialPolicy) NextDelay(attempt int, _ error) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := time.Duration(float64(p.Base) * math.Pow(2, float64(attempt-1)))
+	if delay > p.Cap {
+		return p.Cap
+	}
+	if delay < p.Base {
+		return p.Base
+	}
+	return delay
+}
+
+// FullJitterPolicy draws each delay uniformly from [0, exponential delay]
+// rather than following the exponential curve exactly, so a batch of chunks
+// that all failed against the same backend at once don't all retry in
+// lockstep.
+type FullJitterPolicy struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+	base time.Duration
+	cap  time.Duration
+}
+
+// NewFullJitterPolicy constructs a FullJitterPolicy bounded by base and cap,
+// seeded from seed so tests can reproduce a specific jitter sequence.
+func NewFullJitterPolicy(base, cap time.Duration, seed int64) *FullJitterPolicy {
+	return &FullJitterPolicy{rand: rand.New(rand.NewSource(seed)), base: base, cap: cap}
+}
+
+// NextDelay implements RetryPolicy.
+func (p *FullJitterPolicy) NextDelay(attempt int, _ error) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	upper := time.Duration(float64(p.base) * math.Pow(2, float64(attempt-1)))
+	if upper > p.cap {
+		upper = p.cap
+	}
+	if upper <= 0 {
+		return 0
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return time.Duration(p.rand.Int63n(int64(upper) + 1))
+}
+
+// DecorrelatedJitterPolicy implements the "decorrelated jitter" backoff
+// (sleep = min(cap, random(base, prev*3))): each delay is drawn relative to
+// the delay actually used last time rather than a fixed exponential curve,
+// which spreads concurrent retries out even further than FullJitterPolicy.
+//
+// RetryPolicy.NextDelay carries only an attempt number and an error, not a
+// record identity, so this policy has no way to keep prev scoped to one
+// upload record; it tracks a single prev shared across every record retried
+// through this policy instance. That still achieves the algorithm's goal of
+// spreading retries apart; it just means one record's retry can perturb the
+// delay another record's next retry draws.
+type DecorrelatedJitterPolicy struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+	base time.Duration
+	cap  time.Duration
+	prev time.Duration
+}
+
+// NewDecorrelatedJitterPolicy constructs a DecorrelatedJitterPolicy bounded
+// by base and cap, seeded from seed so tests can reproduce a specific
+// jitter sequence.
+func NewDecorrelatedJitterPolicy(base, cap time.Duration, seed int64) *DecorrelatedJitterPolicy {
+	return &DecorrelatedJitterPolicy{rand: rand.New(rand.NewSource(seed)), base: base, cap: cap}
+}
+
+// NextDelay implements RetryPolicy.
+func (p *DecorrelatedJitterPolicy) NextDelay(_ int, _ error) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	prev := p.prev
+	if prev <= 0 {
+		prev = p.base
+	}
+	upper := prev * 3
+	if upper > p.cap {
+		upper = p.cap
+	}
+	if upper < p.base {
+		upper = p.base
+	}
+
+	delay := p.base
+	if span := upper - p.base; span > 0 {
+		delay += time.Duration(p.rand.Int63n(int64(span) + 1))
+	}
+	p.prev = delay
+	return delay
+}
@@ -0,0 +1,371 @@
+package uploader
+
+import (
+	"container/heap"
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
+)
+
+// Scheduler decides which queued UploadRecord a worker should process next,
+// replacing Uploader's previous plain chan index.UploadRecord. Dequeue is
+// expected to block until a record is eligible (or ctx ends) rather than
+// have the caller poll, so a scheduled backoff delay parks in the scheduler
+// instead of occupying a worker goroutine in time.Sleep.
+type Scheduler interface {
+	// Enqueue admits record for processing as soon as it's eligible.
+	Enqueue(record index.UploadRecord)
+	// Dequeue blocks until a record is eligible and returns it, or returns
+	// ctx.Err() once ctx is done.
+	Dequeue(ctx context.Context) (index.UploadRecord, error)
+	// Requeue re-admits record, typically after a retryable failure,
+	// eligible no earlier than notBefore.
+	Requeue(record index.UploadRecord, notBefore time.Time)
+}
+
+// schedEntry is one record waiting in a scheduler, along with the time it
+// becomes eligible to be dequeued. A zero notBefore means eligible
+// immediately.
+type schedEntry struct {
+	record    index.UploadRecord
+	notBefore time.Time
+	seq       uint64
+}
+
+func (e schedEntry) eligible(now time.Time) bool {
+	return e.notBefore.IsZero() || !e.notBefore.After(now)
+}
+
+// waitFor returns how long until e becomes eligible, relative to now. Only
+// meaningful when !e.eligible(now).
+func (e schedEntry) waitFor(now time.Time) time.Duration {
+	return e.notBefore.Sub(now)
+}
+
+// FIFOScheduler orders records by arrival (Enqueue/Requeue call order),
+// honoring each entry's notBefore so a delayed retry doesn't jump ahead of
+// work that's already eligible. This matches Uploader's behavior from before
+// Scheduler existed, except that a backoff delay now blocks only whichever
+// worker is waiting in Dequeue instead of all of them sharing one channel.
+type FIFOScheduler struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	entries []schedEntry
+	seq     uint64
+}
+
+// NewFIFOScheduler returns a Scheduler with first-in-first-out ordering
+// among eligible entries, the default used when Uploader isn't given one
+// via WithScheduler.
+func NewFIFOScheduler() *FIFOScheduler {
+	s := &FIFOScheduler{}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+func (s *FIFOScheduler) Enqueue(record index.UploadRecord) {
+	s.insert(record, time.Time{})
+}
+
+func (s *FIFOScheduler) Requeue(record index.UploadRecord, notBefore time.Time) {
+	s.insert(record, notBefore)
+}
+
+func (s *FIFOScheduler) insert(record index.UploadRecord, notBefore time.Time) {
+	s.mu.Lock()
+	s.seq++
+	s.entries = append(s.entries, schedEntry{record: record, notBefore: notBefore, seq: s.seq})
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
+
+func (s *FIFOScheduler) Dequeue(ctx context.Context) (index.UploadRecord, error) {
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.cond.Broadcast()
+		case <-stop:
+		}
+	}()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		if err := ctx.Err(); err != nil {
+			return index.UploadRecord{}, err
+		}
+
+		now := time.Now()
+		best := -1
+		var wait time.Duration
+		for i, e := range s.entries {
+			if e.eligible(now) {
+				if best == -1 || e.seq < s.entries[best].seq {
+					best = i
+				}
+				continue
+			}
+			if best == -1 && (wait == 0 || e.waitFor(now) < wait) {
+				wait = e.waitFor(now)
+			}
+		}
+
+		if best >= 0 {
+			record := s.entries[best].record
+			s.entries = append(s.entries[:best], s.entries[best+1:]...)
+			return record, nil
+		}
+
+		if wait > 0 {
+			timer := time.AfterFunc(wait, func() { s.cond.Broadcast() })
+			s.cond.Wait()
+			timer.Stop()
+			continue
+		}
+		s.cond.Wait()
+	}
+}
+
+// PrefixLimit caps how quickly records whose Path starts with Prefix can be
+// dequeued from a PriorityScheduler, implemented as a token bucket: Burst
+// tokens are available immediately, refilling one at a time every
+// RefillInterval. The longest matching Prefix configured for a given record
+// applies; a record matching no Prefix is unbounded.
+type PrefixLimit struct {
+	Prefix         string
+	Burst          int
+	RefillInterval time.Duration
+}
+
+// tokenBucket is a simple token-bucket rate limiter: Take reports whether a
+// token was available and, if so, consumes it.
+type tokenBucket struct {
+	mu     sync.Mutex
+	tokens float64
+	max    float64
+	rate   float64 // tokens per second
+	last   time.Time
+}
+
+func newTokenBucket(burst int, refill time.Duration) *tokenBucket {
+	rate := 1.0
+	if refill > 0 {
+		rate = 1.0 / refill.Seconds()
+	}
+	return &tokenBucket{tokens: float64(burst), max: float64(burst), rate: rate, last: time.Now()}
+}
+
+func (b *tokenBucket) take(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(b.max, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// priorityHeap orders schedEntry by (notBefore, Attempts, Length), ascending
+// in all three: entries eligible earliest go first; among ties, fewer
+// attempts so far go first (spreading work rather than head-of-line
+// blocking on a troublesome record); among further ties, the smaller record
+// goes first (shortest-remaining-size-first tends to drain the queue
+// faster than largest-first).
+type priorityHeap []schedEntry
+
+func (h priorityHeap) Len() int { return len(h) }
+
+func (h priorityHeap) Less(i, j int) bool {
+	if !h[i].notBefore.Equal(h[j].notBefore) {
+		return h[i].notBefore.Before(h[j].notBefore)
+	}
+	if h[i].record.Attempts != h[j].record.Attempts {
+		return h[i].record.Attempts < h[j].record.Attempts
+	}
+	if h[i].record.Length != h[j].record.Length {
+		return h[i].record.Length < h[j].record.Length
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h priorityHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *priorityHeap) Push(x any) { *h = append(*h, x.(schedEntry)) }
+
+func (h *priorityHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// PriorityScheduler orders eligible records by a (deadline, attempt, size)
+// score using an internal min-heap, and optionally enforces a per-path-
+// prefix concurrency cap via token buckets so one noisy directory queuing
+// many uploads can't starve every other prefix's work.
+type PriorityScheduler struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	heap   priorityHeap
+	seq    uint64
+	limits []PrefixLimit // sorted longest-Prefix-first
+
+	bucketsMu sync.Mutex
+	buckets   map[string]*tokenBucket
+
+	// pollInterval bounds how long Dequeue waits before re-checking token
+	// buckets that had no tokens available, since a refill isn't itself an
+	// event Dequeue can wait on the way it waits on a notBefore timer.
+	pollInterval time.Duration
+}
+
+// NewPriorityScheduler returns a Scheduler ordering by (deadline, attempt,
+// size), applying limits (if any) as per-prefix token buckets.
+func NewPriorityScheduler(limits []PrefixLimit) *PriorityScheduler {
+	sorted := append([]PrefixLimit(nil), limits...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i].Prefix) > len(sorted[j].Prefix) })
+
+	s := &PriorityScheduler{
+		limits:       sorted,
+		buckets:      make(map[string]*tokenBucket),
+		pollInterval: 50 * time.Millisecond,
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+func (s *PriorityScheduler) Enqueue(record index.UploadRecord) {
+	s.insert(record, time.Time{})
+}
+
+func (s *PriorityScheduler) Requeue(record index.UploadRecord, notBefore time.Time) {
+	s.insert(record, notBefore)
+}
+
+func (s *PriorityScheduler) insert(record index.UploadRecord, notBefore time.Time) {
+	s.mu.Lock()
+	s.seq++
+	heap.Push(&s.heap, schedEntry{record: record, notBefore: notBefore, seq: s.seq})
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
+
+// bucketFor returns the token bucket matching record.Path's longest
+// configured prefix, or nil if no configured PrefixLimit applies.
+func (s *PriorityScheduler) bucketFor(path string) *tokenBucket {
+	var limit *PrefixLimit
+	for i := range s.limits {
+		if strings.HasPrefix(path, s.limits[i].Prefix) {
+			limit = &s.limits[i]
+			break
+		}
+	}
+	if limit == nil {
+		return nil
+	}
+
+	s.bucketsMu.Lock()
+	defer s.bucketsMu.Unlock()
+	b, ok := s.buckets[limit.Prefix]
+	if !ok {
+		b = newTokenBucket(limit.Burst, limit.RefillInterval)
+		s.buckets[limit.Prefix] = b
+	}
+	return b
+}
+
+func (s *PriorityScheduler) Dequeue(ctx context.Context) (index.UploadRecord, error) {
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.cond.Broadcast()
+		case <-stop:
+		}
+	}()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		if err := ctx.Err(); err != nil {
+			return index.UploadRecord{}, err
+		}
+
+		now := time.Now()
+		record, wait, throttled, ok := s.popEligibleLocked(now)
+		if ok {
+			return record, nil
+		}
+
+		switch {
+		case wait > 0:
+			timer := time.AfterFunc(wait, func() { s.cond.Broadcast() })
+			s.cond.Wait()
+			timer.Stop()
+		case throttled:
+			timer := time.AfterFunc(s.pollInterval, func() { s.cond.Broadcast() })
+			s.cond.Wait()
+			timer.Stop()
+		default:
+			s.cond.Wait()
+		}
+	}
+}
+
+// popEligibleLocked scans the heap in priority order for the best entry
+// that's both time-eligible and not rate-limited by its prefix's token
+// bucket, removing and returning it. Entries skipped for being throttled
+// are left in the heap for the next pass. wait is how long until the
+// earliest not-yet-time-eligible entry becomes so (0 if none); throttled
+// reports whether every eligible entry was skipped only for lacking a
+// token, so the caller knows to poll rather than wait indefinitely.
+func (s *PriorityScheduler) popEligibleLocked(now time.Time) (record index.UploadRecord, wait time.Duration, throttled bool, ok bool) {
+	// container/heap only guarantees the root is the minimum, so scan a
+	// snapshot in heap order, which pop order would follow, until one
+	// passes the token-bucket check.
+	order := append(priorityHeap(nil), s.heap...)
+	sort.Sort(order)
+
+	sawThrottled := false
+	for _, e := range order {
+		if !e.eligible(now) {
+			if wait == 0 || e.waitFor(now) < wait {
+				wait = e.waitFor(now)
+			}
+			continue
+		}
+		bucket := s.bucketFor(e.record.Path)
+		if bucket != nil && !bucket.take(now) {
+			sawThrottled = true
+			continue
+		}
+		s.removeLocked(e.seq)
+		return e.record, 0, false, true
+	}
+	return index.UploadRecord{}, wait, sawThrottled, false
+}
+
+// removeLocked deletes the entry with the given seq from the heap,
+// preserving heap.Interface invariants.
+func (s *PriorityScheduler) removeLocked(seq uint64) {
+	for i, e := range s.heap {
+		if e.seq == seq {
+			heap.Remove(&s.heap, i)
+			return
+		}
+	}
+}
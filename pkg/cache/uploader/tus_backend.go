@@ -0,0 +1,163 @@
+package uploader
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
+)
+
+// tusProtocolVersion is the tus.io protocol version this client speaks.
+const tusProtocolVersion = "1.0.0"
+
+// TusConfig controls TusChunkUploader's HTTP behaviour. Field names mirror
+// cache.UploadConfig's upload: tuning knobs so callers can pass them through
+// directly.
+type TusConfig struct {
+	// Endpoint is the tus.io creation endpoint new upload sessions are
+	// POSTed to.
+	Endpoint string
+	// ConnectTimeoutSec bounds how long a single HTTP request may take.
+	ConnectTimeoutSec int
+	// RetryIntervalSec is the delay the uploader's own backoff uses between
+	// resumed PATCH attempts. It is not enforced by TusChunkUploader itself;
+	// it exists here so callers can size Uploader's Config.BaseRetryDelay
+	// from the same source.
+	RetryIntervalSec int
+	// MaxRetrySec bounds total time spent retrying a single upload before
+	// the uploader gives up. As with RetryIntervalSec, enforcement lives in
+	// Uploader's own Config.MaxAttempts/MaxRetryDelay.
+	MaxRetrySec int
+}
+
+// TusChunkUploader implements ResumableBackend using the tus.io resumable
+// upload protocol (Creation, HEAD offset probing, PATCH) against a remote
+// tus-compatible endpoint. It is an alternative to MultipartBackend for
+// backends that don't speak S3-style multipart but do speak tus.
+type TusChunkUploader struct {
+	cfg    TusConfig
+	client *http.Client
+}
+
+// NewTusChunkUploader constructs a TusChunkUploader from cfg.
+func NewTusChunkUploader(cfg TusConfig) *TusChunkUploader {
+	timeout := time.Duration(cfg.ConnectTimeoutSec) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &TusChunkUploader{
+		cfg:    cfg,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Upload performs a one-shot upload by creating a fresh session and PATCHing
+// the whole chunk. The uploader package prefers the resumable CreateUpload/
+// ProbeOffset/PatchFrom path; Upload exists only to satisfy Backend.
+func (t *TusChunkUploader) Upload(ctx context.Context, record index.UploadRecord, data ReadSeekCloser) error {
+	uploadURL, err := t.CreateUpload(ctx, record)
+	if err != nil {
+		return err
+	}
+	return t.PatchFrom(ctx, uploadURL, 0, data)
+}
+
+// CreateUpload starts a new tus upload session for record and returns the
+// server-assigned upload URL from the Location response header.
+func (t *TusChunkUploader) CreateUpload(ctx context.Context, record index.UploadRecord) (string, error) {
+	if t.cfg.Endpoint == "" {
+		return "", fmt.Errorf("tus uploader: endpoint is not configured")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.cfg.Endpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("tus create upload: %w", err)
+	}
+	req.Header.Set("Tus-Resumable", tusProtocolVersion)
+	req.Header.Set("Upload-Length", strconv.FormatInt(record.Length, 10))
+	req.Header.Set("Upload-Metadata", "path "+base64.StdEncoding.EncodeToString([]byte(record.Path)))
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", RetryableError{Err: fmt.Errorf("tus create upload: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return "", RetryableError{Err: fmt.Errorf("tus create upload: unexpected status %s", resp.Status)}
+	}
+
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return "", fmt.Errorf("tus create upload: response is missing Location header")
+	}
+	return resolveLocation(t.cfg.Endpoint, location)
+}
+
+// ProbeOffset issues a HEAD against uploadURL and returns the server's
+// reported Upload-Offset.
+func (t *TusChunkUploader) ProbeOffset(ctx context.Context, uploadURL string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, uploadURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("tus probe offset: %w", err)
+	}
+	req.Header.Set("Tus-Resumable", tusProtocolVersion)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return 0, RetryableError{Err: fmt.Errorf("tus probe offset: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, RetryableError{Err: fmt.Errorf("tus probe offset: unexpected status %s", resp.Status)}
+	}
+
+	offset, err := strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("tus probe offset: parse Upload-Offset header: %w", err)
+	}
+	return offset, nil
+}
+
+// PatchFrom uploads the remainder of data, already seeked to offset, as a
+// PATCH against uploadURL starting at offset.
+func (t *TusChunkUploader) PatchFrom(ctx context.Context, uploadURL string, offset int64, data ReadSeekCloser) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, uploadURL, data)
+	if err != nil {
+		return fmt.Errorf("tus patch: %w", err)
+	}
+	req.Header.Set("Tus-Resumable", tusProtocolVersion)
+	req.Header.Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	req.Header.Set("Content-Type", "application/offset+octet-stream")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return RetryableError{Err: fmt.Errorf("tus patch: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return RetryableError{Err: fmt.Errorf("tus patch: unexpected status %s", resp.Status)}
+	}
+	return nil
+}
+
+// resolveLocation resolves a (possibly relative) Location header against
+// endpoint, per the tus.io Creation extension.
+func resolveLocation(endpoint, location string) (string, error) {
+	base, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("tus create upload: parse endpoint: %w", err)
+	}
+	ref, err := url.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("tus create upload: parse Location header: %w", err)
+	}
+	return base.ResolveReference(ref).String(), nil
+}
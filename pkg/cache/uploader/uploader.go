@@ -5,8 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"math"
+	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/valandreev/tigrisfs/log"
@@ -22,8 +23,14 @@ const (
 	metricReasonETagMismatch = "etag_mismatch"
 	metricReasonBackendError = "backend_error"
 	metricReasonContext      = "context_cancel"
+	metricReasonBreakerOpen  = "breaker_open"
 )
 
+// maxWorkerStopBacklog bounds how many shrink requests SetMaxConcurrentUploads
+// can queue before a worker consumes one; it comfortably exceeds any
+// realistic MaxConcurrentUploads value.
+const maxWorkerStopBacklog = 256
+
 // ReadSeekCloser combines read, seek, and close semantics for chunk data streams.
 type ReadSeekCloser interface {
 	io.Reader
@@ -41,6 +48,53 @@ type Backend interface {
 	Upload(ctx context.Context, record index.UploadRecord, data ReadSeekCloser) error
 }
 
+// MultipartBackend is implemented by backends that support resumable,
+// checkpointed multipart uploads (a tus-style session layered over S3
+// multipart) in addition to plain single-shot Upload. When the configured
+// Backend satisfies this interface, the uploader checkpoints progress to the
+// index after every part so a crash or restart resumes instead of
+// re-uploading the whole chunk.
+type MultipartBackend interface {
+	Backend
+
+	// CreateMultipartUpload starts a new multipart session for record and
+	// returns the backend-assigned upload ID.
+	CreateMultipartUpload(ctx context.Context, record index.UploadRecord) (uploadID string, err error)
+	// ListParts reconciles an in-progress session after a restart, returning
+	// the ETags already accepted by the backend and the total bytes they
+	// cover.
+	ListParts(ctx context.Context, record index.UploadRecord) (partETags []string, bytesTransferred int64, err error)
+	// UploadPart uploads the remaining bytes of data as the given part
+	// number, returning the part's ETag.
+	UploadPart(ctx context.Context, record index.UploadRecord, partNumber int, data ReadSeekCloser) (etag string, err error)
+	// CompleteMultipartUpload finalizes the session once all parts are
+	// checkpointed.
+	CompleteMultipartUpload(ctx context.Context, record index.UploadRecord) error
+	// AbortMultipartUpload releases backend storage held by an orphaned
+	// session (a failed upload, or a completed one still in the index).
+	AbortMultipartUpload(ctx context.Context, record index.UploadRecord) error
+}
+
+// ResumableBackend is implemented by backends that speak the tus.io
+// resumable upload protocol: the backend owns an upload URL and the
+// server itself tracks progress, so resuming means asking the server where
+// it left off (HEAD) rather than replaying checkpointed part ETags the way
+// MultipartBackend does.
+type ResumableBackend interface {
+	Backend
+
+	// CreateUpload starts a new tus upload session for record and returns
+	// the server-assigned upload URL.
+	CreateUpload(ctx context.Context, record index.UploadRecord) (uploadURL string, err error)
+	// ProbeOffset issues a HEAD against uploadURL and returns the
+	// server-reported Upload-Offset, so a resumed upload knows where to
+	// PATCH from.
+	ProbeOffset(ctx context.Context, uploadURL string) (offset int64, err error)
+	// PatchFrom uploads the remainder of data, already seeked to offset, as
+	// a PATCH against uploadURL starting at offset.
+	PatchFrom(ctx context.Context, uploadURL string, offset int64, data ReadSeekCloser) error
+}
+
 // Logger captures structured log output for uploader operations.
 type Logger interface {
 	Debugf(format string, args ...any)
@@ -61,6 +115,20 @@ type Metrics interface {
 	RecordRetried(record index.UploadRecord)
 	RecordCompleted(record index.UploadRecord)
 	RecordFailed(record index.UploadRecord, reason string)
+	// RecordDeduped is called, in addition to the eventual RecordCompleted,
+	// when a chunk upload was skipped because identical content was already
+	// uploaded, with savedBytes the size of the chunk that didn't need
+	// re-sending.
+	RecordDeduped(record index.UploadRecord, savedBytes int64)
+	// RecordPartCompleted is called after every individual part of a
+	// multipart upload is durably checkpointed, alongside the eventual
+	// RecordCompleted for the whole record.
+	RecordPartCompleted(record index.UploadRecord, partNumber int)
+	// RecordPartRetried is called whenever a single part of a multipart
+	// upload fails, before the error is returned to processRecord; whether
+	// the whole record is then retried (with RecordRetried) or failed
+	// depends on that error and the remaining attempts.
+	RecordPartRetried(record index.UploadRecord, partNumber int)
 }
 
 // Config controls uploader runtime behaviour.
@@ -70,6 +138,52 @@ type Config struct {
 	BaseRetryDelay       time.Duration
 	MaxRetryDelay        time.Duration
 	PollInterval         time.Duration
+
+	// RetryPolicy overrides how long to wait between retries. Nil keeps the
+	// built-in decorrelated-jitter backoff that also backs BaseRetryDelay/
+	// MaxRetryDelay's live reconfiguration via SetRetryDelays; a configured
+	// RetryPolicy owns its own bounds and does not participate in
+	// SetRetryDelays.
+	RetryPolicy RetryPolicy
+
+	// BreakerThreshold is how many backend upload failures within
+	// BreakerWindow trip the circuit breaker. Zero (the default) disables
+	// the breaker entirely: every record is attempted against the backend
+	// regardless of its recent failure history.
+	BreakerThreshold int
+	// BreakerWindow bounds how far back a failure still counts toward
+	// BreakerThreshold. Defaults to 1 minute when BreakerThreshold is set.
+	BreakerWindow time.Duration
+	// BreakerCooldown is how long the breaker stays open (short-circuiting
+	// processRecord instead of calling the backend) before allowing a
+	// single half-open probe upload through to decide whether to close.
+	// Defaults to 30s when BreakerThreshold is set.
+	BreakerCooldown time.Duration
+
+	// MultipartPartSize splits a record uploaded via MultipartBackend into
+	// parts of this size once its Length exceeds it, each checkpointed to
+	// the index as it completes. Zero (the default) never splits: the whole
+	// record uploads as a single part, same as before this option existed.
+	MultipartPartSize int64
+
+	// SweepDirectory, if non-empty, enables a DirectorySource goroutine
+	// that periodically walks this local directory and registers any file
+	// not yet tracked in the index as a new upload, so any process that
+	// drops files into it gets them uploaded without calling into Uploader
+	// directly. Empty (the default) disables directory-sweep ingestion
+	// entirely.
+	SweepDirectory string
+	// SweepInterval is how often SweepDirectory is walked. Defaults to 30s
+	// when SweepDirectory is set.
+	SweepInterval time.Duration
+	// SweepQuiescence skips a discovered file until it hasn't been modified
+	// for at least this long, so a file still being written isn't
+	// registered (and possibly uploaded) mid-write. Defaults to 5s when
+	// SweepDirectory is set.
+	SweepQuiescence time.Duration
+	// SweepWorkers bounds how many files DirectorySource registers
+	// concurrently. Defaults to 1 when SweepDirectory is set.
+	SweepWorkers int
 }
 
 // Option customises uploader construction.
@@ -103,6 +217,33 @@ func WithMetrics(metrics Metrics) Option {
 	}
 }
 
+// WithAuditSink sets where Uploader reports queued/started/retried/
+// completed/failed lifecycle events, alongside WithMetrics. The default is
+// a no-op; see NewZerologAuditSink to back it with a log.NewAuditLogger.
+func WithAuditSink(sink AuditSink) Option {
+	return func(u *Uploader) {
+		u.auditSink = sink
+	}
+}
+
+// WithScheduler overrides how queued records are ordered for dispatch to
+// workers; the default is a NewFIFOScheduler, preserving first-in-first-out
+// behavior. See PriorityScheduler for deadline/attempt/size-aware ordering
+// with per-prefix concurrency limits.
+func WithScheduler(scheduler Scheduler) Option {
+	return func(u *Uploader) {
+		u.scheduler = scheduler
+	}
+}
+
+// WithRetryPolicy overrides how long to wait between retries; see
+// Config.RetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(u *Uploader) {
+		u.retryPolicy = policy
+	}
+}
+
 // RetryableError wraps an underlying error and marks it retryable.
 type RetryableError struct {
 	Err error
@@ -124,19 +265,51 @@ func (RetryableError) Retryable() bool { return true }
 
 // Uploader coordinates background uploads from the cache to the backing store.
 type Uploader struct {
-	cfg      Config
-	idx      index.CacheIndex
-	backend  Backend
-	logger   Logger
-	sleeper  Sleeper
-	provider ChunkProvider
-	metrics  Metrics
-
-	mu       sync.Mutex
-	queued   map[string]struct{}
-	inFlight map[string]struct{}
-	tasks    chan index.UploadRecord
-	running  bool
+	cfg       Config
+	idx       index.CacheIndex
+	backend   Backend
+	logger    Logger
+	sleeper   Sleeper
+	provider  ChunkProvider
+	metrics   Metrics
+	auditSink AuditSink
+	scheduler Scheduler
+
+	// pageCache and inodeResolver are both nil unless WithPageCache is
+	// supplied, in which case every completed upload's data is pushed into
+	// the kernel page cache under the record's resolved inode; see
+	// storeInPageCache.
+	pageCache     PageCache
+	inodeResolver InodeResolver
+
+	// retryPolicy overrides backoffDelay when set (see Config.RetryPolicy).
+	retryPolicy RetryPolicy
+
+	// baseRetryDelay and maxRetryDelay mirror cfg.BaseRetryDelay/MaxRetryDelay
+	// but are read atomically so SetRetryDelays can adjust them while Run is
+	// active without racing backoffDelay.
+	baseRetryDelay atomic.Int64
+	maxRetryDelay  atomic.Int64
+
+	mu            sync.Mutex
+	queued        map[string]struct{}
+	inFlight      map[string]struct{}
+	running       bool
+	runCtx        context.Context
+	wg            *sync.WaitGroup
+	workerStop    chan struct{}
+	activeWorkers atomic.Int32
+
+	// dedupMu guards digestFlights, kept separate from mu since it
+	// serializes a distinct concern (single-flighting concurrent uploads of
+	// identical chunk content, see uploadWithDedup) and callers may block
+	// on a claimed flight's WaitGroup after releasing dedupMu.
+	dedupMu       sync.Mutex
+	digestFlights map[string]*digestFlight
+
+	// breaker short-circuits upload() after too many recent backend
+	// failures; see Config.BreakerThreshold.
+	breaker *circuitBreaker
 }
 
 // New constructs a Uploader with the provided configuration.
@@ -151,16 +324,23 @@ func New(cfg Config, idx index.CacheIndex, backend Backend, opts ...Option) (*Up
 	cfg = applyDefaults(cfg)
 
 	u := &Uploader{
-		cfg:      cfg,
-		idx:      idx,
-		backend:  backend,
-		logger:   defaultLogger(),
-		sleeper:  realSleeper{},
-		metrics:  noopMetrics{},
-		queued:   make(map[string]struct{}),
-		inFlight: make(map[string]struct{}),
+		cfg:       cfg,
+		idx:       idx,
+		backend:   backend,
+		logger:    defaultLogger(),
+		sleeper:   realSleeper{},
+		metrics:   noopMetrics{},
+		auditSink: noopAuditSink{},
+		scheduler: NewFIFOScheduler(),
+		queued:    make(map[string]struct{}),
+		inFlight:  make(map[string]struct{}),
 	}
 
+	u.baseRetryDelay.Store(int64(cfg.BaseRetryDelay))
+	u.maxRetryDelay.Store(int64(cfg.MaxRetryDelay))
+	u.retryPolicy = cfg.RetryPolicy
+	u.breaker = newCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerWindow, cfg.BreakerCooldown)
+
 	for _, opt := range opts {
 		opt(u)
 	}
@@ -174,6 +354,12 @@ func New(cfg Config, idx index.CacheIndex, backend Backend, opts ...Option) (*Up
 	if u.metrics == nil {
 		u.metrics = noopMetrics{}
 	}
+	if u.auditSink == nil {
+		u.auditSink = noopAuditSink{}
+	}
+	if u.scheduler == nil {
+		u.scheduler = NewFIFOScheduler()
+	}
 	if u.provider == nil {
 		return nil, errors.New("cache uploader: chunk provider is required")
 	}
@@ -188,8 +374,8 @@ func (u *Uploader) Run(ctx context.Context) error {
 		workerCount = 1
 	}
 
-	tasks := make(chan index.UploadRecord, workerCount*2)
-	var wg sync.WaitGroup
+	stop := make(chan struct{}, maxWorkerStopBacklog)
+	wg := &sync.WaitGroup{}
 
 	u.mu.Lock()
 	if u.running {
@@ -197,25 +383,33 @@ func (u *Uploader) Run(ctx context.Context) error {
 		return errors.New("cache uploader: already running")
 	}
 	u.running = true
-	u.tasks = tasks
+	u.workerStop = stop
+	u.runCtx = ctx
+	u.wg = wg
 	u.mu.Unlock()
 
 	defer func() {
-		close(tasks)
 		wg.Wait()
 		u.mu.Lock()
-		u.tasks = nil
+		u.workerStop = nil
+		u.runCtx = nil
+		u.wg = nil
 		u.running = false
 		u.queued = make(map[string]struct{})
 		u.inFlight = make(map[string]struct{})
 		u.mu.Unlock()
 	}()
 
-	for i := 0; i < workerCount; i++ {
+	u.spawnWorkers(workerCount, ctx, stop, wg)
+
+	if u.cfg.SweepDirectory != "" {
+		source := NewDirectorySource(u.cfg.SweepDirectory, u.cfg.SweepInterval, u.cfg.SweepQuiescence, u.cfg.SweepWorkers, u.idx, u.logger)
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			u.worker(ctx, tasks)
+			if err := source.Run(ctx); err != nil && !isContextError(err) {
+				u.logger.Warnf("directory source for %s stopped: %v", u.cfg.SweepDirectory, err)
+			}
 		}()
 	}
 
@@ -238,23 +432,120 @@ func (u *Uploader) Run(ctx context.Context) error {
 	}
 }
 
-func (u *Uploader) worker(ctx context.Context, tasks <-chan index.UploadRecord) {
-	for {
+// worker pulls records from the scheduler, blocking there rather than
+// busy-polling, so a record parked with a future NotBefore (a backoff delay,
+// see PriorityScheduler/FIFOScheduler) doesn't cost this worker anything
+// until it's actually eligible. workerCtx is derived from ctx so that either
+// Run ending or a shrink request on stop unblocks a pending Dequeue.
+func (u *Uploader) worker(ctx context.Context, stop <-chan struct{}) {
+	workerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
 		select {
-		case <-ctx.Done():
+		case <-stop:
+			cancel()
+		case <-workerCtx.Done():
+		}
+	}()
+
+	for {
+		record, err := u.scheduler.Dequeue(workerCtx)
+		if err != nil {
 			return
-		case record, ok := <-tasks:
-			if !ok {
-				return
-			}
-			if !u.startProcessing(record.ID) {
-				continue
+		}
+		if !u.startProcessing(record.ID) {
+			continue
+		}
+		u.processRecord(ctx, record)
+	}
+}
+
+// spawnWorkers adds n workers to the running pool, tracked against wg so
+// Run's shutdown waits for them alongside the workers it started with.
+func (u *Uploader) spawnWorkers(n int, ctx context.Context, stop <-chan struct{}, wg *sync.WaitGroup) {
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		u.activeWorkers.Add(1)
+		go func() {
+			defer wg.Done()
+			defer u.activeWorkers.Add(-1)
+			u.worker(ctx, stop)
+		}()
+	}
+}
+
+// SetMaxConcurrentUploads live-resizes the running worker pool. Growing
+// spawns additional workers immediately; shrinking asks the excess workers
+// to exit once they finish their current task rather than aborting an
+// upload mid-flight. Safe to call whether or not Run is active; if the
+// uploader isn't running yet, it just updates the configured pool size for
+// the next Run.
+func (u *Uploader) SetMaxConcurrentUploads(n int) {
+	if n <= 0 {
+		n = 1
+	}
+
+	u.mu.Lock()
+	u.cfg.MaxConcurrentUploads = n
+	if !u.running {
+		u.mu.Unlock()
+		return
+	}
+	ctx := u.runCtx
+	stop := u.workerStop
+	wg := u.wg
+	u.mu.Unlock()
+
+	current := int(u.activeWorkers.Load())
+	switch {
+	case n > current:
+		u.spawnWorkers(n-current, ctx, stop, wg)
+	case n < current:
+		for i := 0; i < current-n; i++ {
+			select {
+			case stop <- struct{}{}:
+			default:
 			}
-			u.processRecord(ctx, record)
 		}
 	}
 }
 
+// SetRetryDelays live-applies new backoff bounds. A sleep already in
+// progress keeps running with the delay it was given, but every retry
+// scheduled after this call uses the new bounds.
+func (u *Uploader) SetRetryDelays(base, maxDelay time.Duration) {
+	if base <= 0 {
+		base = 100 * time.Millisecond
+	}
+	if maxDelay < base {
+		maxDelay = base
+	}
+	u.baseRetryDelay.Store(int64(base))
+	u.maxRetryDelay.Store(int64(maxDelay))
+}
+
+// Submit records entry as a new upload in the index and, if Run is active,
+// immediately enqueues it for dispatch rather than waiting for the next
+// scanAndQueue tick. It is the entry point remote callers use (see the
+// gRPC UploaderServer in core) to submit work without touching the index
+// directly.
+func (u *Uploader) Submit(ctx context.Context, entry index.UploadRecord) (index.UploadRecord, error) {
+	created, err := u.idx.AddUpload(ctx, entry)
+	if err != nil {
+		return index.UploadRecord{}, err
+	}
+	u.enqueue(ctx, created)
+	return created, nil
+}
+
+// Cancel marks id as failed so it stops being retried or re-dispatched,
+// the same terminal state a record reaches after exhausting MaxAttempts.
+// An attempt already in flight still runs to completion; its own status
+// update afterward simply overwrites this one.
+func (u *Uploader) Cancel(ctx context.Context, id string) (index.UploadRecord, error) {
+	return u.idx.UpdateUploadStatus(ctx, id, index.UploadStatusFailed, "cancelled")
+}
+
 func (u *Uploader) scanAndQueue(ctx context.Context) error {
 	uploads, err := u.idx.ListUploads(ctx)
 	if err != nil {
@@ -282,7 +573,7 @@ func (u *Uploader) shouldProcess(record index.UploadRecord) bool {
 
 func (u *Uploader) enqueue(ctx context.Context, record index.UploadRecord) {
 	u.mu.Lock()
-	if !u.running || u.tasks == nil {
+	if !u.running {
 		u.mu.Unlock()
 		return
 	}
@@ -295,18 +586,12 @@ func (u *Uploader) enqueue(ctx context.Context, record index.UploadRecord) {
 		return
 	}
 	u.queued[record.ID] = struct{}{}
-	tasks := u.tasks
+	scheduler := u.scheduler
 	u.mu.Unlock()
 
 	u.metrics.RecordQueued(record)
-
-	select {
-	case <-ctx.Done():
-		u.mu.Lock()
-		delete(u.queued, record.ID)
-		u.mu.Unlock()
-	case tasks <- record:
-	}
+	u.audit("queued", record, record.Attempts, "", 0)
+	scheduler.Enqueue(record)
 }
 
 func (u *Uploader) startProcessing(id string) bool {
@@ -339,6 +624,8 @@ func (u *Uploader) processRecord(ctx context.Context, record index.UploadRecord)
 		}
 	}()
 
+	start := time.Now()
+
 	attemptsBefore := record.Attempts / 2
 	if attemptsBefore >= u.cfg.MaxAttempts {
 		u.logger.Warnf("upload %s reached max attempts", record.ID)
@@ -346,10 +633,33 @@ func (u *Uploader) processRecord(ctx context.Context, record index.UploadRecord)
 			u.logger.Errorf("mark upload %s failed: %v", record.ID, err)
 		} else {
 			u.metrics.RecordFailed(failed, metricReasonMaxAttempts)
+			u.audit("failed", failed, attemptsBefore, "max attempts reached", time.Since(start))
 		}
 		return
 	}
 
+	if !u.breaker.Allow(time.Now()) {
+		msg := "circuit breaker open"
+		u.logger.Warnf("upload %s deferred: %s", record.ID, msg)
+		// Checked before marking the record in-progress, and requeued
+		// without any UpdateUploadStatus round-trip: the backend is never
+		// called here, so nothing about this deferral should draw down the
+		// record's retry budget. UpdateUploadStatus bumps Attempts on every
+		// call, and attemptsBefore counts two of those per real attempt
+		// (in-progress, then a terminal status) - going through that path
+		// here as well would burn a full attempt per deferral, so a
+		// sustained backend outage would exhaust MaxAttempts and mark the
+		// upload permanently Failed without the backend ever having been
+		// called.
+		u.metrics.RecordFailed(record, metricReasonBreakerOpen)
+		u.audit("retried", record, attemptsBefore+1, msg, time.Since(start))
+		u.finishProcessing(record.ID)
+		finished = true
+		u.sleeper.Sleep(u.breaker.Cooldown())
+		u.enqueue(ctx, record)
+		return
+	}
+
 	updated, err := u.idx.UpdateUploadStatus(ctx, record.ID, index.UploadStatusInProgress, "")
 	if err != nil {
 		if errors.Is(err, index.ErrNotFound) {
@@ -360,6 +670,7 @@ func (u *Uploader) processRecord(ctx context.Context, record index.UploadRecord)
 		return
 	}
 	u.metrics.RecordStarted(updated)
+	u.audit("started", updated, attemptsBefore+1, "", 0)
 
 	chunk, err := u.provider.OpenChunk(ctx, updated)
 	if err != nil {
@@ -378,7 +689,11 @@ func (u *Uploader) processRecord(ctx context.Context, record index.UploadRecord)
 
 	currentAttempt := attemptsBefore + 1
 
-	if err := u.backend.Upload(ctx, updated, chunk); err != nil {
+	if err := u.upload(ctx, updated, chunk); err != nil {
+		if !isContextError(err) {
+			u.breaker.RecordFailure(time.Now())
+		}
+
 		if errors.Is(err, ErrETagMismatch) {
 			msg := err.Error()
 			u.logger.Warnf("etag mismatch for %s: %s", record.Path, msg)
@@ -386,6 +701,7 @@ func (u *Uploader) processRecord(ctx context.Context, record index.UploadRecord)
 				u.logger.Errorf("mark etag mismatch for %s failed: %v", record.ID, updateErr)
 			} else {
 				u.metrics.RecordFailed(failed, metricReasonETagMismatch)
+				u.audit("failed", failed, currentAttempt, msg, time.Since(start))
 			}
 			return
 		}
@@ -393,6 +709,7 @@ func (u *Uploader) processRecord(ctx context.Context, record index.UploadRecord)
 		if isContextError(err) {
 			u.logger.Warnf("upload %s cancelled: %v", record.ID, err)
 			u.metrics.RecordRetried(updated)
+			u.audit("retried", updated, currentAttempt, err.Error(), time.Since(start))
 			if _, updateErr := u.idx.UpdateUploadStatus(ctx, record.ID, index.UploadStatusQueued, err.Error()); updateErr != nil {
 				u.logger.Errorf("requeue upload %s after cancel failed: %v", record.ID, updateErr)
 			}
@@ -400,14 +717,31 @@ func (u *Uploader) processRecord(ctx context.Context, record index.UploadRecord)
 		}
 
 		if isRetryable(err) && currentAttempt < u.cfg.MaxAttempts {
-			delay := u.backoffDelay(currentAttempt)
+			delay := u.nextRetryDelay(updated, currentAttempt, err)
 			u.logger.Warnf("retrying upload %s in %s: %v", record.ID, delay, err)
 			u.metrics.RecordRetried(updated)
-			updatedRecord, updateErr := u.idx.UpdateUploadStatus(ctx, record.ID, index.UploadStatusQueued, err.Error())
+			u.audit("retried", updated, currentAttempt, err.Error(), time.Since(start))
+			// A failure that still has a live tus session is resumable
+			// rather than starting over from byte 0, so the retry queue
+			// can tell the two apart.
+			requeueStatus := index.UploadStatusQueued
+			if isResumable(err) {
+				requeueStatus = index.UploadStatusResumable
+			}
+			updatedRecord, updateErr := u.idx.UpdateUploadStatus(ctx, record.ID, requeueStatus, err.Error())
 			if updateErr != nil {
 				u.logger.Errorf("requeue upload %s failed: %v", record.ID, updateErr)
 				return
 			}
+			// Persisted so a resumed retry (after a crash or restart)
+			// continues the decorrelated-jitter sequence from here rather
+			// than restarting at base, same as the in-memory updatedRecord
+			// used for enqueue below.
+			if withDelay, setErr := u.idx.SetRetryDelay(ctx, record.ID, delay); setErr != nil {
+				u.logger.Warnf("persist retry delay for %s failed: %v", record.ID, setErr)
+			} else {
+				updatedRecord = withDelay
+			}
 			u.finishProcessing(record.ID)
 			finished = true
 			u.sleeper.Sleep(delay)
@@ -421,32 +755,294 @@ func (u *Uploader) processRecord(ctx context.Context, record index.UploadRecord)
 			u.logger.Errorf("mark upload %s failed state: %v", record.ID, updateErr)
 		} else {
 			u.metrics.RecordFailed(failed, metricReasonBackendError)
+			u.audit("failed", failed, currentAttempt, msg, time.Since(start))
 		}
 		return
 	}
 
+	u.breaker.RecordSuccess()
+
 	if completed, err := u.idx.UpdateUploadStatus(ctx, record.ID, index.UploadStatusComplete, ""); err != nil {
 		u.logger.Errorf("mark upload %s complete failed: %v", record.ID, err)
 	} else {
 		u.metrics.RecordCompleted(completed)
+		u.audit("completed", completed, currentAttempt, "", time.Since(start))
+		u.storeInPageCache(completed, chunk)
+	}
+}
+
+// upload applies record to the backend, transparently using whichever
+// resumable path the backend supports.
+func (u *Uploader) upload(ctx context.Context, record index.UploadRecord, chunk ReadSeekCloser) error {
+	if rb, ok := u.backend.(ResumableBackend); ok {
+		return u.uploadTus(ctx, rb, record, chunk)
+	}
+	if mb, ok := u.backend.(MultipartBackend); ok {
+		return u.uploadResumable(ctx, mb, record, chunk)
+	}
+	if db, ok := u.backend.(DedupBackend); ok {
+		return u.uploadWithDedup(ctx, db, record, chunk)
+	}
+	return u.backend.Upload(ctx, record, chunk)
+}
+
+// uploadTus drives a tus.io session for record: creating it on first
+// attempt, or probing the server for its last-known offset and resuming the
+// PATCH from there when record.TusURL already holds a session from a prior,
+// interrupted attempt.
+func (u *Uploader) uploadTus(ctx context.Context, backend ResumableBackend, record index.UploadRecord, chunk ReadSeekCloser) error {
+	uploadURL := record.TusURL
+	offset := int64(0)
+
+	if uploadURL == "" {
+		created, err := backend.CreateUpload(ctx, record)
+		if err != nil {
+			return fmt.Errorf("create tus upload: %w", err)
+		}
+		persisted, err := u.idx.SetTusURL(ctx, record.ID, created)
+		if err != nil {
+			return fmt.Errorf("persist tus url: %w", err)
+		}
+		uploadURL = created
+		record = persisted
+	} else {
+		probed, err := backend.ProbeOffset(ctx, uploadURL)
+		if err != nil {
+			u.logger.Warnf("upload %s: probe tus offset failed, resuming from 0: %v", record.ID, err)
+		} else {
+			offset = probed
+		}
+	}
+
+	if offset > 0 {
+		if _, err := chunk.Seek(offset, io.SeekStart); err != nil {
+			return fmt.Errorf("seek to tus offset: %w", err)
+		}
+	}
+
+	if err := backend.PatchFrom(ctx, uploadURL, offset, chunk); err != nil {
+		// uploadURL is already live at this point (created or reused), so a
+		// PATCH failure leaves a resumable session rather than requiring a
+		// restart from byte 0.
+		return resumableError{err: err}
+	}
+	return nil
+}
+
+// resumableError marks an upload failure that still has a live tus session
+// to resume from, so processRecord can requeue it as UploadStatusResumable
+// instead of UploadStatusQueued.
+type resumableError struct{ err error }
+
+func (e resumableError) Error() string   { return e.err.Error() }
+func (e resumableError) Unwrap() error   { return e.err }
+func (e resumableError) Resumable() bool { return true }
+
+func (u *Uploader) uploadResumable(ctx context.Context, backend MultipartBackend, record index.UploadRecord, chunk ReadSeekCloser) error {
+	if record.UploadID == "" {
+		uploadID, err := backend.CreateMultipartUpload(ctx, record)
+		if err != nil {
+			return fmt.Errorf("create multipart upload: %w", err)
+		}
+		persisted, err := u.idx.SetUploadID(ctx, record.ID, uploadID)
+		if err != nil {
+			return fmt.Errorf("persist upload id: %w", err)
+		}
+		record = persisted
+
+		partSize := u.multipartPartSize(record.Length)
+		persisted, err = u.idx.SetPartSize(ctx, record.ID, partSize)
+		if err != nil {
+			return fmt.Errorf("persist part size: %w", err)
+		}
+		record = persisted
+	} else if record.BytesTransferred == 0 && len(record.PartETags) == 0 {
+		// Resumed after a restart: reconcile which parts the backend
+		// already has before re-reading anything from disk.
+		partETags, bytesDone, err := backend.ListParts(ctx, record)
+		if err != nil {
+			u.logger.Warnf("upload %s: list parts failed, restarting from checkpoint 0: %v", record.ID, err)
+		} else {
+			record.PartETags = partETags
+			record.BytesTransferred = bytesDone
+		}
+	}
+
+	partSize := record.PartSize
+	if partSize <= 0 {
+		partSize = record.Length
+	}
+
+	checksummedBackend, checksummed := backend.(ChecksummedMultipartBackend)
+	var knownChecksums map[int]string
+	if checksummed {
+		knownChecksums = partChecksums(ctx, u.provider, record, u.logger)
+	}
+
+	for record.BytesTransferred < record.Length {
+		partNumber := len(record.PartETags) + 1
+		partOffset := record.BytesTransferred
+		thisPartSize := partSize
+		if remaining := record.Length - partOffset; thisPartSize > remaining {
+			thisPartSize = remaining
+		}
+
+		part, err := u.openPart(ctx, record, partNumber, partOffset, thisPartSize, chunk)
+		if err != nil {
+			return fmt.Errorf("open part %d: %w", partNumber, err)
+		}
+
+		var etag string
+		if checksummed {
+			etag, err = u.uploadPartChecksummed(ctx, checksummedBackend, record, partNumber, knownChecksums[partNumber], part)
+		} else {
+			etag, err = backend.UploadPart(ctx, record, partNumber, part)
+		}
+		_ = part.Close()
+		if err != nil {
+			u.metrics.RecordPartRetried(record, partNumber)
+			return err
+		}
+
+		persisted, err := u.idx.UpdateUploadProgress(ctx, record.ID, partOffset+thisPartSize, etag)
+		if err != nil {
+			return fmt.Errorf("checkpoint part %d: %w", partNumber, err)
+		}
+		record = persisted
+		u.metrics.RecordPartCompleted(record, partNumber)
+	}
+
+	return backend.CompleteMultipartUpload(ctx, record)
+}
+
+// uploadPartChecksummed uploads part via backend's content-addressed path.
+// knownSHA256 is the digest already derived from the provider's partition
+// descriptors, if any; otherwise part is hashed (and rewound) here.
+func (u *Uploader) uploadPartChecksummed(ctx context.Context, backend ChecksummedMultipartBackend, record index.UploadRecord, partNumber int, knownSHA256 string, part ReadSeekCloser) (string, error) {
+	sha := knownSHA256
+	if sha == "" {
+		hashed, err := sha256Hex(part)
+		if err != nil {
+			return "", fmt.Errorf("hash part %d: %w", partNumber, err)
+		}
+		sha = hashed
+	}
+
+	etag, serverSHA256, err := backend.UploadPartChecksummed(ctx, record, partNumber, sha, part)
+	if err != nil {
+		return "", err
+	}
+	if serverSHA256 != "" && serverSHA256 != sha {
+		return "", &checksumMismatchError{partNumber: partNumber, want: sha, got: serverSHA256}
+	}
+	return etag, nil
+}
+
+// multipartPartSize decides the part size a new multipart session splits
+// length into: Config.MultipartPartSize when it's smaller than length
+// (genuinely splitting into multiple parts), or length itself (a single
+// part, preserving the pre-split-support behavior for callers who never set
+// MultipartPartSize).
+func (u *Uploader) multipartPartSize(length int64) int64 {
+	if u.cfg.MultipartPartSize > 0 && u.cfg.MultipartPartSize < length {
+		return u.cfg.MultipartPartSize
 	}
+	return length
 }
 
-func (u *Uploader) backoffDelay(attempt int) time.Duration {
-	if attempt < 1 {
-		attempt = 1
+// openPart returns a reader bounded to exactly [partOffset, partOffset+size)
+// of record's data. When the configured ChunkProvider also implements
+// PartProvider, it is asked to serve just those bytes directly, so resuming
+// a multi-part upload doesn't have to read every earlier part just to seek
+// past it. Otherwise chunk, the
+// whole-record reader already open for this attempt, is bounded to the
+// part's span instead.
+func (u *Uploader) openPart(ctx context.Context, record index.UploadRecord, partNumber int, partOffset, size int64, chunk ReadSeekCloser) (ReadSeekCloser, error) {
+	if pp, ok := u.provider.(PartProvider); ok {
+		return pp.OpenPart(ctx, record, partNumber, partOffset, size)
 	}
-	base := u.cfg.BaseRetryDelay
+	return newPartReader(chunk, partOffset, size)
+}
+
+// GCOrphanedMultipart aborts the backend multipart session of every record
+// still listed in the index with an UploadID and a failed or complete
+// status, so orphaned parts don't accumulate in the backend. Records already
+// removed from the index are not visited.
+func (u *Uploader) GCOrphanedMultipart(ctx context.Context) error {
+	mb, ok := u.backend.(MultipartBackend)
+	if !ok {
+		return nil
+	}
+
+	records, err := u.idx.ListUploads(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if record.UploadID == "" {
+			continue
+		}
+		if record.Status != index.UploadStatusFailed && record.Status != index.UploadStatusComplete {
+			continue
+		}
+		if err := mb.AbortMultipartUpload(ctx, record); err != nil {
+			u.logger.Warnf("gc: abort multipart upload %s failed: %v", record.ID, err)
+		}
+	}
+	return nil
+}
+
+// nextRetryDelay decides how long to sleep before retrying attempt against
+// record, in order: an error-reported RetryAfter wins outright, then a
+// configured RetryPolicy, falling back to the built-in decorrelated-jitter
+// backoffDelay when neither applies.
+func (u *Uploader) nextRetryDelay(record index.UploadRecord, attempt int, err error) time.Duration {
+	var ra RetryAfter
+	if errors.As(err, &ra) {
+		if d := ra.RetryAfter(); d > 0 {
+			return d
+		}
+	}
+	if u.retryPolicy != nil {
+		return u.retryPolicy.NextDelay(attempt, err)
+	}
+	return u.backoffDelay(record)
+}
+
+// backoffDelay implements AWS-style decorrelated jitter: each delay is
+// drawn from [base, min(cap, prev*3)], where prev is the delay actually
+// used last time for this specific record (persisted as
+// record.PrevRetryDelay via CacheIndex.SetRetryDelay, so a restart resumes
+// the same sequence instead of starting back over at base). This spreads
+// concurrent workers' retries apart far more than a plain exponential
+// curve, which made every worker retry in lockstep after a shared backend
+// outage.
+func (u *Uploader) backoffDelay(record index.UploadRecord) time.Duration {
+	base := time.Duration(u.baseRetryDelay.Load())
 	if base <= 0 {
 		base = 100 * time.Millisecond
 	}
-	pow := math.Pow(2, float64(attempt-1))
-	delay := time.Duration(float64(base) * pow)
-	if delay > u.cfg.MaxRetryDelay {
-		return u.cfg.MaxRetryDelay
+	maxDelay := time.Duration(u.maxRetryDelay.Load())
+	if maxDelay < base {
+		maxDelay = base
 	}
-	if delay < base {
-		return base
+
+	prev := record.PrevRetryDelay
+	if prev <= 0 {
+		prev = base
+	}
+	upper := prev * 3
+	if upper > maxDelay {
+		upper = maxDelay
+	}
+	if upper < base {
+		upper = base
+	}
+
+	delay := base
+	if span := upper - base; span > 0 {
+		delay += time.Duration(rand.Int63n(int64(span) + 1))
 	}
 	return delay
 }
@@ -470,6 +1066,25 @@ func applyDefaults(cfg Config) Config {
 	if cfg.PollInterval <= 0 {
 		cfg.PollInterval = 200 * time.Millisecond
 	}
+	if cfg.SweepDirectory != "" {
+		if cfg.SweepInterval <= 0 {
+			cfg.SweepInterval = 30 * time.Second
+		}
+		if cfg.SweepQuiescence <= 0 {
+			cfg.SweepQuiescence = 5 * time.Second
+		}
+		if cfg.SweepWorkers <= 0 {
+			cfg.SweepWorkers = 1
+		}
+	}
+	if cfg.BreakerThreshold > 0 {
+		if cfg.BreakerWindow <= 0 {
+			cfg.BreakerWindow = time.Minute
+		}
+		if cfg.BreakerCooldown <= 0 {
+			cfg.BreakerCooldown = 30 * time.Second
+		}
+	}
 	return cfg
 }
 
@@ -522,6 +1137,12 @@ func (noopMetrics) RecordCompleted(index.UploadRecord) {}
 
 func (noopMetrics) RecordFailed(index.UploadRecord, string) {}
 
+func (noopMetrics) RecordDeduped(index.UploadRecord, int64) {}
+
+func (noopMetrics) RecordPartCompleted(index.UploadRecord, int) {}
+
+func (noopMetrics) RecordPartRetried(index.UploadRecord, int) {}
+
 func isRetryable(err error) bool {
 	if err == nil {
 		return false
@@ -543,6 +1164,17 @@ func isRetryable(err error) bool {
 	return false
 }
 
+func isResumable(err error) bool {
+	if err == nil {
+		return false
+	}
+	type resumabler interface {
+		Resumable() bool
+	}
+	var r resumabler
+	return errors.As(err, &r) && r.Resumable()
+}
+
 func isContextError(err error) bool {
 	if err == nil {
 		return false
@@ -553,6 +1185,7 @@ func isContextError(err error) bool {
 // DebugString returns a concise summary for logging and testing.
 func (u *Uploader) DebugString() string {
 	u.mu.Lock()
-	defer u.mu.Unlock()
-	return fmt.Sprintf("queued=%d inFlight=%d", len(u.queued), len(u.inFlight))
+	queued, inFlight := len(u.queued), len(u.inFlight)
+	u.mu.Unlock()
+	return fmt.Sprintf("queued=%d inFlight=%d breaker=%s", queued, inFlight, u.breaker.String())
 }
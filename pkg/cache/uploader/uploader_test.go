@@ -143,11 +143,15 @@ func TestUploaderRetriesWithBackoff(t *testing.T) {
 		t.Fatalf("expected 2 sleep durations, got %d", len(durations))
 	}
 
-	if durations[0] != cfg.BaseRetryDelay {
-		t.Fatalf("expected first backoff %v, got %v", cfg.BaseRetryDelay, durations[0])
-	}
-	if durations[1] != cfg.BaseRetryDelay*2 {
-		t.Fatalf("expected second backoff %v, got %v", cfg.BaseRetryDelay*2, durations[1])
+	// backoffDelay draws from a decorrelated-jitter range rather than a fixed
+	// curve, so only its bounds are asserted: every delay falls in
+	// [BaseRetryDelay, MaxRetryDelay], and the upper bound itself grows from
+	// one retry to the next (prev*3, persisted per-record) until it is
+	// clamped by MaxRetryDelay.
+	for i, d := range durations {
+		if d < cfg.BaseRetryDelay || d > cfg.MaxRetryDelay {
+			t.Fatalf("backoff %d: expected delay in [%v, %v], got %v", i, cfg.BaseRetryDelay, cfg.MaxRetryDelay, d)
+		}
 	}
 	if len(backend.payloads) != 3 {
 		t.Fatalf("expected 3 payloads, got %d", len(backend.payloads))
@@ -397,6 +401,141 @@ func TestUploaderMarksETagMismatchAsFailed(t *testing.T) {
 	}
 }
 
+func TestUploaderDedupsIdenticalChunkContent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	idx := newTestIndex(t)
+
+	first, err := idx.AddUpload(ctx, indexpkg.UploadRecord{
+		Path: "objects/a.bin", Offset: 0, Length: 16, Status: indexpkg.UploadStatusQueued,
+	})
+	if err != nil {
+		t.Fatalf("AddUpload first failed: %v", err)
+	}
+	second, err := idx.AddUpload(ctx, indexpkg.UploadRecord{
+		Path: "objects/b.bin", Offset: 0, Length: 16, Status: indexpkg.UploadStatusQueued,
+	})
+	if err != nil {
+		t.Fatalf("AddUpload second failed: %v", err)
+	}
+
+	payload := bytes.Repeat([]byte{'z'}, 16)
+	chunkData := map[string][]byte{
+		first.Path:  payload,
+		second.Path: payload,
+	}
+	backend := &stubBackend{responses: []error{nil, nil}, dedup: &stubDedup{}}
+	metrics := &stubMetrics{}
+	provider := newStubChunkProvider(chunkData)
+
+	uploader, err := New(Config{
+		MaxConcurrentUploads: 1,
+		MaxAttempts:          3,
+		BaseRetryDelay:       5 * time.Millisecond,
+		MaxRetryDelay:        50 * time.Millisecond,
+		PollInterval:         5 * time.Millisecond,
+	}, idx, backend, WithChunkProvider(provider), WithMetrics(metrics))
+	if err != nil {
+		t.Fatalf("New uploader failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_ = uploader.Run(ctx)
+		close(done)
+	}()
+
+	waitForStatus(t, idx, first.ID, indexpkg.UploadStatusComplete, 500*time.Millisecond)
+	waitForStatus(t, idx, second.ID, indexpkg.UploadStatusComplete, 500*time.Millisecond)
+
+	cancel()
+	<-done
+
+	if len(backend.calls) != 1 {
+		t.Fatalf("expected only 1 actual upload for 2 identical chunks, got %d", len(backend.calls))
+	}
+
+	snap := metrics.Snapshot()
+	if snap.completed != 2 {
+		t.Fatalf("expected both uploads marked complete, got %d", snap.completed)
+	}
+	if snap.deduped != 1 || snap.dedupedSaved != 16 {
+		t.Fatalf("expected 1 dedup of 16 bytes, got deduped=%d savedBytes=%d", snap.deduped, snap.dedupedSaved)
+	}
+}
+
+func TestUploaderFallsBackToUploadWhenHeadFindsStaleDigest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	idx := newTestIndex(t)
+
+	record, err := idx.AddUpload(ctx, indexpkg.UploadRecord{
+		Path: "objects/c.bin", Offset: 0, Length: 8, Status: indexpkg.UploadStatusQueued,
+	})
+	if err != nil {
+		t.Fatalf("AddUpload failed: %v", err)
+	}
+
+	payload := bytes.Repeat([]byte{'q'}, 8)
+	digest, _, err := hashChunk(&memoryChunk{Reader: bytes.NewReader(payload)})
+	if err != nil {
+		t.Fatalf("hashChunk failed: %v", err)
+	}
+	if err := idx.RecordDigest(ctx, digest, 8, "stale-etag"); err != nil {
+		t.Fatalf("RecordDigest failed: %v", err)
+	}
+
+	backend := &stubBackend{
+		responses: []error{nil},
+		dedup:     &stubDedup{deadETags: map[string]bool{"stale-etag": true}},
+	}
+	provider := newStubChunkProvider(map[string][]byte{record.Path: payload})
+	metrics := &stubMetrics{}
+
+	uploader, err := New(Config{
+		MaxConcurrentUploads: 1,
+		MaxAttempts:          3,
+		BaseRetryDelay:       5 * time.Millisecond,
+		MaxRetryDelay:        50 * time.Millisecond,
+		PollInterval:         5 * time.Millisecond,
+	}, idx, backend, WithChunkProvider(provider), WithMetrics(metrics))
+	if err != nil {
+		t.Fatalf("New uploader failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_ = uploader.Run(ctx)
+		close(done)
+	}()
+
+	waitForStatus(t, idx, record.ID, indexpkg.UploadStatusComplete, 500*time.Millisecond)
+
+	cancel()
+	<-done
+
+	if backend.dedup.headCalls == 0 {
+		t.Fatalf("expected HEAD probe to be attempted")
+	}
+	if len(backend.calls) != 1 {
+		t.Fatalf("expected a real upload when the recorded ETag is stale, got %d calls", len(backend.calls))
+	}
+	snap := metrics.Snapshot()
+	if snap.deduped != 0 {
+		t.Fatalf("expected no dedup credit for a stale ETag, got %d", snap.deduped)
+	}
+
+	etag, ok, err := idx.LookupDigest(ctx, digest, 8)
+	if err != nil {
+		t.Fatalf("LookupDigest failed: %v", err)
+	}
+	if !ok || etag == "stale-etag" {
+		t.Fatalf("expected digest map updated with a fresh ETag, got %q", etag)
+	}
+}
+
 // --- Test helpers ---
 
 type stubBackend struct {
@@ -405,6 +544,62 @@ type stubBackend struct {
 	calls     []indexpkg.UploadRecord
 	payloads  [][]byte
 	hook      func()
+
+	// dedup controls the (optional) DedupBackend behavior. stubBackend
+	// always has the DedupBackend methods; with dedup nil, Head reports every
+	// ETag dead and UploadReturningETag returns an empty ETag, so every
+	// upload still reaches Upload.
+	dedup *stubDedup
+}
+
+// stubDedup backs stubBackend's DedupBackend methods. nextETag is returned
+// (and incremented) by each UploadReturningETag call, so distinct uploads
+// get distinguishable ETags; deadETags lists the ETags Head reports as no
+// longer live, and headErr/headCalls let tests inject HEAD probe
+// failures and count how often the probe fired.
+type stubDedup struct {
+	mu          sync.Mutex
+	nextETag    int
+	uploadETags []string
+	headCalls   int
+	headErr     error
+	deadETags   map[string]bool
+}
+
+func (s *stubDedup) Head(ctx context.Context, record indexpkg.UploadRecord, etag string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.headCalls++
+	if s.headErr != nil {
+		return false, s.headErr
+	}
+	return !s.deadETags[etag], nil
+}
+
+func (s *stubDedup) nextETagLocked() string {
+	s.nextETag++
+	return fmt.Sprintf("etag-%d", s.nextETag)
+}
+
+func (s *stubBackend) Head(ctx context.Context, record indexpkg.UploadRecord, etag string) (bool, error) {
+	if s.dedup == nil {
+		return false, nil
+	}
+	return s.dedup.Head(ctx, record, etag)
+}
+
+func (s *stubBackend) UploadReturningETag(ctx context.Context, record indexpkg.UploadRecord, data ReadSeekCloser) (string, error) {
+	if err := s.Upload(ctx, record, data); err != nil {
+		return "", err
+	}
+	if s.dedup == nil {
+		return "", nil
+	}
+	s.dedup.mu.Lock()
+	etag := s.dedup.nextETagLocked()
+	s.dedup.uploadETags = append(s.dedup.uploadETags, etag)
+	s.dedup.mu.Unlock()
+	return etag, nil
 }
 
 func (s *stubBackend) Upload(ctx context.Context, record indexpkg.UploadRecord, data ReadSeekCloser) error {
@@ -540,13 +735,17 @@ type memoryChunk struct {
 func (m *memoryChunk) Close() error { return nil }
 
 type stubMetrics struct {
-	mu          sync.Mutex
-	queued      int
-	started     int
-	retried     int
-	completed   int
-	failed      int
-	failReasons []string
+	mu           sync.Mutex
+	queued       int
+	started      int
+	retried      int
+	completed    int
+	failed       int
+	failReasons  []string
+	deduped      int
+	dedupedSaved int64
+	partsDone    int
+	partsRetried int
 }
 
 func (m *stubMetrics) RecordQueued(indexpkg.UploadRecord) {
@@ -580,13 +779,36 @@ func (m *stubMetrics) RecordFailed(_ indexpkg.UploadRecord, reason string) {
 	m.failReasons = append(m.failReasons, reason)
 }
 
+func (m *stubMetrics) RecordDeduped(_ indexpkg.UploadRecord, savedBytes int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deduped++
+	m.dedupedSaved += savedBytes
+}
+
+func (m *stubMetrics) RecordPartCompleted(indexpkg.UploadRecord, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.partsDone++
+}
+
+func (m *stubMetrics) RecordPartRetried(indexpkg.UploadRecord, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.partsRetried++
+}
+
 type metricsSnapshot struct {
-	queued    int
-	started   int
-	retried   int
-	completed int
-	failed    int
-	reasons   []string
+	queued       int
+	started      int
+	retried      int
+	completed    int
+	failed       int
+	reasons      []string
+	deduped      int
+	dedupedSaved int64
+	partsDone    int
+	partsRetried int
 }
 
 func (m *stubMetrics) Snapshot() metricsSnapshot {
@@ -595,12 +817,16 @@ func (m *stubMetrics) Snapshot() metricsSnapshot {
 	reasons := make([]string, len(m.failReasons))
 	copy(reasons, m.failReasons)
 	return metricsSnapshot{
-		queued:    m.queued,
-		started:   m.started,
-		retried:   m.retried,
-		completed: m.completed,
-		failed:    m.failed,
-		reasons:   reasons,
+		queued:       m.queued,
+		started:      m.started,
+		retried:      m.retried,
+		completed:    m.completed,
+		failed:       m.failed,
+		reasons:      reasons,
+		deduped:      m.deduped,
+		dedupedSaved: m.dedupedSaved,
+		partsDone:    m.partsDone,
+		partsRetried: m.partsRetried,
 	}
 }
 
@@ -669,3 +895,473 @@ func findRecordByPath(t *testing.T, records []indexpkg.UploadRecord, path string
 	t.Fatalf("record with path %s not found", path)
 	return indexpkg.UploadRecord{}
 }
+
+type stubMultipartBackend struct {
+	*stubBackend
+
+	mu            sync.Mutex
+	created       int
+	uploadParts   [][]byte
+	completed     int
+	aborted       int
+	listETags     []string
+	listBytes     int64
+	listErr       error
+	createErr     error
+	uploadPartErr error
+}
+
+func (s *stubMultipartBackend) CreateMultipartUpload(ctx context.Context, record indexpkg.UploadRecord) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.createErr != nil {
+		return "", s.createErr
+	}
+	s.created++
+	return "mpu-1", nil
+}
+
+func (s *stubMultipartBackend) ListParts(ctx context.Context, record indexpkg.UploadRecord) ([]string, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.listETags, s.listBytes, s.listErr
+}
+
+func (s *stubMultipartBackend) UploadPart(ctx context.Context, record indexpkg.UploadRecord, partNumber int, data ReadSeekCloser) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.uploadPartErr != nil {
+		return "", s.uploadPartErr
+	}
+	payload, err := io.ReadAll(data)
+	if err != nil {
+		return "", err
+	}
+	s.uploadParts = append(s.uploadParts, payload)
+	return fmt.Sprintf("part-etag-%d", partNumber), nil
+}
+
+func (s *stubMultipartBackend) CompleteMultipartUpload(ctx context.Context, record indexpkg.UploadRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.completed++
+	return nil
+}
+
+func (s *stubMultipartBackend) AbortMultipartUpload(ctx context.Context, record indexpkg.UploadRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.aborted++
+	return nil
+}
+
+func TestUploaderCheckspointsMultipartProgress(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	idx := newTestIndex(t)
+
+	record, err := idx.AddUpload(ctx, indexpkg.UploadRecord{
+		Path:   "objects/large.bin",
+		Offset: 0,
+		Length: 2048,
+		Status: indexpkg.UploadStatusQueued,
+	})
+	if err != nil {
+		t.Fatalf("AddUpload failed: %v", err)
+	}
+
+	chunkData := map[string][]byte{
+		record.Path: bytes.Repeat([]byte{'z'}, int(record.Length)),
+	}
+	backend := &stubMultipartBackend{stubBackend: &stubBackend{responses: []error{nil}}}
+	provider := newStubChunkProvider(chunkData)
+
+	cfg := Config{
+		MaxConcurrentUploads: 1,
+		MaxAttempts:          3,
+		BaseRetryDelay:       5 * time.Millisecond,
+		MaxRetryDelay:        50 * time.Millisecond,
+		PollInterval:         5 * time.Millisecond,
+	}
+
+	uploader, err := New(cfg, idx, backend, WithSleeper(&stubSleeper{}), WithChunkProvider(provider))
+	if err != nil {
+		t.Fatalf("New uploader failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_ = uploader.Run(ctx)
+		close(done)
+	}()
+
+	final := waitForStatus(t, idx, record.ID, indexpkg.UploadStatusComplete, 500*time.Millisecond)
+
+	cancel()
+	<-done
+
+	if backend.created != 1 {
+		t.Fatalf("expected CreateMultipartUpload once, got %d", backend.created)
+	}
+	if backend.completed != 1 {
+		t.Fatalf("expected CompleteMultipartUpload once, got %d", backend.completed)
+	}
+	if final.UploadID != "mpu-1" {
+		t.Fatalf("expected persisted UploadID mpu-1, got %q", final.UploadID)
+	}
+	if final.BytesTransferred != record.Length {
+		t.Fatalf("expected BytesTransferred %d, got %d", record.Length, final.BytesTransferred)
+	}
+	if len(final.PartETags) != 1 || final.PartETags[0] != "part-etag-1" {
+		t.Fatalf("expected checkpointed part etag, got %v", final.PartETags)
+	}
+}
+
+func TestUploaderSplitsLargeRecordIntoMultipleParts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	idx := newTestIndex(t)
+
+	record, err := idx.AddUpload(ctx, indexpkg.UploadRecord{
+		Path:   "objects/huge.bin",
+		Offset: 0,
+		Length: 2048,
+		Status: indexpkg.UploadStatusQueued,
+	})
+	if err != nil {
+		t.Fatalf("AddUpload failed: %v", err)
+	}
+
+	payload := make([]byte, record.Length)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+	chunkData := map[string][]byte{record.Path: payload}
+	backend := &stubMultipartBackend{stubBackend: &stubBackend{responses: []error{nil}}}
+	provider := newStubChunkProvider(chunkData)
+	metrics := &stubMetrics{}
+
+	cfg := Config{
+		MaxConcurrentUploads: 1,
+		MaxAttempts:          3,
+		BaseRetryDelay:       5 * time.Millisecond,
+		MaxRetryDelay:        50 * time.Millisecond,
+		PollInterval:         5 * time.Millisecond,
+		MultipartPartSize:    800,
+	}
+
+	uploader, err := New(cfg, idx, backend, WithSleeper(&stubSleeper{}), WithChunkProvider(provider), WithMetrics(metrics))
+	if err != nil {
+		t.Fatalf("New uploader failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_ = uploader.Run(ctx)
+		close(done)
+	}()
+
+	final := waitForStatus(t, idx, record.ID, indexpkg.UploadStatusComplete, 500*time.Millisecond)
+
+	cancel()
+	<-done
+
+	if final.PartSize != 800 {
+		t.Fatalf("expected persisted PartSize 800, got %d", final.PartSize)
+	}
+	if len(final.PartETags) != 3 {
+		t.Fatalf("expected 3 parts (800+800+448), got %d: %v", len(final.PartETags), final.PartETags)
+	}
+	if final.BytesTransferred != record.Length {
+		t.Fatalf("expected BytesTransferred %d, got %d", record.Length, final.BytesTransferred)
+	}
+	if backend.completed != 1 {
+		t.Fatalf("expected CompleteMultipartUpload once, got %d", backend.completed)
+	}
+
+	var reassembled []byte
+	for _, part := range backend.uploadParts {
+		reassembled = append(reassembled, part...)
+	}
+	if !bytes.Equal(reassembled, payload) {
+		t.Fatalf("reassembled parts don't match original payload")
+	}
+
+	snap := metrics.Snapshot()
+	if snap.partsDone != 3 {
+		t.Fatalf("expected 3 RecordPartCompleted calls, got %d", snap.partsDone)
+	}
+}
+
+func TestUploaderSetMaxConcurrentUploadsResizesLivePool(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	idx := newTestIndex(t)
+
+	records := make([]indexpkg.UploadRecord, 0, 2)
+	chunkData := make(map[string][]byte)
+	for i := 0; i < 2; i++ {
+		rec, err := idx.AddUpload(ctx, indexpkg.UploadRecord{
+			Path:   filepath.Join("objects", fmt.Sprintf("resize-%d", i)),
+			Offset: 0,
+			Length: 64,
+			Status: indexpkg.UploadStatusQueued,
+		})
+		if err != nil {
+			t.Fatalf("AddUpload failed: %v", err)
+		}
+		records = append(records, rec)
+		chunkData[rec.Path] = bytes.Repeat([]byte{byte('a' + i)}, 64)
+	}
+
+	release := make(chan struct{})
+	var inFlight, maxInFlight int32
+	backend := &stubBackend{
+		hook: func() {
+			n := atomic.AddInt32(&inFlight, 1)
+			for {
+				cur := atomic.LoadInt32(&maxInFlight)
+				if n <= cur || atomic.CompareAndSwapInt32(&maxInFlight, cur, n) {
+					break
+				}
+			}
+			<-release
+			atomic.AddInt32(&inFlight, -1)
+		},
+	}
+	backend.responses = []error{nil, nil}
+	provider := newStubChunkProvider(chunkData)
+
+	cfg := Config{
+		MaxConcurrentUploads: 1,
+		MaxAttempts:          3,
+		BaseRetryDelay:       5 * time.Millisecond,
+		MaxRetryDelay:        20 * time.Millisecond,
+		PollInterval:         5 * time.Millisecond,
+	}
+
+	uploader, err := New(cfg, idx, backend, WithSleeper(&stubSleeper{}), WithChunkProvider(provider))
+	if err != nil {
+		t.Fatalf("New uploader failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_ = uploader.Run(ctx)
+		close(done)
+	}()
+
+	waitUntilTrue(t, func() bool { return atomic.LoadInt32(&inFlight) == 1 }, time.Second)
+	uploader.SetMaxConcurrentUploads(2)
+	waitUntilTrue(t, func() bool { return atomic.LoadInt32(&inFlight) == 2 }, time.Second)
+	close(release)
+
+	for _, rec := range records {
+		waitForStatus(t, idx, rec.ID, indexpkg.UploadStatusComplete, time.Second)
+	}
+
+	cancel()
+	<-done
+
+	if got := atomic.LoadInt32(&maxInFlight); got != 2 {
+		t.Fatalf("expected pool to grow to 2 concurrent uploads, got %d", got)
+	}
+}
+
+func TestUploaderSetRetryDelaysAppliesToNextBackoff(t *testing.T) {
+	idx := newTestIndex(t)
+	backend := &stubBackend{}
+	provider := newStubChunkProvider(nil)
+
+	cfg := Config{
+		MaxConcurrentUploads: 1,
+		MaxAttempts:          3,
+		BaseRetryDelay:       100 * time.Millisecond,
+		MaxRetryDelay:        time.Second,
+		PollInterval:         5 * time.Millisecond,
+	}
+
+	uploader, err := New(cfg, idx, backend, WithChunkProvider(provider))
+	if err != nil {
+		t.Fatalf("New uploader failed: %v", err)
+	}
+
+	// backoffDelay draws from [base, min(max, prev*3)] with prev defaulting
+	// to base, so each check asserts the range the current bounds allow.
+	fresh := indexpkg.UploadRecord{}
+	if got := uploader.backoffDelay(fresh); got < 100*time.Millisecond || got > 300*time.Millisecond {
+		t.Fatalf("expected initial backoff in [100ms, 300ms], got %s", got)
+	}
+
+	uploader.SetRetryDelays(10*time.Millisecond, 40*time.Millisecond)
+
+	if got := uploader.backoffDelay(fresh); got < 10*time.Millisecond || got > 30*time.Millisecond {
+		t.Fatalf("expected updated base backoff in [10ms, 30ms], got %s", got)
+	}
+	if got := uploader.backoffDelay(indexpkg.UploadRecord{PrevRetryDelay: time.Second}); got < 10*time.Millisecond || got > 40*time.Millisecond {
+		t.Fatalf("expected updated backoff capped at 40ms, got %s", got)
+	}
+}
+
+func TestExponentialPolicyGrowsToCap(t *testing.T) {
+	policy := ExponentialPolicy{Base: 10 * time.Millisecond, Cap: 40 * time.Millisecond}
+
+	if got := policy.NextDelay(1, nil); got != 10*time.Millisecond {
+		t.Fatalf("expected first delay 10ms, got %s", got)
+	}
+	if got := policy.NextDelay(3, nil); got != 40*time.Millisecond {
+		t.Fatalf("expected delay capped at 40ms, got %s", got)
+	}
+}
+
+func TestFullJitterPolicyStaysWithinBounds(t *testing.T) {
+	policy := NewFullJitterPolicy(10*time.Millisecond, 40*time.Millisecond, 1)
+
+	for attempt := 1; attempt <= 5; attempt++ {
+		delay := policy.NextDelay(attempt, nil)
+		if delay < 0 || delay > 40*time.Millisecond {
+			t.Fatalf("attempt %d: delay %s outside [0, 40ms]", attempt, delay)
+		}
+	}
+}
+
+func TestDecorrelatedJitterPolicyStaysWithinBounds(t *testing.T) {
+	policy := NewDecorrelatedJitterPolicy(10*time.Millisecond, 40*time.Millisecond, 1)
+
+	for attempt := 1; attempt <= 5; attempt++ {
+		delay := policy.NextDelay(attempt, nil)
+		if delay < 10*time.Millisecond || delay > 40*time.Millisecond {
+			t.Fatalf("attempt %d: delay %s outside [10ms, 40ms]", attempt, delay)
+		}
+	}
+}
+
+func TestUploaderRetriesUsingConfiguredRetryPolicy(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	idx := newTestIndex(t)
+
+	record, err := idx.AddUpload(ctx, indexpkg.UploadRecord{
+		Path:   "objects/retry-policy.bin",
+		Offset: 0,
+		Length: 512,
+		Status: indexpkg.UploadStatusQueued,
+	})
+	if err != nil {
+		t.Fatalf("AddUpload failed: %v", err)
+	}
+
+	retryErr := temporaryError{err: errors.New("transient failure")}
+	backend := &stubBackend{responses: []error{retryErr, nil}}
+	sleeper := &stubSleeper{}
+	provider := newStubChunkProvider(map[string][]byte{record.Path: bytes.Repeat([]byte{'c'}, 512)})
+
+	cfg := Config{
+		MaxConcurrentUploads: 1,
+		MaxAttempts:          3,
+		BaseRetryDelay:       10 * time.Millisecond,
+		MaxRetryDelay:        time.Second,
+		PollInterval:         5 * time.Millisecond,
+		RetryPolicy:          ExponentialPolicy{Base: 7 * time.Millisecond, Cap: 50 * time.Millisecond},
+	}
+
+	uploader, err := New(cfg, idx, backend, WithSleeper(sleeper), WithChunkProvider(provider))
+	if err != nil {
+		t.Fatalf("New uploader failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_ = uploader.Run(ctx)
+		close(done)
+	}()
+
+	waitForStatus(t, idx, record.ID, indexpkg.UploadStatusComplete, 800*time.Millisecond)
+	cancel()
+	<-done
+
+	durations := sleeper.Durations()
+	if len(durations) != 1 {
+		t.Fatalf("expected 1 sleep duration, got %d", len(durations))
+	}
+	if durations[0] != 7*time.Millisecond {
+		t.Fatalf("expected configured RetryPolicy's delay 7ms, got %s", durations[0])
+	}
+}
+
+func TestUploaderHonorsRetryAfterOverRetryPolicy(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	idx := newTestIndex(t)
+
+	record, err := idx.AddUpload(ctx, indexpkg.UploadRecord{
+		Path:   "objects/retry-after.bin",
+		Offset: 0,
+		Length: 512,
+		Status: indexpkg.UploadStatusQueued,
+	})
+	if err != nil {
+		t.Fatalf("AddUpload failed: %v", err)
+	}
+
+	retryErr := retryAfterError{temporaryError: temporaryError{err: errors.New("rate limited")}, after: 33 * time.Millisecond}
+	backend := &stubBackend{responses: []error{retryErr, nil}}
+	sleeper := &stubSleeper{}
+	provider := newStubChunkProvider(map[string][]byte{record.Path: bytes.Repeat([]byte{'d'}, 512)})
+
+	cfg := Config{
+		MaxConcurrentUploads: 1,
+		MaxAttempts:          3,
+		BaseRetryDelay:       10 * time.Millisecond,
+		MaxRetryDelay:        time.Second,
+		PollInterval:         5 * time.Millisecond,
+		RetryPolicy:          ExponentialPolicy{Base: 7 * time.Millisecond, Cap: 50 * time.Millisecond},
+	}
+
+	uploader, err := New(cfg, idx, backend, WithSleeper(sleeper), WithChunkProvider(provider))
+	if err != nil {
+		t.Fatalf("New uploader failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_ = uploader.Run(ctx)
+		close(done)
+	}()
+
+	waitForStatus(t, idx, record.ID, indexpkg.UploadStatusComplete, 800*time.Millisecond)
+	cancel()
+	<-done
+
+	durations := sleeper.Durations()
+	if len(durations) != 1 {
+		t.Fatalf("expected 1 sleep duration, got %d", len(durations))
+	}
+	if durations[0] != 33*time.Millisecond {
+		t.Fatalf("expected RetryAfter's delay 33ms to win over the configured RetryPolicy, got %s", durations[0])
+	}
+}
+
+type retryAfterError struct {
+	temporaryError
+	after time.Duration
+}
+
+func (e retryAfterError) RetryAfter() time.Duration { return e.after }
+
+func waitUntilTrue(t *testing.T, cond func() bool, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("condition not met before deadline")
+}
@@ -0,0 +1,99 @@
+package cache_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/valandreev/tigrisfs/pkg/cache"
+)
+
+const watcherTestConfig = `version: 1
+cache_size_gb: 10
+chunk_mb: 8
+clean_interval_min: 30
+upload:
+  connect_timeout_sec: 10
+  retry_interval_sec: 15
+  max_retry_sec: 300
+  max_concurrent_uploads: 4
+fail_safe:
+  enable: true
+  disk_min_free_percent: 10
+`
+
+func TestWatcherReloadPublishesNewConfigAndNotifiesSubscribers(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(watcherTestConfig), 0o600); err != nil {
+		t.Fatalf("seed config failed: %v", err)
+	}
+
+	var seenOld, seenNew *cache.Config
+	w, err := cache.NewWatcher(configPath, cache.WithOnReload(func(old, newCfg *cache.Config) {
+		seenOld, seenNew = old, newCfg
+	}))
+	if err != nil {
+		t.Fatalf("NewWatcher returned error: %v", err)
+	}
+
+	if got := w.Current().Upload.MaxConcurrentUploads; got != 4 {
+		t.Fatalf("expected initial max_concurrent_uploads 4, got %d", got)
+	}
+
+	updated := `version: 1
+cache_size_gb: 10
+chunk_mb: 8
+clean_interval_min: 5
+upload:
+  connect_timeout_sec: 10
+  retry_interval_sec: 15
+  max_retry_sec: 300
+  max_concurrent_uploads: 8
+fail_safe:
+  enable: true
+  disk_min_free_percent: 10
+`
+	if err := os.WriteFile(configPath, []byte(updated), 0o600); err != nil {
+		t.Fatalf("rewrite config failed: %v", err)
+	}
+
+	if _, err := w.Reload(); err != nil {
+		t.Fatalf("Reload returned error: %v", err)
+	}
+
+	if got := w.Current().Upload.MaxConcurrentUploads; got != 8 {
+		t.Fatalf("expected reloaded max_concurrent_uploads 8, got %d", got)
+	}
+	if seenOld == nil || seenOld.Upload.MaxConcurrentUploads != 4 {
+		t.Fatalf("expected subscriber to see previous config with max_concurrent_uploads 4, got %#v", seenOld)
+	}
+	if seenNew == nil || seenNew.Upload.MaxConcurrentUploads != 8 {
+		t.Fatalf("expected subscriber to see new config with max_concurrent_uploads 8, got %#v", seenNew)
+	}
+}
+
+func TestWatcherReloadKeepsPreviousConfigOnValidationFailure(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(watcherTestConfig), 0o600); err != nil {
+		t.Fatalf("seed config failed: %v", err)
+	}
+
+	w, err := cache.NewWatcher(configPath)
+	if err != nil {
+		t.Fatalf("NewWatcher returned error: %v", err)
+	}
+
+	if err := os.WriteFile(configPath, []byte("version: 1\ncache_size_gb: -1\n"), 0o600); err != nil {
+		t.Fatalf("rewrite config failed: %v", err)
+	}
+
+	if _, err := w.Reload(); err == nil {
+		t.Fatalf("expected Reload to fail on invalid config")
+	}
+
+	if got := w.Current().CacheSizeGB; got != 10 {
+		t.Fatalf("expected previous config to remain published, got cache_size_gb %d", got)
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+// +build !windows
+
+package cache
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// WatchSIGHUP reloads the config on every SIGHUP until ctx is cancelled.
+func (w *Watcher) WatchSIGHUP(ctx context.Context) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigCh:
+			if _, err := w.Reload(); err != nil {
+				w.logger.Warnf("cache config reload on SIGHUP failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,15 @@
+//go:build windows
+// +build windows
+
+package cache
+
+import "context"
+
+// WatchSIGHUP is a no-op on Windows, which has no SIGHUP equivalent; callers
+// on this platform should invoke Reload directly (e.g. from a service
+// control handler) instead. It blocks until ctx is cancelled so it can still
+// be used as a goroutine the same way as on other platforms.
+func (w *Watcher) WatchSIGHUP(ctx context.Context) {
+	w.logger.Warnf("cache config: SIGHUP reload is not available on windows; call Reload directly")
+	<-ctx.Done()
+}
@@ -0,0 +1,103 @@
+// Package client provides a thin gRPC client implementing uploader.Backend
+// by delegating to a remote node's UploaderService (see
+// core.NewUploaderServer), so one node's Uploader can hand chunk uploads
+// off to another node's backend instead of applying them locally.
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/valandreev/tigrisfs/core/pb"
+	"github.com/valandreev/tigrisfs/pkg/cache/index"
+	"github.com/valandreev/tigrisfs/pkg/cache/uploader"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// srcNodeIDMetadataKey and dstNodeIDMetadataKey mirror
+// core.SRC_NODE_ID_METADATA_KEY/DST_NODE_ID_METADATA_KEY so requests from
+// this client carry the same tracing metadata core's own cluster RPCs do.
+// They're duplicated as literals rather than imported so this package
+// doesn't have to pull in all of core (the FUSE mount stack) just to reuse
+// two constant strings.
+const (
+	srcNodeIDMetadataKey = "src-node-id"
+	dstNodeIDMetadataKey = "dst-node-id"
+)
+
+// Client implements uploader.Backend by delegating Upload to a remote
+// node's UploaderService.
+//
+// UploaderService has no RPC that streams a chunk's body, so Upload
+// doesn't ship data over the wire itself: it enqueues the record on the
+// remote node and then blocks on Watch until that node reports the
+// upload completed or failed. The remote node is expected to read the
+// chunk back from the same shared chunk store its own ChunkProvider
+// already reads from.
+type Client struct {
+	conn   *grpc.ClientConn
+	client pb.UploaderServiceClient
+	srcID  string
+	dstID  string
+}
+
+// Dial connects to a remote node's gRPC listener at addr and returns a
+// Client usable as an uploader.Backend. srcID/dstID populate the same
+// node-id metadata core's ConnPool attaches to cluster RPCs, so traces
+// through LogServerInterceptor show which node originated a remote
+// enqueue.
+func Dial(addr, srcID, dstID string, opts ...grpc.DialOption) (*Client, error) {
+	conn, err := grpc.Dial(addr, opts...) //nolint:staticcheck
+	if err != nil {
+		return nil, fmt.Errorf("uploader client: dial %s: %w", addr, err)
+	}
+	return &Client{
+		conn:   conn,
+		client: pb.NewUploaderServiceClient(conn),
+		srcID:  srcID,
+		dstID:  dstID,
+	}, nil
+}
+
+// Close releases the underlying gRPC connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) outgoingCtx(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, srcNodeIDMetadataKey, c.srcID, dstNodeIDMetadataKey, c.dstID)
+}
+
+// Upload implements uploader.Backend: it enqueues record on the remote
+// node and waits for its UploaderService to report the upload completed
+// or failed.
+func (c *Client) Upload(ctx context.Context, record index.UploadRecord, data uploader.ReadSeekCloser) error {
+	ack, err := c.client.Enqueue(c.outgoingCtx(ctx), &pb.UploadRequest{
+		Path:          record.Path,
+		Offset:        record.Offset,
+		Length:        record.Length,
+		KeyGeneration: record.KeyGeneration,
+		MaxAttempts:   int32(record.MaxAttempts),
+	})
+	if err != nil {
+		return fmt.Errorf("uploader client: enqueue %s: %w", record.Path, err)
+	}
+
+	stream, err := c.client.Watch(c.outgoingCtx(ctx), &pb.UploadID{Id: ack.Id})
+	if err != nil {
+		return fmt.Errorf("uploader client: watch %s: %w", ack.Id, err)
+	}
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			return fmt.Errorf("uploader client: watch %s: %w", ack.Id, err)
+		}
+		switch event.Event {
+		case "completed":
+			return nil
+		case "failed":
+			return fmt.Errorf("uploader client: remote upload %s failed: %s", ack.Id, event.Reason)
+		}
+	}
+}